@@ -0,0 +1,147 @@
+package api
+
+import (
+	"POINTSTOKEN/db"
+	"POINTSTOKEN/service"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type adjustmentRequest struct {
+	ChainID uint64 `json:"chain_id"`
+	Address string `json:"address"`
+	Action  string `json:"action"`
+	Amount  string `json:"amount"`
+	Reason  string `json:"reason"`
+	Key     string `json:"key"`
+}
+
+type adjustmentResponse struct {
+	ID             uint64    `json:"id"`
+	ChainID        uint64    `json:"chain_id"`
+	Action         string    `json:"action"`
+	Address        string    `json:"address"`
+	Amount         string    `json:"amount"`
+	Reason         string    `json:"reason"`
+	Operator       string    `json:"operator"`
+	IdempotencyKey string    `json:"idempotency_key"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func newAdjustmentResponse(entry *db.LedgerEntry) adjustmentResponse {
+	address := entry.CreditAccount
+	if entry.EntryType == db.LedgerClawback {
+		address = entry.DebitAccount
+	}
+	return adjustmentResponse{
+		ID:             entry.ID,
+		ChainID:        entry.ChainID,
+		Action:         entry.EntryType,
+		Address:        address,
+		Amount:         entry.Amount.ToBigInt().String(),
+		Reason:         entry.Memo,
+		Operator:       entry.Operator,
+		IdempotencyKey: entry.IdempotencyKey,
+		CreatedAt:      entry.CreatedAt,
+	}
+}
+
+// handleAdjustPoints 为地址发放或扣回积分
+func (s *Server) handleAdjustPoints(w http.ResponseWriter, r *http.Request) {
+	var req adjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "无效的请求体")
+		return
+	}
+	userAddr, ok := parseAddress(req.Address)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的地址")
+		return
+	}
+	amount, ok := new(big.Int).SetString(req.Amount, 10)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的积分数量")
+		return
+	}
+
+	entry, err := s.adjustment.Adjust(service.PointsAdjustment{
+		ChainID:  req.ChainID,
+		UserAddr: userAddr,
+		Action:   req.Action,
+		Amount:   amount,
+		Reason:   req.Reason,
+		Key:      req.Key,
+	}, operatorFrom(r))
+	if errors.Is(err, db.ErrInsufficientPoints) || errors.Is(err, db.ErrIdempotencyConflict) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, newAdjustmentResponse(entry))
+}
+
+// handleImportAdjustments 从 CSV 请求体批量发放或扣回积分，逐条返回结果
+func (s *Server) handleImportAdjustments(w http.ResponseWriter, r *http.Request) {
+	adjs, err := service.ParseAdjustmentsCSV(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	results := s.adjustment.Import(adjs, operatorFrom(r))
+
+	failed := 0
+	resp := make([]map[string]interface{}, 0, len(results))
+	for _, result := range results {
+		item := map[string]interface{}{"line": result.Line}
+		if result.Err != nil {
+			failed++
+			item["error"] = result.Err.Error()
+		} else {
+			item["entry"] = newAdjustmentResponse(result.Entry)
+		}
+		resp = append(resp, item)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total":   len(results),
+		"failed":  failed,
+		"results": resp,
+	})
+}
+
+// handleGetAdjustments 获取最近的人工调整记录
+func (s *Server) handleGetAdjustments(w http.ResponseWriter, r *http.Request) {
+	var chainID uint64
+	if v := r.URL.Query().Get("chain_id"); v != "" {
+		var err error
+		if chainID, err = strconv.ParseUint(v, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "无效的链ID")
+			return
+		}
+	}
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 1000 {
+			writeError(w, http.StatusBadRequest, "无效的 limit")
+			return
+		}
+		limit = n
+	}
+	entries, err := s.adjustment.GetAdjustments(chainID, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp := make([]adjustmentResponse, 0, len(entries))
+	for i := range entries {
+		resp = append(resp, newAdjustmentResponse(&entries[i]))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"adjustments": resp})
+}
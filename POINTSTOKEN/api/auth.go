@@ -0,0 +1,309 @@
+package api
+
+import (
+	"POINTSTOKEN/db"
+	"POINTSTOKEN/service"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type principalKey struct{}
+
+// principal 请求的认证身份：配置的管理员、API 密钥或钱包登录会话
+type principal struct {
+	operator string
+	admin    bool
+	key      *db.APIKey
+	session  *db.AuthSession
+}
+
+// canRead 是否可以查询任意用户的数据
+func (p *principal) canRead() bool {
+	return p.admin || (p.key != nil && p.key.HasScope(db.ScopeRead))
+}
+
+// bearerToken 读取 Authorization: Bearer <token>，WebSocket 连接无法设置请求头时使用 access_token 参数
+func bearerToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	return r.URL.Query().Get("access_token")
+}
+
+// authenticate 识别请求的认证身份，未携带令牌时返回 nil
+func (s *Server) authenticate(r *http.Request) (*principal, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, nil
+	}
+	switch {
+	case strings.HasPrefix(token, service.APIKeyPrefix):
+		key, err := s.auth.AuthenticateAPIKey(token)
+		if err != nil {
+			return nil, err
+		}
+		return &principal{operator: "apikey:" + key.Name, admin: key.HasScope(db.ScopeAdmin), key: key}, nil
+	case strings.HasPrefix(token, service.SessionPrefix):
+		session, err := s.auth.AuthenticateSession(token)
+		if err != nil {
+			return nil, err
+		}
+		return &principal{operator: session.UserAddr.Hex(), session: session}, nil
+	}
+	for _, admin := range s.cfg.Admins {
+		if admin.Token != "" && subtle.ConstantTimeCompare([]byte(admin.Token), []byte(token)) == 1 {
+			return &principal{operator: admin.Name, admin: true}, nil
+		}
+	}
+	return nil, service.ErrUnauthorized
+}
+
+// withPrincipal 认证请求并将身份写入请求上下文，check 返回 false 时拒绝请求
+func (s *Server) withPrincipal(next http.HandlerFunc, check func(*principal, *http.Request) (bool, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, err := s.authenticate(r)
+		if errors.Is(err, service.ErrUnauthorized) {
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if err != nil {
+			log.Printf("认证请求失败: %v", err)
+			writeError(w, http.StatusInternalServerError, "认证失败")
+			return
+		}
+		if p == nil {
+			writeError(w, http.StatusUnauthorized, "缺少认证令牌")
+			return
+		}
+		ok, err := check(p, r)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !ok {
+			writeError(w, http.StatusForbidden, "无权访问")
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+	}
+}
+
+// requireAdmin 要求配置的管理令牌或具有 admin 权限的 API 密钥
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return s.withPrincipal(next, func(p *principal, r *http.Request) (bool, error) {
+		return p.admin, nil
+	})
+}
+
+// requireRead 要求具有 read 权限的 API 密钥
+func (s *Server) requireRead(next http.HandlerFunc) http.HandlerFunc {
+	return s.withPrincipal(next, func(p *principal, r *http.Request) (bool, error) {
+		return p.canRead(), nil
+	})
+}
+
+// requireUser 查询路径中 {address} 的数据：API 密钥需具有 read 权限，会话只能查询自己关联的地址
+func (s *Server) requireUser(next http.HandlerFunc) http.HandlerFunc {
+	return s.withPrincipal(next, func(p *principal, r *http.Request) (bool, error) {
+		if p.canRead() {
+			return true, nil
+		}
+		userAddr, ok := parseAddress(r.PathValue("address"))
+		if !ok {
+			//地址无效时交给处理函数返回 400
+			return true, nil
+		}
+		return s.ownsAddresses(p, []common.Address{userAddr})
+	})
+}
+
+// requireQueryUser 查询参数 address 指定用户的数据：未指定地址时要求 read 权限，会话只能查询自己关联的地址
+func (s *Server) requireQueryUser(next http.HandlerFunc) http.HandlerFunc {
+	return s.withPrincipal(next, func(p *principal, r *http.Request) (bool, error) {
+		if p.canRead() {
+			return true, nil
+		}
+		value := r.URL.Query().Get("address")
+		if value == "" {
+			return false, nil
+		}
+		userAddr, ok := parseAddress(value)
+		if !ok {
+			//地址无效时交给处理函数返回 400
+			return true, nil
+		}
+		return s.ownsAddresses(p, []common.Address{userAddr})
+	})
+}
+
+// ownsAddresses 判断会话是否可以查询全部地址
+func (s *Server) ownsAddresses(p *principal, addrs []common.Address) (bool, error) {
+	if p.session == nil || len(addrs) == 0 {
+		return false, nil
+	}
+	owned, err := s.identity.GetLinkedAddresses(p.session.UserAddr)
+	if err != nil {
+		return false, err
+	}
+	for _, addr := range addrs {
+		found := false
+		for _, o := range owned {
+			if o == addr {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// operatorFrom 获取请求的操作人
+func operatorFrom(r *http.Request) string {
+	if p, ok := r.Context().Value(principalKey{}).(*principal); ok {
+		return p.operator
+	}
+	return ""
+}
+
+type siweRequest struct {
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+}
+
+// handleSIWENonce 获取钱包登录随机数
+func (s *Server) handleSIWENonce(w http.ResponseWriter, r *http.Request) {
+	nonce, err := s.auth.NewNonce()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"nonce":      nonce,
+		"domain":     s.cfg.Auth.Domain,
+		"expires_at": time.Now().Add(s.cfg.Auth.NonceTTL),
+	})
+}
+
+// handleSIWELogin 校验 EIP-4361 登录消息签名，签发会话令牌
+func (s *Server) handleSIWELogin(w http.ResponseWriter, r *http.Request) {
+	var req siweRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "无效的请求体")
+		return
+	}
+	token, session, err := s.auth.SignIn(req.Message, req.Signature)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"token":      token,
+		"address":    session.UserAddr.Hex(),
+		"expires_at": session.ExpiresAt,
+	})
+}
+
+// handleSIWELogout 注销当前会话
+func (s *Server) handleSIWELogout(w http.ResponseWriter, r *http.Request) {
+	token := bearerToken(r)
+	if !strings.HasPrefix(token, service.SessionPrefix) {
+		writeError(w, http.StatusBadRequest, "缺少会话令牌")
+		return
+	}
+	if err := s.auth.SignOut(token); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type apiKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type apiKeyResponse struct {
+	ID         uint64     `json:"id"`
+	Name       string     `json:"name"`
+	Key        string     `json:"key,omitempty"` //明文只在创建时返回
+	KeyPrefix  string     `json:"key_prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+func newAPIKeyResponse(key *db.APIKey) apiKeyResponse {
+	return apiKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		KeyPrefix:  key.KeyPrefix,
+		Scopes:     key.Scopes,
+		CreatedBy:  key.CreatedBy,
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
+
+// handleCreateAPIKey 创建 API 密钥
+func (s *Server) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req apiKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "无效的请求体")
+		return
+	}
+	token, key, err := s.auth.CreateAPIKey(req.Name, req.Scopes, operatorFrom(r))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	resp := newAPIKeyResponse(key)
+	resp.Key = token
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// handleGetAPIKeys 获取全部 API 密钥
+func (s *Server) handleGetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := s.auth.GetAPIKeys()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp := make([]apiKeyResponse, 0, len(keys))
+	for i := range keys {
+		resp = append(resp, newAPIKeyResponse(&keys[i]))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"api_keys": resp})
+}
+
+// handleRevokeAPIKey 吊销 API 密钥
+func (s *Server) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "无效的密钥ID")
+		return
+	}
+	found, err := s.auth.RevokeAPIKey(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "密钥不存在或已吊销")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
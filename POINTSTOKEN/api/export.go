@@ -0,0 +1,53 @@
+package api
+
+import (
+	"POINTSTOKEN/service"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// handleExport 导出余额、余额变动或积分分录
+// 参数: chain_id、from、to（RFC3339，余额导出不需要）、format（csv|parquet，默认 csv）
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := service.ExportRequest{Dataset: r.PathValue("dataset"), Format: query.Get("format")}
+	if req.Format == "" {
+		req.Format = service.ExportCSV
+	}
+	var err error
+	if req.ChainID, err = strconv.ParseUint(query.Get("chain_id"), 10, 64); err != nil {
+		writeError(w, http.StatusBadRequest, "无效的链ID")
+		return
+	}
+	if value := query.Get("from"); value != "" {
+		if req.From, err = time.Parse(time.RFC3339, value); err != nil {
+			writeError(w, http.StatusBadRequest, "无效的 from")
+			return
+		}
+	}
+	if value := query.Get("to"); value != "" {
+		if req.To, err = time.Parse(time.RFC3339, value); err != nil {
+			writeError(w, http.StatusBadRequest, "无效的 to")
+			return
+		}
+	}
+	if err = req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Format == service.ExportParquet {
+		w.Header().Set("Content-Type", "application/vnd.apache.parquet")
+	} else {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, req.FileName()))
+	// 开始写出后无法再返回错误状态，失败时只记录日志，客户端会收到不完整的文件
+	rows, err := s.export.Export(w, req)
+	if err != nil {
+		log.Printf("导出 %s 失败（已写出 %d 行）: %v", req.FileName(), rows, err)
+	}
+}
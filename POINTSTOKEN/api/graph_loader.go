@@ -0,0 +1,207 @@
+package api
+
+import (
+	"POINTSTOKEN/db"
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"sync"
+)
+
+// userSet 本次请求中出现过的地址，列表解析时先登记，加载时一次查询全部已登记的地址
+type userSet struct {
+	mu    sync.Mutex
+	addrs []common.Address
+	seen  map[common.Address]bool
+}
+
+func (s *userSet) add(addrs ...common.Address) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, addr := range addrs {
+		if !s.seen[addr] {
+			s.seen[addr] = true
+			s.addrs = append(s.addrs, addr)
+		}
+	}
+}
+
+func (s *userSet) list() []common.Address {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]common.Address(nil), s.addrs...)
+}
+
+// userLoader 按地址批量加载数据，首次读取某个地址时一并加载所有已登记但尚未加载的地址，
+// 同一请求内并发解析的字段因此只产生一次查询
+type userLoader[V any] struct {
+	mu     sync.Mutex
+	users  *userSet
+	fetch  func(userAddrs []common.Address) (map[common.Address]V, error)
+	loaded map[common.Address]V
+}
+
+func newUserLoader[V any](users *userSet, fetch func([]common.Address) (map[common.Address]V, error)) *userLoader[V] {
+	return &userLoader[V]{users: users, fetch: fetch, loaded: make(map[common.Address]V)}
+}
+
+func (l *userLoader[V]) load(userAddr common.Address) (V, error) {
+	l.users.add(userAddr)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if v, ok := l.loaded[userAddr]; ok {
+		return v, nil
+	}
+	var pending []common.Address
+	for _, addr := range l.users.list() {
+		if _, ok := l.loaded[addr]; !ok {
+			pending = append(pending, addr)
+		}
+	}
+	result, err := l.fetch(pending)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	for _, addr := range pending {
+		l.loaded[addr] = result[addr]
+	}
+	return l.loaded[userAddr], nil
+}
+
+// graphLoaders 单次 GraphQL 请求的批量加载器，请求结束后丢弃
+type graphLoaders struct {
+	repo     db.Repository
+	users    *userSet
+	balances *userLoader[map[uint64]db.UserBalance]
+	points   *userLoader[map[uint64]db.UserPoints]
+	tiers    *userLoader[map[uint64]db.UserTier]
+
+	chainsOnce sync.Once
+	chains     []db.ChainInfo
+	chainsErr  error
+
+	mu           sync.Mutex
+	changes      map[int]*userLoader[map[uint64][]db.UserBalanceChange] //按条数区分
+	calculations map[int]*userLoader[map[uint64][]db.PointsCalculation]
+}
+
+func newGraphLoaders(repo db.Repository) *graphLoaders {
+	users := &userSet{seen: make(map[common.Address]bool)}
+	return &graphLoaders{
+		repo:  repo,
+		users: users,
+		balances: newUserLoader(users, func(userAddrs []common.Address) (map[common.Address]map[uint64]db.UserBalance, error) {
+			balances, err := repo.GetUserBalancesAllChains(userAddrs)
+			if err != nil {
+				return nil, err
+			}
+			result := make(map[common.Address]map[uint64]db.UserBalance)
+			for _, ub := range balances {
+				if result[ub.UserAddr] == nil {
+					result[ub.UserAddr] = make(map[uint64]db.UserBalance)
+				}
+				result[ub.UserAddr][ub.ChainID] = ub
+			}
+			return result, nil
+		}),
+		points: newUserLoader(users, func(userAddrs []common.Address) (map[common.Address]map[uint64]db.UserPoints, error) {
+			points, err := repo.GetUserPointsAllChains(userAddrs)
+			if err != nil {
+				return nil, err
+			}
+			result := make(map[common.Address]map[uint64]db.UserPoints)
+			for _, up := range points {
+				if result[up.UserAddr] == nil {
+					result[up.UserAddr] = make(map[uint64]db.UserPoints)
+				}
+				result[up.UserAddr][up.ChainID] = up
+			}
+			return result, nil
+		}),
+		tiers: newUserLoader(users, func(userAddrs []common.Address) (map[common.Address]map[uint64]db.UserTier, error) {
+			tiers, err := repo.GetUserTiersAllChains(userAddrs)
+			if err != nil {
+				return nil, err
+			}
+			result := make(map[common.Address]map[uint64]db.UserTier)
+			for _, ut := range tiers {
+				if result[ut.UserAddr] == nil {
+					result[ut.UserAddr] = make(map[uint64]db.UserTier)
+				}
+				result[ut.UserAddr][ut.ChainID] = ut
+			}
+			return result, nil
+		}),
+		changes:      make(map[int]*userLoader[map[uint64][]db.UserBalanceChange]),
+		calculations: make(map[int]*userLoader[map[uint64][]db.PointsCalculation]),
+	}
+}
+
+// chainInfos 获取链信息，每个请求只查询一次
+func (g *graphLoaders) chainInfos() ([]db.ChainInfo, error) {
+	g.chainsOnce.Do(func() {
+		g.chains, g.chainsErr = g.repo.GetChainInfos()
+	})
+	return g.chains, g.chainsErr
+}
+
+// recentChanges 获取按条数区分的最近余额变动加载器
+func (g *graphLoaders) recentChanges(limit int) *userLoader[map[uint64][]db.UserBalanceChange] {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if loader, ok := g.changes[limit]; ok {
+		return loader
+	}
+	loader := newUserLoader(g.users, func(userAddrs []common.Address) (map[common.Address]map[uint64][]db.UserBalanceChange, error) {
+		changes, err := g.repo.GetRecentBalanceChanges(userAddrs, limit)
+		if err != nil {
+			return nil, err
+		}
+		result := make(map[common.Address]map[uint64][]db.UserBalanceChange)
+		for _, ubc := range changes {
+			if result[ubc.UserAddr] == nil {
+				result[ubc.UserAddr] = make(map[uint64][]db.UserBalanceChange)
+			}
+			result[ubc.UserAddr][ubc.ChainID] = append(result[ubc.UserAddr][ubc.ChainID], ubc)
+		}
+		return result, nil
+	})
+	g.changes[limit] = loader
+	return loader
+}
+
+// recentCalculations 获取按条数区分的最近积分计算记录加载器
+func (g *graphLoaders) recentCalculations(limit int) *userLoader[map[uint64][]db.PointsCalculation] {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if loader, ok := g.calculations[limit]; ok {
+		return loader
+	}
+	loader := newUserLoader(g.users, func(userAddrs []common.Address) (map[common.Address]map[uint64][]db.PointsCalculation, error) {
+		calculations, err := g.repo.GetRecentPointsCalculations(userAddrs, limit)
+		if err != nil {
+			return nil, err
+		}
+		result := make(map[common.Address]map[uint64][]db.PointsCalculation)
+		for _, pc := range calculations {
+			if result[pc.UserAddr] == nil {
+				result[pc.UserAddr] = make(map[uint64][]db.PointsCalculation)
+			}
+			result[pc.UserAddr][pc.ChainID] = append(result[pc.UserAddr][pc.ChainID], pc)
+		}
+		return result, nil
+	})
+	g.calculations[limit] = loader
+	return loader
+}
+
+type graphLoadersKey struct{}
+
+func withGraphLoaders(ctx context.Context, loaders *graphLoaders) context.Context {
+	return context.WithValue(ctx, graphLoadersKey{}, loaders)
+}
+
+func graphLoadersFrom(ctx context.Context) *graphLoaders {
+	loaders, _ := ctx.Value(graphLoadersKey{}).(*graphLoaders)
+	return loaders
+}
@@ -0,0 +1,564 @@
+package api
+
+import (
+	"POINTSTOKEN/db"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/graph-gophers/graphql-go"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// graphSchema GraphQL 查询结构，金额、积分、区块号以十进制字符串表示，时间为 RFC3339
+const graphSchema = `
+schema {
+	query: Query
+}
+
+type Query {
+	chains: [Chain!]!
+	user(address: String!): User!
+	users(addresses: [String!]!): [User!]!
+	leaderboard(window: String, chainId: Int, date: String, campaign: String, offset: Int = 0, limit: Int = 20): Leaderboard!
+	balanceChanges(chainId: Int!, address: String!, first: Int = 50, after: String,
+		fromTime: String, toTime: String, fromBlock: String, toBlock: String): BalanceChangePage!
+	pointsCalculations(chainId: Int!, address: String!, first: Int = 50, after: String,
+		fromTime: String, toTime: String): PointsCalculationPage!
+}
+
+type Chain {
+	chainId: Int!
+	name: String!
+	contractAddr: String!
+	lastProcessedBlock: String!
+}
+
+type User {
+	address: String!
+	totalPoints: String!
+	chains: [UserChain!]!
+	chain(chainId: Int!): UserChain
+}
+
+type UserChain {
+	chain: Chain!
+	balance: String!
+	points: String!
+	tier: String!
+	tierSince: String
+	recentBalanceChanges(first: Int = 10): [BalanceChange!]!
+	recentPointsCalculations(first: Int = 10): [PointsCalculation!]!
+}
+
+type BalanceChange {
+	id: ID!
+	chainId: Int!
+	address: String!
+	txHash: String!
+	blockNumber: String!
+	changeAmount: String!
+	balanceAfter: String!
+	eventType: String!
+	createdAt: String!
+}
+
+type PointsCalculation {
+	id: ID!
+	chainId: Int!
+	address: String!
+	calculatedAt: String!
+	balance: String!
+	pointsAdded: String!
+	totalPointsAfter: String!
+}
+
+type BalanceChangePage {
+	items: [BalanceChange!]!
+	nextCursor: String!
+}
+
+type PointsCalculationPage {
+	items: [PointsCalculation!]!
+	nextCursor: String!
+}
+
+type Leaderboard {
+	board: String!
+	entries: [LeaderboardEntry!]!
+}
+
+type LeaderboardEntry {
+	rank: Int!
+	score: String!
+	user: User!
+}
+`
+
+// graphMaxRecent 嵌套查询最近记录的最大条数
+const graphMaxRecent = 100
+
+// newGraphSchema 解析 GraphQL 查询结构，结构与解析器不匹配时 panic
+func newGraphSchema(s *Server) *graphql.Schema {
+	return graphql.MustParseSchema(graphSchema, &graphResolver{s: s},
+		graphql.MaxDepth(8),
+		graphql.MaxParallelism(32))
+}
+
+type graphRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// handleGraphQL 执行 GraphQL 查询，每个请求使用独立的批量加载器
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "无效的请求体")
+		return
+	}
+	ctx := withGraphLoaders(r.Context(), newGraphLoaders(s.repository))
+	resp := s.graphSchema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parseGraphAddress 解析地址参数
+func parseGraphAddress(value string) (common.Address, error) {
+	userAddr, ok := parseAddress(value)
+	if !ok {
+		return common.Address{}, fmt.Errorf("无效的地址: %s", value)
+	}
+	return userAddr, nil
+}
+
+// recentLimit 校验嵌套查询的条数
+func recentLimit(first int32) (int, error) {
+	if first < 1 || first > graphMaxRecent {
+		return 0, fmt.Errorf("first 须在 1 到 %d 之间", graphMaxRecent)
+	}
+	return int(first), nil
+}
+
+// parseGraphTime 解析可选的 RFC3339 时间参数
+func parseGraphTime(name string, value *string) (time.Time, error) {
+	if value == nil || *value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("无效的 %s", name)
+	}
+	return t, nil
+}
+
+// parseGraphUint 解析可选的十进制整数参数
+func parseGraphUint(name string, value *string) (uint64, error) {
+	if value == nil || *value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(*value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("无效的 %s", name)
+	}
+	return n, nil
+}
+
+// graphPage 解析分页参数，条数限制与 REST 接口一致
+func graphPage(first int32, after *string) (db.HistoryFilter, error) {
+	if first < 1 || first > 500 {
+		return db.HistoryFilter{}, errors.New("first 须在 1 到 500 之间")
+	}
+	cursor, err := parseGraphUint("after", after)
+	if err != nil {
+		return db.HistoryFilter{}, err
+	}
+	return db.HistoryFilter{Cursor: cursor, Limit: int(first)}, nil
+}
+
+type graphResolver struct {
+	s *Server
+}
+
+func (r *graphResolver) Chains(ctx context.Context) ([]*chainResolver, error) {
+	chains, err := graphLoadersFrom(ctx).chainInfos()
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*chainResolver, 0, len(chains))
+	for _, c := range chains {
+		resolvers = append(resolvers, &chainResolver{c})
+	}
+	return resolvers, nil
+}
+
+func (r *graphResolver) User(ctx context.Context, args struct{ Address string }) (*userResolver, error) {
+	userAddr, err := parseGraphAddress(args.Address)
+	if err != nil {
+		return nil, err
+	}
+	return r.newUsers(ctx, []common.Address{userAddr})[0], nil
+}
+
+func (r *graphResolver) Users(ctx context.Context, args struct{ Addresses []string }) ([]*userResolver, error) {
+	if len(args.Addresses) > 500 {
+		return nil, errors.New("地址数量不能超过 500")
+	}
+	userAddrs := make([]common.Address, 0, len(args.Addresses))
+	for _, value := range args.Addresses {
+		userAddr, err := parseGraphAddress(value)
+		if err != nil {
+			return nil, err
+		}
+		userAddrs = append(userAddrs, userAddr)
+	}
+	return r.newUsers(ctx, userAddrs), nil
+}
+
+// newUsers 登记地址到批量加载器并创建用户解析器
+func (r *graphResolver) newUsers(ctx context.Context, userAddrs []common.Address) []*userResolver {
+	loaders := graphLoadersFrom(ctx)
+	loaders.users.add(userAddrs...)
+	resolvers := make([]*userResolver, 0, len(userAddrs))
+	for _, userAddr := range userAddrs {
+		resolvers = append(resolvers, &userResolver{s: r.s, l: loaders, addr: userAddr})
+	}
+	return resolvers
+}
+
+func (r *graphResolver) Leaderboard(ctx context.Context, args struct {
+	Window   *string
+	ChainID  *int32
+	Date     *string
+	Campaign *string
+	Offset   int32
+	Limit    int32
+}) (*leaderboardResolver, error) {
+	var window, campaign string
+	if args.Window != nil {
+		window = *args.Window
+	}
+	if args.Campaign != nil {
+		campaign = *args.Campaign
+	}
+	var chainID uint64
+	if args.ChainID != nil {
+		if *args.ChainID < 0 {
+			return nil, errors.New("无效的链ID")
+		}
+		chainID = uint64(*args.ChainID)
+	}
+	at, err := parseGraphTime("date", args.Date)
+	if err != nil {
+		return nil, err
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	offset, limit := int(args.Offset), int(args.Limit)
+	if offset < 0 || limit < 1 || limit > 100 {
+		return nil, errors.New("无效的 offset 或 limit")
+	}
+
+	board, entries, err := r.s.leaderboard.Top(window, chainID, at, campaign, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	userAddrs := make([]common.Address, 0, len(entries))
+	for _, entry := range entries {
+		userAddrs = append(userAddrs, entry.UserAddr)
+	}
+	users := r.newUsers(ctx, userAddrs)
+	resolvers := make([]*leaderboardEntryResolver, 0, len(entries))
+	for i, entry := range entries {
+		resolvers = append(resolvers, &leaderboardEntryResolver{entry: entry, user: users[i]})
+	}
+	return &leaderboardResolver{board: board, entries: resolvers}, nil
+}
+
+func (r *graphResolver) BalanceChanges(args struct {
+	ChainID   int32
+	Address   string
+	First     int32
+	After     *string
+	FromTime  *string
+	ToTime    *string
+	FromBlock *string
+	ToBlock   *string
+}) (*balanceChangePageResolver, error) {
+	userAddr, err := parseGraphAddress(args.Address)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := graphPage(args.First, args.After)
+	if err != nil {
+		return nil, err
+	}
+	if filter.FromTime, err = parseGraphTime("fromTime", args.FromTime); err != nil {
+		return nil, err
+	}
+	if filter.ToTime, err = parseGraphTime("toTime", args.ToTime); err != nil {
+		return nil, err
+	}
+	if filter.FromBlock, err = parseGraphUint("fromBlock", args.FromBlock); err != nil {
+		return nil, err
+	}
+	if filter.ToBlock, err = parseGraphUint("toBlock", args.ToBlock); err != nil {
+		return nil, err
+	}
+
+	changes, err := r.s.repository.GetBalanceHistory(uint64(args.ChainID), userAddr, filter)
+	if err != nil {
+		return nil, err
+	}
+	page := &balanceChangePageResolver{}
+	for _, c := range changes {
+		page.items = append(page.items, &balanceChangeResolver{c})
+	}
+	if len(changes) == filter.Limit {
+		page.nextCursor = strconv.FormatUint(changes[len(changes)-1].ID, 10)
+	}
+	return page, nil
+}
+
+func (r *graphResolver) PointsCalculations(args struct {
+	ChainID  int32
+	Address  string
+	First    int32
+	After    *string
+	FromTime *string
+	ToTime   *string
+}) (*pointsCalculationPageResolver, error) {
+	userAddr, err := parseGraphAddress(args.Address)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := graphPage(args.First, args.After)
+	if err != nil {
+		return nil, err
+	}
+	if filter.FromTime, err = parseGraphTime("fromTime", args.FromTime); err != nil {
+		return nil, err
+	}
+	if filter.ToTime, err = parseGraphTime("toTime", args.ToTime); err != nil {
+		return nil, err
+	}
+
+	calculations, err := r.s.repository.GetPointsCalculations(uint64(args.ChainID), userAddr, filter)
+	if err != nil {
+		return nil, err
+	}
+	page := &pointsCalculationPageResolver{}
+	for _, c := range calculations {
+		page.items = append(page.items, &pointsCalculationResolver{c})
+	}
+	if len(calculations) == filter.Limit {
+		page.nextCursor = strconv.FormatUint(calculations[len(calculations)-1].ID, 10)
+	}
+	return page, nil
+}
+
+type chainResolver struct {
+	c db.ChainInfo
+}
+
+func (r *chainResolver) ChainID() int32       { return int32(r.c.ChainID) }
+func (r *chainResolver) Name() string         { return r.c.Name }
+func (r *chainResolver) ContractAddr() string { return r.c.ContractAddr }
+func (r *chainResolver) LastProcessedBlock() string {
+	return strconv.FormatUint(r.c.LastProcessedBlock, 10)
+}
+
+type userResolver struct {
+	s    *Server
+	l    *graphLoaders
+	addr common.Address
+}
+
+func (r *userResolver) Address() string { return r.addr.Hex() }
+
+// TotalPoints 按链权重加权后的跨链总积分，不包括关联地址
+func (r *userResolver) TotalPoints(ctx context.Context) (string, error) {
+	points, err := r.l.points.load(r.addr)
+	if err != nil {
+		return "", err
+	}
+	userPoints := make([]db.UserPoints, 0, len(points))
+	for _, up := range points {
+		userPoints = append(userPoints, up)
+	}
+	return r.s.aggregator.WeightedTotal(userPoints).String(), nil
+}
+
+func (r *userResolver) Chains(ctx context.Context) ([]*userChainResolver, error) {
+	chains, err := r.l.chainInfos()
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*userChainResolver, 0, len(chains))
+	for _, c := range chains {
+		resolvers = append(resolvers, &userChainResolver{l: r.l, chain: c, addr: r.addr})
+	}
+	return resolvers, nil
+}
+
+func (r *userResolver) Chain(ctx context.Context, args struct{ ChainID int32 }) (*userChainResolver, error) {
+	chains, err := r.l.chainInfos()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range chains {
+		if c.ChainID == uint64(args.ChainID) {
+			return &userChainResolver{l: r.l, chain: c, addr: r.addr}, nil
+		}
+	}
+	return nil, nil
+}
+
+type userChainResolver struct {
+	l     *graphLoaders
+	chain db.ChainInfo
+	addr  common.Address
+}
+
+func (r *userChainResolver) Chain() *chainResolver { return &chainResolver{r.chain} }
+
+func (r *userChainResolver) Balance(ctx context.Context) (string, error) {
+	balances, err := r.l.balances.load(r.addr)
+	if err != nil {
+		return "", err
+	}
+	ub := balances[r.chain.ChainID]
+	return ub.Balance.ToBigInt().String(), nil
+}
+
+func (r *userChainResolver) Points(ctx context.Context) (string, error) {
+	points, err := r.l.points.load(r.addr)
+	if err != nil {
+		return "", err
+	}
+	up := points[r.chain.ChainID]
+	return up.TotalPoints.ToBigInt().String(), nil
+}
+
+// Tier 当前会员等级，未达到任何等级时为空字符串
+func (r *userChainResolver) Tier(ctx context.Context) (string, error) {
+	tiers, err := r.l.tiers.load(r.addr)
+	if err != nil {
+		return "", err
+	}
+	return tiers[r.chain.ChainID].Tier, nil
+}
+
+func (r *userChainResolver) TierSince(ctx context.Context) (*string, error) {
+	tiers, err := r.l.tiers.load(r.addr)
+	if err != nil {
+		return nil, err
+	}
+	tier, ok := tiers[r.chain.ChainID]
+	if !ok || tier.Tier == "" {
+		return nil, nil
+	}
+	since := tier.Since.Format(time.RFC3339)
+	return &since, nil
+}
+
+func (r *userChainResolver) RecentBalanceChanges(ctx context.Context, args struct{ First int32 }) ([]*balanceChangeResolver, error) {
+	limit, err := recentLimit(args.First)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := r.l.recentChanges(limit).load(r.addr)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*balanceChangeResolver, 0, len(changes[r.chain.ChainID]))
+	for _, c := range changes[r.chain.ChainID] {
+		resolvers = append(resolvers, &balanceChangeResolver{c})
+	}
+	return resolvers, nil
+}
+
+func (r *userChainResolver) RecentPointsCalculations(ctx context.Context, args struct{ First int32 }) ([]*pointsCalculationResolver, error) {
+	limit, err := recentLimit(args.First)
+	if err != nil {
+		return nil, err
+	}
+	calculations, err := r.l.recentCalculations(limit).load(r.addr)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*pointsCalculationResolver, 0, len(calculations[r.chain.ChainID]))
+	for _, c := range calculations[r.chain.ChainID] {
+		resolvers = append(resolvers, &pointsCalculationResolver{c})
+	}
+	return resolvers, nil
+}
+
+type balanceChangeResolver struct {
+	c db.UserBalanceChange
+}
+
+func (r *balanceChangeResolver) ID() graphql.ID       { return graphql.ID(strconv.FormatUint(r.c.ID, 10)) }
+func (r *balanceChangeResolver) ChainID() int32       { return int32(r.c.ChainID) }
+func (r *balanceChangeResolver) Address() string      { return r.c.UserAddr.Hex() }
+func (r *balanceChangeResolver) TxHash() string       { return r.c.TxHash.Hex() }
+func (r *balanceChangeResolver) BlockNumber() string  { return strconv.FormatUint(r.c.BlockNumber, 10) }
+func (r *balanceChangeResolver) ChangeAmount() string { return r.c.BalanceChange.ToBigInt().String() }
+func (r *balanceChangeResolver) BalanceAfter() string { return r.c.BalanceAfter.ToBigInt().String() }
+func (r *balanceChangeResolver) EventType() string    { return r.c.EventType }
+func (r *balanceChangeResolver) CreatedAt() string    { return r.c.CreatedAt.Format(time.RFC3339) }
+
+type pointsCalculationResolver struct {
+	c db.PointsCalculation
+}
+
+func (r *pointsCalculationResolver) ID() graphql.ID {
+	return graphql.ID(strconv.FormatUint(r.c.ID, 10))
+}
+func (r *pointsCalculationResolver) ChainID() int32  { return int32(r.c.ChainID) }
+func (r *pointsCalculationResolver) Address() string { return r.c.UserAddr.Hex() }
+func (r *pointsCalculationResolver) CalculatedAt() string {
+	return r.c.CalculatedAt.Format(time.RFC3339)
+}
+func (r *pointsCalculationResolver) Balance() string     { return r.c.Balance.ToBigInt().String() }
+func (r *pointsCalculationResolver) PointsAdded() string { return r.c.PointsAdded.ToBigInt().String() }
+func (r *pointsCalculationResolver) TotalPointsAfter() string {
+	return r.c.TotalPointsAfter.ToBigInt().String()
+}
+
+type balanceChangePageResolver struct {
+	items      []*balanceChangeResolver
+	nextCursor string
+}
+
+func (r *balanceChangePageResolver) Items() []*balanceChangeResolver { return r.items }
+func (r *balanceChangePageResolver) NextCursor() string              { return r.nextCursor }
+
+type pointsCalculationPageResolver struct {
+	items      []*pointsCalculationResolver
+	nextCursor string
+}
+
+func (r *pointsCalculationPageResolver) Items() []*pointsCalculationResolver { return r.items }
+func (r *pointsCalculationPageResolver) NextCursor() string                  { return r.nextCursor }
+
+type leaderboardResolver struct {
+	board   string
+	entries []*leaderboardEntryResolver
+}
+
+func (r *leaderboardResolver) Board() string                        { return r.board }
+func (r *leaderboardResolver) Entries() []*leaderboardEntryResolver { return r.entries }
+
+type leaderboardEntryResolver struct {
+	entry db.LeaderboardEntry
+	user  *userResolver
+}
+
+func (r *leaderboardEntryResolver) Rank() int32         { return int32(r.entry.Rank) }
+func (r *leaderboardEntryResolver) Score() string       { return r.entry.Score.ToBigInt().String() }
+func (r *leaderboardEntryResolver) User() *userResolver { return r.user }
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"POINTSTOKEN/service"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	"net/http"
+	"time"
+)
+
+type walletProofRequest struct {
+	Address   string `json:"address"`
+	Signature string `json:"signature"`
+}
+
+type linkWalletsRequest struct {
+	Account  string               `json:"account"`
+	IssuedAt time.Time            `json:"issued_at"`
+	Wallets  []walletProofRequest `json:"wallets"`
+}
+
+type identityResponse struct {
+	Identity  string   `json:"identity"`
+	Addresses []string `json:"addresses"`
+}
+
+type chainPointsResponse struct {
+	ChainID  uint64  `json:"chain_id"`
+	Name     string  `json:"name"`
+	Address  string  `json:"address"`
+	Points   string  `json:"points"`
+	Weight   float64 `json:"weight"`
+	Weighted string  `json:"weighted"`
+}
+
+type aggregatedPointsResponse struct {
+	Address   string                `json:"address"`
+	Addresses []string              `json:"addresses"`
+	Total     string                `json:"total"`
+	Chains    []chainPointsResponse `json:"chains"`
+}
+
+// parseAddress 解析路径或查询参数中的地址
+func parseAddress(value string) (common.Address, bool) {
+	if !common.IsHexAddress(value) {
+		return common.Address{}, false
+	}
+	return common.HexToAddress(value), true
+}
+
+func addressStrings(addresses []common.Address) []string {
+	result := make([]string, len(addresses))
+	for i, addr := range addresses {
+		result[i] = addr.Hex()
+	}
+	return result
+}
+
+// handleLinkMessage 返回钱包需要签名的关联消息
+func (s *Server) handleLinkMessage(w http.ResponseWriter, r *http.Request) {
+	account, ok := parseAddress(r.URL.Query().Get("account"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的账户地址")
+		return
+	}
+	wallet, ok := parseAddress(r.URL.Query().Get("wallet"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的钱包地址")
+		return
+	}
+	issuedAt := time.Now().UTC().Truncate(time.Second)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issued_at": issuedAt,
+		"message":   service.LinkMessage(account, wallet, issuedAt),
+	})
+}
+
+// handleLinkWallets 验证各钱包签名并关联到同一账户
+func (s *Server) handleLinkWallets(w http.ResponseWriter, r *http.Request) {
+	var req linkWalletsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "无效的请求体")
+		return
+	}
+	account, ok := parseAddress(req.Account)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的账户地址")
+		return
+	}
+	if len(req.Wallets) == 0 {
+		writeError(w, http.StatusBadRequest, "缺少钱包签名")
+		return
+	}
+	proofs := make([]service.WalletProof, 0, len(req.Wallets))
+	for _, wallet := range req.Wallets {
+		addr, ok := parseAddress(wallet.Address)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "无效的钱包地址: "+wallet.Address)
+			return
+		}
+		proofs = append(proofs, service.WalletProof{Address: addr, Signature: wallet.Signature})
+	}
+
+	if err := s.identity.LinkWallets(account, req.IssuedAt, proofs); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeIdentity(w, account)
+}
+
+// handleGetIdentity 获取地址关联的全部地址
+func (s *Server) handleGetIdentity(w http.ResponseWriter, r *http.Request) {
+	addr, ok := parseAddress(r.PathValue("address"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的地址")
+		return
+	}
+	s.writeIdentity(w, addr)
+}
+
+func (s *Server) writeIdentity(w http.ResponseWriter, addr common.Address) {
+	identityAddr, err := s.repository.GetIdentityAddr(addr)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	addresses, err := s.identity.GetLinkedAddresses(addr)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, identityResponse{
+		Identity:  identityAddr.Hex(),
+		Addresses: addressStrings(addresses),
+	})
+}
+
+// handleAggregatePoints 获取地址及其关联地址的跨链汇总积分
+func (s *Server) handleAggregatePoints(w http.ResponseWriter, r *http.Request) {
+	addr, ok := parseAddress(r.PathValue("address"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的地址")
+		return
+	}
+	aggregated, err := s.aggregator.GetIdentityPoints(addr)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := aggregatedPointsResponse{
+		Address:   aggregated.UserAddr.Hex(),
+		Addresses: addressStrings(aggregated.Addresses),
+		Total:     aggregated.Total.String(),
+		Chains:    []chainPointsResponse{},
+	}
+	for _, chain := range aggregated.Chains {
+		resp.Chains = append(resp.Chains, chainPointsResponse{
+			ChainID:  chain.ChainID,
+			Name:     chain.Name,
+			Address:  chain.UserAddr.Hex(),
+			Points:   chain.Points.String(),
+			Weight:   chain.Weight,
+			Weighted: chain.Weighted.String(),
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
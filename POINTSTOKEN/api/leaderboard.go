@@ -0,0 +1,138 @@
+package api
+
+import (
+	"POINTSTOKEN/db"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type leaderboardEntryResponse struct {
+	Rank    uint64 `json:"rank"`
+	Address string `json:"address"`
+	Score   string `json:"score"`
+}
+
+func newLeaderboardEntries(entries []db.LeaderboardEntry) []leaderboardEntryResponse {
+	resp := make([]leaderboardEntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		resp = append(resp, leaderboardEntryResponse{
+			Rank:    entry.Rank,
+			Address: entry.UserAddr.Hex(),
+			Score:   entry.Score.ToBigInt().String(),
+		})
+	}
+	return resp
+}
+
+// leaderboardQuery 排行榜公共参数：chain_id（0 或省略为跨链榜）、window、date（RFC3339，日榜、周榜使用）、campaign
+type leaderboardQuery struct {
+	chainID  uint64
+	window   string
+	at       time.Time
+	campaign string
+}
+
+func parseLeaderboardQuery(r *http.Request) (*leaderboardQuery, bool) {
+	query := r.URL.Query()
+	q := &leaderboardQuery{
+		window:   query.Get("window"),
+		at:       time.Now(),
+		campaign: query.Get("campaign"),
+	}
+	if value := query.Get("chain_id"); value != "" {
+		chainID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		q.chainID = chainID
+	}
+	if value := query.Get("date"); value != "" {
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, false
+		}
+		q.at = at
+	}
+	return q, true
+}
+
+// queryInt 读取整数参数，缺省时返回 def，超出 [min, max] 时返回 false
+func queryInt(r *http.Request, name string, def int, min int, max int) (int, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, false
+	}
+	return n, true
+}
+
+// handleLeaderboard 分页获取排行榜
+func (s *Server) handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	q, ok := parseLeaderboardQuery(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的查询参数")
+		return
+	}
+	limit, ok := queryInt(r, "limit", 100, 1, 1000)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的 limit")
+		return
+	}
+	offset, ok := queryInt(r, "offset", 0, 0, 1<<30)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的 offset")
+		return
+	}
+
+	board, entries, err := s.leaderboard.Top(q.window, q.chainID, q.at, q.campaign, offset, limit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"board":    board,
+		"chain_id": q.chainID,
+		"entries":  newLeaderboardEntries(entries),
+	})
+}
+
+// handleLeaderboardRank 获取地址的名次及前后各 neighbours 名用户
+func (s *Server) handleLeaderboardRank(w http.ResponseWriter, r *http.Request) {
+	userAddr, ok := parseAddress(r.PathValue("address"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的地址")
+		return
+	}
+	q, ok := parseLeaderboardQuery(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的查询参数")
+		return
+	}
+	neighbours, ok := queryInt(r, "neighbours", 5, 0, 100)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的 neighbours")
+		return
+	}
+
+	rank, err := s.leaderboard.Rank(q.window, q.chainID, q.at, q.campaign, userAddr, neighbours)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if rank.Entry == nil {
+		writeError(w, http.StatusNotFound, "地址不在排行榜上")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"board":   rank.Entry.Board,
+		"rank":    rank.Entry.Rank,
+		"address": userAddr.Hex(),
+		"score":   rank.Entry.Score.ToBigInt().String(),
+		"above":   newLeaderboardEntries(rank.Above),
+		"below":   newLeaderboardEntries(rank.Below),
+	})
+}
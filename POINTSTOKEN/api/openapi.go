@@ -0,0 +1,49 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"gopkg.in/yaml.v3"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// openAPISpec 查询接口的 OpenAPI 3 描述，修改接口时同步更新 openapi.yaml 和 client 包
+//
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+var (
+	openAPIJSONOnce sync.Once
+	openAPIJSON     []byte
+)
+
+// handleOpenAPIYAML 输出 OpenAPI 描述
+func (s *Server) handleOpenAPIYAML(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+	w.Write(openAPISpec)
+}
+
+// handleOpenAPIJSON 输出 JSON 格式的 OpenAPI 描述
+func (s *Server) handleOpenAPIJSON(w http.ResponseWriter, r *http.Request) {
+	openAPIJSONOnce.Do(func() {
+		var spec map[string]interface{}
+		if err := yaml.Unmarshal(openAPISpec, &spec); err != nil {
+			log.Printf("解析 OpenAPI 描述失败: %v", err)
+			return
+		}
+		data, err := json.Marshal(spec)
+		if err != nil {
+			log.Printf("转换 OpenAPI 描述失败: %v", err)
+			return
+		}
+		openAPIJSON = data
+	})
+	if openAPIJSON == nil {
+		writeError(w, http.StatusInternalServerError, "OpenAPI 描述无效")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(openAPIJSON)
+}
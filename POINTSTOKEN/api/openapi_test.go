@@ -0,0 +1,72 @@
+package api
+
+import (
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// routeRecorder 记录注册的路由
+type routeRecorder []string
+
+func (r *routeRecorder) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	*r = append(*r, pattern)
+}
+
+// openAPIExcluded 不在 OpenAPI 描述中的路由前缀
+var openAPIExcluded = []string{"/api/v1/admin/", "/api/v1/graphql", "/api/v1/ws", "/api/v1/openapi."}
+
+// openAPI 描述中的每个接口都有对应的路由，除管理接口等外的每个路由都在描述中
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]interface{} `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatal(err)
+	}
+	documented := make(map[string]bool)
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			documented[strings.ToUpper(method)+" /api/v1"+path] = true
+		}
+	}
+
+	var routes routeRecorder
+	(&Server{}).registerRoutes(&routes)
+	registered := make(map[string]bool)
+	for _, pattern := range routes {
+		method, path, _ := strings.Cut(pattern, " ")
+		excluded := false
+		for _, prefix := range openAPIExcluded {
+			if strings.HasPrefix(path, prefix) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			registered[method+" "+path] = true
+		}
+	}
+
+	var missing, unknown []string
+	for route := range registered {
+		if !documented[route] {
+			missing = append(missing, route)
+		}
+	}
+	for route := range documented {
+		if !registered[route] {
+			unknown = append(unknown, route)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(unknown)
+	if len(missing) > 0 {
+		t.Errorf("路由未在 openapi.yaml 中描述: %v", missing)
+	}
+	if len(unknown) > 0 {
+		t.Errorf("openapi.yaml 中的接口没有对应的路由: %v", unknown)
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"POINTSTOKEN/service"
+	"bytes"
+	"github.com/ethereum/go-ethereum/common"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// handlePointsPreview 试算积分但不写入，参数 chain、address（可选）、until（RFC3339，可选）、format
+func (s *Server) handlePointsPreview(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	chainID, err := strconv.ParseUint(query.Get("chain"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "无效的链ID")
+		return
+	}
+	until := time.Now()
+	if value := query.Get("until"); value != "" {
+		if until, err = time.Parse(time.RFC3339, value); err != nil {
+			writeError(w, http.StatusBadRequest, "无效的截止时间")
+			return
+		}
+	}
+	var userAddr *common.Address
+	if value := query.Get("address"); value != "" {
+		addr, ok := parseAddress(value)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "无效的地址")
+			return
+		}
+		userAddr = &addr
+	}
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	preview, err := s.calculator.Preview(chainID, until, userAddr)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var buf bytes.Buffer
+	if err := service.WritePreview(&buf, preview, format); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	switch format {
+	case "json":
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"POINTSTOKEN/db"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type balanceChangeResponse struct {
+	ID           uint64    `json:"id"`
+	TxHash       string    `json:"tx_hash"`
+	BlockNumber  uint64    `json:"block_number"`
+	ChangeAmount string    `json:"change_amount"`
+	BalanceAfter string    `json:"balance_after"`
+	EventType    string    `json:"event_type"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type pointsCalculationResponse struct {
+	ID               uint64    `json:"id"`
+	CalculatedAt     time.Time `json:"calculated_at"`
+	Balance          string    `json:"balance"`
+	PointsAdded      string    `json:"points_added"`
+	TotalPointsAfter string    `json:"total_points_after"`
+}
+
+// parseChainUser 解析路径中的 chain_id 和 address
+func parseChainUser(w http.ResponseWriter, r *http.Request) (uint64, common.Address, bool) {
+	chainID, err := strconv.ParseUint(r.PathValue("chain_id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "无效的链ID")
+		return 0, common.Address{}, false
+	}
+	userAddr, ok := parseAddress(r.PathValue("address"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的地址")
+		return 0, common.Address{}, false
+	}
+	return chainID, userAddr, true
+}
+
+// parseHistoryFilter 解析分页和过滤参数：cursor、limit、from_time、to_time（RFC3339）、from_block、to_block
+func parseHistoryFilter(r *http.Request) (db.HistoryFilter, error) {
+	query := r.URL.Query()
+	filter := db.HistoryFilter{Limit: 50}
+	var err error
+	if value := query.Get("cursor"); value != "" {
+		if filter.Cursor, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return filter, errors.New("无效的 cursor")
+		}
+	}
+	limit, ok := queryInt(r, "limit", 50, 1, 500)
+	if !ok {
+		return filter, errors.New("无效的 limit")
+	}
+	filter.Limit = limit
+	if value := query.Get("from_time"); value != "" {
+		if filter.FromTime, err = time.Parse(time.RFC3339, value); err != nil {
+			return filter, errors.New("无效的 from_time")
+		}
+	}
+	if value := query.Get("to_time"); value != "" {
+		if filter.ToTime, err = time.Parse(time.RFC3339, value); err != nil {
+			return filter, errors.New("无效的 to_time")
+		}
+	}
+	if value := query.Get("from_block"); value != "" {
+		if filter.FromBlock, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return filter, errors.New("无效的 from_block")
+		}
+	}
+	if value := query.Get("to_block"); value != "" {
+		if filter.ToBlock, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return filter, errors.New("无效的 to_block")
+		}
+	}
+	return filter, nil
+}
+
+// nextCursor 返回下一页的游标，本页不满时没有下一页
+func nextCursor(count int, limit int, lastID uint64) string {
+	if count < limit {
+		return ""
+	}
+	return strconv.FormatUint(lastID, 10)
+}
+
+// handleGetBalance 获取用户在链上的余额
+func (s *Server) handleGetBalance(w http.ResponseWriter, r *http.Request) {
+	chainID, userAddr, ok := parseChainUser(w, r)
+	if !ok {
+		return
+	}
+	balance, err := s.repository.GetUserBalance(chainID, userAddr)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"chain_id": chainID,
+		"address":  userAddr.Hex(),
+		"balance":  balance,
+	})
+}
+
+// handleGetPoints 获取用户在链上的积分及最近一次计算时间
+func (s *Server) handleGetPoints(w http.ResponseWriter, r *http.Request) {
+	chainID, userAddr, ok := parseChainUser(w, r)
+	if !ok {
+		return
+	}
+	points, err := s.repository.GetUserPoints(chainID, userAddr)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	lastCalculated, err := s.repository.GetPointLastRecordTime(chainID, userAddr)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp := map[string]interface{}{
+		"chain_id": chainID,
+		"address":  userAddr.Hex(),
+		"points":   points,
+	}
+	if !lastCalculated.IsZero() {
+		resp["last_calculated_at"] = lastCalculated
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleGetBalanceHistory 分页获取用户的余额变动记录
+func (s *Server) handleGetBalanceHistory(w http.ResponseWriter, r *http.Request) {
+	chainID, userAddr, ok := parseChainUser(w, r)
+	if !ok {
+		return
+	}
+	filter, err := parseHistoryFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	changes, err := s.repository.GetBalanceHistory(chainID, userAddr, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	items := make([]balanceChangeResponse, 0, len(changes))
+	var lastID uint64
+	for _, c := range changes {
+		items = append(items, balanceChangeResponse{
+			ID:           c.ID,
+			TxHash:       c.TxHash.Hex(),
+			BlockNumber:  c.BlockNumber,
+			ChangeAmount: c.BalanceChange.ToBigInt().String(),
+			BalanceAfter: c.BalanceAfter.ToBigInt().String(),
+			EventType:    c.EventType,
+			CreatedAt:    c.CreatedAt,
+		})
+		lastID = c.ID
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"chain_id":    chainID,
+		"address":     userAddr.Hex(),
+		"items":       items,
+		"next_cursor": nextCursor(len(items), filter.Limit, lastID),
+	})
+}
+
+// handleGetPointsHistory 分页获取用户的积分计算记录
+func (s *Server) handleGetPointsHistory(w http.ResponseWriter, r *http.Request) {
+	chainID, userAddr, ok := parseChainUser(w, r)
+	if !ok {
+		return
+	}
+	filter, err := parseHistoryFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	calculations, err := s.repository.GetPointsCalculations(chainID, userAddr, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	items := make([]pointsCalculationResponse, 0, len(calculations))
+	var lastID uint64
+	for _, c := range calculations {
+		items = append(items, pointsCalculationResponse{
+			ID:               c.ID,
+			CalculatedAt:     c.CalculatedAt,
+			Balance:          c.Balance.ToBigInt().String(),
+			PointsAdded:      c.PointsAdded.ToBigInt().String(),
+			TotalPointsAfter: c.TotalPointsAfter.ToBigInt().String(),
+		})
+		lastID = c.ID
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"chain_id":    chainID,
+		"address":     userAddr.Hex(),
+		"items":       items,
+		"next_cursor": nextCursor(len(items), filter.Limit, lastID),
+	})
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"POINTSTOKEN/db"
+	"POINTSTOKEN/service"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type registerReferralRequest struct {
+	Referee   string    `json:"referee"`
+	Referrer  string    `json:"referrer"`
+	IssuedAt  time.Time `json:"issued_at"`
+	Signature string    `json:"signature"`
+}
+
+type refereeResponse struct {
+	Referee   string    `json:"referee"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// handleReferralMessage 返回被推荐人需要签名的推荐登记消息
+func (s *Server) handleReferralMessage(w http.ResponseWriter, r *http.Request) {
+	referee, ok := parseAddress(r.URL.Query().Get("referee"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的被推荐人地址")
+		return
+	}
+	referrer, ok := parseAddress(r.URL.Query().Get("referrer"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的推荐人地址")
+		return
+	}
+	issuedAt := time.Now().UTC().Truncate(time.Second)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issued_at": issuedAt,
+		"message":   service.ReferralMessage(referee, referrer, issuedAt),
+	})
+}
+
+// handleRegisterReferral 验证被推荐人签名并登记推荐关系
+func (s *Server) handleRegisterReferral(w http.ResponseWriter, r *http.Request) {
+	var req registerReferralRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "无效的请求体")
+		return
+	}
+	referee, ok := parseAddress(req.Referee)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的被推荐人地址")
+		return
+	}
+	referrer, ok := parseAddress(req.Referrer)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的推荐人地址")
+		return
+	}
+
+	err := s.referral.RegisterSigned(referee, referrer, req.IssuedAt, req.Signature)
+	if errors.Is(err, db.ErrReferralExists) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{
+		"referee":  referee.Hex(),
+		"referrer": referrer.Hex(),
+	})
+}
+
+// handleGetReferees 获取推荐人直接推荐的地址
+func (s *Server) handleGetReferees(w http.ResponseWriter, r *http.Request) {
+	referrer, ok := parseAddress(r.PathValue("address"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的地址")
+		return
+	}
+	referrals, err := s.referral.GetReferees(referrer)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp := make([]refereeResponse, 0, len(referrals))
+	for _, ref := range referrals {
+		resp = append(resp, refereeResponse{
+			Referee:   ref.RefereeAddr.Hex(),
+			Source:    ref.Source,
+			CreatedAt: ref.CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"referrer": referrer.Hex(),
+		"referees": resp,
+	})
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"POINTSTOKEN/db"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type pointsRunResponse struct {
+	ID             uint64     `json:"id"`
+	ChainID        uint64     `json:"chain_id"`
+	Status         string     `json:"status"`
+	RuleVersion    string     `json:"rule_version"`
+	StartedAt      time.Time  `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at"`
+	CalculatedAt   time.Time  `json:"calculated_at"`
+	UsersProcessed int        `json:"users_processed"`
+	UsersExcluded  int        `json:"users_excluded"`
+	UsersCapped    int        `json:"users_capped"`
+	UsersPenalized int        `json:"users_penalized"`
+	PointsUncapped string     `json:"points_uncapped"`
+	PointsEmitted  string     `json:"points_emitted"`
+	ReferralPoints string     `json:"referral_points"`
+	BudgetScaled   bool       `json:"budget_scaled"`
+	EntriesCount   int        `json:"entries_count"`
+	EntriesDigest  string     `json:"entries_digest"`
+	Error          string     `json:"error,omitempty"`
+}
+
+func newPointsRunResponse(run *db.PointsRun) pointsRunResponse {
+	return pointsRunResponse{
+		ID:             run.ID,
+		ChainID:        run.ChainID,
+		Status:         run.Status,
+		RuleVersion:    run.RuleVersion,
+		StartedAt:      run.StartedAt,
+		FinishedAt:     run.FinishedAt,
+		CalculatedAt:   run.CalculatedAt,
+		UsersProcessed: run.UsersProcessed,
+		UsersExcluded:  run.UsersExcluded,
+		UsersCapped:    run.UsersCapped,
+		UsersPenalized: run.UsersPenalized,
+		PointsUncapped: run.PointsUncapped.ToBigInt().String(),
+		PointsEmitted:  run.PointsEmitted.ToBigInt().String(),
+		ReferralPoints: run.ReferralPoints.ToBigInt().String(),
+		BudgetScaled:   run.BudgetScaled,
+		EntriesCount:   run.EntriesCount,
+		EntriesDigest:  run.EntriesDigest,
+		Error:          run.Error,
+	}
+}
+
+// handleGetRuns 获取最近的积分计算运行记录
+func (s *Server) handleGetRuns(w http.ResponseWriter, r *http.Request) {
+	var chainID uint64
+	if value := r.URL.Query().Get("chain_id"); value != "" {
+		var err error
+		if chainID, err = strconv.ParseUint(value, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "无效的链ID")
+			return
+		}
+	}
+	limit, ok := queryInt(r, "limit", 50, 1, 1000)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的 limit")
+		return
+	}
+	runs, err := s.calculator.GetRuns(chainID, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp := make([]pointsRunResponse, 0, len(runs))
+	for i := range runs {
+		resp = append(resp, newPointsRunResponse(&runs[i]))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"runs": resp})
+}
+
+// handleGetRun 获取单次运行记录
+func (s *Server) handleGetRun(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "无效的运行ID")
+		return
+	}
+	run, err := s.calculator.GetRun(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if run == nil {
+		writeError(w, http.StatusNotFound, "运行记录不存在")
+		return
+	}
+	writeJSON(w, http.StatusOK, newPointsRunResponse(run))
+}
+
+// handleVerifyRun 将运行记录与账本核对
+func (s *Server) handleVerifyRun(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "无效的运行ID")
+		return
+	}
+	v, err := s.calculator.VerifyRun(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	mismatches := v.Mismatches
+	if mismatches == nil {
+		mismatches = []string{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"run":             newPointsRunResponse(v.Run),
+		"verified":        len(v.Mismatches) == 0,
+		"ledger_entries":  v.LedgerEntries,
+		"ledger_emitted":  v.LedgerEmitted.String(),
+		"ledger_referral": v.LedgerReferral.String(),
+		"ledger_digest":   v.LedgerDigest,
+		"mismatches":      mismatches,
+	})
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"POINTSTOKEN/config"
+	"POINTSTOKEN/db"
+	"POINTSTOKEN/service"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/graph-gophers/graphql-go"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Services API 依赖的业务服务
+type Services struct {
+	Aggregator  *service.PointsAggregator
+	Identity    *service.IdentityService
+	Referral    *service.ReferralService
+	Calculator  *service.PointsCalculator
+	Adjustment  *service.AdjustmentService
+	Leaderboard *service.LeaderboardService
+	Tier        *service.TierService
+	Events      *service.EventBus
+	Auth        *service.AuthService
+	Export      *service.ExportService
+	Snapshot    *service.SnapshotService
+}
+
+// Server HTTP API 服务
+type Server struct {
+	cfg         *config.APIConfig
+	repository  db.Repository
+	aggregator  *service.PointsAggregator
+	identity    *service.IdentityService
+	referral    *service.ReferralService
+	calculator  *service.PointsCalculator
+	adjustment  *service.AdjustmentService
+	leaderboard *service.LeaderboardService
+	tier        *service.TierService
+	events      *service.EventBus
+	auth        *service.AuthService
+	export      *service.ExportService
+	snapshot    *service.SnapshotService
+	graphSchema *graphql.Schema
+	httpServer  *http.Server
+}
+
+func NewServer(cfg *config.APIConfig, repo db.Repository, services Services) *Server {
+	s := &Server{
+		cfg:         cfg,
+		repository:  repo,
+		aggregator:  services.Aggregator,
+		identity:    services.Identity,
+		referral:    services.Referral,
+		calculator:  services.Calculator,
+		adjustment:  services.Adjustment,
+		leaderboard: services.Leaderboard,
+		tier:        services.Tier,
+		events:      services.Events,
+		auth:        services.Auth,
+		export:      services.Export,
+		snapshot:    services.Snapshot,
+	}
+	s.graphSchema = newGraphSchema(s)
+	s.httpServer = &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	return mux
+}
+
+// routeMux 注册路由的接口，测试中用于列出全部路由
+type routeMux interface {
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+}
+
+func (s *Server) registerRoutes(mux routeMux) {
+	mux.HandleFunc("GET /api/v1/chains/{chain_id}/users/{address}/balance", s.requireUser(s.handleGetBalance))
+	mux.HandleFunc("GET /api/v1/chains/{chain_id}/users/{address}/balance-changes", s.requireUser(s.handleGetBalanceHistory))
+	mux.HandleFunc("GET /api/v1/chains/{chain_id}/users/{address}/balance-at", s.requireUser(s.handleGetBalanceAt))
+	mux.HandleFunc("GET /api/v1/chains/{chain_id}/users/{address}/points", s.requireUser(s.handleGetPoints))
+	mux.HandleFunc("GET /api/v1/chains/{chain_id}/users/{address}/points-calculations", s.requireUser(s.handleGetPointsHistory))
+	mux.HandleFunc("GET /api/v1/chains/{chain_id}/holders", s.requireRead(s.handleGetHolders))
+	mux.HandleFunc("GET /api/v1/identities/link-message", s.handleLinkMessage)
+	mux.HandleFunc("POST /api/v1/identities/link", s.handleLinkWallets)
+	mux.HandleFunc("GET /api/v1/identities/{address}", s.requireUser(s.handleGetIdentity))
+	mux.HandleFunc("GET /api/v1/points/{address}/aggregate", s.requireUser(s.handleAggregatePoints))
+	mux.HandleFunc("GET /api/v1/points/preview", s.requireQueryUser(s.handlePointsPreview))
+	mux.HandleFunc("GET /api/v1/referrals/message", s.handleReferralMessage)
+	mux.HandleFunc("POST /api/v1/referrals", s.handleRegisterReferral)
+	mux.HandleFunc("GET /api/v1/referrals/{address}", s.requireUser(s.handleGetReferees))
+	mux.HandleFunc("GET /api/v1/leaderboard", s.handleLeaderboard)
+	mux.HandleFunc("GET /api/v1/leaderboard/{address}", s.handleLeaderboardRank)
+	mux.HandleFunc("GET /api/v1/tiers", s.handleGetTiers)
+	mux.HandleFunc("GET /api/v1/tiers/{address}", s.requireUser(s.handleGetUserTier))
+	mux.HandleFunc("POST /api/v1/graphql", s.requireRead(s.handleGraphQL))
+	mux.HandleFunc("GET /api/v1/ws", s.handleWebSocket)
+	mux.HandleFunc("GET /api/v1/openapi.yaml", s.handleOpenAPIYAML)
+	mux.HandleFunc("GET /api/v1/openapi.json", s.handleOpenAPIJSON)
+	mux.HandleFunc("GET /api/v1/auth/nonce", s.handleSIWENonce)
+	mux.HandleFunc("POST /api/v1/auth/login", s.handleSIWELogin)
+	mux.HandleFunc("POST /api/v1/auth/logout", s.handleSIWELogout)
+	mux.HandleFunc("POST /api/v1/admin/points/adjustments", s.requireAdmin(s.handleAdjustPoints))
+	mux.HandleFunc("POST /api/v1/admin/points/adjustments/import", s.requireAdmin(s.handleImportAdjustments))
+	mux.HandleFunc("GET /api/v1/admin/points/adjustments", s.requireAdmin(s.handleGetAdjustments))
+	mux.HandleFunc("GET /api/v1/admin/runs", s.requireAdmin(s.handleGetRuns))
+	mux.HandleFunc("GET /api/v1/admin/runs/{id}", s.requireAdmin(s.handleGetRun))
+	mux.HandleFunc("GET /api/v1/admin/runs/{id}/verify", s.requireAdmin(s.handleVerifyRun))
+	mux.HandleFunc("POST /api/v1/admin/webhooks", s.requireAdmin(s.handleCreateWebhook))
+	mux.HandleFunc("GET /api/v1/admin/webhooks", s.requireAdmin(s.handleGetWebhooks))
+	mux.HandleFunc("DELETE /api/v1/admin/webhooks/{id}", s.requireAdmin(s.handleDeleteWebhook))
+	mux.HandleFunc("GET /api/v1/admin/webhooks/{id}/deliveries", s.requireAdmin(s.handleGetWebhookDeliveries))
+	mux.HandleFunc("GET /api/v1/admin/webhooks/deliveries/{delivery_id}/attempts", s.requireAdmin(s.handleGetDeliveryAttempts))
+	mux.HandleFunc("POST /api/v1/admin/api-keys", s.requireAdmin(s.handleCreateAPIKey))
+	mux.HandleFunc("GET /api/v1/admin/api-keys", s.requireAdmin(s.handleGetAPIKeys))
+	mux.HandleFunc("DELETE /api/v1/admin/api-keys/{id}", s.requireAdmin(s.handleRevokeAPIKey))
+	mux.HandleFunc("GET /api/v1/admin/exports/{dataset}", s.requireAdmin(s.handleExport))
+	mux.HandleFunc("POST /api/v1/admin/snapshots", s.requireAdmin(s.handleCreateHolderSnapshot))
+	mux.HandleFunc("GET /api/v1/admin/snapshots", s.requireAdmin(s.handleGetHolderSnapshots))
+	mux.HandleFunc("DELETE /api/v1/admin/snapshots/{id}", s.requireAdmin(s.handleDeleteHolderSnapshot))
+}
+
+// Start 启动 HTTP 服务
+func (s *Server) Start() {
+	go func() {
+		log.Printf("HTTP API 服务已启动，监听 %s", s.cfg.Listen)
+		if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("HTTP API 服务异常退出: %v", err)
+		}
+	}()
+}
+
+// Stop 优雅关闭 HTTP 服务
+func (s *Server) Stop(ctx context.Context) {
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		log.Printf("关闭 HTTP API 服务失败: %v", err)
+	}
+}
+
+// writeJSON 输出 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("输出响应失败: %v", err)
+	}
+}
+
+// writeError 输出错误响应
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
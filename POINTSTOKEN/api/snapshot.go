@@ -0,0 +1,215 @@
+package api
+
+import (
+	"POINTSTOKEN/db"
+	"POINTSTOKEN/service"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type holderResponse struct {
+	Address string `json:"address"`
+	Balance string `json:"balance"`
+}
+
+type holderSnapshotResponse struct {
+	ID           uint64    `json:"id"`
+	ChainID      uint64    `json:"chain_id"`
+	BlockNumber  uint64    `json:"block_number"`
+	Holders      uint64    `json:"holders"`
+	TotalBalance string    `json:"total_balance"`
+	CreatedBy    string    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func newHolderSnapshotResponse(snapshot *db.HolderSnapshot) holderSnapshotResponse {
+	return holderSnapshotResponse{
+		ID:           snapshot.ID,
+		ChainID:      snapshot.ChainID,
+		BlockNumber:  snapshot.BlockNumber,
+		Holders:      snapshot.Holders,
+		TotalBalance: snapshot.TotalBalance.ToBigInt().String(),
+		CreatedBy:    snapshot.CreatedBy,
+		CreatedAt:    snapshot.CreatedAt,
+	}
+}
+
+// resolveBlock 解析 block 或 time（RFC3339）参数并确定查询的区块高度，二者必须且只能指定一个
+func (s *Server) resolveBlock(w http.ResponseWriter, chainID uint64, blockValue string, timeValue string) (uint64, bool) {
+	if (blockValue == "") == (timeValue == "") {
+		writeError(w, http.StatusBadRequest, "请指定 block 或 time 其中之一")
+		return 0, false
+	}
+	var blockNumber uint64
+	var at time.Time
+	var err error
+	if blockValue != "" {
+		if blockNumber, err = strconv.ParseUint(blockValue, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "无效的 block")
+			return 0, false
+		}
+	} else if at, err = time.Parse(time.RFC3339, timeValue); err != nil {
+		writeError(w, http.StatusBadRequest, "无效的 time")
+		return 0, false
+	}
+	blockNumber, err = s.snapshot.ResolveBlock(chainID, blockNumber, at)
+	switch {
+	case errors.Is(err, service.ErrUnknownChain):
+		writeError(w, http.StatusNotFound, err.Error())
+		return 0, false
+	case errors.Is(err, service.ErrBlockNotSynced):
+		writeError(w, http.StatusBadRequest, err.Error())
+		return 0, false
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return 0, false
+	}
+	return blockNumber, true
+}
+
+// handleGetBalanceAt 获取用户在指定区块高度或时间的余额
+func (s *Server) handleGetBalanceAt(w http.ResponseWriter, r *http.Request) {
+	chainID, userAddr, ok := parseChainUser(w, r)
+	if !ok {
+		return
+	}
+	blockNumber, ok := s.resolveBlock(w, chainID, r.URL.Query().Get("block"), r.URL.Query().Get("time"))
+	if !ok {
+		return
+	}
+	balance, err := s.snapshot.BalanceAt(chainID, userAddr, blockNumber)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"chain_id":          chainID,
+		"address":           userAddr.Hex(),
+		"block_number":      blockNumber,
+		"balance":           balance.Balance,
+		"last_change_block": balance.LastChangeBlock,
+		"last_change_at":    balance.LastChangeAt,
+	})
+}
+
+// handleGetHolders 按地址顺序分页获取指定区块高度或时间的持有人，cursor 为上一页最后一个地址
+func (s *Server) handleGetHolders(w http.ResponseWriter, r *http.Request) {
+	chainID, err := strconv.ParseUint(r.PathValue("chain_id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "无效的链ID")
+		return
+	}
+	query := r.URL.Query()
+	cursor := query.Get("cursor")
+	if cursor != "" {
+		addr, ok := parseAddress(cursor)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "无效的 cursor")
+			return
+		}
+		cursor = addr.Hex()
+	}
+	limit, ok := queryInt(r, "limit", 1000, 1, 10000)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的 limit")
+		return
+	}
+	blockNumber, ok := s.resolveBlock(w, chainID, query.Get("block"), query.Get("time"))
+	if !ok {
+		return
+	}
+
+	holders, snapshotID, err := s.snapshot.Holders(chainID, blockNumber, cursor, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	items := make([]holderResponse, 0, len(holders))
+	for _, h := range holders {
+		items = append(items, holderResponse{Address: h.UserAddr.Hex(), Balance: h.Balance.ToBigInt().String()})
+	}
+	var nextCursor string
+	if len(items) == limit {
+		nextCursor = items[len(items)-1].Address
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"chain_id":     chainID,
+		"block_number": blockNumber,
+		"materialized": snapshotID > 0,
+		"holders":      items,
+		"next_cursor":  nextCursor,
+	})
+}
+
+type holderSnapshotRequest struct {
+	ChainID     uint64  `json:"chain_id"`
+	BlockNumber *uint64 `json:"block_number"`
+	Time        string  `json:"time"` //RFC3339，与 block_number 二选一
+}
+
+// handleCreateHolderSnapshot 物化指定区块高度或时间的持有人快照
+func (s *Server) handleCreateHolderSnapshot(w http.ResponseWriter, r *http.Request) {
+	var req holderSnapshotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "无效的请求体")
+		return
+	}
+	var blockValue string
+	if req.BlockNumber != nil {
+		blockValue = strconv.FormatUint(*req.BlockNumber, 10)
+	}
+	blockNumber, ok := s.resolveBlock(w, req.ChainID, blockValue, req.Time)
+	if !ok {
+		return
+	}
+	snapshot, err := s.snapshot.CreateSnapshot(req.ChainID, blockNumber, operatorFrom(r))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, newHolderSnapshotResponse(snapshot))
+}
+
+// handleGetHolderSnapshots 获取物化快照，可按 chain_id 过滤
+func (s *Server) handleGetHolderSnapshots(w http.ResponseWriter, r *http.Request) {
+	var chainID uint64
+	if value := r.URL.Query().Get("chain_id"); value != "" {
+		var err error
+		if chainID, err = strconv.ParseUint(value, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "无效的链ID")
+			return
+		}
+	}
+	snapshots, err := s.snapshot.GetSnapshots(chainID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp := make([]holderSnapshotResponse, 0, len(snapshots))
+	for i := range snapshots {
+		resp = append(resp, newHolderSnapshotResponse(&snapshots[i]))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"snapshots": resp})
+}
+
+// handleDeleteHolderSnapshot 删除物化快照，之后的查询改为由余额变动计算
+func (s *Server) handleDeleteHolderSnapshot(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "无效的快照ID")
+		return
+	}
+	found, err := s.snapshot.DeleteSnapshot(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "快照不存在")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type tierChangeResponse struct {
+	FromTier  string    `json:"from_tier"`
+	ToTier    string    `json:"to_tier"`
+	Direction string    `json:"direction"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// handleGetTiers 返回按从低到高排列的等级
+func (s *Server) handleGetTiers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tiers": s.tier.Tiers()})
+}
+
+// handleGetUserTier 获取地址在链上的当前等级和等级变更历史
+func (s *Server) handleGetUserTier(w http.ResponseWriter, r *http.Request) {
+	userAddr, ok := parseAddress(r.PathValue("address"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的地址")
+		return
+	}
+	chainID, err := strconv.ParseUint(r.URL.Query().Get("chain_id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "无效的链ID")
+		return
+	}
+
+	current, history, err := s.tier.GetUserTier(chainID, userAddr)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp := map[string]interface{}{
+		"chain_id": chainID,
+		"address":  userAddr.Hex(),
+		"tier":     "",
+	}
+	if current != nil {
+		resp["tier"] = current.Tier
+		resp["since"] = current.Since
+	}
+	changes := make([]tierChangeResponse, 0, len(history))
+	for _, c := range history {
+		changes = append(changes, tierChangeResponse{
+			FromTier:  c.FromTier,
+			ToTier:    c.ToTier,
+			Direction: c.Direction,
+			ChangedAt: c.ChangedAt,
+		})
+	}
+	resp["history"] = changes
+	writeJSON(w, http.StatusOK, resp)
+}
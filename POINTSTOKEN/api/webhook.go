@@ -0,0 +1,229 @@
+package api
+
+import (
+	"POINTSTOKEN/db"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type webhookRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"` //为空时自动生成
+	EventTypes []string `json:"event_types"`
+	ChainIDs   []uint64 `json:"chain_ids"`
+	Addresses  []string `json:"addresses"`
+}
+
+type webhookResponse struct {
+	ID         uint64    `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"` //只在创建时返回
+	EventTypes []string  `json:"event_types"`
+	ChainIDs   []uint64  `json:"chain_ids"`
+	Addresses  []string  `json:"addresses"`
+	Active     bool      `json:"active"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func newWebhookResponse(sub *db.WebhookSubscription) webhookResponse {
+	resp := webhookResponse{
+		ID:         sub.ID,
+		URL:        sub.URL,
+		EventTypes: sub.EventTypes,
+		ChainIDs:   sub.ChainIDs,
+		Addresses:  make([]string, 0, len(sub.Addresses)),
+		Active:     sub.Active,
+		CreatedBy:  sub.CreatedBy,
+		CreatedAt:  sub.CreatedAt,
+	}
+	for _, addr := range sub.Addresses {
+		resp.Addresses = append(resp.Addresses, addr.Hex())
+	}
+	return resp
+}
+
+type webhookDeliveryResponse struct {
+	ID             uint64     `json:"id"`
+	OutboxID       uint64     `json:"outbox_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code"`
+	LastError      string     `json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type deliveryAttemptResponse struct {
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code"`
+	Error      string    `json:"error"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// handleCreateWebhook 创建 webhook 订阅，过滤条件为空表示接收全部事件
+func (s *Server) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "无效的请求体")
+		return
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		writeError(w, http.StatusBadRequest, "无效的 URL")
+		return
+	}
+	for _, eventType := range req.EventTypes {
+		if eventType != db.OutboxBalanceChange && eventType != db.OutboxPoints {
+			writeError(w, http.StatusBadRequest, "无效的事件类型: "+eventType)
+			return
+		}
+	}
+	sub := &db.WebhookSubscription{
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+		ChainIDs:   req.ChainIDs,
+		Active:     true,
+		CreatedBy:  operatorFrom(r),
+	}
+	for _, value := range req.Addresses {
+		userAddr, ok := parseAddress(value)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "无效的地址")
+			return
+		}
+		sub.Addresses = append(sub.Addresses, userAddr)
+	}
+	if sub.Secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		sub.Secret = hex.EncodeToString(buf)
+	}
+	if err := s.repository.CreateWebhookSubscription(sub); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sub.CreatedAt = time.Now()
+	resp := newWebhookResponse(sub)
+	resp.Secret = sub.Secret
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// handleGetWebhooks 获取全部 webhook 订阅
+func (s *Server) handleGetWebhooks(w http.ResponseWriter, r *http.Request) {
+	subs, err := s.repository.GetWebhookSubscriptions(false)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp := make([]webhookResponse, 0, len(subs))
+	for i := range subs {
+		resp = append(resp, newWebhookResponse(&subs[i]))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"webhooks": resp})
+}
+
+// handleDeleteWebhook 停用 webhook 订阅，保留投递日志
+func (s *Server) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "无效的订阅ID")
+		return
+	}
+	found, err := s.repository.DeactivateWebhookSubscription(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "订阅不存在")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetWebhookDeliveries 获取订阅的投递日志，可按 status 过滤
+func (s *Server) handleGetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "无效的订阅ID")
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status != "" && status != db.DeliveryPending && status != db.DeliveryDelivered && status != db.DeliveryFailed {
+		writeError(w, http.StatusBadRequest, "无效的 status")
+		return
+	}
+	limit, ok := queryInt(r, "limit", 50, 1, 1000)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "无效的 limit")
+		return
+	}
+	sub, err := s.repository.GetWebhookSubscription(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if sub == nil {
+		writeError(w, http.StatusNotFound, "订阅不存在")
+		return
+	}
+	deliveries, err := s.repository.GetWebhookDeliveries(id, status, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp := make([]webhookDeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		resp = append(resp, webhookDeliveryResponse{
+			ID:             d.ID,
+			OutboxID:       d.OutboxID,
+			EventType:      d.EventType,
+			Status:         d.Status,
+			Attempts:       d.Attempts,
+			NextAttemptAt:  d.NextAttemptAt,
+			LastStatusCode: d.LastStatusCode,
+			LastError:      d.LastError,
+			DeliveredAt:    d.DeliveredAt,
+			CreatedAt:      d.CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deliveries": resp})
+}
+
+// handleGetDeliveryAttempts 获取一次投递的每次请求结果
+func (s *Server) handleGetDeliveryAttempts(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("delivery_id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "无效的投递ID")
+		return
+	}
+	attempts, err := s.repository.GetDeliveryAttempts(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp := make([]deliveryAttemptResponse, 0, len(attempts))
+	for _, a := range attempts {
+		resp = append(resp, deliveryAttemptResponse{
+			Attempt:    a.Attempt,
+			StatusCode: a.StatusCode,
+			Error:      a.Error,
+			DurationMs: a.Duration.Milliseconds(),
+			CreatedAt:  a.CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"attempts": resp})
+}
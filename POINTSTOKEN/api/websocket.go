@@ -0,0 +1,288 @@
+package api
+
+import (
+	"POINTSTOKEN/db"
+	"POINTSTOKEN/service"
+	"errors"
+	"github.com/gorilla/websocket"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	wsWriteWait    = 10 * time.Second
+	wsPongWait     = 60 * time.Second
+	wsPingPeriod   = 30 * time.Second
+	wsMaxAddresses = 100
+	wsReplayPage   = 500
+	wsMaxReplay    = 10000 //单次重连最多补发的事件数，超过时客户端需通过查询接口重新同步
+)
+
+// wsBalanceMessage 余额变动推送，id 为 balance_changes 记录ID，cursor 为发件箱序号
+type wsBalanceMessage struct {
+	Type         string    `json:"type"`
+	Cursor       uint64    `json:"cursor"`
+	ID           uint64    `json:"id"`
+	ChainID      uint64    `json:"chain_id"`
+	Address      string    `json:"address"`
+	TxHash       string    `json:"tx_hash"`
+	BlockNumber  uint64    `json:"block_number"`
+	EventType    string    `json:"event_type"`
+	ChangeAmount string    `json:"change_amount"`
+	BalanceAfter string    `json:"balance_after"`
+	Timestamp    time.Time `json:"timestamp"`
+	Replayed     bool      `json:"replayed,omitempty"`
+}
+
+// wsPointsMessage 积分变化推送，ledger_id 为 points_ledger 分录ID，cursor 为发件箱序号，出账时 delta 为负数
+type wsPointsMessage struct {
+	Type       string    `json:"type"`
+	Cursor     uint64    `json:"cursor"`
+	LedgerID   uint64    `json:"ledger_id"`
+	ChainID    uint64    `json:"chain_id"`
+	Address    string    `json:"address"`
+	EntryType  string    `json:"entry_type"`
+	Delta      string    `json:"delta"`
+	TotalAfter string    `json:"total_after"`
+	Timestamp  time.Time `json:"timestamp"`
+	Replayed   bool      `json:"replayed,omitempty"`
+}
+
+type wsErrorMessage struct {
+	Type  string `json:"type"`
+	Error string `json:"error"`
+}
+
+// wsSubscription 连接参数：address、chain_id 可重复，events 为逗号分隔的事件类型，
+// cursor 为断线前收到的最后一条消息的 cursor，提供时先按发件箱顺序补发之后的事件；
+// 浏览器无法设置请求头时通过 access_token 传递认证令牌
+type wsSubscription struct {
+	filter service.EventFilter
+	cursor *uint64
+}
+
+func parseWSSubscription(r *http.Request) (*wsSubscription, error) {
+	query := r.URL.Query()
+	sub := &wsSubscription{}
+	if len(query["address"]) > wsMaxAddresses {
+		return nil, errors.New("订阅地址过多")
+	}
+	for _, value := range query["address"] {
+		userAddr, ok := parseAddress(value)
+		if !ok {
+			return nil, errors.New("无效的地址")
+		}
+		sub.filter.Addresses = append(sub.filter.Addresses, userAddr)
+	}
+	for _, value := range query["chain_id"] {
+		chainID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, errors.New("无效的链ID")
+		}
+		sub.filter.ChainIDs = append(sub.filter.ChainIDs, chainID)
+	}
+	sub.filter.Types = []string{service.EventBalanceChange, service.EventPointsDelta}
+	if value := query.Get("events"); value != "" {
+		sub.filter.Types = nil
+		for _, eventType := range strings.Split(value, ",") {
+			if eventType != service.EventBalanceChange && eventType != service.EventPointsDelta {
+				return nil, errors.New("无效的事件类型: " + eventType)
+			}
+			sub.filter.Types = append(sub.filter.Types, eventType)
+		}
+	}
+	if value := query.Get("cursor"); value != "" {
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, errors.New("无效的 cursor")
+		}
+		sub.cursor = &n
+	}
+	return sub, nil
+}
+
+func (s *Server) wsUpgrader() *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 4096,
+		CheckOrigin: func(r *http.Request) bool {
+			//未配置时允许所有来源，推送的数据与公开查询接口一致
+			if len(s.cfg.WebSocketOrigins) == 0 {
+				return true
+			}
+			origin := r.Header.Get("Origin")
+			for _, allowed := range s.cfg.WebSocketOrigins {
+				if origin == allowed {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// handleWebSocket 推送订阅地址的余额变动和积分变化，会话只能订阅自己关联的地址
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	sub, err := parseWSSubscription(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.withPrincipal(func(w http.ResponseWriter, r *http.Request) {
+		s.serveWebSocket(w, r, sub)
+	}, func(p *principal, r *http.Request) (bool, error) {
+		if p.canRead() {
+			return true, nil
+		}
+		return s.ownsAddresses(p, sub.filter.Addresses)
+	})(w, r)
+}
+
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request, sub *wsSubscription) {
+	conn, err := s.wsUpgrader().Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	//先订阅再补发，补发期间产生的事件在补发后按 cursor 去重
+	events := s.events.Subscribe(sub.filter)
+	defer events.Close()
+
+	//读取客户端消息以处理 pong 和关闭帧
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.SetReadLimit(512)
+		_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(v interface{}) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		return conn.WriteJSON(v)
+	}
+
+	var lastSeq uint64
+	if err := s.wsReplay(sub, send, &lastSeq); err != nil {
+		_ = send(wsErrorMessage{Type: "error", Error: err.Error()})
+		return
+	}
+
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case e, ok := <-events.C:
+			if !ok {
+				_ = send(wsErrorMessage{Type: "error", Error: "消费过慢，订阅已断开，请使用 cursor 重连"})
+				return
+			}
+			if e.Seq <= lastSeq {
+				continue
+			}
+			msg := newWSMessage(e, false)
+			if msg == nil {
+				continue
+			}
+			if err := send(msg); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// wsReplay 按发件箱序号补发 cursor 之后的事件，记录补发到的最后序号
+func (s *Server) wsReplay(sub *wsSubscription, send func(interface{}) error, lastSeq *uint64) error {
+	if sub.cursor == nil {
+		return nil
+	}
+	*lastSeq = *sub.cursor
+	filter := db.EventFilter{ChainIDs: sub.filter.ChainIDs, UserAddrs: sub.filter.Addresses}
+	eventTypes := service.OutboxEventTypes(sub.filter.Types)
+	replayed := 0
+	for {
+		events, err := s.repository.GetOutboxEventsAfter(*lastSeq, eventTypes, filter, wsReplayPage)
+		if err != nil {
+			log.Printf("补发事件失败: %v", err)
+			return errors.New("补发事件失败")
+		}
+		for i := range events {
+			e, err := service.EventFromOutbox(&events[i])
+			if err != nil {
+				return err
+			}
+			if err := send(newWSMessage(e, true)); err != nil {
+				return err
+			}
+			*lastSeq = e.Seq
+		}
+		replayed += len(events)
+		if len(events) < wsReplayPage {
+			return nil
+		}
+		if replayed >= wsMaxReplay {
+			return errors.New("需补发的事件过多，请通过查询接口重新同步")
+		}
+	}
+}
+
+// newWSMessage 转换为推送消息，未知的事件类型返回 nil
+func newWSMessage(e service.Event, replayed bool) interface{} {
+	switch e.Type {
+	case service.EventBalanceChange:
+		msg := newWSBalanceMessage(e.Balance)
+		msg.Cursor, msg.Replayed = e.Seq, replayed
+		return msg
+	case service.EventPointsDelta:
+		msg := newWSPointsMessage(e.Points)
+		msg.Cursor, msg.Replayed = e.Seq, replayed
+		return msg
+	}
+	return nil
+}
+
+func newWSBalanceMessage(b *service.BalanceEvent) wsBalanceMessage {
+	return wsBalanceMessage{
+		Type:         service.EventBalanceChange,
+		ID:           b.ID,
+		ChainID:      b.ChainID,
+		Address:      b.UserAddr.Hex(),
+		TxHash:       b.TxHash.Hex(),
+		BlockNumber:  b.BlockNumber,
+		EventType:    b.EventType,
+		ChangeAmount: b.ChangeAmount.String(),
+		BalanceAfter: b.BalanceAfter.String(),
+		Timestamp:    b.Timestamp,
+	}
+}
+
+func newWSPointsMessage(p *service.PointsEvent) wsPointsMessage {
+	return wsPointsMessage{
+		Type:       service.EventPointsDelta,
+		LedgerID:   p.LedgerID,
+		ChainID:    p.ChainID,
+		Address:    p.UserAddr.Hex(),
+		EntryType:  p.EntryType,
+		Delta:      p.Delta.String(),
+		TotalAfter: p.TotalAfter.String(),
+		Timestamp:  p.CalculatedAt,
+	}
+}
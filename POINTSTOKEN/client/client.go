@@ -0,0 +1,328 @@
+// Package client 积分服务查询接口的 Go 客户端，与 api/openapi.yaml 描述的接口对应
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError 接口返回的错误
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+// Client 查询接口客户端，可并发使用
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// Option 客户端选项
+type Option func(*Client)
+
+// WithToken 设置认证令牌：API 密钥或钱包登录会话令牌
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient 设置发送请求的 HTTP 客户端，默认超时 30 秒
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// New 创建客户端，baseURL 为服务地址，如 http://localhost:8080
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/") + "/api/v1",
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// do 发送请求并解析 JSON 响应，out 为 nil 时忽略响应体
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body interface{}, out interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(data, &e) != nil || e.Error == "" {
+			e.Error = strings.TrimSpace(string(data))
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: e.Error}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func userPath(chainID uint64, userAddr common.Address, suffix string) string {
+	return fmt.Sprintf("/chains/%d/users/%s/%s", chainID, userAddr.Hex(), suffix)
+}
+
+func (q *HistoryQuery) values() url.Values {
+	v := url.Values{}
+	if q == nil {
+		return v
+	}
+	if q.Cursor != "" {
+		v.Set("cursor", q.Cursor)
+	}
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if !q.FromTime.IsZero() {
+		v.Set("from_time", q.FromTime.Format(time.RFC3339))
+	}
+	if !q.ToTime.IsZero() {
+		v.Set("to_time", q.ToTime.Format(time.RFC3339))
+	}
+	if q.FromBlock > 0 {
+		v.Set("from_block", strconv.FormatUint(q.FromBlock, 10))
+	}
+	if q.ToBlock > 0 {
+		v.Set("to_block", strconv.FormatUint(q.ToBlock, 10))
+	}
+	return v
+}
+
+func (q AtQuery) values() url.Values {
+	v := url.Values{}
+	if !q.Time.IsZero() {
+		v.Set("time", q.Time.Format(time.RFC3339))
+	} else {
+		v.Set("block", strconv.FormatUint(q.Block, 10))
+	}
+	return v
+}
+
+func (q *LeaderboardQuery) values() url.Values {
+	v := url.Values{}
+	if q == nil {
+		return v
+	}
+	if q.Window != "" {
+		v.Set("window", q.Window)
+	}
+	if q.ChainID > 0 {
+		v.Set("chain_id", strconv.FormatUint(q.ChainID, 10))
+	}
+	if !q.Date.IsZero() {
+		v.Set("date", q.Date.Format(time.RFC3339))
+	}
+	if q.Campaign != "" {
+		v.Set("campaign", q.Campaign)
+	}
+	return v
+}
+
+// GetBalance 获取用户在链上的余额
+func (c *Client) GetBalance(ctx context.Context, chainID uint64, userAddr common.Address) (*Balance, error) {
+	var out Balance
+	if err := c.do(ctx, http.MethodGet, userPath(chainID, userAddr, "balance"), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetBalanceHistory 分页获取余额变动记录，query 可为 nil
+func (c *Client) GetBalanceHistory(ctx context.Context, chainID uint64, userAddr common.Address, query *HistoryQuery) (*BalanceHistory, error) {
+	var out BalanceHistory
+	if err := c.do(ctx, http.MethodGet, userPath(chainID, userAddr, "balance-changes"), query.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetBalanceAt 获取用户在指定区块高度或时间的余额
+func (c *Client) GetBalanceAt(ctx context.Context, chainID uint64, userAddr common.Address, at AtQuery) (*HistoricalBalance, error) {
+	var out HistoricalBalance
+	if err := c.do(ctx, http.MethodGet, userPath(chainID, userAddr, "balance-at"), at.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetHolders 按地址顺序分页获取指定区块高度或时间的持有人，需要 read 权限的 API 密钥
+// cursor 为上一页的 NextCursor，第一页为空；limit 为 0 时默认 1000
+func (c *Client) GetHolders(ctx context.Context, chainID uint64, at AtQuery, cursor string, limit int) (*Holders, error) {
+	v := at.values()
+	if cursor != "" {
+		v.Set("cursor", cursor)
+	}
+	if limit > 0 {
+		v.Set("limit", strconv.Itoa(limit))
+	}
+	var out Holders
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/chains/%d/holders", chainID), v, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetPoints 获取用户在链上的积分
+func (c *Client) GetPoints(ctx context.Context, chainID uint64, userAddr common.Address) (*Points, error) {
+	var out Points
+	if err := c.do(ctx, http.MethodGet, userPath(chainID, userAddr, "points"), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetPointsHistory 分页获取积分计算记录，query 可为 nil
+func (c *Client) GetPointsHistory(ctx context.Context, chainID uint64, userAddr common.Address, query *HistoryQuery) (*PointsHistory, error) {
+	var out PointsHistory
+	if err := c.do(ctx, http.MethodGet, userPath(chainID, userAddr, "points-calculations"), query.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAggregatedPoints 获取用户关联的全部地址在各链上的积分及加权汇总
+func (c *Client) GetAggregatedPoints(ctx context.Context, userAddr common.Address) (*AggregatedPoints, error) {
+	var out AggregatedPoints
+	if err := c.do(ctx, http.MethodGet, "/points/"+userAddr.Hex()+"/aggregate", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetIdentity 获取地址所属用户及其关联的全部地址
+func (c *Client) GetIdentity(ctx context.Context, userAddr common.Address) (*Identity, error) {
+	var out Identity
+	if err := c.do(ctx, http.MethodGet, "/identities/"+userAddr.Hex(), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetReferees 获取推荐人直接推荐的地址
+func (c *Client) GetReferees(ctx context.Context, referrer common.Address) (*Referees, error) {
+	var out Referees
+	if err := c.do(ctx, http.MethodGet, "/referrals/"+referrer.Hex(), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLeaderboard 分页获取排行榜，query 可为 nil
+func (c *Client) GetLeaderboard(ctx context.Context, query *LeaderboardQuery, offset int, limit int) (*Leaderboard, error) {
+	v := query.values()
+	v.Set("offset", strconv.Itoa(offset))
+	if limit > 0 {
+		v.Set("limit", strconv.Itoa(limit))
+	}
+	var out Leaderboard
+	if err := c.do(ctx, http.MethodGet, "/leaderboard", v, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLeaderboardRank 获取用户的名次及前后各 neighbours 个用户，不在榜上时返回 404 的 APIError
+func (c *Client) GetLeaderboardRank(ctx context.Context, userAddr common.Address, query *LeaderboardQuery, neighbours int) (*LeaderboardRank, error) {
+	v := query.values()
+	v.Set("neighbours", strconv.Itoa(neighbours))
+	var out LeaderboardRank
+	if err := c.do(ctx, http.MethodGet, "/leaderboard/"+userAddr.Hex(), v, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTiers 获取按从低到高排列的等级名称
+func (c *Client) GetTiers(ctx context.Context) ([]string, error) {
+	var out struct {
+		Tiers []string `json:"tiers"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/tiers", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Tiers, nil
+}
+
+// GetUserTier 获取用户当前等级及变更记录
+func (c *Client) GetUserTier(ctx context.Context, chainID uint64, userAddr common.Address) (*UserTier, error) {
+	v := url.Values{"chain_id": {strconv.FormatUint(chainID, 10)}}
+	var out UserTier
+	if err := c.do(ctx, http.MethodGet, "/tiers/"+userAddr.Hex(), v, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetNonce 获取钱包登录随机数
+func (c *Client) GetNonce(ctx context.Context) (*Nonce, error) {
+	var out Nonce
+	if err := c.do(ctx, http.MethodGet, "/auth/nonce", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Login 提交 EIP-4361 登录消息及签名，返回的会话令牌可通过 WithToken 用于新的客户端
+func (c *Client) Login(ctx context.Context, message string, signature string) (*Session, error) {
+	body := map[string]string{"message": message, "signature": signature}
+	var out Session
+	if err := c.do(ctx, http.MethodPost, "/auth/login", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Logout 注销客户端当前使用的会话令牌
+func (c *Client) Logout(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/auth/logout", nil, nil, nil)
+}
@@ -0,0 +1,213 @@
+package client
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// Amount 接口中以十进制字符串表示的金额或积分
+type Amount struct {
+	*big.Int
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		return fmt.Errorf("无效的金额: %s", data)
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return fmt.Errorf("无效的金额: %s", s)
+	}
+	a.Int = n
+	return nil
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	if a.Int == nil {
+		return []byte(`"0"`), nil
+	}
+	return []byte(strconv.Quote(a.Int.String())), nil
+}
+
+// Balance 用户在链上的余额
+type Balance struct {
+	ChainID uint64         `json:"chain_id"`
+	Address common.Address `json:"address"`
+	Balance Amount         `json:"balance"`
+}
+
+// HistoricalBalance 用户在某一区块高度的余额，从未变动时 LastChangeBlock 为 0、LastChangeAt 为 nil
+type HistoricalBalance struct {
+	ChainID         uint64         `json:"chain_id"`
+	Address         common.Address `json:"address"`
+	BlockNumber     uint64         `json:"block_number"`
+	Balance         Amount         `json:"balance"`
+	LastChangeBlock uint64         `json:"last_change_block"`
+	LastChangeAt    *time.Time     `json:"last_change_at"`
+}
+
+// AtQuery 历史查询的区块高度或时间，二者只能指定一个
+type AtQuery struct {
+	Block uint64
+	Time  time.Time //取该时间及之前最后一次余额变动所在的区块
+}
+
+type Holder struct {
+	Address common.Address `json:"address"`
+	Balance Amount         `json:"balance"`
+}
+
+// Holders 持有人快照的一页，NextCursor 为空表示没有下一页
+type Holders struct {
+	ChainID      uint64   `json:"chain_id"`
+	BlockNumber  uint64   `json:"block_number"`
+	Materialized bool     `json:"materialized"`
+	Holders      []Holder `json:"holders"`
+	NextCursor   string   `json:"next_cursor"`
+}
+
+// Points 用户在链上的积分，从未计算过时 LastCalculatedAt 为 nil
+type Points struct {
+	ChainID          uint64         `json:"chain_id"`
+	Address          common.Address `json:"address"`
+	Points           Amount         `json:"points"`
+	LastCalculatedAt *time.Time     `json:"last_calculated_at"`
+}
+
+// HistoryQuery 分页和过滤参数，零值表示不过滤
+type HistoryQuery struct {
+	Cursor    string //上一页返回的 NextCursor
+	Limit     int    //默认 50，最大 500
+	FromTime  time.Time
+	ToTime    time.Time
+	FromBlock uint64
+	ToBlock   uint64
+}
+
+type BalanceChange struct {
+	ID           uint64    `json:"id"`
+	TxHash       string    `json:"tx_hash"`
+	BlockNumber  uint64    `json:"block_number"`
+	ChangeAmount Amount    `json:"change_amount"`
+	BalanceAfter Amount    `json:"balance_after"`
+	EventType    string    `json:"event_type"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// BalanceHistory 余额变动记录，NextCursor 为空表示没有下一页
+type BalanceHistory struct {
+	ChainID    uint64          `json:"chain_id"`
+	Address    common.Address  `json:"address"`
+	Items      []BalanceChange `json:"items"`
+	NextCursor string          `json:"next_cursor"`
+}
+
+type PointsCalculation struct {
+	ID               uint64    `json:"id"`
+	CalculatedAt     time.Time `json:"calculated_at"`
+	Balance          Amount    `json:"balance"`
+	PointsAdded      Amount    `json:"points_added"`
+	TotalPointsAfter Amount    `json:"total_points_after"`
+}
+
+// PointsHistory 积分计算记录，NextCursor 为空表示没有下一页
+type PointsHistory struct {
+	ChainID    uint64              `json:"chain_id"`
+	Address    common.Address      `json:"address"`
+	Items      []PointsCalculation `json:"items"`
+	NextCursor string              `json:"next_cursor"`
+}
+
+type ChainPoints struct {
+	ChainID  uint64         `json:"chain_id"`
+	Name     string         `json:"name"`
+	Address  common.Address `json:"address"`
+	Points   Amount         `json:"points"`
+	Weight   float64        `json:"weight"`
+	Weighted Amount         `json:"weighted"`
+}
+
+// AggregatedPoints 用户关联的全部地址在各链上的积分及加权汇总
+type AggregatedPoints struct {
+	Address   common.Address   `json:"address"`
+	Addresses []common.Address `json:"addresses"`
+	Total     Amount           `json:"total"`
+	Chains    []ChainPoints    `json:"chains"`
+}
+
+type Identity struct {
+	Identity  common.Address   `json:"identity"`
+	Addresses []common.Address `json:"addresses"`
+}
+
+type Referee struct {
+	Referee   common.Address `json:"referee"`
+	Source    string         `json:"source"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type Referees struct {
+	Referrer common.Address `json:"referrer"`
+	Referees []Referee      `json:"referees"`
+}
+
+// LeaderboardQuery 排行榜参数，Window 为空时为总榜，ChainID 为 0 时为跨链榜
+type LeaderboardQuery struct {
+	Window   string //total、daily、weekly、campaign
+	ChainID  uint64
+	Date     time.Time //日榜、周榜的日期，默认当前时间
+	Campaign string
+}
+
+type LeaderboardEntry struct {
+	Rank    uint64         `json:"rank"`
+	Address common.Address `json:"address"`
+	Score   Amount         `json:"score"`
+}
+
+type Leaderboard struct {
+	Board   string             `json:"board"`
+	ChainID uint64             `json:"chain_id"`
+	Entries []LeaderboardEntry `json:"entries"`
+}
+
+type LeaderboardRank struct {
+	Board   string             `json:"board"`
+	Rank    uint64             `json:"rank"`
+	Address common.Address     `json:"address"`
+	Score   Amount             `json:"score"`
+	Above   []LeaderboardEntry `json:"above"`
+	Below   []LeaderboardEntry `json:"below"`
+}
+
+type TierChange struct {
+	FromTier  string    `json:"from_tier"`
+	ToTier    string    `json:"to_tier"`
+	Direction string    `json:"direction"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// UserTier 用户当前等级，未达到任何等级时 Tier 为空
+type UserTier struct {
+	ChainID uint64         `json:"chain_id"`
+	Address common.Address `json:"address"`
+	Tier    string         `json:"tier"`
+	Since   *time.Time     `json:"since"`
+	History []TierChange   `json:"history"`
+}
+
+type Nonce struct {
+	Nonce     string    `json:"nonce"`
+	Domain    string    `json:"domain"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type Session struct {
+	Token     string         `json:"token"`
+	Address   common.Address `json:"address"`
+	ExpiresAt time.Time      `json:"expires_at"`
+}
@@ -0,0 +1,724 @@
+package main
+
+import (
+	"POINTSTOKEN/config"
+	"POINTSTOKEN/db"
+	"POINTSTOKEN/service"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// runCommand 执行子命令
+func runCommand(name string, args []string) error {
+	switch name {
+	case "voucher":
+		return runVoucherCommand(args)
+	case "exclusions":
+		return runExclusionsCommand(args)
+	case "washflags":
+		return runWashFlagsCommand(args)
+	case "points":
+		return runPointsCommand(args)
+	case "adjust":
+		return runAdjustCommand(args)
+	case "runs":
+		return runRunsCommand(args)
+	case "apikeys":
+		return runAPIKeysCommand(args)
+	case "export":
+		return runExportCommand(args)
+	case "snapshots":
+		return runSnapshotsCommand(args)
+	case "balances":
+		return runBalancesCommand(args)
+	default:
+		return fmt.Errorf("未知命令: %s", name)
+	}
+}
+
+// openRepository 加载配置并连接数据库
+func openRepository(cfgPath string) (*config.Config, *db.DB, *db.DBRepository, error) {
+	cfg, err := config.LoadConfigFile(cfgPath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("加载配置失败: %w", err)
+	}
+	database, err := db.NewDB(cfg.Database)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("连接数据库失败: %w", err)
+	}
+	return cfg, database, db.NewDBRepository(database), nil
+}
+
+// runVoucherCommand 为用户签发积分兑换凭证，或退回过期未兑换凭证的积分
+// 用法: voucher -chain 11155111 -user 0x... -amount 100
+//
+//	voucher refund
+func runVoucherCommand(args []string) error {
+	if len(args) > 0 && args[0] == "refund" {
+		return runVoucherRefundCommand(args[1:])
+	}
+	fs := flag.NewFlagSet("voucher", flag.ExitOnError)
+	cfgPath := fs.String("config", "config.yaml", "Path to configuration file")
+	chainID := fs.Uint64("chain", 0, "积分所在链ID")
+	user := fs.String("user", "", "用户地址")
+	amount := fs.String("amount", "", "兑换积分数量")
+	fs.Parse(args)
+
+	if !common.IsHexAddress(*user) {
+		return fmt.Errorf("无效的用户地址: %s", *user)
+	}
+	amountInt, ok := new(big.Int).SetString(*amount, 10)
+	if !ok {
+		return fmt.Errorf("无效的兑换数量: %s", *amount)
+	}
+
+	cfg, database, repo, err := openRepository(*cfgPath)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	voucherService, err := service.NewVoucherService(&cfg.Voucher, repo)
+	if err != nil {
+		return err
+	}
+	userAddr := common.HexToAddress(*user)
+	voucher, err := voucherService.IssueVoucher(*chainID, userAddr, amountInt)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]interface{}{
+		"signer":    voucherService.Signer().Hex(),
+		"signature": voucher.Signature,
+		"typedData": voucherService.TypedData(userAddr, amountInt, voucher.Nonce, voucher.Deadline),
+	})
+}
+
+// runVoucherRefundCommand 立即结清已过期的凭证，需配置 voucher.nonce_used_method
+func runVoucherRefundCommand(args []string) error {
+	fs := flag.NewFlagSet("voucher refund", flag.ExitOnError)
+	cfgPath := fs.String("config", "config.yaml", "Path to configuration file")
+	fs.Parse(args)
+
+	cfg, database, repo, err := openRepository(*cfgPath)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	refunder, err := service.NewVoucherRefunder(&cfg.Voucher, cfg.Chains, repo)
+	if err != nil {
+		return err
+	}
+	if refunder == nil {
+		return fmt.Errorf("未配置 voucher.nonce_used_method，无法确认凭证是否已兑换")
+	}
+	refunded, err := refunder.Refund(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已退回 %d 张过期凭证\n", refunded)
+	return nil
+}
+
+// runExclusionsCommand 管理积分排除名单
+// 用法: exclusions list|add|remove -chain 11155111 [-addr 0x...] [-reason 说明]
+func runExclusionsCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: exclusions list|add|remove [参数]")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("exclusions "+action, flag.ExitOnError)
+	cfgPath := fs.String("config", "config.yaml", "Path to configuration file")
+	chainID := fs.Uint64("chain", 0, "链ID，0 表示所有链")
+	addr := fs.String("addr", "", "地址")
+	reason := fs.String("reason", "", "排除原因")
+	fs.Parse(args[1:])
+
+	_, database, repo, err := openRepository(*cfgPath)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	switch action {
+	case "list":
+		exclusions, err := repo.GetExclusions(*chainID)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "CHAIN\tADDRESS\tSOURCE\tREASON")
+		for _, e := range exclusions {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", e.ChainID, e.UserAddr.Hex(), e.Source, e.Reason)
+		}
+		return w.Flush()
+	case "add", "remove":
+		if !common.IsHexAddress(*addr) {
+			return fmt.Errorf("无效的地址: %s", *addr)
+		}
+		if action == "remove" {
+			return repo.RemoveExclusion(*chainID, common.HexToAddress(*addr))
+		}
+		if *reason == "" {
+			return fmt.Errorf("请填写排除原因")
+		}
+		return repo.AddExclusion(*chainID, common.HexToAddress(*addr), *reason, db.ExclusionSourceManual)
+	default:
+		return fmt.Errorf("未知操作: %s", action)
+	}
+}
+
+// runWashFlagsCommand 查看和审核刷量标记
+// 用法: washflags list -chain 11155111 [-since 168h] [-status open]
+//
+//	washflags review -id 1 -status confirmed|dismissed
+func runWashFlagsCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: washflags list|review [参数]")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("washflags "+action, flag.ExitOnError)
+	cfgPath := fs.String("config", "config.yaml", "Path to configuration file")
+	chainID := fs.Uint64("chain", 0, "链ID")
+	since := fs.Duration("since", 7*24*time.Hour, "查看最近多长时间内的标记")
+	status := fs.String("status", "", "审核状态 open|confirmed|dismissed")
+	id := fs.Uint64("id", 0, "标记ID")
+	fs.Parse(args[1:])
+
+	_, database, repo, err := openRepository(*cfgPath)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	switch action {
+	case "list":
+		flags, err := repo.GetWashFlags(*chainID, time.Now().Add(-*since), *status)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tADDRESS\tTYPE\tSTATUS\tACTIVITY_AT\tTX\tDETAIL")
+		for _, f := range flags {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", f.ID, f.UserAddr.Hex(), f.FlagType, f.Status,
+				f.ActivityAt.Format(time.DateTime), f.TxHash.Hex(), f.Detail)
+		}
+		return w.Flush()
+	case "review":
+		if *status != db.WashStatusConfirmed && *status != db.WashStatusDismissed && *status != db.WashStatusOpen {
+			return fmt.Errorf("无效的审核状态: %s", *status)
+		}
+		return repo.UpdateWashFlagStatus(*id, *status)
+	default:
+		return fmt.Errorf("未知操作: %s", action)
+	}
+}
+
+// runPointsCommand 积分计算相关命令
+// 用法: points preview -chain 11155111 [-addr 0x...] [-until 2006-01-02T15:04:05Z] [-format table|json|csv]
+//
+//	points recompute -chain 11155111 [-until 2006-01-02T15:04:05Z] [-format table|json|csv] [-apply]
+func runPointsCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: points preview|recompute [参数]")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("points "+action, flag.ExitOnError)
+	cfgPath := fs.String("config", "config.yaml", "Path to configuration file")
+	chainID := fs.Uint64("chain", 0, "链ID")
+	addr := fs.String("addr", "", "只输出该地址")
+	until := fs.String("until", "", "计算截止时间（RFC3339），默认当前时间")
+	format := fs.String("format", "table", "输出格式 table|json|csv")
+	apply := fs.Bool("apply", false, "重算后用结果替换线上积分")
+	fs.Parse(args[1:])
+
+	untilTime := time.Now()
+	if *until != "" {
+		var err error
+		if untilTime, err = time.Parse(time.RFC3339, *until); err != nil {
+			return fmt.Errorf("无效的截止时间: %w", err)
+		}
+	}
+
+	cfg, database, repo, err := openRepository(*cfgPath)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+	calculator := service.NewPointsCalculator(&cfg.Points, repo)
+
+	switch action {
+	case "preview":
+		var userAddr *common.Address
+		if *addr != "" {
+			if !common.IsHexAddress(*addr) {
+				return fmt.Errorf("无效的地址: %s", *addr)
+			}
+			a := common.HexToAddress(*addr)
+			userAddr = &a
+		}
+		preview, err := calculator.Preview(*chainID, untilTime, userAddr)
+		if err != nil {
+			return err
+		}
+		return service.WritePreview(os.Stdout, preview, *format)
+	case "recompute":
+		recomputation, err := calculator.Recompute(*chainID, untilTime)
+		if err != nil {
+			return err
+		}
+		if err := service.WriteRecomputation(os.Stdout, recomputation, *format); err != nil {
+			return err
+		}
+		if !*apply {
+			return nil
+		}
+		adjusted, clamped, err := calculator.ApplyRecomputation(*chainID)
+		if err != nil {
+			return fmt.Errorf("替换线上积分失败: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "已替换链 %d 的线上积分，调整用户 %d，积分不足冲回截断 %d\n", *chainID, adjusted, clamped)
+		return nil
+	default:
+		return fmt.Errorf("未知操作: %s", action)
+	}
+}
+
+// runAdjustCommand 人工发放、扣回积分
+// 用法: adjust grant|clawback -chain 11155111 -addr 0x... -amount 100 -reason "AMA" [-key ...] [-operator name]
+//
+//	adjust import -file adjustments.csv [-operator name]
+//	adjust list [-chain 11155111] [-limit 100]
+func runAdjustCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: adjust grant|clawback|import|list [参数]")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("adjust "+action, flag.ExitOnError)
+	cfgPath := fs.String("config", "config.yaml", "Path to configuration file")
+	chainID := fs.Uint64("chain", 0, "链ID")
+	addr := fs.String("addr", "", "用户地址")
+	amount := fs.String("amount", "", "积分数量")
+	reason := fs.String("reason", "", "调整原因")
+	key := fs.String("key", "", "幂等键，默认随机生成；重试时传入上次输出的键")
+	operator := fs.String("operator", os.Getenv("USER"), "操作人")
+	file := fs.String("file", "", "批量调整 CSV 文件：chain_id,address,action,amount,reason[,key]")
+	limit := fs.Int("limit", 100, "显示条数")
+	fs.Parse(args[1:])
+
+	_, database, repo, err := openRepository(*cfgPath)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+	adjustment := service.NewAdjustmentService(repo)
+
+	switch action {
+	case db.LedgerGrant, db.LedgerClawback:
+		if !common.IsHexAddress(*addr) {
+			return fmt.Errorf("无效的地址: %s", *addr)
+		}
+		amountInt, ok := new(big.Int).SetString(*amount, 10)
+		if !ok {
+			return fmt.Errorf("无效的积分数量: %s", *amount)
+		}
+		entry, err := adjustment.Adjust(service.PointsAdjustment{
+			ChainID:  *chainID,
+			UserAddr: common.HexToAddress(*addr),
+			Action:   action,
+			Amount:   amountInt,
+			Reason:   *reason,
+			Key:      *key,
+		}, *operator)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("分录 %d: %s %s %s (%s)\n", entry.ID, entry.EntryType, *addr, entry.Amount.ToBigInt().String(), entry.IdempotencyKey)
+		return nil
+	case "import":
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		adjs, err := service.ParseAdjustmentsCSV(f)
+		if err != nil {
+			return err
+		}
+		failed := 0
+		for _, result := range adjustment.Import(adjs, *operator) {
+			if result.Err != nil {
+				failed++
+				fmt.Fprintf(os.Stderr, "第 %d 行失败: %v\n", result.Line, result.Err)
+				continue
+			}
+			fmt.Printf("第 %d 行: 分录 %d %s %s %s (%s)\n", result.Line, result.Entry.ID, result.Entry.EntryType,
+				result.Adjustment.UserAddr.Hex(), result.Entry.Amount.ToBigInt().String(), result.Entry.IdempotencyKey)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d/%d 条调整失败", failed, len(adjs))
+		}
+		return nil
+	case "list":
+		entries, err := adjustment.GetAdjustments(*chainID, *limit)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tCHAIN\tTYPE\tDEBIT\tCREDIT\tAMOUNT\tOPERATOR\tCREATED_AT\tREASON")
+		for _, e := range entries {
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.ID, e.ChainID, e.EntryType, e.DebitAccount,
+				e.CreditAccount, e.Amount.ToBigInt().String(), e.Operator, e.CreatedAt.Format(time.DateTime), e.Memo)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("未知操作: %s", action)
+	}
+}
+
+// runRunsCommand 查看积分计算运行记录并与账本核对
+// 用法: runs list [-chain 11155111] [-limit 50]
+//
+//	runs verify -id 1
+func runRunsCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: runs list|verify [参数]")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("runs "+action, flag.ExitOnError)
+	cfgPath := fs.String("config", "config.yaml", "Path to configuration file")
+	chainID := fs.Uint64("chain", 0, "链ID")
+	limit := fs.Int("limit", 50, "显示条数")
+	id := fs.Uint64("id", 0, "运行记录ID")
+	fs.Parse(args[1:])
+
+	cfg, database, repo, err := openRepository(*cfgPath)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+	calculator := service.NewPointsCalculator(&cfg.Points, repo)
+
+	switch action {
+	case "list":
+		runs, err := calculator.GetRuns(*chainID, *limit)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tCHAIN\tSTATUS\tRULES\tSTARTED_AT\tDURATION\tUSERS\tEMITTED\tREFERRAL\tENTRIES\tERROR")
+		for _, run := range runs {
+			duration := "-"
+			if run.FinishedAt != nil {
+				duration = run.FinishedAt.Sub(run.StartedAt).String()
+			}
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%d\t%s\n", run.ID, run.ChainID, run.Status, run.RuleVersion,
+				run.StartedAt.Format(time.DateTime), duration, run.UsersProcessed, run.PointsEmitted.ToBigInt().String(),
+				run.ReferralPoints.ToBigInt().String(), run.EntriesCount, run.Error)
+		}
+		return w.Flush()
+	case "verify":
+		v, err := calculator.VerifyRun(*id)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("运行 %d（链 %d，计算时间 %s，规则 %s）: 分录 %d, 发放 %s, 推荐奖励 %s\n", v.Run.ID, v.Run.ChainID,
+			v.Run.CalculatedAt.Format(time.DateTime), v.Run.RuleVersion, v.LedgerEntries, v.LedgerEmitted.String(), v.LedgerReferral.String())
+		if len(v.Mismatches) == 0 {
+			fmt.Println("核对通过")
+			return nil
+		}
+		for _, m := range v.Mismatches {
+			fmt.Println("不一致:", m)
+		}
+		return fmt.Errorf("运行 %d 与账本不一致", v.Run.ID)
+	default:
+		return fmt.Errorf("未知操作: %s", action)
+	}
+}
+
+// runAPIKeysCommand 管理 API 密钥
+// 用法: apikeys list
+//
+//	apikeys create -name 数据团队 -scopes read
+//	apikeys revoke -id 1
+func runAPIKeysCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: apikeys list|create|revoke [参数]")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("apikeys "+action, flag.ExitOnError)
+	cfgPath := fs.String("config", "config.yaml", "Path to configuration file")
+	name := fs.String("name", "", "密钥名称")
+	scopes := fs.String("scopes", db.ScopeRead, "逗号分隔的权限 read|admin")
+	id := fs.Uint64("id", 0, "密钥ID")
+	fs.Parse(args[1:])
+
+	cfg, database, repo, err := openRepository(*cfgPath)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+	auth := service.NewAuthService(cfg.API.Auth, repo)
+
+	switch action {
+	case "list":
+		keys, err := auth.GetAPIKeys()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSCOPES\tCREATED_BY\tCREATED_AT\tLAST_USED_AT\tREVOKED")
+		for _, key := range keys {
+			lastUsed := "-"
+			if key.LastUsedAt != nil {
+				lastUsed = key.LastUsedAt.Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%t\n", key.ID, key.Name, key.KeyPrefix, strings.Join(key.Scopes, ","),
+				key.CreatedBy, key.CreatedAt.Format(time.DateTime), lastUsed, key.RevokedAt != nil)
+		}
+		return w.Flush()
+	case "create":
+		token, key, err := auth.CreateAPIKey(*name, strings.Split(*scopes, ","), "cli")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("已创建 API 密钥 %d（%s），请妥善保存，密钥不会再次显示:\n%s\n", key.ID, key.Name, token)
+		return nil
+	case "revoke":
+		found, err := auth.RevokeAPIKey(*id)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("密钥 %d 不存在或已吊销", *id)
+		}
+		return nil
+	default:
+		return fmt.Errorf("未知操作: %s", action)
+	}
+}
+
+// runExportCommand 导出余额、余额变动或积分分录
+// 用法: export -chain 11155111 -dataset ledger -from 2026-09-01T00:00:00Z -to 2026-10-01T00:00:00Z -format parquet -out ledger.parquet
+func runExportCommand(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	cfgPath := fs.String("config", "config.yaml", "Path to configuration file")
+	chainID := fs.Uint64("chain", 0, "链ID")
+	dataset := fs.String("dataset", service.ExportBalances, "数据集 balances|balance-changes|ledger")
+	from := fs.String("from", "", "开始时间（RFC3339，包含），余额导出不需要")
+	to := fs.String("to", "", "结束时间（RFC3339，不包含），默认当前时间")
+	format := fs.String("format", service.ExportCSV, "导出格式 csv|parquet")
+	outPath := fs.String("out", "", "输出文件，默认标准输出")
+	fs.Parse(args)
+
+	req := service.ExportRequest{Dataset: *dataset, Format: *format, ChainID: *chainID, To: time.Now()}
+	var err error
+	if *from != "" {
+		if req.From, err = time.Parse(time.RFC3339, *from); err != nil {
+			return fmt.Errorf("无效的开始时间: %w", err)
+		}
+	}
+	if *to != "" {
+		if req.To, err = time.Parse(time.RFC3339, *to); err != nil {
+			return fmt.Errorf("无效的结束时间: %w", err)
+		}
+	}
+	if err = req.Validate(); err != nil {
+		return err
+	}
+
+	_, database, repo, err := openRepository(*cfgPath)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	out := os.Stdout
+	if *outPath != "" {
+		if out, err = os.Create(*outPath); err != nil {
+			return err
+		}
+	}
+	rows, err := service.NewExportService(repo).Export(out, req)
+	if *outPath != "" {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("导出失败（已写出 %d 行）: %w", rows, err)
+	}
+	fmt.Fprintf(os.Stderr, "已导出 %d 行\n", rows)
+	return nil
+}
+
+// runSnapshotsCommand 查询历史持有人及管理物化快照，-block 与 -time 二选一
+// 用法: snapshots list -chain 11155111
+//
+//	snapshots create -chain 11155111 -block 6000000
+//	snapshots holders -chain 11155111 -time 2026-10-01T00:00:00Z -out holders.csv
+//	snapshots delete -id 1
+func runSnapshotsCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: snapshots list|create|holders|delete [参数]")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("snapshots "+action, flag.ExitOnError)
+	cfgPath := fs.String("config", "config.yaml", "Path to configuration file")
+	chainID := fs.Uint64("chain", 0, "链ID")
+	block := fs.Uint64("block", 0, "区块高度")
+	at := fs.String("time", "", "时间（RFC3339），取该时间及之前最后一次余额变动的区块")
+	id := fs.Uint64("id", 0, "快照ID")
+	outPath := fs.String("out", "", "持有人 CSV 输出文件，默认标准输出")
+	fs.Parse(args[1:])
+
+	_, database, repo, err := openRepository(*cfgPath)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+	snapshots := service.NewSnapshotService(repo)
+
+	resolveBlock := func() (uint64, error) {
+		var atTime time.Time
+		if *at != "" {
+			if atTime, err = time.Parse(time.RFC3339, *at); err != nil {
+				return 0, fmt.Errorf("无效的时间: %w", err)
+			}
+		}
+		return snapshots.ResolveBlock(*chainID, *block, atTime)
+	}
+
+	switch action {
+	case "list":
+		list, err := snapshots.GetSnapshots(*chainID)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tCHAIN\tBLOCK\tHOLDERS\tTOTAL_BALANCE\tCREATED_BY\tCREATED_AT")
+		for _, snapshot := range list {
+			fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%s\t%s\t%s\n", snapshot.ID, snapshot.ChainID, snapshot.BlockNumber, snapshot.Holders,
+				snapshot.TotalBalance.ToBigInt().String(), snapshot.CreatedBy, snapshot.CreatedAt.Format(time.DateTime))
+		}
+		return w.Flush()
+	case "create":
+		blockNumber, err := resolveBlock()
+		if err != nil {
+			return err
+		}
+		snapshot, err := snapshots.CreateSnapshot(*chainID, blockNumber, "cli")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("已创建快照 %d: 链 %d 区块 %d，持有人 %d，总余额 %s\n", snapshot.ID, snapshot.ChainID, snapshot.BlockNumber,
+			snapshot.Holders, snapshot.TotalBalance.ToBigInt().String())
+		return nil
+	case "holders":
+		blockNumber, err := resolveBlock()
+		if err != nil {
+			return err
+		}
+		out := os.Stdout
+		if *outPath != "" {
+			if out, err = os.Create(*outPath); err != nil {
+				return err
+			}
+			defer out.Close()
+		}
+		w := csv.NewWriter(out)
+		w.Write([]string{"address", "balance"})
+		var rows int
+		err = snapshots.StreamHolders(*chainID, blockNumber, func(h *db.HolderBalance) error {
+			rows++
+			return w.Write([]string{h.UserAddr.Hex(), h.Balance.ToBigInt().String()})
+		})
+		if err != nil {
+			return err
+		}
+		w.Flush()
+		if err = w.Error(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "区块 %d 共 %d 个持有人\n", blockNumber, rows)
+		return nil
+	case "delete":
+		found, err := snapshots.DeleteSnapshot(*id)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("快照 %d 不存在", *id)
+		}
+		return nil
+	default:
+		return fmt.Errorf("未知操作: %s", action)
+	}
+}
+
+// runBalancesCommand 维护余额变动记录
+// 用法: balances reindex -chain 11155111 [-from-block 0]
+func runBalancesCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: balances reindex [参数]")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("balances "+action, flag.ExitOnError)
+	cfgPath := fs.String("config", "config.yaml", "Path to configuration file")
+	chainID := fs.Uint64("chain", 0, "链ID")
+	fromBlock := fs.Uint64("from-block", 0, "从该区块开始重建")
+	fs.Parse(args[1:])
+
+	cfg, database, repo, err := openRepository(*cfgPath)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	switch action {
+	case "reindex":
+		var chainConfig *config.ChainConfig
+		for i := range cfg.Chains {
+			if cfg.Chains[i].ChainID == *chainID {
+				chainConfig = &cfg.Chains[i]
+			}
+		}
+		if chainConfig == nil {
+			return fmt.Errorf("链 %d 未配置", *chainID)
+		}
+		handler, err := service.NewChainHandler(*chainConfig, repo)
+		if err != nil {
+			return fmt.Errorf("连接链 %d 失败: %w", *chainID, err)
+		}
+		reads, updated, err := handler.ReindexBalances(context.Background(), *fromBlock)
+		fmt.Printf("链 %d 读取余额 %d 次，更新 %d 条余额变动\n", *chainID, reads, updated)
+		if err != nil {
+			return err
+		}
+		snapshots, err := repo.GetHolderSnapshots(*chainID)
+		if err != nil {
+			return err
+		}
+		if len(snapshots) > 0 {
+			fmt.Printf("链 %d 有 %d 个物化快照基于重建前的余额，需删除后重新创建（snapshots delete / snapshots create）\n",
+				*chainID, len(snapshots))
+		}
+		return nil
+	default:
+		return fmt.Errorf("未知操作: %s", action)
+	}
+}
@@ -10,6 +10,11 @@ type Config struct {
 	Database DatabaseConfig `mapstructure:"database"`
 	Chains   []ChainConfig  `mapstructure:"chains"`
 	Points   PointsConfig   `mapstructure:"points"`
+	Voucher  VoucherConfig  `mapstructure:"voucher"`
+	API      APIConfig      `mapstructure:"api"`
+	GRPC     GRPCConfig     `mapstructure:"grpc"`
+	Webhook  WebhookConfig  `mapstructure:"webhook"`
+	Sinks    SinksConfig    `mapstructure:"sinks"`
 }
 
 type DatabaseConfig struct {
@@ -27,11 +32,160 @@ type ChainConfig struct {
 	StartBlock    uint64        `mapstructure:"start_block"`
 	Confirmations uint64        `mapstructure:"confirmations"`
 	PollInterval  time.Duration `mapstructure:"poll_interval"`
+	PointsWeight  *float64      `mapstructure:"points_weight"` //跨链汇总积分时的权重，未配置时为1，0 表示不计入汇总
+	//通过 eth_getCode 自动将合约地址加入积分排除名单
+	ExcludeContracts bool `mapstructure:"exclude_contracts"`
+	//链上推荐注册事件，如 "Referred(address,address)"，topics[1] 为被推荐人，topics[2] 为推荐人
+	ReferralContract string `mapstructure:"referral_contract"`
+	ReferralEvent    string `mapstructure:"referral_event"`
 }
 
 type PointsConfig struct {
-	Rate     float64 `mapstructure:"rate"`      //积分计算比例
-	CronSpec string  `mapstructure:"cron_spec"` //定时任务表达式
+	Rate           float64           `mapstructure:"rate"`             //积分计算比例
+	CronSpec       string            `mapstructure:"cron_spec"`        //定时任务表达式
+	PolicyCronSpec string            `mapstructure:"policy_cron_spec"` //过期、衰减任务表达式
+	Expiry         ExpiryConfig      `mapstructure:"expiry"`
+	Decay          DecayConfig       `mapstructure:"decay"`
+	Exclusions     []Exclusion       `mapstructure:"exclusions"` //不参与积分计算的地址
+	Referral       ReferralConfig    `mapstructure:"referral"`
+	Caps           CapsConfig        `mapstructure:"caps"`
+	WashTrading    WashConfig        `mapstructure:"wash_trading"`
+	Leaderboard    LeaderboardConfig `mapstructure:"leaderboard"`
+	Tiers          []TierConfig      `mapstructure:"tiers"` //等级定义，按从低到高排列
+}
+
+// TierConfig 会员等级，用户取满足全部门槛的最高等级
+type TierConfig struct {
+	Name       string        `mapstructure:"name"`
+	MinPoints  string        `mapstructure:"min_points"`  //积分门槛，留空表示不限制
+	MinBalance string        `mapstructure:"min_balance"` //当前余额门槛，留空表示不限制
+	MinHolding time.Duration `mapstructure:"min_holding"` //连续持有时长门槛
+	Multiplier float64       `mapstructure:"multiplier"`  //持币积分倍数，默认1
+}
+
+// LeaderboardConfig 排行榜，除总榜、日榜、周榜外按活动时间段统计活动榜
+type LeaderboardConfig struct {
+	Campaigns    []CampaignConfig `mapstructure:"campaigns"`
+	PollInterval time.Duration    `mapstructure:"poll_interval"` //扫描发件箱中积分分录的间隔
+}
+
+// CampaignConfig 活动时间段 [Start, End)，时间为 RFC3339 格式
+type CampaignConfig struct {
+	Name  string `mapstructure:"name"`
+	Start string `mapstructure:"start"`
+	End   string `mapstructure:"end"`
+}
+
+// WashConfig 刷量检测：来回转账、环形转账和短时持有
+type WashConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	Lookback        time.Duration `mapstructure:"lookback"`          //每次检测回看的时间范围
+	RoundTripWindow time.Duration `mapstructure:"round_trip_window"` //A→B→A 的时间窗口
+	CircularWindow  time.Duration `mapstructure:"circular_window"`   //环形转账的时间窗口
+	MaxCycleLength  int           `mapstructure:"max_cycle_length"`  //环形转账最多涉及的地址数
+	MinHolding      time.Duration `mapstructure:"min_holding"`       //转入后少于该时长即转出视为短时持有
+	AmountTolerance float64       `mapstructure:"amount_tolerance"`  //来回转账金额允许的偏差百分比
+	Action          string        `mapstructure:"action"`            //discount（打折）或 exclude（不计分）
+	DiscountPercent float64       `mapstructure:"discount_percent"`  //打折时扣减的百分比
+}
+
+// CapsConfig 积分上限，数量为十进制字符串，留空表示不限制
+type CapsConfig struct {
+	UserPerPeriod string        `mapstructure:"user_per_period"` //每个用户每个周期的积分上限
+	Period        time.Duration `mapstructure:"period"`
+	UserLifetime  string        `mapstructure:"user_lifetime"` //每个用户累计积分上限
+	EpochBudget   string        `mapstructure:"epoch_budget"`  //每个纪元全局发放预算，超出时按比例缩减
+	Epoch         time.Duration `mapstructure:"epoch"`
+}
+
+// ReferralConfig 推荐奖励：第 i 级推荐人获得被推荐人新增积分的 Percents[i]%，层级数即最大深度
+type ReferralConfig struct {
+	Percents []float64 `mapstructure:"percents"`
+}
+
+// Exclusion 静态配置的积分排除地址，ChainID 为 0 时对所有链生效
+type Exclusion struct {
+	ChainID uint64 `mapstructure:"chain_id"`
+	Address string `mapstructure:"address"`
+	Reason  string `mapstructure:"reason"`
+}
+
+// ExpiryConfig 积分过期策略：入账满 Days 天后按先进先出过期
+type ExpiryConfig struct {
+	Days int `mapstructure:"days"` //0 表示不过期
+}
+
+// DecayConfig 积分衰减策略：每个周期按余额的 Percent% 扣减
+type DecayConfig struct {
+	Percent float64       `mapstructure:"percent"` //0 表示不衰减
+	Period  time.Duration `mapstructure:"period"`
+}
+
+type VoucherConfig struct {
+	KeystorePath      string        `mapstructure:"keystore_path"`      //签名私钥keystore文件
+	KeystorePassword  string        `mapstructure:"keystore_password"`  //keystore密码
+	DomainName        string        `mapstructure:"domain_name"`        //EIP-712 domain name
+	DomainVersion     string        `mapstructure:"domain_version"`     //EIP-712 domain version
+	ChainID           uint64        `mapstructure:"chain_id"`           //EIP-712 domain chainId
+	VerifyingContract string        `mapstructure:"verifying_contract"` //兑换合约地址
+	ValidFor          time.Duration `mapstructure:"valid_for"`          //凭证有效期
+	//兑换合约查询nonce是否已使用的方法，如 "usedNonces(address,uint256)"，返回 bool；留空则不退回过期凭证
+	NonceUsedMethod string        `mapstructure:"nonce_used_method"`
+	RefundInterval  time.Duration `mapstructure:"refund_interval"` //检查过期凭证的间隔
+}
+
+type APIConfig struct {
+	Listen       string        `mapstructure:"listen"`        //HTTP监听地址
+	SignatureTTL time.Duration `mapstructure:"signature_ttl"` //签名消息有效期
+	Admins       []AdminConfig `mapstructure:"admins"`        //管理接口的操作人
+	//允许建立 WebSocket 连接的页面来源，为空时不限制
+	WebSocketOrigins []string   `mapstructure:"websocket_origins"`
+	Auth             AuthConfig `mapstructure:"auth"`
+}
+
+// AuthConfig 查询接口认证：API 密钥可查询任意用户，钱包登录（EIP-4361）的会话只能查询自己关联的地址
+type AuthConfig struct {
+	Domain     string        `mapstructure:"domain"`      //登录消息中的域名，为空时不启用钱包登录
+	NonceTTL   time.Duration `mapstructure:"nonce_ttl"`   //登录随机数和登录消息的有效期
+	SessionTTL time.Duration `mapstructure:"session_ttl"` //会话有效期
+}
+
+// AdminConfig 管理接口操作人，请求头 Authorization: Bearer <token> 识别操作人
+type AdminConfig struct {
+	Name  string `mapstructure:"name"`
+	Token string `mapstructure:"token"`
+}
+
+// GRPCConfig 内部 gRPC 服务配置，监听地址为空时不启动
+type GRPCConfig struct {
+	Listen      string `mapstructure:"listen"`       //gRPC监听地址
+	EventBuffer int    `mapstructure:"event_buffer"` //每个订阅缓冲的事件数，消费过慢的订阅会被断开
+}
+
+// WebhookConfig webhook 投递配置，失败后按 RetryBase*2^(n-1) 退避重试，不超过 RetryMax
+type WebhookConfig struct {
+	PollInterval time.Duration `mapstructure:"poll_interval"` //扫描发件箱和待投递记录的间隔
+	Timeout      time.Duration `mapstructure:"timeout"`       //单次请求超时
+	MaxAttempts  int           `mapstructure:"max_attempts"`  //达到次数仍失败则标记为 failed
+	RetryBase    time.Duration `mapstructure:"retry_base"`
+	RetryMax     time.Duration `mapstructure:"retry_max"`
+	//发件箱事件及投递记录的保留时间，超过后删除所有消费者都已处理过的部分
+	Retention time.Duration `mapstructure:"retention"`
+}
+
+// SinksConfig 事件输出配置，发件箱中的余额变动和积分分录事件按提交顺序写入全部输出
+type SinksConfig struct {
+	PollInterval time.Duration `mapstructure:"poll_interval"` //扫描发件箱的间隔
+	Outputs      []SinkConfig  `mapstructure:"outputs"`
+}
+
+// SinkConfig 单个输出：file、stdout 或 queue
+type SinkConfig struct {
+	Type           string        `mapstructure:"type"`
+	Path           string        `mapstructure:"path"`            //file、queue 的输出目录
+	MaxSize        int64         `mapstructure:"max_size"`        //file 单个文件的最大字节数
+	RotateInterval time.Duration `mapstructure:"rotate_interval"` //file 轮转间隔
+	MaxFiles       int           `mapstructure:"max_files"`       //file 保留的文件数
 }
 
 // LoadConfigFile 加载配置文件
@@ -54,6 +208,10 @@ func LoadConfigFile(path string) (*Config, error) {
 		if config.Chains[i].PollInterval == 0 {
 			config.Chains[i].PollInterval = 30 * time.Second
 		}
+		if config.Chains[i].PointsWeight == nil {
+			weight := 1.0
+			config.Chains[i].PointsWeight = &weight
+		}
 	}
 	if config.Points.Rate == 0 {
 		config.Points.Rate = 0.05
@@ -61,6 +219,86 @@ func LoadConfigFile(path string) (*Config, error) {
 	if config.Points.CronSpec == "" {
 		config.Points.CronSpec = "0 * * * *" //每小时执行一次
 	}
+	if config.Points.PolicyCronSpec == "" {
+		config.Points.PolicyCronSpec = "0 0 * * *" //每天执行一次
+	}
+	if config.Points.Decay.Period == 0 {
+		config.Points.Decay.Period = 30 * 24 * time.Hour
+	}
+	if config.API.Listen == "" {
+		config.API.Listen = ":8080"
+	}
+	if config.API.Auth.NonceTTL == 0 {
+		config.API.Auth.NonceTTL = 10 * time.Minute
+	}
+	if config.API.Auth.SessionTTL == 0 {
+		config.API.Auth.SessionTTL = 24 * time.Hour
+	}
+	if config.API.SignatureTTL == 0 {
+		config.API.SignatureTTL = 10 * time.Minute
+	}
+	if config.Points.Caps.Period == 0 {
+		config.Points.Caps.Period = 24 * time.Hour
+	}
+	if config.Points.Caps.Epoch == 0 {
+		config.Points.Caps.Epoch = 24 * time.Hour
+	}
+	if config.Points.WashTrading.Lookback == 0 {
+		config.Points.WashTrading.Lookback = 7 * 24 * time.Hour
+	}
+	if config.Points.WashTrading.RoundTripWindow == 0 {
+		config.Points.WashTrading.RoundTripWindow = time.Hour
+	}
+	if config.Points.WashTrading.CircularWindow == 0 {
+		config.Points.WashTrading.CircularWindow = 24 * time.Hour
+	}
+	if config.Points.WashTrading.MaxCycleLength == 0 {
+		config.Points.WashTrading.MaxCycleLength = 4
+	}
+	if config.Points.WashTrading.MinHolding == 0 {
+		config.Points.WashTrading.MinHolding = 10 * time.Minute
+	}
+	if config.Points.WashTrading.Action == "" {
+		config.Points.WashTrading.Action = "discount"
+	}
+	for i := range config.Points.Tiers {
+		if config.Points.Tiers[i].Multiplier == 0 {
+			config.Points.Tiers[i].Multiplier = 1
+		}
+	}
+	if config.Points.Leaderboard.PollInterval == 0 {
+		config.Points.Leaderboard.PollInterval = 5 * time.Second
+	}
+	if config.Webhook.PollInterval == 0 {
+		config.Webhook.PollInterval = 5 * time.Second
+	}
+	if config.Webhook.Timeout == 0 {
+		config.Webhook.Timeout = 10 * time.Second
+	}
+	if config.Webhook.MaxAttempts == 0 {
+		config.Webhook.MaxAttempts = 10
+	}
+	if config.Webhook.RetryBase == 0 {
+		config.Webhook.RetryBase = 30 * time.Second
+	}
+	if config.Webhook.RetryMax == 0 {
+		config.Webhook.RetryMax = 6 * time.Hour
+	}
+	if config.Webhook.Retention == 0 {
+		config.Webhook.Retention = 7 * 24 * time.Hour
+	}
+	if config.Sinks.PollInterval == 0 {
+		config.Sinks.PollInterval = time.Second
+	}
+	if config.Voucher.DomainVersion == "" {
+		config.Voucher.DomainVersion = "1"
+	}
+	if config.Voucher.ValidFor == 0 {
+		config.Voucher.ValidFor = 7 * 24 * time.Hour
+	}
+	if config.Voucher.RefundInterval == 0 {
+		config.Voucher.RefundInterval = time.Hour
+	}
 	return &config, nil
 }
 
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	cfg, err := LoadConfigFile("../config.yaml")
+	if err != nil {
+		t.Fatalf("加载示例配置失败: %v", err)
+	}
+	if len(cfg.Chains) != 1 {
+		t.Fatalf("chains = %d, want 1", len(cfg.Chains))
+	}
+	chain := cfg.Chains[0]
+	if chain.Name != "sepolia" || chain.PointsWeight == nil || *chain.PointsWeight != 1 || !chain.ExcludeContracts {
+		t.Errorf("unexpected chain %+v", chain)
+	}
+	//示例中的会员等级默认注释掉，启用后会改变持币积分
+	if len(cfg.Points.Tiers) != 0 {
+		t.Errorf("tiers = %d, want 0", len(cfg.Points.Tiers))
+	}
+	//事件输出默认不启用，避免未配置的部署写入本地文件
+	if len(cfg.Sinks.Outputs) != 0 {
+		t.Errorf("sinks outputs = %d, want 0", len(cfg.Sinks.Outputs))
+	}
+}
+
+func TestLoadConfigFilePointsWeight(t *testing.T) {
+	example, err := os.ReadFile("../config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		replace string
+		want    float64
+	}{
+		{"显式配置为0", "points_weight: 0", 0},
+		{"省略时为1", "", 1},
+		{"显式配置", "points_weight: 0.5", 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			data := strings.Replace(string(example), "points_weight: 1", tt.replace, 1)
+			if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := LoadConfigFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if weight := cfg.Chains[0].PointsWeight; weight == nil || *weight != tt.want {
+				t.Errorf("PointsWeight = %v, want %v", weight, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AdjustPoints 人工发放（grant）或扣回（clawback）积分，原因记入 memo 并记录操作人
+// 扣回超出可用余额时返回 ErrInsufficientPoints
+func (r *DBRepository) AdjustPoints(chainID uint64, userAddr common.Address, entryType string,
+	amount string, idempotencyKey string, reason string, operator string) (*LedgerEntry, error) {
+	if entryType != LedgerGrant && entryType != LedgerClawback {
+		return nil, fmt.Errorf("%s 不是人工调整类型", entryType)
+	}
+	if operator == "" {
+		return nil, fmt.Errorf("操作人不能为空")
+	}
+	return r.postPoints(chainID, userAddr, entryType, amount, idempotencyKey, reason, operator)
+}
+
+// GetAdjustments 获取链上的人工调整分录，按时间倒序，chainID 为0时返回所有链
+func (r *DBRepository) GetAdjustments(chainID uint64, limit int) ([]LedgerEntry, error) {
+	rows, err := r.Db.Query(`
+		select id, chain_id, entry_type, debit_account, credit_account, amount, idempotency_key, memo, operator, created_at
+		from points_ledger where (chain_id = ? or ? = 0) and entry_type in (?, ?)
+		order by id desc limit ?`, chainID, chainID, LedgerGrant, LedgerClawback, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []LedgerEntry
+	for rows.Next() {
+		var entry LedgerEntry
+		err = rows.Scan(&entry.ID, &entry.ChainID, &entry.EntryType, &entry.DebitAccount, &entry.CreditAccount,
+			&entry.Amount, &entry.IdempotencyKey, &entry.Memo, &entry.Operator, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"strings"
+	"time"
+)
+
+// API 密钥权限
+const (
+	ScopeRead  = "read"  //查询任意用户的数据
+	ScopeAdmin = "admin" //管理接口，包含 read
+)
+
+// APIKey API 密钥，明文只在创建时返回一次
+type APIKey struct {
+	ID         uint64
+	Name       string
+	KeyPrefix  string //明文前缀，便于识别密钥
+	KeyHash    string
+	Scopes     []string
+	CreatedBy  string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// HasScope 判断密钥是否具有权限，admin 包含 read
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthSession 钱包登录会话
+type AuthSession struct {
+	ID        uint64
+	UserAddr  common.Address
+	ChainID   uint64
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// CreateAPIKey 保存 API 密钥，写入后填充 ID
+func (r *DBRepository) CreateAPIKey(key *APIKey) error {
+	result, err := r.Db.Exec(`
+		insert into api_keys (name, key_prefix, key_hash, scopes, created_by) values (?,?,?,?,?)`,
+		key.Name, key.KeyPrefix, key.KeyHash, strings.Join(key.Scopes, ","), key.CreatedBy)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	key.ID = uint64(id)
+	return nil
+}
+
+const apiKeyColumns = `id, name, key_prefix, key_hash, scopes, created_by, created_at, last_used_at, revoked_at`
+
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	var key APIKey
+	var scopes string
+	var lastUsedAt, revokedAt sql.NullTime
+	err := row.Scan(&key.ID, &key.Name, &key.KeyPrefix, &key.KeyHash, &scopes, &key.CreatedBy, &key.CreatedAt,
+		&lastUsedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	key.Scopes = splitList(scopes)
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return &key, nil
+}
+
+// GetAPIKeyByHash 按摘要获取未吊销的 API 密钥，不存在时返回 nil
+func (r *DBRepository) GetAPIKeyByHash(keyHash string) (*APIKey, error) {
+	key, err := scanAPIKey(r.Db.QueryRow(`
+		select `+apiKeyColumns+` from api_keys where key_hash = ? and revoked_at is null`, keyHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return key, err
+}
+
+// GetAPIKeys 获取全部 API 密钥，包含已吊销的
+func (r *DBRepository) GetAPIKeys() ([]APIKey, error) {
+	rows, err := r.Db.Query(`select ` + apiKeyColumns + ` from api_keys order by id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// TouchAPIKey 更新密钥最后使用时间，距上次更新不足 interval 时跳过以减少写入
+func (r *DBRepository) TouchAPIKey(id uint64, now time.Time, interval time.Duration) error {
+	_, err := r.Db.Exec(`
+		update api_keys set last_used_at = ? where id = ? and (last_used_at is null or last_used_at < ?)`,
+		now, id, now.Add(-interval))
+	return err
+}
+
+// RevokeAPIKey 吊销 API 密钥，返回密钥是否存在且未吊销
+func (r *DBRepository) RevokeAPIKey(id uint64, now time.Time) (bool, error) {
+	result, err := r.Db.Exec(`update api_keys set revoked_at = ? where id = ? and revoked_at is null`, now, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// CreateSIWENonce 保存登录随机数，同时清理已过期的随机数
+func (r *DBRepository) CreateSIWENonce(nonce string, now time.Time, expiresAt time.Time) error {
+	if _, err := r.Db.Exec(`delete from siwe_nonces where expires_at < ?`, now); err != nil {
+		return err
+	}
+	_, err := r.Db.Exec(`insert into siwe_nonces (nonce, expires_at) values (?,?)`, nonce, expiresAt)
+	return err
+}
+
+// ConsumeSIWENonce 使用登录随机数，随机数不存在或已过期时返回 false，每个随机数只能使用一次
+func (r *DBRepository) ConsumeSIWENonce(nonce string, now time.Time) (bool, error) {
+	result, err := r.Db.Exec(`delete from siwe_nonces where nonce = ? and expires_at >= ?`, nonce, now)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// CreateSession 保存登录会话，写入后填充 ID
+func (r *DBRepository) CreateSession(tokenHash string, session *AuthSession) error {
+	result, err := r.Db.Exec(`
+		insert into auth_sessions (token_hash, user_addr, chain_id, issued_at, expires_at) values (?,?,?,?,?)`,
+		tokenHash, session.UserAddr.Hex(), session.ChainID, session.IssuedAt, session.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	session.ID = uint64(id)
+	return nil
+}
+
+// GetSessionByHash 获取有效的登录会话，不存在、已过期或已注销时返回 nil
+func (r *DBRepository) GetSessionByHash(tokenHash string, now time.Time) (*AuthSession, error) {
+	var session AuthSession
+	var addrStr string
+	err := r.Db.QueryRow(`
+		select id, user_addr, chain_id, issued_at, expires_at from auth_sessions
+		where token_hash = ? and expires_at > ? and revoked_at is null`, tokenHash, now).Scan(
+		&session.ID, &addrStr, &session.ChainID, &session.IssuedAt, &session.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	session.UserAddr = common.HexToAddress(addrStr)
+	return &session, nil
+}
+
+// RevokeSession 注销登录会话
+func (r *DBRepository) RevokeSession(tokenHash string, now time.Time) error {
+	_, err := r.Db.Exec(`update auth_sessions set revoked_at = ? where token_hash = ? and revoked_at is null`,
+		now, tokenHash)
+	return err
+}
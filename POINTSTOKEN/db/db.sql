@@ -4,6 +4,7 @@ CREATE TABLE IF NOT EXISTS chains (
       chain_id BIGINT NOT NULL UNIQUE,
       contract_addr VARCHAR(66) NOT NULL,
       last_processed_block BIGINT NOT NULL DEFAULT 0,
+      last_block_time TIMESTAMP NULL,
       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
       updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -22,18 +23,22 @@ CREATE TABLE IF NOT EXISTS balance_changes (
        chain_id BIGINT NOT NULL,
        user_addr VARCHAR(66) NOT NULL,
        transaction_hash VARCHAR(66) NOT NULL,
+       log_index INT NOT NULL DEFAULT 0,
        block_number BIGINT NOT NULL,
        change_amount DECIMAL(50, 0) NOT NULL,
        balance_after DECIMAL(50, 0) NOT NULL,
        event_type VARCHAR(20) NOT NULL,
-       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       KEY idx_chain_user (chain_id, user_addr, id),
+       KEY idx_chain_user_block (chain_id, user_addr, block_number),
+       UNIQUE KEY unique_balance_change (chain_id, transaction_hash, log_index, user_addr)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
 
 CREATE TABLE IF NOT EXISTS user_points (
        id INT AUTO_INCREMENT PRIMARY KEY,
        chain_id BIGINT NOT NULL,
-       user_addr VARCHAR(66) NOT NULL UNIQUE,
+       user_addr VARCHAR(66) NOT NULL,
        total_points DECIMAL(50, 0) NOT NULL DEFAULT 0,
        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
        UNIQUE KEY unique_user_points (chain_id, user_addr)
@@ -51,4 +56,334 @@ CREATE TABLE IF NOT EXISTS points_calculations (
        KEY idx_user_addr (user_addr),
        KEY idx_calculated_at (calculated_at),
        UNIQUE KEY unique_points_calculations (chain_id, user_addr, calculated_at)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
\ No newline at end of file
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 积分兑换凭证，签发时扣减积分；redeemed_at 为确认已在链上兑换的时间，refunded_at 为过期未兑换、退回积分的时间
+CREATE TABLE IF NOT EXISTS points_vouchers (
+       id INT AUTO_INCREMENT PRIMARY KEY,
+       chain_id BIGINT NOT NULL,
+       user_addr VARCHAR(66) NOT NULL,
+       amount DECIMAL(50, 0) NOT NULL,
+       nonce BIGINT NOT NULL,
+       deadline TIMESTAMP NOT NULL,
+       signature VARCHAR(132) NOT NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       redeemed_at TIMESTAMP NULL,
+       refunded_at TIMESTAMP NULL,
+       KEY idx_chain_user (chain_id, user_addr),
+       KEY idx_deadline (deadline),
+       UNIQUE KEY unique_voucher_nonce (user_addr, nonce)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 凭证nonce计数器，每个用户一行，签发时锁定该行直到事务提交
+CREATE TABLE IF NOT EXISTS voucher_nonces (
+       user_addr VARCHAR(66) PRIMARY KEY,
+       nonce BIGINT NOT NULL DEFAULT 0
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- 积分账本（复式记账）：每条分录从 debit_account 转入 credit_account
+-- 用户账户为用户地址，系统账户为 system:<entry_type>
+CREATE TABLE IF NOT EXISTS points_ledger (
+       id BIGINT AUTO_INCREMENT PRIMARY KEY,
+       chain_id BIGINT NOT NULL,
+       entry_type VARCHAR(20) NOT NULL,
+       debit_account VARCHAR(66) NOT NULL,
+       credit_account VARCHAR(66) NOT NULL,
+       amount DECIMAL(50, 0) NOT NULL,
+       idempotency_key VARCHAR(128) NOT NULL,
+       memo VARCHAR(255) NOT NULL DEFAULT '',
+       operator VARCHAR(64) NOT NULL DEFAULT '',
+       run_id BIGINT NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       KEY idx_debit_account (chain_id, debit_account),
+       KEY idx_credit_account (chain_id, credit_account),
+       KEY idx_run (run_id),
+       UNIQUE KEY unique_idempotency_key (chain_id, idempotency_key)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- 地址关联：同一 identity_addr 下的地址视为同一用户
+CREATE TABLE IF NOT EXISTS identities (
+       id INT AUTO_INCREMENT PRIMARY KEY,
+       identity_addr VARCHAR(66) NOT NULL,
+       user_addr VARCHAR(66) NOT NULL UNIQUE,
+       signature VARCHAR(132) NOT NULL,
+       linked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       KEY idx_identity_addr (identity_addr)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- 积分排除名单，chain_id 为 0 时对所有链生效
+-- source: config（配置文件）、manual（人工添加）、contract（eth_getCode 检测）
+CREATE TABLE IF NOT EXISTS points_exclusions (
+       id INT AUTO_INCREMENT PRIMARY KEY,
+       chain_id BIGINT NOT NULL,
+       user_addr VARCHAR(66) NOT NULL,
+       reason VARCHAR(255) NOT NULL,
+       source VARCHAR(20) NOT NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       UNIQUE KEY unique_exclusion (chain_id, user_addr)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- 积分计算排除地址的变更记录，只在地址开始被排除、原因变化或恢复计分时写入
+-- action: excluded（排除）、included（恢复计分）
+CREATE TABLE IF NOT EXISTS points_exclusion_audit (
+       id BIGINT AUTO_INCREMENT PRIMARY KEY,
+       chain_id BIGINT NOT NULL,
+       user_addr VARCHAR(66) NOT NULL,
+       calculated_at TIMESTAMP NOT NULL,
+       action VARCHAR(10) NOT NULL,
+       reason VARCHAR(255) NOT NULL,
+       source VARCHAR(20) NOT NULL,
+       KEY idx_calculated_at (chain_id, calculated_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- 推荐关系，每个被推荐人只能有一个推荐人
+-- source: signature（签名注册）、event（链上事件）
+CREATE TABLE IF NOT EXISTS referrals (
+       id INT AUTO_INCREMENT PRIMARY KEY,
+       referee_addr VARCHAR(66) NOT NULL UNIQUE,
+       referrer_addr VARCHAR(66) NOT NULL,
+       source VARCHAR(20) NOT NULL,
+       source_ref VARCHAR(132) NOT NULL DEFAULT '',
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       KEY idx_referrer_addr (referrer_addr)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- 刷量检测标记，status: open（待审核）、confirmed（确认）、dismissed（误报）
+CREATE TABLE IF NOT EXISTS wash_flags (
+       id BIGINT AUTO_INCREMENT PRIMARY KEY,
+       chain_id BIGINT NOT NULL,
+       user_addr VARCHAR(66) NOT NULL,
+       flag_type VARCHAR(20) NOT NULL,
+       transaction_hash VARCHAR(66) NOT NULL,
+       counterparty VARCHAR(66) NOT NULL DEFAULT '',
+       detail VARCHAR(255) NOT NULL DEFAULT '',
+       activity_at TIMESTAMP NOT NULL,
+       status VARCHAR(20) NOT NULL DEFAULT 'open',
+       detected_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       KEY idx_chain_activity (chain_id, activity_at),
+       UNIQUE KEY unique_wash_flag (chain_id, user_addr, flag_type, transaction_hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- 从头重算积分的影子表，确认差异后由 ApplyRecomputation 以账本分录替换线上积分
+CREATE TABLE IF NOT EXISTS user_points_recompute (
+       id INT AUTO_INCREMENT PRIMARY KEY,
+       chain_id BIGINT NOT NULL,
+       user_addr VARCHAR(66) NOT NULL,
+       balance DECIMAL(50, 0) NOT NULL DEFAULT 0,
+       accrual_points DECIMAL(50, 0) NOT NULL DEFAULT 0,
+       referral_points DECIMAL(50, 0) NOT NULL DEFAULT 0,
+       other_points DECIMAL(50, 0) NOT NULL DEFAULT 0,
+       total_points DECIMAL(50, 0) NOT NULL DEFAULT 0,
+       computed_until TIMESTAMP NOT NULL,
+       UNIQUE KEY unique_user_points_recompute (chain_id, user_addr)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- 排行榜分数，board: total（总积分）、daily:2006-01-02、weekly:2006-W01、campaign:<名称>
+-- chain_id 为 0 表示跨链榜，按链权重加权汇总
+CREATE TABLE IF NOT EXISTS leaderboard_scores (
+       id BIGINT AUTO_INCREMENT PRIMARY KEY,
+       board VARCHAR(64) NOT NULL,
+       chain_id BIGINT NOT NULL,
+       user_addr VARCHAR(66) NOT NULL,
+       score DECIMAL(50, 0) NOT NULL DEFAULT 0,
+       updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+       KEY idx_board_score (board, chain_id, score, user_addr),
+       UNIQUE KEY unique_leaderboard_score (board, chain_id, user_addr)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- 用户当前会员等级，tier 为空表示未达到任何等级
+CREATE TABLE IF NOT EXISTS user_tiers (
+       id INT AUTO_INCREMENT PRIMARY KEY,
+       chain_id BIGINT NOT NULL,
+       user_addr VARCHAR(66) NOT NULL,
+       tier VARCHAR(32) NOT NULL DEFAULT '',
+       since TIMESTAMP NOT NULL,
+       updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+       UNIQUE KEY unique_user_tiers (chain_id, user_addr)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- 会员等级变更历史，direction: upgrade（升级）、downgrade（降级）
+CREATE TABLE IF NOT EXISTS tier_history (
+       id BIGINT AUTO_INCREMENT PRIMARY KEY,
+       chain_id BIGINT NOT NULL,
+       user_addr VARCHAR(66) NOT NULL,
+       from_tier VARCHAR(32) NOT NULL DEFAULT '',
+       to_tier VARCHAR(32) NOT NULL DEFAULT '',
+       direction VARCHAR(10) NOT NULL,
+       changed_at TIMESTAMP NOT NULL,
+       KEY idx_chain_user (chain_id, user_addr, changed_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- 积分计算运行记录，status: running、succeeded、failed
+-- entries_digest 为本次写入的持币积分、推荐奖励分录摘要，用于事后与账本核对
+CREATE TABLE IF NOT EXISTS points_runs (
+       id BIGINT AUTO_INCREMENT PRIMARY KEY,
+       chain_id BIGINT NOT NULL,
+       status VARCHAR(20) NOT NULL,
+       rule_version VARCHAR(64) NOT NULL,
+       started_at TIMESTAMP NOT NULL,
+       finished_at TIMESTAMP NULL,
+       calculated_at TIMESTAMP NOT NULL,
+       users_processed INT NOT NULL DEFAULT 0,
+       users_excluded INT NOT NULL DEFAULT 0,
+       users_capped INT NOT NULL DEFAULT 0,
+       users_penalized INT NOT NULL DEFAULT 0,
+       points_uncapped DECIMAL(50, 0) NOT NULL DEFAULT 0,
+       points_emitted DECIMAL(50, 0) NOT NULL DEFAULT 0,
+       referral_points DECIMAL(50, 0) NOT NULL DEFAULT 0,
+       budget_scaled BOOLEAN NOT NULL DEFAULT FALSE,
+       entries_count INT NOT NULL DEFAULT 0,
+       entries_digest VARCHAR(64) NOT NULL DEFAULT '',
+       error TEXT,
+       KEY idx_chain_started (chain_id, started_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- 事件发件箱，与余额变动、积分分录在同一事务中写入，webhook 分发器、推送和其他消费者按 seq 顺序读取
+-- event_type: balance_change（余额变动）、points（积分分录）
+-- seq 为事件序号，写入时为空，提交后由事件发布任务通过 outbox_sequence 按 ID 顺序分配，连续递增，消费者以 seq 作为游标
+CREATE TABLE IF NOT EXISTS event_outbox (
+       id BIGINT AUTO_INCREMENT PRIMARY KEY,
+       seq BIGINT NULL,
+       event_type VARCHAR(20) NOT NULL,
+       chain_id BIGINT NOT NULL,
+       user_addr VARCHAR(66) NOT NULL,
+       payload JSON NOT NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       dispatched_at TIMESTAMP NULL,
+       KEY idx_dispatched (dispatched_at, seq),
+       UNIQUE KEY unique_seq (seq)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- 发件箱序号计数器，只有一行，首次分配序号时创建；只在分配序号的事务中锁定，记账事务不会访问该行
+CREATE TABLE IF NOT EXISTS outbox_sequence (
+       id TINYINT PRIMARY KEY,
+       seq BIGINT NOT NULL DEFAULT 0
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- 发件箱消费进度，排行榜、事件输出等按 seq 顺序读取发件箱的消费者各自保存已处理到的事件序号
+CREATE TABLE IF NOT EXISTS outbox_cursors (
+       consumer VARCHAR(50) PRIMARY KEY,
+       last_seq BIGINT NOT NULL DEFAULT 0,
+       updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- webhook 订阅，event_types、chain_ids、addresses 为逗号分隔的过滤条件，为空表示不过滤
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+       id BIGINT AUTO_INCREMENT PRIMARY KEY,
+       url VARCHAR(512) NOT NULL,
+       secret VARCHAR(128) NOT NULL,
+       event_types VARCHAR(255) NOT NULL DEFAULT '',
+       chain_ids VARCHAR(255) NOT NULL DEFAULT '',
+       addresses TEXT NOT NULL,
+       active BOOLEAN NOT NULL DEFAULT TRUE,
+       created_by VARCHAR(64) NOT NULL DEFAULT '',
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- webhook 投递，每个订阅的每个事件只生成一条，status: pending、delivered、failed
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+       id BIGINT AUTO_INCREMENT PRIMARY KEY,
+       subscription_id BIGINT NOT NULL,
+       outbox_id BIGINT NOT NULL,
+       event_type VARCHAR(20) NOT NULL,
+       status VARCHAR(20) NOT NULL,
+       attempts INT NOT NULL DEFAULT 0,
+       next_attempt_at TIMESTAMP NOT NULL,
+       last_status_code INT NOT NULL DEFAULT 0,
+       last_error VARCHAR(512) NOT NULL DEFAULT '',
+       delivered_at TIMESTAMP NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       UNIQUE KEY unique_subscription_outbox (subscription_id, outbox_id),
+       KEY idx_outbox (outbox_id),
+       KEY idx_status_next (status, next_attempt_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- webhook 投递日志，记录每次请求的结果
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+       id BIGINT AUTO_INCREMENT PRIMARY KEY,
+       delivery_id BIGINT NOT NULL,
+       attempt INT NOT NULL,
+       status_code INT NOT NULL DEFAULT 0,
+       error VARCHAR(512) NOT NULL DEFAULT '',
+       duration_ms INT NOT NULL DEFAULT 0,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       KEY idx_delivery (delivery_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- API 密钥，只保存 SHA-256 摘要，scopes 为逗号分隔的权限：read、admin
+CREATE TABLE IF NOT EXISTS api_keys (
+       id BIGINT AUTO_INCREMENT PRIMARY KEY,
+       name VARCHAR(64) NOT NULL,
+       key_prefix VARCHAR(16) NOT NULL,
+       key_hash CHAR(64) NOT NULL,
+       scopes VARCHAR(64) NOT NULL,
+       created_by VARCHAR(64) NOT NULL DEFAULT '',
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       last_used_at TIMESTAMP NULL,
+       revoked_at TIMESTAMP NULL,
+       UNIQUE KEY unique_key_hash (key_hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- Sign-In with Ethereum 一次性随机数
+CREATE TABLE IF NOT EXISTS siwe_nonces (
+       nonce VARCHAR(32) PRIMARY KEY,
+       expires_at TIMESTAMP NOT NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       KEY idx_expires (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- 钱包登录会话，只保存令牌的 SHA-256 摘要
+CREATE TABLE IF NOT EXISTS auth_sessions (
+       id BIGINT AUTO_INCREMENT PRIMARY KEY,
+       token_hash CHAR(64) NOT NULL,
+       user_addr VARCHAR(66) NOT NULL,
+       chain_id BIGINT NOT NULL,
+       issued_at TIMESTAMP NOT NULL,
+       expires_at TIMESTAMP NOT NULL,
+       revoked_at TIMESTAMP NULL,
+       UNIQUE KEY unique_token_hash (token_hash),
+       KEY idx_user_addr (user_addr)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- 持有人快照，保存指定区块高度全部余额为正的地址，由 balance_changes 计算，用于治理投票和空投
+CREATE TABLE IF NOT EXISTS holder_snapshots (
+       id BIGINT AUTO_INCREMENT PRIMARY KEY,
+       chain_id BIGINT NOT NULL,
+       block_number BIGINT NOT NULL,
+       holders INT NOT NULL DEFAULT 0,
+       total_balance DECIMAL(50, 0) NOT NULL DEFAULT 0,
+       created_by VARCHAR(64) NOT NULL DEFAULT '',
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       UNIQUE KEY unique_chain_block (chain_id, block_number)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+-- 持有人快照中各地址的余额
+CREATE TABLE IF NOT EXISTS holder_snapshot_balances (
+       snapshot_id BIGINT NOT NULL,
+       user_addr VARCHAR(66) NOT NULL,
+       balance DECIMAL(50, 0) NOT NULL,
+       PRIMARY KEY (snapshot_id, user_addr)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
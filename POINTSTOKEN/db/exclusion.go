@@ -0,0 +1,173 @@
+package db
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"time"
+)
+
+// 排除名单来源
+const (
+	ExclusionSourceConfig   = "config"
+	ExclusionSourceManual   = "manual"
+	ExclusionSourceContract = "contract"
+)
+
+// Exclusion 不参与积分计算的地址，ChainID 为 0 时对所有链生效
+type Exclusion struct {
+	ChainID   uint64
+	UserAddr  common.Address
+	Reason    string
+	Source    string
+	CreatedAt time.Time
+}
+
+// AddExclusion 添加排除地址，已存在时保持原记录不变
+func (r *DBRepository) AddExclusion(chainID uint64, userAddr common.Address, reason string, source string) error {
+	_, err := r.Db.Exec(`
+		insert into points_exclusions (chain_id, user_addr, reason, source) values (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE id = id`, chainID, userAddr.Hex(), reason, source)
+	return err
+}
+
+// ReplaceConfigExclusions 用配置文件中的排除地址替换 source 为 config 的记录，人工添加的记录保持不变
+func (r *DBRepository) ReplaceConfigExclusions(exclusions []Exclusion) error {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`delete from points_exclusions where source = ?`, ExclusionSourceConfig)
+	if err != nil {
+		return err
+	}
+	for _, e := range exclusions {
+		_, err = tx.Exec(`
+			insert into points_exclusions (chain_id, user_addr, reason, source) values (?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE id = id`, e.ChainID, e.UserAddr.Hex(), e.Reason, ExclusionSourceConfig)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RemoveExclusion 移除排除地址
+func (r *DBRepository) RemoveExclusion(chainID uint64, userAddr common.Address) error {
+	_, err := r.Db.Exec(`
+		delete from points_exclusions where chain_id = ? and user_addr = ?`, chainID, userAddr.Hex())
+	return err
+}
+
+// GetExclusions 获取对链生效的排除地址（包括 chain_id 为 0 的全局地址）
+func (r *DBRepository) GetExclusions(chainID uint64) ([]Exclusion, error) {
+	rows, err := r.Db.Query(`
+		select chain_id, user_addr, reason, source, created_at from points_exclusions 
+		where chain_id = ? or chain_id = 0 order by chain_id desc, id`, chainID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exclusions []Exclusion
+	for rows.Next() {
+		var e Exclusion
+		var addrStr string
+		if err = rows.Scan(&e.ChainID, &addrStr, &e.Reason, &e.Source, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.UserAddr = common.HexToAddress(addrStr)
+		exclusions = append(exclusions, e)
+	}
+	return exclusions, rows.Err()
+}
+
+// 排除记录的变更类型
+const (
+	ExclusionActionExcluded = "excluded"
+	ExclusionActionIncluded = "included"
+)
+
+// exclusionStateSQL 截至某次计算每个地址最后一条变更记录
+const exclusionStateSQL = `
+		select user_addr, action, reason, source, calculated_at from (
+			select user_addr, action, reason, source, calculated_at,
+				row_number() over (partition by user_addr order by calculated_at desc, id desc) as rn
+			from points_exclusion_audit where chain_id = ? and calculated_at <= ?
+		) t where rn = 1`
+
+// RecordExclusionAudit 与上一次的排除状态比较，只记录本次新增、原因变化和恢复计分的地址
+func (r *DBRepository) RecordExclusionAudit(chainID uint64, calculatedAt time.Time, exclusions []Exclusion) error {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(exclusionStateSQL+` and action = ?`, chainID, calculatedAt, ExclusionActionExcluded)
+	if err != nil {
+		return err
+	}
+	previous := make(map[common.Address]Exclusion)
+	for rows.Next() {
+		var e Exclusion
+		var addrStr, action string
+		if err = rows.Scan(&addrStr, &action, &e.Reason, &e.Source, &e.CreatedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		e.UserAddr = common.HexToAddress(addrStr)
+		previous[e.UserAddr] = e
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	insert := func(e Exclusion, action string) error {
+		_, err := tx.Exec(`
+			insert into points_exclusion_audit (chain_id, user_addr, calculated_at, action, reason, source) 
+			values (?, ?, ?, ?, ?, ?)`, chainID, e.UserAddr.Hex(), calculatedAt, action, e.Reason, e.Source)
+		return err
+	}
+	current := make(map[common.Address]bool, len(exclusions))
+	for _, e := range exclusions {
+		current[e.UserAddr] = true
+		if prev, ok := previous[e.UserAddr]; ok && prev.Reason == e.Reason && prev.Source == e.Source {
+			continue
+		}
+		if err := insert(e, ExclusionActionExcluded); err != nil {
+			return err
+		}
+	}
+	for addr, prev := range previous {
+		if !current[addr] {
+			if err := insert(prev, ExclusionActionIncluded); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// GetExclusionAudit 由变更记录还原某次积分计算中被排除的地址，CreatedAt 为地址最近一次被排除的计算时间
+func (r *DBRepository) GetExclusionAudit(chainID uint64, calculatedAt time.Time) ([]Exclusion, error) {
+	rows, err := r.Db.Query(exclusionStateSQL+` and action = ? order by user_addr`,
+		chainID, calculatedAt, ExclusionActionExcluded)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exclusions []Exclusion
+	for rows.Next() {
+		e := Exclusion{ChainID: chainID}
+		var addrStr, action string
+		if err = rows.Scan(&addrStr, &action, &e.Reason, &e.Source, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.UserAddr = common.HexToAddress(addrStr)
+		exclusions = append(exclusions, e)
+	}
+	return exclusions, rows.Err()
+}
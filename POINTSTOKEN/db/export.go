@@ -0,0 +1,92 @@
+package db
+
+import (
+	. "POINTSTOKEN/types"
+	"github.com/ethereum/go-ethereum/common"
+	"time"
+)
+
+// ExportBalance 导出的用户当前余额
+type ExportBalance struct {
+	ChainID   uint64
+	UserAddr  common.Address
+	Balance   BigInt
+	UpdatedAt time.Time
+}
+
+// 以下导出查询逐行回调，不在内存中保存结果集，fn 返回错误时停止
+
+// StreamUserBalances 按地址顺序导出链上余额不为 0 的用户
+func (r *DBRepository) StreamUserBalances(chainID uint64, fn func(*ExportBalance) error) error {
+	rows, err := r.Db.Query(`
+		select chain_id, user_addr, balance, updated_at from user_balances
+		where chain_id = ? and balance <> 0 order by user_addr`, chainID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b ExportBalance
+		var addrStr string
+		if err = rows.Scan(&b.ChainID, &addrStr, &b.Balance, &b.UpdatedAt); err != nil {
+			return err
+		}
+		b.UserAddr = common.HexToAddress(addrStr)
+		if err = fn(&b); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// StreamBalanceChanges 按 ID 顺序导出时间段 [from, to) 内的余额变动
+func (r *DBRepository) StreamBalanceChanges(chainID uint64, from time.Time, to time.Time, fn func(*UserBalanceChange) error) error {
+	rows, err := r.Db.Query(`
+		select id, chain_id, user_addr, transaction_hash, block_number, change_amount, balance_after, event_type, created_at
+		from balance_changes where chain_id = ? and created_at >= ? and created_at < ? order by id`, chainID, from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ubc UserBalanceChange
+		var addrStr, txHash string
+		err = rows.Scan(&ubc.ID, &ubc.ChainID, &addrStr, &txHash, &ubc.BlockNumber, &ubc.BalanceChange,
+			&ubc.BalanceAfter, &ubc.EventType, &ubc.CreatedAt)
+		if err != nil {
+			return err
+		}
+		ubc.UserAddr = common.HexToAddress(addrStr)
+		ubc.TxHash = common.HexToHash(txHash)
+		if err = fn(&ubc); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// StreamLedgerEntries 按 ID 顺序导出时间段 [from, to) 内的积分分录
+func (r *DBRepository) StreamLedgerEntries(chainID uint64, from time.Time, to time.Time, fn func(*LedgerEntry) error) error {
+	rows, err := r.Db.Query(`
+		select id, chain_id, entry_type, debit_account, credit_account, amount, idempotency_key, memo, operator, created_at
+		from points_ledger where chain_id = ? and created_at >= ? and created_at < ? order by id`, chainID, from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry LedgerEntry
+		err = rows.Scan(&entry.ID, &entry.ChainID, &entry.EntryType, &entry.DebitAccount, &entry.CreditAccount,
+			&entry.Amount, &entry.IdempotencyKey, &entry.Memo, &entry.Operator, &entry.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if err = fn(&entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,194 @@
+package db
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"strings"
+)
+
+// ChainInfo 链的同步信息
+type ChainInfo struct {
+	ChainID            uint64
+	Name               string
+	ContractAddr       string
+	LastProcessedBlock uint64
+}
+
+// addressesIn 生成 in 查询的占位符和参数
+func addressesIn(userAddrs []common.Address) (string, []interface{}) {
+	placeholders := make([]string, len(userAddrs))
+	args := make([]interface{}, len(userAddrs))
+	for i, addr := range userAddrs {
+		placeholders[i] = "?"
+		args[i] = addr.Hex()
+	}
+	return strings.Join(placeholders, ","), args
+}
+
+// chainAddressFilter 生成按链和地址过滤的条件。这些表的索引以 (chain_id, user_addr) 开头，
+// 只按 user_addr 过滤会全表扫描，因此把 chains 表中的链ID以常量列出，链为空时 ok 为 false
+func (r *DBRepository) chainAddressFilter(userAddrs []common.Address) (cond string, args []interface{}, ok bool, err error) {
+	chains, err := r.GetChains()
+	if err != nil || len(chains) == 0 {
+		return "", nil, false, err
+	}
+	placeholders := make([]string, len(chains))
+	for i, chainID := range chains {
+		placeholders[i] = "?"
+		args = append(args, chainID)
+	}
+	in, addrArgs := addressesIn(userAddrs)
+	return "chain_id in (" + strings.Join(placeholders, ",") + ") and user_addr in (" + in + ")",
+		append(args, addrArgs...), true, nil
+}
+
+// GetChainInfos 获取所有链的同步信息
+func (r *DBRepository) GetChainInfos() ([]ChainInfo, error) {
+	rows, err := r.Db.Query(`
+		select chain_id, name, contract_addr, last_processed_block from chains order by chain_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chains []ChainInfo
+	for rows.Next() {
+		var c ChainInfo
+		if err = rows.Scan(&c.ChainID, &c.Name, &c.ContractAddr, &c.LastProcessedBlock); err != nil {
+			return nil, err
+		}
+		chains = append(chains, c)
+	}
+	return chains, rows.Err()
+}
+
+// GetUserBalancesAllChains 获取一组地址在所有链上的余额
+func (r *DBRepository) GetUserBalancesAllChains(userAddrs []common.Address) ([]UserBalance, error) {
+	if len(userAddrs) == 0 {
+		return nil, nil
+	}
+	cond, args, ok, err := r.chainAddressFilter(userAddrs)
+	if err != nil || !ok {
+		return nil, err
+	}
+	rows, err := r.Db.Query(`
+		select chain_id, user_addr, balance from user_balances
+		where `+cond+` order by chain_id, user_addr`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []UserBalance
+	for rows.Next() {
+		var ub UserBalance
+		var addrStr string
+		if err = rows.Scan(&ub.ChainID, &addrStr, &ub.Balance); err != nil {
+			return nil, err
+		}
+		ub.UserAddr = common.HexToAddress(addrStr)
+		balances = append(balances, ub)
+	}
+	return balances, rows.Err()
+}
+
+// GetUserTiersAllChains 获取一组地址在所有链上的当前会员等级
+func (r *DBRepository) GetUserTiersAllChains(userAddrs []common.Address) ([]UserTier, error) {
+	if len(userAddrs) == 0 {
+		return nil, nil
+	}
+	cond, args, ok, err := r.chainAddressFilter(userAddrs)
+	if err != nil || !ok {
+		return nil, err
+	}
+	rows, err := r.Db.Query(`
+		select chain_id, user_addr, tier, since from user_tiers
+		where `+cond+` order by chain_id, user_addr`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tiers []UserTier
+	for rows.Next() {
+		var ut UserTier
+		var addrStr string
+		if err = rows.Scan(&ut.ChainID, &addrStr, &ut.Tier, &ut.Since); err != nil {
+			return nil, err
+		}
+		ut.UserAddr = common.HexToAddress(addrStr)
+		tiers = append(tiers, ut)
+	}
+	return tiers, rows.Err()
+}
+
+// GetRecentBalanceChanges 获取一组地址在每条链上最近的 limit 条余额变动，按 ID 倒序
+func (r *DBRepository) GetRecentBalanceChanges(userAddrs []common.Address, limit int) ([]UserBalanceChange, error) {
+	if len(userAddrs) == 0 {
+		return nil, nil
+	}
+	cond, args, ok, err := r.chainAddressFilter(userAddrs)
+	if err != nil || !ok {
+		return nil, err
+	}
+	args = append(args, limit)
+	rows, err := r.Db.Query(`
+		select id, chain_id, user_addr, transaction_hash, block_number, change_amount, balance_after, event_type, created_at
+		from (
+			select *, row_number() over (partition by chain_id, user_addr order by id desc) as rn
+			from balance_changes where `+cond+`
+		) t where rn <= ? order by chain_id, user_addr, id desc`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []UserBalanceChange
+	for rows.Next() {
+		var ubc UserBalanceChange
+		var addrStr, txHash string
+		err = rows.Scan(&ubc.ID, &ubc.ChainID, &addrStr, &txHash, &ubc.BlockNumber, &ubc.BalanceChange,
+			&ubc.BalanceAfter, &ubc.EventType, &ubc.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		ubc.UserAddr = common.HexToAddress(addrStr)
+		ubc.TxHash = common.HexToHash(txHash)
+		changes = append(changes, ubc)
+	}
+	return changes, rows.Err()
+}
+
+// GetRecentPointsCalculations 获取一组地址在每条链上最近的 limit 条积分计算记录，按 ID 倒序
+func (r *DBRepository) GetRecentPointsCalculations(userAddrs []common.Address, limit int) ([]PointsCalculation, error) {
+	if len(userAddrs) == 0 {
+		return nil, nil
+	}
+	cond, args, ok, err := r.chainAddressFilter(userAddrs)
+	if err != nil || !ok {
+		return nil, err
+	}
+	args = append(args, limit)
+	rows, err := r.Db.Query(`
+		select id, chain_id, user_addr, calculated_at, balance, points_added, total_points_after
+		from (
+			select *, row_number() over (partition by chain_id, user_addr order by id desc) as rn
+			from points_calculations where `+cond+`
+		) t where rn <= ? order by chain_id, user_addr, id desc`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var calculations []PointsCalculation
+	for rows.Next() {
+		var pc PointsCalculation
+		var addrStr string
+		err = rows.Scan(&pc.ID, &pc.ChainID, &addrStr, &pc.CalculatedAt, &pc.Balance, &pc.PointsAdded, &pc.TotalPointsAfter)
+		if err != nil {
+			return nil, err
+		}
+		pc.UserAddr = common.HexToAddress(addrStr)
+		calculations = append(calculations, pc)
+	}
+	return calculations, rows.Err()
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	. "POINTSTOKEN/types"
+	"github.com/ethereum/go-ethereum/common"
+	"time"
+)
+
+// HistoryFilter 历史记录的分页和过滤条件，记录按 ID 倒序返回
+// Cursor 为上一页最后一条记录的 ID，0 表示从最新记录开始；时间和区块范围为零值时不过滤
+type HistoryFilter struct {
+	Cursor    uint64
+	Limit     int
+	FromTime  time.Time //包含
+	ToTime    time.Time //不包含
+	FromBlock uint64    //包含，仅用于余额变动
+	ToBlock   uint64    //包含，仅用于余额变动
+}
+
+// PointsCalculation 积分计算记录
+type PointsCalculation struct {
+	ID               uint64
+	ChainID          uint64
+	UserAddr         common.Address
+	CalculatedAt     time.Time
+	Balance          BigInt
+	PointsAdded      BigInt
+	TotalPointsAfter BigInt
+}
+
+// where 根据过滤条件拼接查询条件
+func (f HistoryFilter) where(timeColumn string, withBlock bool) (string, []interface{}) {
+	var clause string
+	var args []interface{}
+	if f.Cursor > 0 {
+		clause += " and id < ?"
+		args = append(args, f.Cursor)
+	}
+	if !f.FromTime.IsZero() {
+		clause += " and " + timeColumn + " >= ?"
+		args = append(args, f.FromTime)
+	}
+	if !f.ToTime.IsZero() {
+		clause += " and " + timeColumn + " < ?"
+		args = append(args, f.ToTime)
+	}
+	if withBlock && f.FromBlock > 0 {
+		clause += " and block_number >= ?"
+		args = append(args, f.FromBlock)
+	}
+	if withBlock && f.ToBlock > 0 {
+		clause += " and block_number <= ?"
+		args = append(args, f.ToBlock)
+	}
+	return clause, args
+}
+
+// GetBalanceHistory 分页获取用户的余额变动记录
+func (r *DBRepository) GetBalanceHistory(chainID uint64, userAddr common.Address, filter HistoryFilter) ([]UserBalanceChange, error) {
+	clause, args := filter.where("created_at", true)
+	args = append([]interface{}{chainID, userAddr.Hex()}, args...)
+	args = append(args, filter.Limit)
+	rows, err := r.Db.Query(`
+		select id, chain_id, user_addr, transaction_hash, block_number, change_amount, balance_after, event_type, created_at
+		from balance_changes where chain_id = ? and user_addr = ?`+clause+` order by id desc limit ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []UserBalanceChange
+	for rows.Next() {
+		var ubc UserBalanceChange
+		var addrStr, txHash string
+		err = rows.Scan(&ubc.ID, &ubc.ChainID, &addrStr, &txHash, &ubc.BlockNumber, &ubc.BalanceChange,
+			&ubc.BalanceAfter, &ubc.EventType, &ubc.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		ubc.UserAddr = common.HexToAddress(addrStr)
+		ubc.TxHash = common.HexToHash(txHash)
+		changes = append(changes, ubc)
+	}
+	return changes, rows.Err()
+}
+
+// GetPointsCalculations 分页获取用户的积分计算记录
+func (r *DBRepository) GetPointsCalculations(chainID uint64, userAddr common.Address, filter HistoryFilter) ([]PointsCalculation, error) {
+	clause, args := filter.where("calculated_at", false)
+	args = append([]interface{}{chainID, userAddr.Hex()}, args...)
+	args = append(args, filter.Limit)
+	rows, err := r.Db.Query(`
+		select id, chain_id, user_addr, calculated_at, balance, points_added, total_points_after
+		from points_calculations where chain_id = ? and user_addr = ?`+clause+` order by id desc limit ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var calculations []PointsCalculation
+	for rows.Next() {
+		var pc PointsCalculation
+		var addrStr string
+		err = rows.Scan(&pc.ID, &pc.ChainID, &addrStr, &pc.CalculatedAt, &pc.Balance, &pc.PointsAdded, &pc.TotalPointsAfter)
+		if err != nil {
+			return nil, err
+		}
+		pc.UserAddr = common.HexToAddress(addrStr)
+		calculations = append(calculations, pc)
+	}
+	return calculations, rows.Err()
+}
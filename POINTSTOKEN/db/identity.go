@@ -0,0 +1,124 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// LinkedWallet 已验证所有权的关联钱包
+type LinkedWallet struct {
+	UserAddr  common.Address
+	Signature string
+}
+
+// LinkAddresses 将钱包关联到 identityAddr 下，已关联到其他账户的钱包会被移动
+// 钱包移出后原账户剩余的地址在同一事务中整理，见 regroupIdentityTx
+func (r *DBRepository) LinkAddresses(identityAddr common.Address, wallets []LinkedWallet) error {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldIdentities []string
+	seen := make(map[string]bool)
+	for _, wallet := range wallets {
+		var oldIdentity string
+		err = tx.QueryRow(`
+			select identity_addr from identities where user_addr = ? for update`, wallet.UserAddr.Hex()).Scan(&oldIdentity)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err == nil && oldIdentity != identityAddr.Hex() && !seen[oldIdentity] {
+			seen[oldIdentity] = true
+			oldIdentities = append(oldIdentities, oldIdentity)
+		}
+
+		_, err = tx.Exec(`
+			insert into identities (identity_addr, user_addr, signature) values (?, ?, ?)
+			ON DUPLICATE KEY UPDATE identity_addr = ?, signature = ?, linked_at = NOW()`,
+			identityAddr.Hex(), wallet.UserAddr.Hex(), wallet.Signature, identityAddr.Hex(), wallet.Signature)
+		if err != nil {
+			return err
+		}
+	}
+	for _, oldIdentity := range oldIdentities {
+		if err := regroupIdentityTx(tx, oldIdentity); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// regroupIdentityTx 整理有钱包被移出的原账户：
+// 只剩一个地址时删除该关联；标识地址本身被移出时，剩余地址改挂到其中最早关联的地址下
+func regroupIdentityTx(tx *sql.Tx, identity string) error {
+	rows, err := tx.Query(`
+		select user_addr from identities where identity_addr = ? order by id for update`, identity)
+	if err != nil {
+		return err
+	}
+	var members []string
+	for rows.Next() {
+		var addrStr string
+		if err = rows.Scan(&addrStr); err != nil {
+			rows.Close()
+			return err
+		}
+		members = append(members, addrStr)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if len(members) <= 1 {
+		_, err = tx.Exec(`delete from identities where identity_addr = ?`, identity)
+		return err
+	}
+	for _, member := range members {
+		if member == identity {
+			return nil
+		}
+	}
+	_, err = tx.Exec(`update identities set identity_addr = ? where identity_addr = ?`, members[0], identity)
+	return err
+}
+
+// GetLinkedAddresses 获取与地址属于同一用户的全部地址（包含自身）
+func (r *DBRepository) GetLinkedAddresses(userAddr common.Address) ([]common.Address, error) {
+	rows, err := r.Db.Query(`
+		select user_addr from identities where identity_addr = (
+			select identity_addr from identities where user_addr = ?) order by id`, userAddr.Hex())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := []common.Address{userAddr}
+	for rows.Next() {
+		var addrStr string
+		if err = rows.Scan(&addrStr); err != nil {
+			return nil, err
+		}
+		if addr := common.HexToAddress(addrStr); addr != userAddr {
+			addresses = append(addresses, addr)
+		}
+	}
+	return addresses, rows.Err()
+}
+
+// GetIdentityAddr 获取地址所属的用户标识地址，未关联时返回自身
+func (r *DBRepository) GetIdentityAddr(userAddr common.Address) (common.Address, error) {
+	var identityStr string
+	err := r.Db.QueryRow(`
+		select identity_addr from identities where user_addr = ?`, userAddr.Hex()).Scan(&identityStr)
+	if errors.Is(err, sql.ErrNoRows) {
+		return userAddr, nil
+	}
+	if err != nil {
+		return userAddr, err
+	}
+	return common.HexToAddress(identityStr), nil
+}
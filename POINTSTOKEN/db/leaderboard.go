@@ -0,0 +1,170 @@
+package db
+
+import (
+	. "POINTSTOKEN/types"
+	"database/sql"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"strings"
+	"time"
+)
+
+// LeaderboardEntry 排行榜中的一条记录，Rank 从1开始，同分按地址排序
+type LeaderboardEntry struct {
+	Board     string
+	ChainID   uint64
+	UserAddr  common.Address
+	Score     BigInt
+	Rank      uint64
+	UpdatedAt time.Time
+}
+
+// SaveLeaderboardScores 更新排行榜中用户的分数
+func (r *DBRepository) SaveLeaderboardScores(board string, chainID uint64, entries []LeaderboardEntry) error {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, entry := range entries {
+		score := entry.Score.ToBigInt().String()
+		_, err = tx.Exec(`
+			insert into leaderboard_scores (board, chain_id, user_addr, score) values (?,?,?,?)
+			ON DUPLICATE KEY UPDATE score = ?`, board, chainID, entry.UserAddr.Hex(), score, score)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func scanLeaderboardEntries(rows *sql.Rows) ([]LeaderboardEntry, error) {
+	defer rows.Close()
+	var entries []LeaderboardEntry
+	for rows.Next() {
+		var entry LeaderboardEntry
+		var addrStr string
+		if err := rows.Scan(&entry.Board, &entry.ChainID, &addrStr, &entry.Score, &entry.UpdatedAt); err != nil {
+			return nil, err
+		}
+		entry.UserAddr = common.HexToAddress(addrStr)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetLeaderboard 按分数从高到低分页获取排行榜
+func (r *DBRepository) GetLeaderboard(board string, chainID uint64, offset int, limit int) ([]LeaderboardEntry, error) {
+	rows, err := r.Db.Query(`
+		select board, chain_id, user_addr, score, updated_at from leaderboard_scores
+		where board = ? and chain_id = ? and score > 0
+		order by score desc, user_addr asc limit ? offset ?`, board, chainID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := scanLeaderboardEntries(rows)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].Rank = uint64(offset + i + 1)
+	}
+	return entries, nil
+}
+
+// GetLeaderboardEntry 获取用户在排行榜中的分数和名次，不在榜上时返回 nil
+func (r *DBRepository) GetLeaderboardEntry(board string, chainID uint64, userAddr common.Address) (*LeaderboardEntry, error) {
+	var entry LeaderboardEntry
+	var addrStr string
+	err := r.Db.QueryRow(`
+		select board, chain_id, user_addr, score, updated_at from leaderboard_scores
+		where board = ? and chain_id = ? and user_addr = ? and score > 0`, board, chainID, userAddr.Hex()).Scan(
+		&entry.Board, &entry.ChainID, &addrStr, &entry.Score, &entry.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry.UserAddr = common.HexToAddress(addrStr)
+
+	score := entry.Score.ToBigInt().String()
+	var ahead uint64
+	err = r.Db.QueryRow(`
+		select count(*) from leaderboard_scores where board = ? and chain_id = ?
+		and (score > ? or (score = ? and user_addr < ?))`, board, chainID, score, score, addrStr).Scan(&ahead)
+	if err != nil {
+		return nil, err
+	}
+	entry.Rank = ahead + 1
+	return &entry, nil
+}
+
+// GetLeaderboardNeighbours 获取排在 entry 前后各 n 名的用户，按名次排序
+func (r *DBRepository) GetLeaderboardNeighbours(entry *LeaderboardEntry, n int) ([]LeaderboardEntry, []LeaderboardEntry, error) {
+	score := entry.Score.ToBigInt().String()
+	addr := entry.UserAddr.Hex()
+	rows, err := r.Db.Query(`
+		select board, chain_id, user_addr, score, updated_at from leaderboard_scores
+		where board = ? and chain_id = ? and (score > ? or (score = ? and user_addr < ?))
+		order by score asc, user_addr desc limit ?`, entry.Board, entry.ChainID, score, score, addr, n)
+	if err != nil {
+		return nil, nil, err
+	}
+	above, err := scanLeaderboardEntries(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	//前面的用户按名次从高到低排列
+	for i, j := 0, len(above)-1; i < j; i, j = i+1, j-1 {
+		above[i], above[j] = above[j], above[i]
+	}
+	for i := range above {
+		above[i].Rank = entry.Rank - uint64(len(above)-i)
+	}
+
+	rows, err = r.Db.Query(`
+		select board, chain_id, user_addr, score, updated_at from leaderboard_scores
+		where board = ? and chain_id = ? and score > 0 and (score < ? or (score = ? and user_addr > ?))
+		order by score desc, user_addr asc limit ?`, entry.Board, entry.ChainID, score, score, addr, n)
+	if err != nil {
+		return nil, nil, err
+	}
+	below, err := scanLeaderboardEntries(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range below {
+		below[i].Rank = entry.Rank + uint64(i+1)
+	}
+	return above, below, nil
+}
+
+// SumUserEarned 获取用户在 [since, until) 内获得的积分（所有入账分录）
+func (r *DBRepository) SumUserEarned(chainID uint64, userAddr common.Address, since time.Time, until time.Time) (string, error) {
+	var total BigInt
+	err := r.Db.QueryRow(`
+		select cast(coalesce(sum(amount), 0) as char) from points_ledger
+		where chain_id = ? and credit_account = ? and created_at >= ? and created_at < ?`,
+		chainID, userAddr.Hex(), since, until).Scan(&total)
+	if err != nil {
+		return "0", err
+	}
+	return total.ToBigInt().String(), nil
+}
+
+// DeleteLeaderboardScores 从排行榜中移除用户，用于钱包关联到其他账户后移除其单独的记录
+func (r *DBRepository) DeleteLeaderboardScores(board string, chainID uint64, userAddrs []common.Address) error {
+	if len(userAddrs) == 0 {
+		return nil
+	}
+	args := []interface{}{board, chainID}
+	for _, addr := range userAddrs {
+		args = append(args, addr.Hex())
+	}
+	_, err := r.Db.Exec(`
+		delete from leaderboard_scores where board = ? and chain_id = ? and user_addr in (?`+
+		strings.Repeat(",?", len(userAddrs)-1)+`)`, args...)
+	return err
+}
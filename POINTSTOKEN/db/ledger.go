@@ -0,0 +1,454 @@
+package db
+
+import (
+	. "POINTSTOKEN/types"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// 账本分录类型，前七种为入账（credit），其余为出账（debit）
+const (
+	LedgerAccrual    = "accrual"    //持币积分累计
+	LedgerOpening    = "opening"    //启用账本前的历史积分，由 migrate.sql 迁入
+	LedgerBonus      = "bonus"      //奖励
+	LedgerGrant      = "grant"      //人工发放
+	LedgerReferral   = "referral"   //推荐奖励
+	LedgerCorrection = "correction" //重算补发
+	LedgerRefund     = "refund"     //过期凭证退回
+	LedgerRedemption = "redemption" //兑换
+	LedgerExpiry     = "expiry"     //过期
+	LedgerDecay      = "decay"      //衰减
+	LedgerClawback   = "clawback"   //人工扣回
+	LedgerReversal   = "reversal"   //重算冲回，不校验余额
+)
+
+// ErrIdempotencyConflict 幂等键已被不同参数的分录使用
+var ErrIdempotencyConflict = errors.New("幂等键已被其他分录使用")
+
+// LedgerEntry 积分账本分录
+type LedgerEntry struct {
+	ID             uint64
+	ChainID        uint64
+	EntryType      string
+	DebitAccount   string
+	CreditAccount  string
+	Amount         BigInt
+	IdempotencyKey string
+	Memo           string
+	Operator       string //人工发放、扣回的操作人，系统分录为空
+	RunID          uint64 //写入分录的积分计算运行ID，其他分录为0
+	CreatedAt      time.Time
+}
+
+// IsCreditType 判断分录类型是否为用户入账
+func IsCreditType(entryType string) bool {
+	switch entryType {
+	case LedgerAccrual, LedgerOpening, LedgerBonus, LedgerGrant, LedgerReferral, LedgerCorrection, LedgerRefund:
+		return true
+	}
+	return false
+}
+
+// SystemAccount 返回分录类型对应的系统账户
+func SystemAccount(entryType string) string {
+	return "system:" + entryType
+}
+
+// newLedgerEntry 构造用户与系统账户之间的分录
+func newLedgerEntry(chainID uint64, userAddr common.Address, entryType string, amount *big.Int,
+	idempotencyKey string, memo string) *LedgerEntry {
+	entry := &LedgerEntry{
+		ChainID:        chainID,
+		EntryType:      entryType,
+		Amount:         *FromBigInt(amount),
+		IdempotencyKey: idempotencyKey,
+		Memo:           memo,
+		CreatedAt:      time.Now(),
+	}
+	if IsCreditType(entryType) {
+		entry.DebitAccount = SystemAccount(entryType)
+		entry.CreditAccount = userAddr.Hex()
+	} else {
+		entry.DebitAccount = userAddr.Hex()
+		entry.CreditAccount = SystemAccount(entryType)
+	}
+	return entry
+}
+
+// CreditPoints 为用户入账积分，幂等键相同的重复请求直接返回已有分录
+func (r *DBRepository) CreditPoints(chainID uint64, userAddr common.Address, entryType string,
+	amount string, idempotencyKey string, memo string) (*LedgerEntry, error) {
+	if !IsCreditType(entryType) {
+		return nil, fmt.Errorf("%s 不是入账类型", entryType)
+	}
+	return r.postPoints(chainID, userAddr, entryType, amount, idempotencyKey, memo, "")
+}
+
+// SpendPoints 原子地扣减用户积分，超出可用余额时返回 ErrInsufficientPoints
+func (r *DBRepository) SpendPoints(chainID uint64, userAddr common.Address, entryType string,
+	amount string, idempotencyKey string, memo string) (*LedgerEntry, error) {
+	if IsCreditType(entryType) {
+		return nil, fmt.Errorf("%s 不是出账类型", entryType)
+	}
+	return r.postPoints(chainID, userAddr, entryType, amount, idempotencyKey, memo, "")
+}
+
+func (r *DBRepository) postPoints(chainID uint64, userAddr common.Address, entryType string,
+	amount string, idempotencyKey string, memo string, operator string) (*LedgerEntry, error) {
+	amountInt, ok := new(big.Int).SetString(amount, 10)
+	if !ok || amountInt.Sign() <= 0 {
+		return nil, fmt.Errorf("无效的积分数量: %s", amount)
+	}
+	if idempotencyKey == "" {
+		return nil, fmt.Errorf("幂等键不能为空")
+	}
+
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	entry := newLedgerEntry(chainID, userAddr, entryType, amountInt, idempotencyKey, memo)
+	entry.Operator = operator
+	if err := postLedgerEntryTx(tx, entry); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// postLedgerEntryTx 在事务中写入分录并同步 user_points 中的余额
+// 出账时按账本推导的余额校验，幂等键已存在时用已有分录填充 entry
+func postLedgerEntryTx(tx *sql.Tx, entry *LedgerEntry) error {
+	existing, err := getLedgerEntryByKeyTx(tx, entry.ChainID, entry.IdempotencyKey)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if existing.EntryType != entry.EntryType || existing.DebitAccount != entry.DebitAccount ||
+			existing.CreditAccount != entry.CreditAccount || existing.Amount.ToBigInt().Cmp(entry.Amount.ToBigInt()) != 0 {
+			return ErrIdempotencyConflict
+		}
+		*entry = *existing
+		return nil
+	}
+
+	userAccount := entry.CreditAccount
+	if !IsCreditType(entry.EntryType) {
+		userAccount = entry.DebitAccount
+	}
+
+	//锁定用户积分行，串行化同一用户的记账
+	_, err = tx.Exec(`
+		insert into user_points (chain_id, user_addr, total_points) values (?, ?, 0)
+		ON DUPLICATE KEY UPDATE id = id`, entry.ChainID, userAccount)
+	if err != nil {
+		return err
+	}
+	var cached string
+	err = tx.QueryRow(`
+		select total_points from user_points where chain_id = ? and user_addr = ? for update`,
+		entry.ChainID, userAccount).Scan(&cached)
+	if err != nil {
+		return err
+	}
+
+	if !IsCreditType(entry.EntryType) && entry.EntryType != LedgerReversal {
+		balance, err := ledgerBalanceTx(tx, entry.ChainID, userAccount)
+		if err != nil {
+			return err
+		}
+		if balance.Cmp(entry.Amount.ToBigInt()) < 0 {
+			return ErrInsufficientPoints
+		}
+	}
+
+	result, err := tx.Exec(`
+		insert into points_ledger (
+		chain_id, entry_type, debit_account, credit_account, amount, idempotency_key, memo, operator, run_id, created_at)
+		values (?,?,?,?,?,?,?,?,nullif(?, 0),?)`, entry.ChainID, entry.EntryType, entry.DebitAccount, entry.CreditAccount,
+		entry.Amount.ToBigInt().String(), entry.IdempotencyKey, entry.Memo, entry.Operator, entry.RunID, entry.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	entry.ID = uint64(id)
+
+	delta := entry.Amount.ToBigInt().String()
+	if !IsCreditType(entry.EntryType) {
+		delta = "-" + delta
+	}
+	_, err = tx.Exec(`
+		update user_points set total_points = total_points + cast(? as decimal(50, 0)) where chain_id = ? and user_addr = ?`,
+		delta, entry.ChainID, userAccount)
+	if err != nil {
+		return err
+	}
+
+	//与分录同一事务写入发件箱，保证 webhook 事件不丢不多
+	var totalAfter string
+	err = tx.QueryRow(`select total_points from user_points where chain_id = ? and user_addr = ?`,
+		entry.ChainID, userAccount).Scan(&totalAfter)
+	if err != nil {
+		return err
+	}
+	return insertOutboxTx(tx, OutboxPoints, entry.ChainID, userAccount, PointsPayload{
+		LedgerID:       entry.ID,
+		ChainID:        entry.ChainID,
+		Address:        userAccount,
+		EntryType:      entry.EntryType,
+		Delta:          delta,
+		TotalAfter:     totalAfter,
+		IdempotencyKey: entry.IdempotencyKey,
+		CreatedAt:      entry.CreatedAt,
+	})
+}
+
+func getLedgerEntryByKeyTx(tx *sql.Tx, chainID uint64, idempotencyKey string) (*LedgerEntry, error) {
+	var entry LedgerEntry
+	err := tx.QueryRow(`
+		select id, chain_id, entry_type, debit_account, credit_account, amount, idempotency_key, memo, operator, created_at
+		from points_ledger where chain_id = ? and idempotency_key = ?`, chainID, idempotencyKey).Scan(
+		&entry.ID, &entry.ChainID, &entry.EntryType, &entry.DebitAccount, &entry.CreditAccount,
+		&entry.Amount, &entry.IdempotencyKey, &entry.Memo, &entry.Operator, &entry.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// ledgerBalanceTx 由账本分录推导账户余额：入账合计 - 出账合计
+func ledgerBalanceTx(tx *sql.Tx, chainID uint64, account string) (*big.Int, error) {
+	var balance BigInt
+	err := tx.QueryRow(ledgerBalanceSQL, chainID, account, chainID, account).Scan(&balance)
+	if err != nil {
+		return nil, err
+	}
+	return balance.ToBigInt(), nil
+}
+
+const ledgerBalanceSQL = `
+		select cast(
+			coalesce((select sum(amount) from points_ledger where chain_id = ? and credit_account = ?), 0) -
+			coalesce((select sum(amount) from points_ledger where chain_id = ? and debit_account = ?), 0)
+		as char)`
+
+// GetLedgerBalance 获取由账本推导的用户积分余额
+func (r *DBRepository) GetLedgerBalance(chainID uint64, userAddr common.Address) (string, error) {
+	var balance BigInt
+	err := r.Db.QueryRow(ledgerBalanceSQL, chainID, userAddr.Hex(), chainID, userAddr.Hex()).Scan(&balance)
+	if err != nil {
+		return "0", err
+	}
+	return balance.ToBigInt().String(), nil
+}
+
+// GetLedgerEntries 分页获取用户的账本分录，按时间倒序
+func (r *DBRepository) GetLedgerEntries(chainID uint64, userAddr common.Address, filter HistoryFilter) ([]LedgerEntry, error) {
+	clause, args := filter.where("created_at", false)
+	args = append([]interface{}{chainID, userAddr.Hex(), userAddr.Hex()}, args...)
+	args = append(args, filter.Limit)
+	rows, err := r.Db.Query(`
+		select id, chain_id, entry_type, debit_account, credit_account, amount, idempotency_key, memo, operator, created_at
+		from points_ledger where chain_id = ? and (credit_account = ? or debit_account = ?)`+clause+`
+		order by id desc limit ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []LedgerEntry
+	for rows.Next() {
+		var entry LedgerEntry
+		err = rows.Scan(&entry.ID, &entry.ChainID, &entry.EntryType, &entry.DebitAccount, &entry.CreditAccount,
+			&entry.Amount, &entry.IdempotencyKey, &entry.Memo, &entry.Operator, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetLedgerEntryByKey 按幂等键获取分录，不存在时返回 nil
+func (r *DBRepository) GetLedgerEntryByKey(chainID uint64, idempotencyKey string) (*LedgerEntry, error) {
+	var entry LedgerEntry
+	err := r.Db.QueryRow(`
+		select id, chain_id, entry_type, debit_account, credit_account, amount, idempotency_key, memo, operator, created_at
+		from points_ledger where chain_id = ? and idempotency_key = ?`, chainID, idempotencyKey).Scan(
+		&entry.ID, &entry.ChainID, &entry.EntryType, &entry.DebitAccount, &entry.CreditAccount,
+		&entry.Amount, &entry.IdempotencyKey, &entry.Memo, &entry.Operator, &entry.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// ReferralCredit 与持币积分同一事务记入的推荐奖励，记入后填充 EntryID 和 TotalAfter
+type ReferralCredit struct {
+	Referrer       common.Address
+	Amount         *big.Int
+	IdempotencyKey string
+	Memo           string
+	EntryID        uint64
+	TotalAfter     string
+}
+
+// RecordAccrual 在同一事务中记入持币积分、推荐人的推荐奖励并记录积分计算，分录记录所属的运行ID，
+// 返回计算后的总积分和分录ID（未写分录时为0）
+func (r *DBRepository) RecordAccrual(chainID uint64, runID uint64, userAddr common.Address, calculatedAt time.Time,
+	balance string, pointsAdded string, referrals []ReferralCredit) (string, uint64, error) {
+	amountInt, ok := new(big.Int).SetString(pointsAdded, 10)
+	if !ok || amountInt.Sign() < 0 {
+		return "", 0, fmt.Errorf("无效的积分数量: %s", pointsAdded)
+	}
+
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return "", 0, err
+	}
+	defer tx.Rollback()
+
+	//新增积分为0（如被上限削减）时只记录计算时间，不写分录
+	var entryID uint64
+	if amountInt.Sign() > 0 {
+		key := fmt.Sprintf("%s:%s:%d", LedgerAccrual, userAddr.Hex(), calculatedAt.Unix())
+		entry := newLedgerEntry(chainID, userAddr, LedgerAccrual, amountInt, key, "")
+		entry.RunID = runID
+		entry.CreatedAt = calculatedAt
+		if err := postLedgerEntryTx(tx, entry); err != nil {
+			return "", 0, err
+		}
+		entryID = entry.ID
+	}
+	for i := range referrals {
+		referral := &referrals[i]
+		entry := newLedgerEntry(chainID, referral.Referrer, LedgerReferral, referral.Amount,
+			referral.IdempotencyKey, referral.Memo)
+		entry.RunID = runID
+		entry.CreatedAt = calculatedAt
+		if err := postLedgerEntryTx(tx, entry); err != nil {
+			return "", 0, fmt.Errorf("记入推荐奖励失败: %w", err)
+		}
+		referral.EntryID = entry.ID
+		err = tx.QueryRow(`
+			select total_points from user_points where chain_id = ? and user_addr = ?`,
+			chainID, referral.Referrer.Hex()).Scan(&referral.TotalAfter)
+		if err != nil {
+			return "", 0, err
+		}
+	}
+
+	totalAfter := "0"
+	err = tx.QueryRow(`
+		select total_points from user_points where chain_id = ? and user_addr = ?`,
+		chainID, userAddr.Hex()).Scan(&totalAfter)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", 0, err
+	}
+	_, err = tx.Exec(`
+		insert into points_calculations (chain_id, user_addr, calculated_at, balance, points_added, total_points_after)
+		values (?,?,?,?,?,?)
+		ON DUPLICATE KEY UPDATE points_added = ?`, chainID, userAddr.Hex(), calculatedAt, balance, pointsAdded, totalAfter, pointsAdded)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", 0, err
+	}
+	return totalAfter, entryID, nil
+}
+
+// GetPointsUsers 获取链上积分余额大于0的用户
+func (r *DBRepository) GetPointsUsers(chainID uint64) ([]common.Address, error) {
+	rows, err := r.Db.Query(`
+		select user_addr from user_points where chain_id = ? and total_points > 0`, chainID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []common.Address
+	for rows.Next() {
+		var addrStr string
+		if err = rows.Scan(&addrStr); err != nil {
+			return nil, err
+		}
+		users = append(users, common.HexToAddress(addrStr))
+	}
+	return users, rows.Err()
+}
+
+// GetExpirablePoints 按先进先出计算已到期但未被消耗的积分：
+// cutoff 之前的入账合计 - 全部出账合计（出账总是先消耗最早的入账）
+func (r *DBRepository) GetExpirablePoints(chainID uint64, userAddr common.Address, cutoff time.Time) (string, error) {
+	var expirable BigInt
+	err := r.Db.QueryRow(`
+		select cast(
+			coalesce((select sum(amount) from points_ledger 
+				where chain_id = ? and credit_account = ? and created_at < ?), 0) -
+			coalesce((select sum(amount) from points_ledger where chain_id = ? and debit_account = ?), 0)
+		as char)`, chainID, userAddr.Hex(), cutoff, chainID, userAddr.Hex()).Scan(&expirable)
+	if err != nil {
+		return "0", err
+	}
+	if expirable.ToBigInt().Sign() < 0 {
+		return "0", nil
+	}
+	return expirable.ToBigInt().String(), nil
+}
+
+// SumUserCredits 获取用户自 since 起指定类型入账分录的合计
+func (r *DBRepository) SumUserCredits(chainID uint64, userAddr common.Address, entryTypes []string, since time.Time) (string, error) {
+	placeholders, typeArgs := entryTypeArgs(entryTypes)
+	args := append([]interface{}{chainID, userAddr.Hex(), since}, typeArgs...)
+	var total BigInt
+	err := r.Db.QueryRow(`
+		select cast(coalesce(sum(amount), 0) as char) from points_ledger 
+		where chain_id = ? and credit_account = ? and created_at >= ? and entry_type in (`+placeholders+`)`,
+		args...).Scan(&total)
+	if err != nil {
+		return "0", err
+	}
+	return total.ToBigInt().String(), nil
+}
+
+// SumChainCredits 获取链上自 since 起指定类型入账分录的合计
+func (r *DBRepository) SumChainCredits(chainID uint64, entryTypes []string, since time.Time) (string, error) {
+	placeholders, typeArgs := entryTypeArgs(entryTypes)
+	args := append([]interface{}{chainID, since}, typeArgs...)
+	var total BigInt
+	err := r.Db.QueryRow(`
+		select cast(coalesce(sum(amount), 0) as char) from points_ledger 
+		where chain_id = ? and created_at >= ? and entry_type in (`+placeholders+`)`, args...).Scan(&total)
+	if err != nil {
+		return "0", err
+	}
+	return total.ToBigInt().String(), nil
+}
+
+// entryTypeArgs 返回 entry_type in (...) 的占位符和参数
+func entryTypeArgs(entryTypes []string) (string, []interface{}) {
+	args := make([]interface{}, len(entryTypes))
+	for i, entryType := range entryTypes {
+		args[i] = entryType
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", len(entryTypes)), ","), args
+}
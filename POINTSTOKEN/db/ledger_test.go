@@ -0,0 +1,60 @@
+package db
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"testing"
+)
+
+func TestIsCreditType(t *testing.T) {
+	tests := []struct {
+		entryType string
+		want      bool
+	}{
+		{LedgerAccrual, true},
+		{LedgerOpening, true},
+		{LedgerBonus, true},
+		{LedgerGrant, true},
+		{LedgerReferral, true},
+		{LedgerCorrection, true},
+		{LedgerRefund, true},
+		{LedgerRedemption, false},
+		{LedgerExpiry, false},
+		{LedgerDecay, false},
+		{LedgerClawback, false},
+		{LedgerReversal, false},
+		{"unknown", false},
+	}
+	for _, tt := range tests {
+		if got := IsCreditType(tt.entryType); got != tt.want {
+			t.Errorf("IsCreditType(%q) = %v, want %v", tt.entryType, got, tt.want)
+		}
+	}
+}
+
+func TestNewLedgerEntry(t *testing.T) {
+	user := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	tests := []struct {
+		entryType string
+		debit     string
+		credit    string
+	}{
+		{LedgerAccrual, "system:accrual", user.Hex()},
+		{LedgerGrant, "system:grant", user.Hex()},
+		{LedgerRedemption, user.Hex(), "system:redemption"},
+		{LedgerReversal, user.Hex(), "system:reversal"},
+	}
+	for _, tt := range tests {
+		entry := newLedgerEntry(1, user, tt.entryType, big.NewInt(42), "key", "memo")
+		if entry.DebitAccount != tt.debit || entry.CreditAccount != tt.credit {
+			t.Errorf("%s: debit=%s credit=%s, want debit=%s credit=%s",
+				tt.entryType, entry.DebitAccount, entry.CreditAccount, tt.debit, tt.credit)
+		}
+		if entry.Amount.ToBigInt().Cmp(big.NewInt(42)) != 0 {
+			t.Errorf("%s: amount=%s, want 42", tt.entryType, entry.Amount.ToBigInt())
+		}
+		if entry.ChainID != 1 || entry.EntryType != tt.entryType || entry.IdempotencyKey != "key" || entry.Memo != "memo" {
+			t.Errorf("%s: unexpected entry %+v", tt.entryType, entry)
+		}
+	}
+}
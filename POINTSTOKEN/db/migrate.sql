@@ -0,0 +1,45 @@
+-- 已有数据库升级：先执行 db.sql 创建新增的表，再按顺序执行本文件
+-- 新建的数据库直接使用 db.sql，无需执行本文件
+
+
+-- user-027 积分账本
+-- user_points 原先 user_addr 单列唯一，同一地址无法在多条链上各有一行积分
+ALTER TABLE user_points DROP INDEX user_addr;
+
+-- 启用账本前 user_points 中已累计的积分没有分录，按差额记入一条 opening 分录作为期初余额
+-- 幂等键为 opening:<地址>，重复执行不会重复迁入
+INSERT IGNORE INTO points_ledger (
+       chain_id, entry_type, debit_account, credit_account, amount, idempotency_key, memo, created_at)
+SELECT up.chain_id, 'opening', 'system:opening', up.user_addr,
+       up.total_points
+           - coalesce((select sum(amount) from points_ledger l
+                       where l.chain_id = up.chain_id and l.credit_account = up.user_addr), 0)
+           + coalesce((select sum(amount) from points_ledger l
+                       where l.chain_id = up.chain_id and l.debit_account = up.user_addr), 0),
+       concat('opening:', up.user_addr), '启用账本前的历史积分', up.updated_at
+FROM user_points up
+WHERE up.total_points
+          - coalesce((select sum(amount) from points_ledger l
+                      where l.chain_id = up.chain_id and l.credit_account = up.user_addr), 0)
+          + coalesce((select sum(amount) from points_ledger l
+                      where l.chain_id = up.chain_id and l.debit_account = up.user_addr), 0) > 0;
+
+
+-- user-032 余额变动按日志去重，重复处理同一区块范围不会重复写入
+-- 旧记录没有日志序号，按同一交易同一地址的写入顺序编号后再建唯一键；
+-- 此前因重试产生的重复记录无法与同一交易中的多笔转账区分，需要时应清空 balance_changes 后从 start_block 重新同步
+ALTER TABLE balance_changes ADD COLUMN log_index INT NOT NULL DEFAULT 0 AFTER transaction_hash;
+UPDATE balance_changes b
+    JOIN (SELECT id, row_number() over (partition by chain_id, transaction_hash, user_addr order by id) - 1 AS rn
+          FROM balance_changes) t ON b.id = t.id
+SET b.log_index = t.rn;
+ALTER TABLE balance_changes ADD UNIQUE KEY unique_balance_change (chain_id, transaction_hash, log_index, user_addr);
+
+
+-- user-050 记录最后处理区块的时间，按时间查询历史余额时不能超过该时间
+ALTER TABLE chains ADD COLUMN last_block_time TIMESTAMP NULL AFTER last_processed_block;
+
+-- 此前同步的余额变动按同步时的最新余额写入 balance_after，补同步的历史区块与区块高度不对应，
+-- 历史余额和持有人快照会不准确。升级后执行（需要归档节点）：
+--   POINTSTOKEN balances reindex -chain <链ID> [-from-block <区块>]
+-- 按区块重新读取余额；重建前创建的物化快照需删除后重新创建
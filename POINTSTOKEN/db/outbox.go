@@ -0,0 +1,217 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"time"
+)
+
+// 发件箱事件类型，也是 webhook 订阅可选的事件类型
+const (
+	OutboxBalanceChange = "balance_change" //balance_changes 新增记录
+	OutboxPoints        = "points"         //points_ledger 新增分录，包含入账和出账
+)
+
+// OutboxEvent 发件箱中的事件
+type OutboxEvent struct {
+	ID        uint64
+	Seq       uint64 //事件提交后分配、连续递增的序号
+	EventType string
+	ChainID   uint64
+	UserAddr  common.Address
+	Payload   json.RawMessage
+	CreatedAt time.Time
+}
+
+// BalanceChangePayload 余额变动事件内容
+type BalanceChangePayload struct {
+	ID           uint64    `json:"id"`
+	ChainID      uint64    `json:"chain_id"`
+	Address      string    `json:"address"`
+	TxHash       string    `json:"tx_hash"`
+	BlockNumber  uint64    `json:"block_number"`
+	EventType    string    `json:"event_type"`
+	ChangeAmount string    `json:"change_amount"`
+	BalanceAfter string    `json:"balance_after"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// PointsPayload 积分分录事件内容，delta 为用户积分的变化，出账时为负数
+type PointsPayload struct {
+	LedgerID       uint64    `json:"ledger_id"`
+	ChainID        uint64    `json:"chain_id"`
+	Address        string    `json:"address"`
+	EntryType      string    `json:"entry_type"`
+	Delta          string    `json:"delta"`
+	TotalAfter     string    `json:"total_after"`
+	IdempotencyKey string    `json:"idempotency_key"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// insertOutboxTx 在事务中写入发件箱事件，序号为空，提交后由 SequenceOutboxEvents 分配。
+// 写入事务不锁定任何共享行，不会与其他记账事务互相等待
+func insertOutboxTx(tx *sql.Tx, eventType string, chainID uint64, userAddr string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		insert into event_outbox (event_type, chain_id, user_addr, payload) values (?,?,?,?)`,
+		eventType, chainID, userAddr, string(data))
+	return err
+}
+
+// SequenceOutboxEvents 按 ID 顺序为已提交、尚未分配序号的事件分配连续递增的序号，返回本次分配的事件数。
+// 只有分配序号的事务锁定 outbox_sequence，多个进程同时执行时依次分配；
+// 未提交的事件不可见，提交后在下一次分配时获得更大的序号，按序号读取的消费者不会错过事件
+func (r *DBRepository) SequenceOutboxEvents(limit int) (int, error) {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`insert into outbox_sequence (id, seq) values (1, 0) ON DUPLICATE KEY UPDATE id = id`)
+	if err != nil {
+		return 0, err
+	}
+	var seq uint64
+	if err = tx.QueryRow(`select seq from outbox_sequence where id = 1 for update`).Scan(&seq); err != nil {
+		return 0, err
+	}
+	//持有计数器行锁之后再读取，能看到之前所有分配事务的结果
+	rows, err := tx.Query(`select id from event_outbox where seq is null order by id limit ?`, limit)
+	if err != nil {
+		return 0, err
+	}
+	var ids []uint64
+	for rows.Next() {
+		var id uint64
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	for _, id := range ids {
+		seq++
+		if _, err = tx.Exec(`update event_outbox set seq = ? where id = ? and seq is null`, seq, id); err != nil {
+			return 0, err
+		}
+	}
+	if _, err = tx.Exec(`update outbox_sequence set seq = ? where id = 1`, seq); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+const outboxColumns = `id, seq, event_type, chain_id, user_addr, payload, created_at`
+
+func scanOutboxEvents(rows *sql.Rows) ([]OutboxEvent, error) {
+	defer rows.Close()
+	var events []OutboxEvent
+	for rows.Next() {
+		var e OutboxEvent
+		var addrStr string
+		var payload []byte
+		if err := rows.Scan(&e.ID, &e.Seq, &e.EventType, &e.ChainID, &addrStr, &payload, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.UserAddr = common.HexToAddress(addrStr)
+		e.Payload = payload
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// GetUndispatchedEvents 按序号顺序获取已分配序号、尚未分发的事件
+func (r *DBRepository) GetUndispatchedEvents(limit int) ([]OutboxEvent, error) {
+	rows, err := r.Db.Query(`
+		select `+outboxColumns+` from event_outbox where dispatched_at is null and seq is not null order by seq limit ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxEvents(rows)
+}
+
+// DispatchEvent 为匹配的订阅生成投递记录并将事件标记为已分发，在同一事务中完成，
+// 重复分发时已有的投递记录不会重复生成
+func (r *DBRepository) DispatchEvent(event *OutboxEvent, subscriptionIDs []uint64, now time.Time) error {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, subscriptionID := range subscriptionIDs {
+		_, err = tx.Exec(`
+			insert into webhook_deliveries (subscription_id, outbox_id, event_type, status, next_attempt_at)
+			values (?,?,?,?,?) ON DUPLICATE KEY UPDATE id = id`,
+			subscriptionID, event.ID, event.EventType, DeliveryPending, now)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`
+		update event_outbox set dispatched_at = ? where id = ? and dispatched_at is null`, now, event.ID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetOutboxEventsAfter 按序号顺序获取 afterSeq 之后的事件，eventTypes 和 filter 为空表示不过滤
+func (r *DBRepository) GetOutboxEventsAfter(afterSeq uint64, eventTypes []string, filter EventFilter, limit int) ([]OutboxEvent, error) {
+	clause, args := filter.where("user_addr")
+	if len(eventTypes) > 0 {
+		typeClause, typeArgs := entryTypeArgs(eventTypes)
+		clause = " and event_type in (" + typeClause + ")" + clause
+		args = append(typeArgs, args...)
+	}
+	args = append([]interface{}{afterSeq}, args...)
+	args = append(args, limit)
+	rows, err := r.Db.Query(`
+		select `+outboxColumns+` from event_outbox where seq > ?`+clause+` order by seq limit ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxEvents(rows)
+}
+
+// GetOutboxLastSeq 获取最新事件的序号，没有事件时返回 0
+func (r *DBRepository) GetOutboxLastSeq() (uint64, error) {
+	var seq uint64
+	err := r.Db.QueryRow(`select coalesce(max(seq), 0) from event_outbox`).Scan(&seq)
+	return seq, err
+}
+
+// GetOutboxCursor 获取消费者已处理到的事件序号，尚未消费过时返回 0
+func (r *DBRepository) GetOutboxCursor(consumer string) (uint64, error) {
+	var lastSeq uint64
+	err := r.Db.QueryRow(`select last_seq from outbox_cursors where consumer = ?`, consumer).Scan(&lastSeq)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return lastSeq, err
+}
+
+// SaveOutboxCursor 保存消费者已处理到的事件序号
+func (r *DBRepository) SaveOutboxCursor(consumer string, lastSeq uint64) error {
+	_, err := r.Db.Exec(`
+		insert into outbox_cursors (consumer, last_seq) values (?,?)
+		ON DUPLICATE KEY UPDATE last_seq = ?`, consumer, lastSeq, lastSeq)
+	return err
+}
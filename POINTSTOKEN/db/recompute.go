@@ -0,0 +1,286 @@
+package db
+
+import (
+	. "POINTSTOKEN/types"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// ErrRecomputeStale 重算截止时间早于最近一次积分计算，替换会重复计分
+var ErrRecomputeStale = errors.New("重算截止时间早于最近一次积分计算，请重新计算")
+
+// ErrChainPointsLocked 等待链积分锁超时，另一个积分计算或重算替换正在进行
+var ErrChainPointsLocked = errors.New("链积分正在计算，请稍后重试")
+
+// LockChainPoints 获取链积分的命名锁（GET_LOCK），定时积分计算与重算替换互斥，跨进程生效
+// 锁与获取它的数据库连接绑定，返回的函数释放锁并归还连接
+func (r *DBRepository) LockChainPoints(chainID uint64, timeout time.Duration) (func(), error) {
+	ctx := context.Background()
+	conn, err := r.Db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("points:chain:%d", chainID)
+	var acquired sql.NullInt64
+	err = conn.QueryRowContext(ctx, `select get_lock(?, ?)`, name, int(timeout.Seconds())).Scan(&acquired)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !acquired.Valid || acquired.Int64 != 1 {
+		conn.Close()
+		return nil, ErrChainPointsLocked
+	}
+	return func() {
+		conn.ExecContext(ctx, `select release_lock(?)`, name)
+		conn.Close()
+	}, nil
+}
+
+// RecomputedPoints 影子表中单个用户重算后的积分
+type RecomputedPoints struct {
+	ChainID        uint64
+	UserAddr       common.Address
+	Balance        BigInt
+	AccrualPoints  BigInt //持币积分
+	ReferralPoints BigInt //推荐奖励
+	OtherPoints    BigInt //发放、兑换、过期等其他分录的净额，按账本原样保留
+	TotalPoints    BigInt
+	ComputedUntil  time.Time
+}
+
+// recomputedTypes 重算时重新推导的分录类型，其余分录按账本原样保留
+// 历史积分迁入的是启用账本前的持币积分，同样由重算覆盖
+var recomputedTypes = []string{LedgerAccrual, LedgerOpening, LedgerReferral, LedgerCorrection, LedgerReversal}
+
+// LedgerNetChange 一条分录对用户积分的影响，出账为负数
+type LedgerNetChange struct {
+	UserAddr  common.Address
+	Amount    *big.Int
+	CreatedAt time.Time
+}
+
+// GetLedgerNetChanges 按时间顺序获取链上除持币积分、历史积分、推荐奖励及重算调整外的分录对用户积分的影响
+func (r *DBRepository) GetLedgerNetChanges(chainID uint64) ([]LedgerNetChange, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(recomputedTypes)), ",")
+	args := []interface{}{chainID}
+	for _, t := range recomputedTypes {
+		args = append(args, t)
+	}
+	args = append(args, chainID)
+	for _, t := range recomputedTypes {
+		args = append(args, t)
+	}
+	rows, err := r.Db.Query(fmt.Sprintf(`
+		select account, cast(amount as char), created_at from (
+			select id, credit_account as account, amount, created_at from points_ledger
+			where chain_id = ? and entry_type not in (%s)
+			union all
+			select id, debit_account as account, -amount, created_at from points_ledger
+			where chain_id = ? and entry_type not in (%s)
+		) t where account not like 'system:%%' order by created_at, id`, placeholders, placeholders), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []LedgerNetChange
+	for rows.Next() {
+		var addrStr string
+		var amount BigInt
+		var change LedgerNetChange
+		if err = rows.Scan(&addrStr, &amount, &change.CreatedAt); err != nil {
+			return nil, err
+		}
+		change.UserAddr = common.HexToAddress(addrStr)
+		change.Amount = new(big.Int).Set(amount.ToBigInt())
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// GetChainUserPoints 获取链上所有用户的线上积分
+func (r *DBRepository) GetChainUserPoints(chainID uint64) ([]UserPoints, error) {
+	rows, err := r.Db.Query(`
+		select chain_id, user_addr, total_points from user_points where chain_id = ?`, chainID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []UserPoints
+	for rows.Next() {
+		var up UserPoints
+		var addrStr string
+		if err = rows.Scan(&up.ChainID, &addrStr, &up.TotalPoints); err != nil {
+			return nil, err
+		}
+		up.UserAddr = common.HexToAddress(addrStr)
+		points = append(points, up)
+	}
+	return points, rows.Err()
+}
+
+// SaveRecomputation 用本次重算结果替换影子表中该链的数据
+func (r *DBRepository) SaveRecomputation(chainID uint64, rows []RecomputedPoints) error {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(`delete from user_points_recompute where chain_id = ?`, chainID); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		_, err = tx.Exec(`
+			insert into user_points_recompute (
+			chain_id, user_addr, balance, accrual_points, referral_points, other_points, total_points, computed_until)
+			values (?,?,?,?,?,?,?,?)`, chainID, row.UserAddr.Hex(), row.Balance.ToBigInt().String(),
+			row.AccrualPoints.ToBigInt().String(), row.ReferralPoints.ToBigInt().String(),
+			row.OtherPoints.ToBigInt().String(), row.TotalPoints.ToBigInt().String(), row.ComputedUntil)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetRecomputation 获取影子表中该链的重算结果
+func (r *DBRepository) GetRecomputation(chainID uint64) ([]RecomputedPoints, error) {
+	rows, err := r.Db.Query(`
+		select chain_id, user_addr, balance, accrual_points, referral_points, other_points, total_points, computed_until
+		from user_points_recompute where chain_id = ? order by user_addr`, chainID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []RecomputedPoints
+	for rows.Next() {
+		var row RecomputedPoints
+		var addrStr string
+		err = rows.Scan(&row.ChainID, &addrStr, &row.Balance, &row.AccrualPoints, &row.ReferralPoints,
+			&row.OtherPoints, &row.TotalPoints, &row.ComputedUntil)
+		if err != nil {
+			return nil, err
+		}
+		row.UserAddr = common.HexToAddress(addrStr)
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// ApplyRecomputation 在一个事务中用影子表替换线上积分：
+// 重算覆盖的分录（持币积分、历史积分、推荐奖励及重算调整）与重算结果的差额，以重算截止时间的 correction / reversal 分录记入账本，
+// 其他分录（包括重算之后新增的兑换等）保持不变；冲回不会使积分低于0，积分不足时只冲回现有积分，并计入 clamped。
+// 同时在重算截止时间记录积分计算，之后的定时计算从该时间继续。返回被调整的用户数和冲回被截断的用户数
+func (r *DBRepository) ApplyRecomputation(chainID uint64) (int, int, error) {
+	rows, err := r.GetRecomputation(chainID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(rows) == 0 {
+		return 0, 0, fmt.Errorf("链 %d 没有重算结果", chainID)
+	}
+	until := rows[0].ComputedUntil
+
+	//持有链积分锁直到替换提交，期间定时计算不会写入新的积分计算，保证下面的过期检查有效
+	unlock, err := r.LockChainPoints(chainID, 30*time.Second)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer unlock()
+
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	var latest sql.NullTime
+	err = tx.QueryRow(`
+		select max(calculated_at) from points_calculations where chain_id = ?`, chainID).Scan(&latest)
+	if err != nil {
+		return 0, 0, err
+	}
+	if latest.Valid && latest.Time.After(until) {
+		return 0, 0, ErrRecomputeStale
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(recomputedTypes)), ",")
+	adjusted, clamped := 0, 0
+	for _, row := range rows {
+		//锁定用户积分行，读取余额和重算覆盖的分录净额
+		_, err = tx.Exec(`
+			insert into user_points (chain_id, user_addr, total_points) values (?, ?, 0)
+			ON DUPLICATE KEY UPDATE id = id`, chainID, row.UserAddr.Hex())
+		if err != nil {
+			return 0, 0, err
+		}
+		var current BigInt
+		err = tx.QueryRow(`
+			select total_points from user_points where chain_id = ? and user_addr = ? for update`,
+			chainID, row.UserAddr.Hex()).Scan(&current)
+		if err != nil {
+			return 0, 0, err
+		}
+		args := []interface{}{row.UserAddr.Hex(), chainID, row.UserAddr.Hex(), row.UserAddr.Hex()}
+		for _, t := range recomputedTypes {
+			args = append(args, t)
+		}
+		var covered BigInt
+		err = tx.QueryRow(fmt.Sprintf(`
+			select cast(coalesce(sum(case when credit_account = ? then amount else -amount end), 0) as char)
+			from points_ledger where chain_id = ? and (credit_account = ? or debit_account = ?) and entry_type in (%s)`,
+			placeholders), args...).Scan(&covered)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		target := new(big.Int).Add(row.AccrualPoints.ToBigInt(), row.ReferralPoints.ToBigInt())
+		diff := new(big.Int).Sub(target, covered.ToBigInt())
+		if balance := current.ToBigInt(); diff.Sign() < 0 && new(big.Int).Add(balance, diff).Sign() < 0 {
+			diff.Neg(balance)
+			clamped++
+		}
+		if diff.Sign() != 0 {
+			entryType := LedgerCorrection
+			if diff.Sign() < 0 {
+				entryType = LedgerReversal
+			}
+			key := fmt.Sprintf("recompute:%s:%d", row.UserAddr.Hex(), until.Unix())
+			entry := newLedgerEntry(chainID, row.UserAddr, entryType, new(big.Int).Abs(diff), key, "重算调整")
+			entry.CreatedAt = until
+			if err := postLedgerEntryTx(tx, entry); err != nil {
+				return 0, 0, err
+			}
+			adjusted++
+		}
+
+		var totalAfter string
+		err = tx.QueryRow(`select total_points from user_points where chain_id = ? and user_addr = ?`,
+			chainID, row.UserAddr.Hex()).Scan(&totalAfter)
+		if err != nil {
+			return 0, 0, err
+		}
+		_, err = tx.Exec(`
+			insert into points_calculations (chain_id, user_addr, calculated_at, balance, points_added, total_points_after)
+			values (?,?,?,?,0,?)
+			ON DUPLICATE KEY UPDATE total_points_after = ?`, chainID, row.UserAddr.Hex(), until,
+			row.Balance.ToBigInt().String(), totalAfter, totalAfter)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return adjusted, clamped, nil
+}
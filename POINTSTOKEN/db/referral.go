@@ -0,0 +1,121 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"time"
+)
+
+// 推荐关系来源
+const (
+	ReferralSourceSignature = "signature"
+	ReferralSourceEvent     = "event"
+)
+
+var (
+	// ErrReferralExists 被推荐人已有推荐人
+	ErrReferralExists = errors.New("该地址已登记推荐人")
+	// ErrReferralCycle 推荐关系成环
+	ErrReferralCycle = errors.New("推荐关系不能成环")
+)
+
+// Referral 推荐关系
+type Referral struct {
+	RefereeAddr  common.Address
+	ReferrerAddr common.Address
+	Source       string
+	SourceRef    string //签名或交易哈希
+	CreatedAt    time.Time
+}
+
+// RegisterReferral 登记推荐关系，拒绝自我推荐、重复登记以及成环的关系
+func (r *DBRepository) RegisterReferral(referee common.Address, referrer common.Address, source string, sourceRef string) error {
+	if referee == referrer {
+		return ErrReferralCycle
+	}
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var existing string
+	err = tx.QueryRow(`
+		select referrer_addr from referrals where referee_addr = ? for update`, referee.Hex()).Scan(&existing)
+	if err == nil {
+		return ErrReferralExists
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	//沿推荐人向上查找，若遇到被推荐人则成环
+	current := referrer
+	for {
+		var upper string
+		err = tx.QueryRow(`
+			select referrer_addr from referrals where referee_addr = ?`, current.Hex()).Scan(&upper)
+		if errors.Is(err, sql.ErrNoRows) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		current = common.HexToAddress(upper)
+		if current == referee {
+			return ErrReferralCycle
+		}
+	}
+
+	_, err = tx.Exec(`
+		insert into referrals (referee_addr, referrer_addr, source, source_ref) values (?, ?, ?, ?)`,
+		referee.Hex(), referrer.Hex(), source, sourceRef)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetReferrers 获取被推荐人向上最多 depth 级的推荐人，按层级排序
+func (r *DBRepository) GetReferrers(referee common.Address, depth int) ([]common.Address, error) {
+	var referrers []common.Address
+	current := referee
+	for len(referrers) < depth {
+		var upper string
+		err := r.Db.QueryRow(`
+			select referrer_addr from referrals where referee_addr = ?`, current.Hex()).Scan(&upper)
+		if errors.Is(err, sql.ErrNoRows) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		current = common.HexToAddress(upper)
+		referrers = append(referrers, current)
+	}
+	return referrers, nil
+}
+
+// GetReferees 获取推荐人直接推荐的地址
+func (r *DBRepository) GetReferees(referrer common.Address) ([]Referral, error) {
+	rows, err := r.Db.Query(`
+		select referee_addr, source, source_ref, created_at from referrals 
+		where referrer_addr = ? order by id`, referrer.Hex())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var referrals []Referral
+	for rows.Next() {
+		ref := Referral{ReferrerAddr: referrer}
+		var addrStr string
+		if err = rows.Scan(&addrStr, &ref.Source, &ref.SourceRef, &ref.CreatedAt); err != nil {
+			return nil, err
+		}
+		ref.RefereeAddr = common.HexToAddress(addrStr)
+		referrals = append(referrals, ref)
+	}
+	return referrals, rows.Err()
+}
@@ -12,23 +12,164 @@ type Repository interface {
 	//链相关操作
 	SaveChain(chainName string, chainID uint64, contractAddr string, lastBlock uint64) error
 	GetChains() ([]uint64, error)
-	UpdateChainLastBlock(chainID uint64, lastBlock uint64) error
+	UpdateChainLastBlock(chainID uint64, lastBlock uint64, blockTime time.Time) error
 	GetChainLastBlock(chainID uint64) (uint64, error)
+	GetChainLastBlockTime(chainID uint64) (time.Time, error)
 
 	// 余额相关操作
-	UpdateUserBalance(chainID uint64, userAddr common.Address, balance uint64) error
-	GetUserBalance(chainID uint64, userAddr common.Address) (uint64, error)
-	RecordBalanceChange(chainID uint64, userAddr common.Address, txHash common.Hash,
-		blockNumber uint64, changeAmount uint64, balanceAfter string, eventType string, timeStamp time.Time) error
+	UpdateUserBalance(chainID uint64, userAddr common.Address, balance string) error
+	GetUserBalance(chainID uint64, userAddr common.Address) (string, error)
+	RecordBalanceChange(chainID uint64, userAddr common.Address, txHash common.Hash, logIndex uint,
+		blockNumber uint64, changeAmount string, balanceAfter string, eventType string, timeStamp time.Time) (uint64, error)
 	GetBalanceChange(chainID uint64) ([]UserBalanceChange, error)
 	GetAllUserBalance() ([]UserBalance, error)
+	GetTransfers(chainID uint64, since time.Time) ([]Transfer, error)
+
+	GetBalanceHistory(chainID uint64, userAddr common.Address, filter HistoryFilter) ([]UserBalanceChange, error)
 
 	// 积分相关操作
-	UpdateUserPoints(chainId uint64, userAddr common.Address, addPoints string) (string, error)
 	GetUserPoints(chainId uint64, userAddr common.Address) (string, error)
+	GetUserPointsAllChains(userAddrs []common.Address) ([]UserPoints, error)
 	RecordPointsCalculation(chainId uint64, userAddr common.Address, calculation time.Time,
 		balance string, pointsAdd string, totalAfter string) error
 	GetPointLastRecordTime(chainId uint64, userAddr common.Address) (time.Time, error)
+	RecordAccrual(chainID uint64, runID uint64, userAddr common.Address, calculatedAt time.Time,
+		balance string, pointsAdded string, referrals []ReferralCredit) (string, uint64, error)
+	GetPointsCalculations(chainID uint64, userAddr common.Address, filter HistoryFilter) ([]PointsCalculation, error)
+
+	// 积分账本相关操作
+	CreditPoints(chainID uint64, userAddr common.Address, entryType string,
+		amount string, idempotencyKey string, memo string) (*LedgerEntry, error)
+	SpendPoints(chainID uint64, userAddr common.Address, entryType string,
+		amount string, idempotencyKey string, memo string) (*LedgerEntry, error)
+	GetLedgerBalance(chainID uint64, userAddr common.Address) (string, error)
+	GetLedgerEntries(chainID uint64, userAddr common.Address, filter HistoryFilter) ([]LedgerEntry, error)
+	GetLedgerEntryByKey(chainID uint64, idempotencyKey string) (*LedgerEntry, error)
+	GetPointsUsers(chainID uint64) ([]common.Address, error)
+	GetExpirablePoints(chainID uint64, userAddr common.Address, cutoff time.Time) (string, error)
+	SumUserCredits(chainID uint64, userAddr common.Address, entryTypes []string, since time.Time) (string, error)
+	SumChainCredits(chainID uint64, entryTypes []string, since time.Time) (string, error)
+	AdjustPoints(chainID uint64, userAddr common.Address, entryType string,
+		amount string, idempotencyKey string, reason string, operator string) (*LedgerEntry, error)
+	GetAdjustments(chainID uint64, limit int) ([]LedgerEntry, error)
+
+	// 积分重算相关操作
+	GetLedgerNetChanges(chainID uint64) ([]LedgerNetChange, error)
+	GetChainUserPoints(chainID uint64) ([]UserPoints, error)
+	SaveRecomputation(chainID uint64, rows []RecomputedPoints) error
+	GetRecomputation(chainID uint64) ([]RecomputedPoints, error)
+	ApplyRecomputation(chainID uint64) (int, int, error)
+	LockChainPoints(chainID uint64, timeout time.Duration) (func(), error)
+
+	// 积分计算运行记录相关操作
+	CreatePointsRun(run *PointsRun) error
+	FinishPointsRun(run *PointsRun) error
+	GetPointsRun(id uint64) (*PointsRun, error)
+	GetPointsRuns(chainID uint64, limit int) ([]PointsRun, error)
+	GetRunLedgerEntries(runID uint64) ([]LedgerEntry, error)
+
+	// 排行榜相关操作
+	SaveLeaderboardScores(board string, chainID uint64, entries []LeaderboardEntry) error
+	GetLeaderboard(board string, chainID uint64, offset int, limit int) ([]LeaderboardEntry, error)
+	GetLeaderboardEntry(board string, chainID uint64, userAddr common.Address) (*LeaderboardEntry, error)
+	GetLeaderboardNeighbours(entry *LeaderboardEntry, n int) ([]LeaderboardEntry, []LeaderboardEntry, error)
+	SumUserEarned(chainID uint64, userAddr common.Address, since time.Time, until time.Time) (string, error)
+	DeleteLeaderboardScores(board string, chainID uint64, userAddrs []common.Address) error
+
+	// 会员等级相关操作
+	GetUserTier(chainID uint64, userAddr common.Address) (*UserTier, error)
+	SetUserTier(chainID uint64, userAddr common.Address, tier string, direction string, changedAt time.Time) (bool, error)
+	GetTierHistory(chainID uint64, userAddr *common.Address) ([]TierChange, error)
+	GetHoldingInfo(chainID uint64, userAddr common.Address) (string, time.Time, error)
+
+	// 批量查询相关操作
+	GetChainInfos() ([]ChainInfo, error)
+	GetUserBalancesAllChains(userAddrs []common.Address) ([]UserBalance, error)
+	GetUserTiersAllChains(userAddrs []common.Address) ([]UserTier, error)
+	GetRecentBalanceChanges(userAddrs []common.Address, limit int) ([]UserBalanceChange, error)
+	GetRecentPointsCalculations(userAddrs []common.Address, limit int) ([]PointsCalculation, error)
+
+	// 发件箱消费及事件补发相关操作
+	SequenceOutboxEvents(limit int) (int, error)
+	GetOutboxEventsAfter(afterSeq uint64, eventTypes []string, filter EventFilter, limit int) ([]OutboxEvent, error)
+	GetOutboxLastSeq() (uint64, error)
+	GetOutboxCursor(consumer string) (uint64, error)
+	SaveOutboxCursor(consumer string, lastSeq uint64) error
+
+	// webhook 相关操作
+	GetUndispatchedEvents(limit int) ([]OutboxEvent, error)
+	DispatchEvent(event *OutboxEvent, subscriptionIDs []uint64, now time.Time) error
+	CreateWebhookSubscription(sub *WebhookSubscription) error
+	GetWebhookSubscriptions(activeOnly bool) ([]WebhookSubscription, error)
+	GetWebhookSubscription(id uint64) (*WebhookSubscription, error)
+	DeactivateWebhookSubscription(id uint64) (bool, error)
+	ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) ([]WebhookDelivery, error)
+	RecordDeliveryAttempt(attempt *DeliveryAttempt, status string, nextAttemptAt time.Time) error
+	PurgeOutbox(before time.Time, limit int) (int, error)
+	GetWebhookDeliveries(subscriptionID uint64, status string, limit int) ([]WebhookDelivery, error)
+	GetDeliveryAttempts(deliveryID uint64) ([]DeliveryAttempt, error)
+
+	// 认证相关操作
+	CreateAPIKey(key *APIKey) error
+	GetAPIKeyByHash(keyHash string) (*APIKey, error)
+	GetAPIKeys() ([]APIKey, error)
+	TouchAPIKey(id uint64, now time.Time, interval time.Duration) error
+	RevokeAPIKey(id uint64, now time.Time) (bool, error)
+	CreateSIWENonce(nonce string, now time.Time, expiresAt time.Time) error
+	ConsumeSIWENonce(nonce string, now time.Time) (bool, error)
+	CreateSession(tokenHash string, session *AuthSession) error
+	GetSessionByHash(tokenHash string, now time.Time) (*AuthSession, error)
+	RevokeSession(tokenHash string, now time.Time) error
+
+	// 导出相关操作
+	StreamUserBalances(chainID uint64, fn func(*ExportBalance) error) error
+	StreamBalanceChanges(chainID uint64, from time.Time, to time.Time, fn func(*UserBalanceChange) error) error
+	StreamLedgerEntries(chainID uint64, from time.Time, to time.Time, fn func(*LedgerEntry) error) error
+
+	// 历史余额和持有人快照相关操作
+	GetLastBalanceChange(chainID uint64, userAddr common.Address, blockNumber uint64) (*UserBalanceChange, error)
+	GetBlockAtTime(chainID uint64, at time.Time) (uint64, error)
+	GetBalanceChangeRefs(chainID uint64, fromBlock uint64, afterID uint64, limit int) ([]BalanceChangeRef, error)
+	UpdateBalanceAfter(chainID uint64, userAddr common.Address, blockNumber uint64, balance string) (int64, error)
+	GetHolders(chainID uint64, blockNumber uint64, after string, limit int) ([]HolderBalance, error)
+	StreamHolders(chainID uint64, blockNumber uint64, fn func(*HolderBalance) error) error
+	CreateHolderSnapshot(snapshot *HolderSnapshot) error
+	GetHolderSnapshot(chainID uint64, blockNumber uint64) (*HolderSnapshot, error)
+	GetHolderSnapshots(chainID uint64) ([]HolderSnapshot, error)
+	DeleteHolderSnapshot(id uint64) (bool, error)
+	GetSnapshotHolders(snapshotID uint64, after string, limit int) ([]HolderBalance, error)
+	StreamSnapshotHolders(snapshotID uint64, fn func(*HolderBalance) error) error
+
+	// 积分排除名单相关操作
+	AddExclusion(chainID uint64, userAddr common.Address, reason string, source string) error
+	ReplaceConfigExclusions(exclusions []Exclusion) error
+	RemoveExclusion(chainID uint64, userAddr common.Address) error
+	GetExclusions(chainID uint64) ([]Exclusion, error)
+	RecordExclusionAudit(chainID uint64, calculatedAt time.Time, exclusions []Exclusion) error
+	GetExclusionAudit(chainID uint64, calculatedAt time.Time) ([]Exclusion, error)
+
+	// 刷量检测相关操作
+	SaveWashFlags(flags []WashFlag) error
+	GetWashFlags(chainID uint64, since time.Time, status string) ([]WashFlag, error)
+	UpdateWashFlagStatus(id uint64, status string) error
+
+	// 地址关联相关操作
+	LinkAddresses(identityAddr common.Address, wallets []LinkedWallet) error
+	GetLinkedAddresses(userAddr common.Address) ([]common.Address, error)
+	GetIdentityAddr(userAddr common.Address) (common.Address, error)
+
+	// 推荐关系相关操作
+	RegisterReferral(referee common.Address, referrer common.Address, source string, sourceRef string) error
+	GetReferrers(referee common.Address, depth int) ([]common.Address, error)
+	GetReferees(referrer common.Address) ([]Referral, error)
+
+	// 兑换凭证相关操作
+	CreateVoucher(chainID uint64, userAddr common.Address, amount string,
+		deadline time.Time, sign VoucherSigner) (*PointsVoucher, error)
+	GetUserVouchers(userAddr common.Address) ([]PointsVoucher, error)
+	GetUnsettledVouchers(before time.Time, limit int) ([]PointsVoucher, error)
+	MarkVoucherRedeemed(userAddr common.Address, nonce uint64) error
+	RefundVoucher(userAddr common.Address, nonce uint64) (*LedgerEntry, error)
 }
 
 type UserBalance struct {
@@ -36,9 +177,17 @@ type UserBalance struct {
 	UserAddr common.Address
 	Balance  BigInt
 }
+type UserPoints struct {
+	ChainID     uint64
+	UserAddr    common.Address
+	TotalPoints BigInt
+}
 type UserBalanceChange struct {
+	ID            uint64
 	ChainID       uint64
 	UserAddr      common.Address
+	TxHash        common.Hash
+	BlockNumber   uint64
 	BalanceChange BigInt
 	BalanceAfter  BigInt
 	EventType     string
@@ -82,11 +231,11 @@ func (r *DBRepository) GetChains() ([]uint64, error) {
 	return chains, rows.Err()
 }
 
-// UpdateChainLastBlock 更新链的最后处理区快
-func (r *DBRepository) UpdateChainLastBlock(chainID uint64, lastBlock uint64) error {
+// UpdateChainLastBlock 更新链的最后处理区快及该区块的时间
+func (r *DBRepository) UpdateChainLastBlock(chainID uint64, lastBlock uint64, blockTime time.Time) error {
 	_, err := r.Db.Exec(`
-		UPDATE chains SET last_processed_block = ?, updated_at = NOW() 
-		where chain_id = ? `, lastBlock, chainID)
+		UPDATE chains SET last_processed_block = ?, last_block_time = ?, updated_at = NOW() 
+		where chain_id = ? `, lastBlock, blockTime, chainID)
 	return err
 }
 
@@ -98,8 +247,16 @@ func (r *DBRepository) GetChainLastBlock(chainID uint64) (uint64, error) {
 	return lastBlock, err
 }
 
+// GetChainLastBlockTime 获取链最后处理区块的时间，尚未记录时返回零值
+func (r *DBRepository) GetChainLastBlockTime(chainID uint64) (time.Time, error) {
+	var blockTime sql.NullTime
+	err := r.Db.QueryRow(`
+		select last_block_time from chains where chain_id = ?`, chainID).Scan(&blockTime)
+	return blockTime.Time, err
+}
+
 // UpdateUserBalance 更新用户余额
-func (r *DBRepository) UpdateUserBalance(chainID uint64, userAddr common.Address, balance uint64) error {
+func (r *DBRepository) UpdateUserBalance(chainID uint64, userAddr common.Address, balance string) error {
 	_, err := r.Db.Exec(`
 		insert into user_balances (chain_id, user_addr, balance) 
 		values (?, ?, ?) ON DUPLICATE KEY UPDATE balance = ?`, chainID, userAddr.Hex(), balance, balance)
@@ -107,32 +264,72 @@ func (r *DBRepository) UpdateUserBalance(chainID uint64, userAddr common.Address
 }
 
 // GetUserBalance 获取用户余额
-func (r *DBRepository) GetUserBalance(chainID uint64, userAddr common.Address) (uint64, error) {
-	var balance uint64
+func (r *DBRepository) GetUserBalance(chainID uint64, userAddr common.Address) (string, error) {
+	var balance BigInt
 	err := r.Db.QueryRow(`
 		select balance from user_balances where chain_id = ? and user_addr = ?`,
 		chainID, userAddr.Hex()).Scan(&balance)
 	if errors.Is(err, sql.ErrNoRows) {
-		return 0, nil
+		return "0", nil
 	}
-	return balance, err
+	if err != nil {
+		return "0", err
+	}
+	return balance.ToBigInt().String(), nil
 }
 
-// RecordBalanceChange 记录余额变动
-func (r *DBRepository) RecordBalanceChange(chainID uint64, userAddr common.Address, txHash common.Hash,
-	blockNumber uint64, changeAmount uint64, balanceAfter string, eventType string, timeStamp time.Time) error {
-	_, err := r.Db.Exec(`
-		insert into balance_changes (
-		chain_id, user_addr, transaction_hash, block_number, change_amount, balance_after, event_type, created_at) 
-		values (?,?,?,?,?,?,?,?)`, chainID, userAddr.Hex(), txHash.Hex(), blockNumber, changeAmount, balanceAfter, eventType, timeStamp)
-	return err
+// RecordBalanceChange 记录余额变动，返回记录ID
+// 同一笔日志对同一地址只记录一次，重复处理区块时返回 0 且不写发件箱
+func (r *DBRepository) RecordBalanceChange(chainID uint64, userAddr common.Address, txHash common.Hash, logIndex uint,
+	blockNumber uint64, changeAmount string, balanceAfter string, eventType string, timeStamp time.Time) (uint64, error) {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		insert ignore into balance_changes (
+		chain_id, user_addr, transaction_hash, log_index, block_number, change_amount, balance_after, event_type, created_at) 
+		values (?,?,?,?,?,?,?,?,?)`, chainID, userAddr.Hex(), txHash.Hex(), logIndex, blockNumber, changeAmount, balanceAfter,
+		eventType, timeStamp)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if affected == 0 {
+		return 0, nil
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	//与余额变动同一事务写入发件箱
+	err = insertOutboxTx(tx, OutboxBalanceChange, chainID, userAddr.Hex(), BalanceChangePayload{
+		ID:           uint64(id),
+		ChainID:      chainID,
+		Address:      userAddr.Hex(),
+		TxHash:       txHash.Hex(),
+		BlockNumber:  blockNumber,
+		EventType:    eventType,
+		ChangeAmount: changeAmount,
+		BalanceAfter: balanceAfter,
+		Timestamp:    timeStamp,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return uint64(id), tx.Commit()
 }
 
 // GetBalanceChange 获取余额变动信息
 func (r *DBRepository) GetBalanceChange(chainID uint64) ([]UserBalanceChange, error) {
 	rows, err := r.Db.Query(`
-		select chain_id, user_addr, change_amount, balance_after,event_type,created_at 
-		from balance_changes where chain_id = ? order by chain_id,user_addr`, chainID)
+		select id, chain_id, user_addr, transaction_hash, block_number, change_amount, balance_after, event_type, created_at
+		from balance_changes where chain_id = ? order by user_addr, block_number, id`, chainID)
 	if err != nil {
 		return nil, err
 	}
@@ -140,12 +337,14 @@ func (r *DBRepository) GetBalanceChange(chainID uint64) ([]UserBalanceChange, er
 	var userBalanceChange []UserBalanceChange
 	for rows.Next() {
 		var ubc UserBalanceChange
-		var addrStr string
-		err = rows.Scan(&ubc.ChainID, &addrStr, &ubc.BalanceChange, &ubc.BalanceAfter, &ubc.EventType, &ubc.CreatedAt)
+		var addrStr, txHash string
+		err = rows.Scan(&ubc.ID, &ubc.ChainID, &addrStr, &txHash, &ubc.BlockNumber, &ubc.BalanceChange,
+			&ubc.BalanceAfter, &ubc.EventType, &ubc.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
 		ubc.UserAddr = common.HexToAddress(addrStr)
+		ubc.TxHash = common.HexToHash(txHash)
 		userBalanceChange = append(userBalanceChange, ubc)
 	}
 	return userBalanceChange, rows.Err()
@@ -173,34 +372,7 @@ func (r *DBRepository) GetAllUserBalance() ([]UserBalance, error) {
 	return userBalances, rows.Err()
 }
 
-func (r *DBRepository) UpdateUserPoints(chainId uint64, userAddr common.Address, addPoints string) (string, error) {
-	tx, err := r.Db.Begin()
-	if err != nil {
-		return "", err
-	}
-	defer tx.Rollback()
-
-	//更新积分
-	_, err = tx.Exec(`
-		insert into user_points (chain_id, user_addr, total_points) values (?, ?, ?) 
-		ON DUPLICATE KEY UPDATE total_points = ?
-		`, chainId, userAddr.Hex(), addPoints, addPoints)
-	if err != nil {
-		return "", err
-	}
-
-	var finalPoints string
-	err = tx.QueryRow(`
-		select total_points from user_points where user_addr = ?  and chain_id = ?`, userAddr.Hex(), chainId).Scan(&finalPoints)
-	if err != nil && err != sql.ErrNoRows {
-		return "", err
-	}
-	if err := tx.Commit(); err != nil {
-		return "", err
-	}
-	return finalPoints, nil
-}
-
+// GetUserPoints 获取用户积分余额（与账本同步维护）
 func (r *DBRepository) GetUserPoints(chainId uint64, userAddr common.Address) (string, error) {
 	var points string
 	err := r.Db.QueryRow(`
@@ -214,6 +386,37 @@ func (r *DBRepository) GetUserPoints(chainId uint64, userAddr common.Address) (s
 	return points, nil
 }
 
+// GetUserPointsAllChains 获取一组地址在所有链上的积分
+func (r *DBRepository) GetUserPointsAllChains(userAddrs []common.Address) ([]UserPoints, error) {
+	if len(userAddrs) == 0 {
+		return nil, nil
+	}
+	cond, args, ok, err := r.chainAddressFilter(userAddrs)
+	if err != nil || !ok {
+		return nil, err
+	}
+	rows, err := r.Db.Query(`
+		select chain_id, user_addr, total_points from user_points 
+		where `+cond+` order by chain_id, user_addr`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userPoints []UserPoints
+	for rows.Next() {
+		var up UserPoints
+		var addrStr string
+		err = rows.Scan(&up.ChainID, &addrStr, &up.TotalPoints)
+		if err != nil {
+			return nil, err
+		}
+		up.UserAddr = common.HexToAddress(addrStr)
+		userPoints = append(userPoints, up)
+	}
+	return userPoints, rows.Err()
+}
+
 // RecordPointsCalculation 记录积分计算
 func (r *DBRepository) RecordPointsCalculation(chainId uint64, userAddr common.Address, calculatedAt time.Time,
 	balance, pointsAdded, totalAfter string) error {
@@ -224,11 +427,15 @@ func (r *DBRepository) RecordPointsCalculation(chainId uint64, userAddr common.A
 	return err
 }
 
+// GetPointLastRecordTime 获取用户最后一次积分计算时间，从未计算时返回零值
 func (r *DBRepository) GetPointLastRecordTime(chainId uint64, userAddr common.Address) (time.Time, error) {
 	var lastTime time.Time
 	err := r.Db.QueryRow(`
 		select calculated_at from points_calculations 
-		where user_addr = ? and chain_id = ? order by calculated_at desc`, userAddr.Hex(), chainId).Scan(&lastTime)
+		where user_addr = ? and chain_id = ? order by calculated_at desc limit 1`, userAddr.Hex(), chainId).Scan(&lastTime)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
 	if err != nil {
 		return lastTime, err
 	}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	. "POINTSTOKEN/types"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// 积分计算运行状态
+const (
+	RunRunning   = "running"
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+// PointsRun 单链积分计算的运行记录
+type PointsRun struct {
+	ID             uint64
+	ChainID        uint64
+	Status         string
+	RuleVersion    string //积分规则配置摘要
+	StartedAt      time.Time
+	FinishedAt     *time.Time
+	CalculatedAt   time.Time //本次计算的截止时间，也是分录幂等键中的时间，本次写入的分录以 run_id 关联
+	UsersProcessed int
+	UsersExcluded  int
+	UsersCapped    int
+	UsersPenalized int
+	PointsUncapped BigInt
+	PointsEmitted  BigInt
+	ReferralPoints BigInt
+	BudgetScaled   bool
+	EntriesCount   int
+	EntriesDigest  string
+	Error          string
+}
+
+// CreatePointsRun 记录一次开始的积分计算
+func (r *DBRepository) CreatePointsRun(run *PointsRun) error {
+	result, err := r.Db.Exec(`
+		insert into points_runs (chain_id, status, rule_version, started_at, calculated_at) values (?,?,?,?,?)`,
+		run.ChainID, RunRunning, run.RuleVersion, run.StartedAt, run.CalculatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	run.ID = uint64(id)
+	run.Status = RunRunning
+	return nil
+}
+
+// FinishPointsRun 记录积分计算的结果
+func (r *DBRepository) FinishPointsRun(run *PointsRun) error {
+	_, err := r.Db.Exec(`
+		update points_runs set status = ?, finished_at = ?, users_processed = ?, users_excluded = ?, users_capped = ?,
+		users_penalized = ?, points_uncapped = ?, points_emitted = ?, referral_points = ?, budget_scaled = ?,
+		entries_count = ?, entries_digest = ?, error = ? where id = ?`,
+		run.Status, run.FinishedAt, run.UsersProcessed, run.UsersExcluded, run.UsersCapped, run.UsersPenalized,
+		run.PointsUncapped.ToBigInt().String(), run.PointsEmitted.ToBigInt().String(), run.ReferralPoints.ToBigInt().String(),
+		run.BudgetScaled, run.EntriesCount, run.EntriesDigest, run.Error, run.ID)
+	return err
+}
+
+const pointsRunColumns = `id, chain_id, status, rule_version, started_at, finished_at, calculated_at, users_processed,
+		users_excluded, users_capped, users_penalized, points_uncapped, points_emitted, referral_points, budget_scaled,
+		entries_count, entries_digest, coalesce(error, '')`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPointsRun(row rowScanner) (*PointsRun, error) {
+	var run PointsRun
+	var finishedAt sql.NullTime
+	err := row.Scan(&run.ID, &run.ChainID, &run.Status, &run.RuleVersion, &run.StartedAt, &finishedAt, &run.CalculatedAt,
+		&run.UsersProcessed, &run.UsersExcluded, &run.UsersCapped, &run.UsersPenalized, &run.PointsUncapped,
+		&run.PointsEmitted, &run.ReferralPoints, &run.BudgetScaled, &run.EntriesCount, &run.EntriesDigest, &run.Error)
+	if err != nil {
+		return nil, err
+	}
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	return &run, nil
+}
+
+// GetPointsRun 获取运行记录，不存在时返回 nil
+func (r *DBRepository) GetPointsRun(id uint64) (*PointsRun, error) {
+	run, err := scanPointsRun(r.Db.QueryRow(`select `+pointsRunColumns+` from points_runs where id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return run, err
+}
+
+// GetPointsRuns 获取最近的运行记录，按开始时间倒序，chainID 为0时返回所有链
+func (r *DBRepository) GetPointsRuns(chainID uint64, limit int) ([]PointsRun, error) {
+	rows, err := r.Db.Query(`select `+pointsRunColumns+` from points_runs
+		where chain_id = ? or ? = 0 order by id desc limit ?`, chainID, chainID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []PointsRun
+	for rows.Next() {
+		run, err := scanPointsRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+	return runs, rows.Err()
+}
+
+// GetRunLedgerEntries 获取某次计算写入的持币积分和推荐奖励分录
+func (r *DBRepository) GetRunLedgerEntries(runID uint64) ([]LedgerEntry, error) {
+	rows, err := r.Db.Query(`
+		select id, chain_id, entry_type, debit_account, credit_account, amount, idempotency_key, memo, operator, created_at
+		from points_ledger where run_id = ? order by id`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []LedgerEntry
+	for rows.Next() {
+		var entry LedgerEntry
+		err = rows.Scan(&entry.ID, &entry.ChainID, &entry.EntryType, &entry.DebitAccount, &entry.CreditAccount,
+			&entry.Amount, &entry.IdempotencyKey, &entry.Memo, &entry.Operator, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entry.RunID = runID
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,314 @@
+package db
+
+import (
+	. "POINTSTOKEN/types"
+	"database/sql"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"time"
+)
+
+// HolderBalance 持有人在某一区块高度的余额
+type HolderBalance struct {
+	UserAddr common.Address
+	Balance  BigInt
+}
+
+// HolderSnapshot 物化的持有人快照
+type HolderSnapshot struct {
+	ID           uint64
+	ChainID      uint64
+	BlockNumber  uint64
+	Holders      uint64
+	TotalBalance BigInt
+	CreatedBy    string
+	CreatedAt    time.Time
+}
+
+// latestChanges 每个地址在区块范围内最后一条余额变动（rn = 1），参数为 chain_id 和区块范围
+func latestChanges(withFrom bool) string {
+	clause := ` and block_number <= ?`
+	if withFrom {
+		clause = ` and block_number > ?` + clause
+	}
+	return `select user_addr, balance_after, row_number() over (partition by user_addr order by block_number desc, id desc) as rn
+		from balance_changes where chain_id = ?` + clause
+}
+
+// GetLastBalanceChange 获取用户在区块高度 blockNumber 及之前的最后一条余额变动，没有时返回 nil
+func (r *DBRepository) GetLastBalanceChange(chainID uint64, userAddr common.Address, blockNumber uint64) (*UserBalanceChange, error) {
+	var ubc UserBalanceChange
+	var addrStr, txHash string
+	err := r.Db.QueryRow(`
+		select id, chain_id, user_addr, transaction_hash, block_number, change_amount, balance_after, event_type, created_at
+		from balance_changes where chain_id = ? and user_addr = ? and block_number <= ?
+		order by block_number desc, id desc limit 1`, chainID, userAddr.Hex(), blockNumber).Scan(
+		&ubc.ID, &ubc.ChainID, &addrStr, &txHash, &ubc.BlockNumber, &ubc.BalanceChange, &ubc.BalanceAfter, &ubc.EventType, &ubc.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ubc.UserAddr = common.HexToAddress(addrStr)
+	ubc.TxHash = common.HexToHash(txHash)
+	return &ubc, nil
+}
+
+// GetBlockAtTime 获取时间 at 及之前最后一次余额变动所在的区块高度，没有时返回 0
+// 余额只在有变动的区块改变，该区块的余额即为时间 at 的余额
+func (r *DBRepository) GetBlockAtTime(chainID uint64, at time.Time) (uint64, error) {
+	var blockNumber sql.NullInt64
+	err := r.Db.QueryRow(`
+		select max(block_number) from balance_changes where chain_id = ? and created_at <= ?`, chainID, at).Scan(&blockNumber)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(blockNumber.Int64), nil
+}
+
+// BalanceChangeRef 余额变动记录的地址和区块，用于按区块重建 balance_after
+type BalanceChangeRef struct {
+	ID          uint64
+	UserAddr    common.Address
+	BlockNumber uint64
+}
+
+// GetBalanceChangeRefs 按 ID 顺序分页获取链上区块高度 fromBlock 及之后的余额变动
+func (r *DBRepository) GetBalanceChangeRefs(chainID uint64, fromBlock uint64, afterID uint64, limit int) ([]BalanceChangeRef, error) {
+	rows, err := r.Db.Query(`
+		select id, user_addr, block_number from balance_changes
+		where chain_id = ? and id > ? and block_number >= ? order by id limit ?`, chainID, afterID, fromBlock, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []BalanceChangeRef
+	for rows.Next() {
+		var ref BalanceChangeRef
+		var addrStr string
+		if err := rows.Scan(&ref.ID, &addrStr, &ref.BlockNumber); err != nil {
+			return nil, err
+		}
+		ref.UserAddr = common.HexToAddress(addrStr)
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// UpdateBalanceAfter 将用户在区块 blockNumber 的全部余额变动的 balance_after 更新为该区块结束时的余额，返回更新的记录数
+func (r *DBRepository) UpdateBalanceAfter(chainID uint64, userAddr common.Address, blockNumber uint64, balance string) (int64, error) {
+	result, err := r.Db.Exec(`
+		update balance_changes set balance_after = ? where chain_id = ? and user_addr = ? and block_number = ?`,
+		balance, chainID, userAddr.Hex(), blockNumber)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func scanHolderBalances(rows *sql.Rows, fn func(*HolderBalance) error) error {
+	defer rows.Close()
+	for rows.Next() {
+		var h HolderBalance
+		var addrStr string
+		if err := rows.Scan(&addrStr, &h.Balance); err != nil {
+			return err
+		}
+		h.UserAddr = common.HexToAddress(addrStr)
+		if err := fn(&h); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetHolders 由余额变动计算区块高度 blockNumber 时余额为正的地址，按地址顺序从 after 之后分页返回
+func (r *DBRepository) GetHolders(chainID uint64, blockNumber uint64, after string, limit int) ([]HolderBalance, error) {
+	rows, err := r.Db.Query(`
+		select user_addr, balance_after from (`+latestChanges(false)+` and user_addr > ?) c
+		where rn = 1 and balance_after > 0 order by user_addr limit ?`, chainID, blockNumber, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	var holders []HolderBalance
+	err = scanHolderBalances(rows, func(h *HolderBalance) error {
+		holders = append(holders, *h)
+		return nil
+	})
+	return holders, err
+}
+
+// StreamHolders 由余额变动计算区块高度 blockNumber 时余额为正的地址，按地址顺序逐行回调
+func (r *DBRepository) StreamHolders(chainID uint64, blockNumber uint64, fn func(*HolderBalance) error) error {
+	rows, err := r.Db.Query(`
+		select user_addr, balance_after from (`+latestChanges(false)+`) c
+		where rn = 1 and balance_after > 0 order by user_addr`, chainID, blockNumber)
+	if err != nil {
+		return err
+	}
+	return scanHolderBalances(rows, fn)
+}
+
+// CreateHolderSnapshot 物化区块高度 snapshot.BlockNumber 的持有人快照，并回填 ID、持有人数和总余额
+// 存在更早的快照时，只需在其基础上合并之后的余额变动
+func (r *DBRepository) CreateHolderSnapshot(snapshot *HolderSnapshot) error {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var baseID, baseBlock uint64
+	err = tx.QueryRow(`
+		select id, block_number from holder_snapshots where chain_id = ? and block_number < ?
+		order by block_number desc limit 1`, snapshot.ChainID, snapshot.BlockNumber).Scan(&baseID, &baseBlock)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	result, err := tx.Exec(`
+		insert into holder_snapshots (chain_id, block_number, created_by, created_at) values (?, ?, ?, ?)`,
+		snapshot.ChainID, snapshot.BlockNumber, snapshot.CreatedBy, snapshot.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	snapshot.ID = uint64(id)
+
+	if baseID == 0 {
+		_, err = tx.Exec(`
+			insert into holder_snapshot_balances (snapshot_id, user_addr, balance)
+			select ?, user_addr, balance_after from (`+latestChanges(false)+`) c where rn = 1 and balance_after > 0`,
+			snapshot.ID, snapshot.ChainID, snapshot.BlockNumber)
+	} else {
+		//基准快照中之后没有变动的地址余额不变，有变动的取最后一条变动
+		_, err = tx.Exec(`
+			insert into holder_snapshot_balances (snapshot_id, user_addr, balance)
+			select ?, user_addr, balance from (
+				select user_addr, balance_after as balance from (`+latestChanges(true)+`) c where rn = 1
+				union all
+				select p.user_addr, p.balance from holder_snapshot_balances p
+				where p.snapshot_id = ? and not exists (
+					select 1 from balance_changes c where c.chain_id = ? and c.user_addr = p.user_addr
+					and c.block_number > ? and c.block_number <= ?)
+			) t where balance > 0`,
+			snapshot.ID, snapshot.ChainID, baseBlock, snapshot.BlockNumber,
+			baseID, snapshot.ChainID, baseBlock, snapshot.BlockNumber)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(`
+		select count(*), coalesce(sum(balance), 0) from holder_snapshot_balances where snapshot_id = ?`,
+		snapshot.ID).Scan(&snapshot.Holders, &snapshot.TotalBalance)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		update holder_snapshots set holders = ?, total_balance = ? where id = ?`,
+		snapshot.Holders, &snapshot.TotalBalance, snapshot.ID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const holderSnapshotColumns = `id, chain_id, block_number, holders, total_balance, created_by, created_at`
+
+func scanHolderSnapshot(row rowScanner) (*HolderSnapshot, error) {
+	var s HolderSnapshot
+	err := row.Scan(&s.ID, &s.ChainID, &s.BlockNumber, &s.Holders, &s.TotalBalance, &s.CreatedBy, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetHolderSnapshot 获取链在区块高度 blockNumber 的物化快照，不存在时返回 nil
+func (r *DBRepository) GetHolderSnapshot(chainID uint64, blockNumber uint64) (*HolderSnapshot, error) {
+	s, err := scanHolderSnapshot(r.Db.QueryRow(`
+		select `+holderSnapshotColumns+` from holder_snapshots where chain_id = ? and block_number = ?`, chainID, blockNumber))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return s, err
+}
+
+// GetHolderSnapshots 获取物化快照，chainID 为 0 时返回全部链，按区块高度倒序
+func (r *DBRepository) GetHolderSnapshots(chainID uint64) ([]HolderSnapshot, error) {
+	query := `select ` + holderSnapshotColumns + ` from holder_snapshots`
+	var args []interface{}
+	if chainID > 0 {
+		query += ` where chain_id = ?`
+		args = append(args, chainID)
+	}
+	rows, err := r.Db.Query(query+` order by chain_id, block_number desc`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []HolderSnapshot
+	for rows.Next() {
+		s, err := scanHolderSnapshot(rows)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, *s)
+	}
+	return snapshots, rows.Err()
+}
+
+// DeleteHolderSnapshot 删除物化快照及其余额
+func (r *DBRepository) DeleteHolderSnapshot(id uint64) (bool, error) {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`delete from holder_snapshots where id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	if _, err = tx.Exec(`delete from holder_snapshot_balances where snapshot_id = ?`, id); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// GetSnapshotHolders 按地址顺序从 after 之后分页获取物化快照中的持有人
+func (r *DBRepository) GetSnapshotHolders(snapshotID uint64, after string, limit int) ([]HolderBalance, error) {
+	rows, err := r.Db.Query(`
+		select user_addr, balance from holder_snapshot_balances
+		where snapshot_id = ? and user_addr > ? order by user_addr limit ?`, snapshotID, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	var holders []HolderBalance
+	err = scanHolderBalances(rows, func(h *HolderBalance) error {
+		holders = append(holders, *h)
+		return nil
+	})
+	return holders, err
+}
+
+// StreamSnapshotHolders 按地址顺序逐行回调物化快照中的持有人
+func (r *DBRepository) StreamSnapshotHolders(snapshotID uint64, fn func(*HolderBalance) error) error {
+	rows, err := r.Db.Query(`
+		select user_addr, balance from holder_snapshot_balances where snapshot_id = ? order by user_addr`, snapshotID)
+	if err != nil {
+		return err
+	}
+	return scanHolderBalances(rows, fn)
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"strings"
+)
+
+// EventFilter 按链和地址过滤发件箱中的事件，为空表示不过滤
+type EventFilter struct {
+	ChainIDs  []uint64
+	UserAddrs []common.Address
+}
+
+// where 拼接链和地址的过滤条件
+func (f EventFilter) where(addrColumn string) (string, []interface{}) {
+	var clause string
+	var args []interface{}
+	if len(f.ChainIDs) > 0 {
+		placeholders := make([]string, len(f.ChainIDs))
+		for i, chainID := range f.ChainIDs {
+			placeholders[i] = "?"
+			args = append(args, chainID)
+		}
+		clause += " and chain_id in (" + strings.Join(placeholders, ",") + ")"
+	}
+	if len(f.UserAddrs) > 0 {
+		in, addrArgs := addressesIn(f.UserAddrs)
+		clause += " and " + addrColumn + " in (" + in + ")"
+		args = append(args, addrArgs...)
+	}
+	return clause, args
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	. "POINTSTOKEN/types"
+	"database/sql"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"time"
+)
+
+// 会员等级变更方向
+const (
+	TierUpgrade   = "upgrade"
+	TierDowngrade = "downgrade"
+)
+
+// UserTier 用户当前会员等级
+type UserTier struct {
+	ChainID  uint64
+	UserAddr common.Address
+	Tier     string
+	Since    time.Time
+}
+
+// TierChange 会员等级变更记录
+type TierChange struct {
+	ID        uint64
+	ChainID   uint64
+	UserAddr  common.Address
+	FromTier  string
+	ToTier    string
+	Direction string
+	ChangedAt time.Time
+}
+
+// GetUserTier 获取用户当前会员等级，没有记录时返回 nil
+func (r *DBRepository) GetUserTier(chainID uint64, userAddr common.Address) (*UserTier, error) {
+	tier := UserTier{ChainID: chainID, UserAddr: userAddr}
+	err := r.Db.QueryRow(`
+		select tier, since from user_tiers where chain_id = ? and user_addr = ?`,
+		chainID, userAddr.Hex()).Scan(&tier.Tier, &tier.Since)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tier, nil
+}
+
+// SetUserTier 更新用户会员等级，等级变化时记录变更历史，返回是否发生变化
+func (r *DBRepository) SetUserTier(chainID uint64, userAddr common.Address, tier string,
+	direction string, changedAt time.Time) (bool, error) {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var current string
+	err = tx.QueryRow(`
+		select tier from user_tiers where chain_id = ? and user_addr = ? for update`,
+		chainID, userAddr.Hex()).Scan(&current)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	if err == nil && current == tier {
+		return false, nil
+	}
+	//首次评估且未达到任何等级时不记录
+	if errors.Is(err, sql.ErrNoRows) && tier == "" {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`
+		insert into user_tiers (chain_id, user_addr, tier, since) values (?,?,?,?)
+		ON DUPLICATE KEY UPDATE tier = ?, since = ?`, chainID, userAddr.Hex(), tier, changedAt, tier, changedAt)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.Exec(`
+		insert into tier_history (chain_id, user_addr, from_tier, to_tier, direction, changed_at)
+		values (?,?,?,?,?,?)`, chainID, userAddr.Hex(), current, tier, direction, changedAt)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// GetTierHistory 获取会员等级变更历史，按时间排序，userAddr 为 nil 时返回链上所有用户
+func (r *DBRepository) GetTierHistory(chainID uint64, userAddr *common.Address) ([]TierChange, error) {
+	query := `
+		select id, chain_id, user_addr, from_tier, to_tier, direction, changed_at
+		from tier_history where chain_id = ?`
+	args := []interface{}{chainID}
+	if userAddr != nil {
+		query += " and user_addr = ?"
+		args = append(args, userAddr.Hex())
+	}
+	rows, err := r.Db.Query(query+" order by changed_at, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []TierChange
+	for rows.Next() {
+		var c TierChange
+		var addrStr string
+		err = rows.Scan(&c.ID, &c.ChainID, &addrStr, &c.FromTier, &c.ToTier, &c.Direction, &c.ChangedAt)
+		if err != nil {
+			return nil, err
+		}
+		c.UserAddr = common.HexToAddress(addrStr)
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// GetHoldingInfo 获取用户当前余额及本次连续持有的开始时间（余额最近一次从0变为正数的时间）
+// 当前未持有时 holdingSince 为零值
+func (r *DBRepository) GetHoldingInfo(chainID uint64, userAddr common.Address) (string, time.Time, error) {
+	var balance BigInt
+	err := r.Db.QueryRow(`
+		select balance_after from balance_changes where chain_id = ? and user_addr = ?
+		order by block_number desc, id desc limit 1`, chainID, userAddr.Hex()).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "0", time.Time{}, nil
+	}
+	if err != nil {
+		return "0", time.Time{}, err
+	}
+	if balance.ToBigInt().Sign() <= 0 {
+		return "0", time.Time{}, nil
+	}
+
+	var since time.Time
+	err = r.Db.QueryRow(`
+		select min(created_at) from balance_changes where chain_id = ? and user_addr = ? and id > coalesce(
+			(select max(id) from balance_changes where chain_id = ? and user_addr = ? and balance_after = 0), 0)`,
+		chainID, userAddr.Hex(), chainID, userAddr.Hex()).Scan(&since)
+	if err != nil {
+		return "0", time.Time{}, err
+	}
+	return balance.ToBigInt().String(), since, nil
+}
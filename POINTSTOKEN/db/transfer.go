@@ -0,0 +1,56 @@
+package db
+
+import (
+	. "POINTSTOKEN/types"
+	"github.com/ethereum/go-ethereum/common"
+	"time"
+)
+
+// Transfer 由 balance_changes 中同一笔转账的转出、转入两条记录还原的转账
+type Transfer struct {
+	ChainID     uint64
+	TxHash      common.Hash
+	BlockNumber uint64
+	From        common.Address
+	To          common.Address
+	Amount      BigInt
+	EventType   string
+	CreatedAt   time.Time
+}
+
+// GetTransfers 获取链上自 since 起的转账，按记录顺序返回
+// 每笔 Transfer 事件按先转出方、后转入方的顺序写入两条余额变动，这里按 id 顺序两两配对
+func (r *DBRepository) GetTransfers(chainID uint64, since time.Time) ([]Transfer, error) {
+	rows, err := r.Db.Query(`
+		select user_addr, transaction_hash, block_number, change_amount, event_type, created_at 
+		from balance_changes where chain_id = ? and created_at >= ? order by id`, chainID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []Transfer
+	var pending *Transfer
+	for rows.Next() {
+		var t Transfer
+		var addrStr, txHash string
+		err = rows.Scan(&addrStr, &txHash, &t.BlockNumber, &t.Amount, &t.EventType, &t.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		t.ChainID = chainID
+		t.TxHash = common.HexToHash(txHash)
+		addr := common.HexToAddress(addrStr)
+
+		if pending != nil && pending.TxHash == t.TxHash && pending.EventType == t.EventType &&
+			pending.Amount.ToBigInt().Cmp(t.Amount.ToBigInt()) == 0 {
+			pending.To = addr
+			transfers = append(transfers, *pending)
+			pending = nil
+			continue
+		}
+		t.From = addr
+		pending = &t
+	}
+	return transfers, rows.Err()
+}
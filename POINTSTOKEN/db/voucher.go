@@ -0,0 +1,184 @@
+package db
+
+import (
+	. "POINTSTOKEN/types"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"time"
+)
+
+// ErrInsufficientPoints 可用积分不足
+var ErrInsufficientPoints = errors.New("可用积分不足")
+
+// PointsVoucher 积分兑换凭证
+type PointsVoucher struct {
+	ChainID   uint64
+	UserAddr  common.Address
+	Amount    BigInt
+	Nonce     uint64
+	Deadline  time.Time
+	Signature string
+	CreatedAt time.Time
+	//链上已兑换的确认时间，过期退回的时间，均为空表示尚未结清
+	RedeemedAt *time.Time
+	RefundedAt *time.Time
+}
+
+// VoucherSigner 使用分配到的nonce对凭证签名，返回十六进制签名
+type VoucherSigner func(nonce uint64) (string, error)
+
+// CreateVoucher 在同一事务中分配nonce、签名、记录凭证，并以兑换分录扣减积分
+func (r *DBRepository) CreateVoucher(chainID uint64, userAddr common.Address, amount string,
+	deadline time.Time, sign VoucherSigner) (*PointsVoucher, error) {
+	amountInt, ok := new(big.Int).SetString(amount, 10)
+	if !ok || amountInt.Sign() <= 0 {
+		return nil, fmt.Errorf("无效的兑换数量: %s", amount)
+	}
+
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	//nonce 由用户的计数行分配并锁定到事务提交，同一用户并发签发时串行递增，事务回滚时一并回滚
+	result, err := tx.Exec(`
+		insert into voucher_nonces (user_addr, nonce) values (?, last_insert_id(1))
+		ON DUPLICATE KEY UPDATE nonce = last_insert_id(nonce + 1)`, userAddr.Hex())
+	if err != nil {
+		return nil, err
+	}
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	nonce := uint64(lastID)
+
+	signature, err := sign(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt := time.Now()
+	_, err = tx.Exec(`
+		insert into points_vouchers (chain_id, user_addr, amount, nonce, deadline, signature, created_at) 
+		values (?,?,?,?,?,?,?)`, chainID, userAddr.Hex(), amount, nonce, deadline, signature, createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	//记入兑换出账分录，账本余额不足时整个事务回滚
+	key := fmt.Sprintf("voucher:%s:%d", userAddr.Hex(), nonce)
+	entry := newLedgerEntry(chainID, userAddr, LedgerRedemption, amountInt, key, "EIP-712 voucher")
+	if err := postLedgerEntryTx(tx, entry); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &PointsVoucher{
+		ChainID:   chainID,
+		UserAddr:  userAddr,
+		Amount:    *FromBigInt(amountInt),
+		Nonce:     nonce,
+		Deadline:  deadline,
+		Signature: signature,
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// GetUserVouchers 获取用户已发放的凭证
+func (r *DBRepository) GetUserVouchers(userAddr common.Address) ([]PointsVoucher, error) {
+	rows, err := r.Db.Query(`
+		select chain_id, user_addr, amount, nonce, deadline, signature, created_at, redeemed_at, refunded_at 
+		from points_vouchers where user_addr = ? order by nonce`, userAddr.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return scanVouchers(rows)
+}
+
+// GetUnsettledVouchers 获取截止时间早于 before 且尚未确认兑换或退回的凭证，按截止时间排序
+func (r *DBRepository) GetUnsettledVouchers(before time.Time, limit int) ([]PointsVoucher, error) {
+	rows, err := r.Db.Query(`
+		select chain_id, user_addr, amount, nonce, deadline, signature, created_at, redeemed_at, refunded_at 
+		from points_vouchers where deadline < ? and redeemed_at is null and refunded_at is null 
+		order by deadline, id limit ?`, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanVouchers(rows)
+}
+
+func scanVouchers(rows *sql.Rows) ([]PointsVoucher, error) {
+	defer rows.Close()
+	var vouchers []PointsVoucher
+	for rows.Next() {
+		var v PointsVoucher
+		var userAddr string
+		var redeemedAt, refundedAt sql.NullTime
+		err := rows.Scan(&v.ChainID, &userAddr, &v.Amount, &v.Nonce, &v.Deadline, &v.Signature, &v.CreatedAt,
+			&redeemedAt, &refundedAt)
+		if err != nil {
+			return nil, err
+		}
+		v.UserAddr = common.HexToAddress(userAddr)
+		if redeemedAt.Valid {
+			v.RedeemedAt = &redeemedAt.Time
+		}
+		if refundedAt.Valid {
+			v.RefundedAt = &refundedAt.Time
+		}
+		vouchers = append(vouchers, v)
+	}
+	return vouchers, rows.Err()
+}
+
+// MarkVoucherRedeemed 记录凭证已在链上兑换，已兑换的凭证不会再退回
+func (r *DBRepository) MarkVoucherRedeemed(userAddr common.Address, nonce uint64) error {
+	_, err := r.Db.Exec(`
+		update points_vouchers set redeemed_at = ? where user_addr = ? and nonce = ? and redeemed_at is null and refunded_at is null`,
+		time.Now(), userAddr.Hex(), nonce)
+	return err
+}
+
+// RefundVoucher 过期未兑换的凭证以退回分录返还签发时扣减的积分，凭证已结清时返回 nil
+func (r *DBRepository) RefundVoucher(userAddr common.Address, nonce uint64) (*LedgerEntry, error) {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var chainID uint64
+	var amount BigInt
+	err = tx.QueryRow(`
+		select chain_id, amount from points_vouchers 
+		where user_addr = ? and nonce = ? and redeemed_at is null and refunded_at is null for update`,
+		userAddr.Hex(), nonce).Scan(&chainID, &amount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`update points_vouchers set refunded_at = ? where user_addr = ? and nonce = ?`,
+		time.Now(), userAddr.Hex(), nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("voucher-refund:%s:%d", userAddr.Hex(), nonce)
+	entry := newLedgerEntry(chainID, userAddr, LedgerRefund, amount.ToBigInt(), key, "expired EIP-712 voucher")
+	if err := postLedgerEntryTx(tx, entry); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"time"
+)
+
+// 刷量标记类型
+const (
+	WashRoundTrip    = "round_trip"    //A→B→A 来回转账
+	WashCircular     = "circular"      //多个地址间的环形转账
+	WashShortHolding = "short_holding" //转入后短时间内转出
+)
+
+// 刷量标记审核状态
+const (
+	WashStatusOpen      = "open"
+	WashStatusConfirmed = "confirmed"
+	WashStatusDismissed = "dismissed"
+)
+
+// WashFlag 刷量检测标记
+type WashFlag struct {
+	ID           uint64
+	ChainID      uint64
+	UserAddr     common.Address
+	FlagType     string
+	TxHash       common.Hash
+	Counterparty string
+	Detail       string
+	ActivityAt   time.Time
+	Status       string
+	DetectedAt   time.Time
+}
+
+// SaveWashFlags 保存刷量标记，已存在的标记保持原审核状态
+func (r *DBRepository) SaveWashFlags(flags []WashFlag) error {
+	if len(flags) == 0 {
+		return nil
+	}
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, f := range flags {
+		_, err = tx.Exec(`
+			insert into wash_flags (chain_id, user_addr, flag_type, transaction_hash, counterparty, detail, activity_at) 
+			values (?, ?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE id = id`,
+			f.ChainID, f.UserAddr.Hex(), f.FlagType, f.TxHash.Hex(), f.Counterparty, f.Detail, f.ActivityAt)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetWashFlags 获取链上自 since 起发生的刷量标记，status 为空时返回全部状态
+func (r *DBRepository) GetWashFlags(chainID uint64, since time.Time, status string) ([]WashFlag, error) {
+	rows, err := r.Db.Query(`
+		select id, user_addr, flag_type, transaction_hash, counterparty, detail, activity_at, status, detected_at 
+		from wash_flags where chain_id = ? and activity_at >= ? and (? = '' or status = ?) 
+		order by activity_at, id`, chainID, since, status, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flags []WashFlag
+	for rows.Next() {
+		f := WashFlag{ChainID: chainID}
+		var addrStr, txHash string
+		err = rows.Scan(&f.ID, &addrStr, &f.FlagType, &txHash, &f.Counterparty, &f.Detail,
+			&f.ActivityAt, &f.Status, &f.DetectedAt)
+		if err != nil {
+			return nil, err
+		}
+		f.UserAddr = common.HexToAddress(addrStr)
+		f.TxHash = common.HexToHash(txHash)
+		flags = append(flags, f)
+	}
+	return flags, rows.Err()
+}
+
+// UpdateWashFlagStatus 更新刷量标记的审核状态
+func (r *DBRepository) UpdateWashFlagStatus(id uint64, status string) error {
+	_, err := r.Db.Exec(`update wash_flags set status = ? where id = ?`, status, id)
+	return err
+}
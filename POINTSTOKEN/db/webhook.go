@@ -0,0 +1,398 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// webhook 投递状态
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookSubscription webhook 订阅，过滤条件为空表示不过滤
+type WebhookSubscription struct {
+	ID         uint64
+	URL        string
+	Secret     string
+	EventTypes []string
+	ChainIDs   []uint64
+	Addresses  []common.Address
+	Active     bool
+	CreatedBy  string
+	CreatedAt  time.Time
+}
+
+// WebhookDelivery 一个事件向一个订阅的投递
+type WebhookDelivery struct {
+	ID             uint64
+	SubscriptionID uint64
+	OutboxID       uint64
+	EventType      string
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+
+	//领取待投递记录时一并返回
+	URL     string
+	Secret  string
+	Payload json.RawMessage
+}
+
+// DeliveryAttempt 一次投递请求的结果
+type DeliveryAttempt struct {
+	DeliveryID uint64
+	Attempt    int
+	StatusCode int
+	Error      string
+	Duration   time.Duration
+	CreatedAt  time.Time
+}
+
+func joinUints(values []uint64) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.FormatUint(v, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+func joinAddresses(addrs []common.Address) string {
+	parts := make([]string, len(addrs))
+	for i, addr := range addrs {
+		parts[i] = addr.Hex()
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// CreateWebhookSubscription 创建 webhook 订阅，写入后填充 ID
+func (r *DBRepository) CreateWebhookSubscription(sub *WebhookSubscription) error {
+	result, err := r.Db.Exec(`
+		insert into webhook_subscriptions (url, secret, event_types, chain_ids, addresses, active, created_by)
+		values (?,?,?,?,?,?,?)`, sub.URL, sub.Secret, strings.Join(sub.EventTypes, ","), joinUints(sub.ChainIDs),
+		joinAddresses(sub.Addresses), sub.Active, sub.CreatedBy)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	sub.ID = uint64(id)
+	return nil
+}
+
+const webhookSubscriptionColumns = `id, url, secret, event_types, chain_ids, addresses, active, created_by, created_at`
+
+func scanWebhookSubscription(row rowScanner) (*WebhookSubscription, error) {
+	var sub WebhookSubscription
+	var eventTypes, chainIDs, addresses string
+	err := row.Scan(&sub.ID, &sub.URL, &sub.Secret, &eventTypes, &chainIDs, &addresses, &sub.Active,
+		&sub.CreatedBy, &sub.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	sub.EventTypes = splitList(eventTypes)
+	for _, value := range splitList(chainIDs) {
+		chainID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		sub.ChainIDs = append(sub.ChainIDs, chainID)
+	}
+	for _, value := range splitList(addresses) {
+		sub.Addresses = append(sub.Addresses, common.HexToAddress(value))
+	}
+	return &sub, nil
+}
+
+// GetWebhookSubscriptions 获取 webhook 订阅，activeOnly 为 true 时只返回启用的订阅
+func (r *DBRepository) GetWebhookSubscriptions(activeOnly bool) ([]WebhookSubscription, error) {
+	query := `select ` + webhookSubscriptionColumns + ` from webhook_subscriptions`
+	if activeOnly {
+		query += ` where active = true`
+	}
+	rows, err := r.Db.Query(query + ` order by id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []WebhookSubscription
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, rows.Err()
+}
+
+// GetWebhookSubscription 获取 webhook 订阅，不存在时返回 nil
+func (r *DBRepository) GetWebhookSubscription(id uint64) (*WebhookSubscription, error) {
+	sub, err := scanWebhookSubscription(r.Db.QueryRow(`
+		select `+webhookSubscriptionColumns+` from webhook_subscriptions where id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return sub, err
+}
+
+// DeactivateWebhookSubscription 停用 webhook 订阅，未投递的记录不再投递，返回订阅是否存在
+func (r *DBRepository) DeactivateWebhookSubscription(id uint64) (bool, error) {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`update webhook_subscriptions set active = false where id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	_, err = tx.Exec(`
+		update webhook_deliveries set status = ?, last_error = '订阅已停用'
+		where subscription_id = ? and status = ?`, DeliveryFailed, id, DeliveryPending)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// ClaimDueDeliveries 领取已启用订阅的到期待投递记录，多个进程同时投递时同一订阅只会被一个进程领取。
+// 同一订阅按投递记录ID顺序投递：前面有未到期的待投递记录（重试等待中或已被领取）时，后面的记录不会被领取。
+// 第 k 条（从0开始）的下次投递时间推迟到 now + (k+1)*lease，租约覆盖排在前面的请求所需的时间，进程中断时租约到期后重新投递
+func (r *DBRepository) ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) ([]WebhookDelivery, error) {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	//先锁定订阅，其他进程正在领取的订阅跳过，领取提交后的租约对之后的领取可见
+	subRows, err := tx.Query(`select id from webhook_subscriptions where active for update skip locked`)
+	if err != nil {
+		return nil, err
+	}
+	var subIDs []uint64
+	for subRows.Next() {
+		var id uint64
+		if err = subRows.Scan(&id); err != nil {
+			subRows.Close()
+			return nil, err
+		}
+		subIDs = append(subIDs, id)
+	}
+	subRows.Close()
+	if err = subRows.Err(); err != nil {
+		return nil, err
+	}
+	if len(subIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := tx.Query(`
+		select d.id, d.subscription_id, d.outbox_id, d.event_type, d.status, d.attempts, d.next_attempt_at,
+			d.last_status_code, d.last_error, d.created_at, s.url, s.secret, o.payload
+		from webhook_deliveries d
+		join webhook_subscriptions s on s.id = d.subscription_id
+		join event_outbox o on o.id = d.outbox_id
+		where d.subscription_id in (`+joinUints(subIDs)+`) and d.status = ? and d.next_attempt_at <= ?
+			and not exists (
+				select 1 from webhook_deliveries p
+				where p.subscription_id = d.subscription_id and p.status = ? and p.id < d.id and p.next_attempt_at > ?)
+		order by d.id limit ?
+		for update of d`, DeliveryPending, now, DeliveryPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		var payload []byte
+		err = rows.Scan(&d.ID, &d.SubscriptionID, &d.OutboxID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.URL, &d.Secret, &payload)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		d.Payload = payload
+		deliveries = append(deliveries, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	queued := make(map[uint64]int)
+	for _, d := range deliveries {
+		queued[d.SubscriptionID]++
+		leaseUntil := now.Add(time.Duration(queued[d.SubscriptionID]) * lease)
+		_, err = tx.Exec(`update webhook_deliveries set next_attempt_at = ? where id = ?`, leaseUntil, d.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return deliveries, tx.Commit()
+}
+
+// PurgeOutbox 删除 before 之前创建、所有消费者都已处理过的发件箱事件及其投递记录和请求日志，返回删除的事件数。
+// 事件须已分发给 webhook 且没有待投递的记录，序号不超过 outbox_cursors 中最小的游标
+func (r *DBRepository) PurgeOutbox(before time.Time, limit int) (int, error) {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		select o.id from event_outbox o
+		where o.created_at < ? and o.dispatched_at is not null
+			and o.seq <= coalesce((select min(last_seq) from outbox_cursors), o.seq)
+			and not exists (select 1 from webhook_deliveries d where d.outbox_id = o.id and d.status = ?)
+		order by o.seq limit ?`, before, DeliveryPending, limit)
+	if err != nil {
+		return 0, err
+	}
+	var ids []uint64
+	for rows.Next() {
+		var id uint64
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	in := joinUints(ids)
+	_, err = tx.Exec(`
+		delete a from webhook_delivery_attempts a
+		join webhook_deliveries d on d.id = a.delivery_id
+		where d.outbox_id in (` + in + `)`)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = tx.Exec(`delete from webhook_deliveries where outbox_id in (` + in + `)`); err != nil {
+		return 0, err
+	}
+	if _, err = tx.Exec(`delete from event_outbox where id in (` + in + `)`); err != nil {
+		return 0, err
+	}
+	return len(ids), tx.Commit()
+}
+
+// RecordDeliveryAttempt 记录一次投递结果并更新投递状态，status 为 pending 时在 nextAttemptAt 重试
+func (r *DBRepository) RecordDeliveryAttempt(attempt *DeliveryAttempt, status string, nextAttemptAt time.Time) error {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		insert into webhook_delivery_attempts (delivery_id, attempt, status_code, error, duration_ms, created_at)
+		values (?,?,?,?,?,?)`, attempt.DeliveryID, attempt.Attempt, attempt.StatusCode, attempt.Error,
+		attempt.Duration.Milliseconds(), attempt.CreatedAt)
+	if err != nil {
+		return err
+	}
+	var deliveredAt interface{}
+	if status == DeliveryDelivered {
+		deliveredAt = attempt.CreatedAt
+	}
+	_, err = tx.Exec(`
+		update webhook_deliveries set status = ?, attempts = ?, next_attempt_at = ?, last_status_code = ?,
+		last_error = ?, delivered_at = ? where id = ?`, status, attempt.Attempt, nextAttemptAt, attempt.StatusCode,
+		attempt.Error, deliveredAt, attempt.DeliveryID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetWebhookDeliveries 按 ID 倒序获取订阅的投递记录，status 为空时不过滤
+func (r *DBRepository) GetWebhookDeliveries(subscriptionID uint64, status string, limit int) ([]WebhookDelivery, error) {
+	query := `
+		select id, subscription_id, outbox_id, event_type, status, attempts, next_attempt_at,
+			last_status_code, last_error, delivered_at, created_at
+		from webhook_deliveries where subscription_id = ?`
+	args := []interface{}{subscriptionID}
+	if status != "" {
+		query += ` and status = ?`
+		args = append(args, status)
+	}
+	args = append(args, limit)
+	rows, err := r.Db.Query(query+` order by id desc limit ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		var deliveredAt sql.NullTime
+		err = rows.Scan(&d.ID, &d.SubscriptionID, &d.OutboxID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatusCode, &d.LastError, &deliveredAt, &d.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetDeliveryAttempts 获取投递的请求日志
+func (r *DBRepository) GetDeliveryAttempts(deliveryID uint64) ([]DeliveryAttempt, error) {
+	rows, err := r.Db.Query(`
+		select delivery_id, attempt, status_code, error, duration_ms, created_at
+		from webhook_delivery_attempts where delivery_id = ? order by id`, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []DeliveryAttempt
+	for rows.Next() {
+		var a DeliveryAttempt
+		var durationMs int64
+		if err = rows.Scan(&a.DeliveryID, &a.Attempt, &a.StatusCode, &a.Error, &durationMs, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		a.Duration = time.Duration(durationMs) * time.Millisecond
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
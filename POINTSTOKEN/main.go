@@ -1,19 +1,30 @@
 package main
 
 import (
+	"POINTSTOKEN/api"
 	"POINTSTOKEN/config"
 	"POINTSTOKEN/db"
+	"POINTSTOKEN/rpc"
 	"POINTSTOKEN/service"
 	"context"
 	"flag"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 )
 
 func main() {
+	// 子命令模式，如 voucher
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatalf("命令 %s 执行失败: %v", os.Args[1], err)
+		}
+		return
+	}
+
 	// 加载配置
 	cfgPath := flag.String("config", "config.yaml", "Path to configuration file")
 	flag.Parse()
@@ -40,14 +51,84 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	manager.StartEventListeners(ctx)
 
+	// 余额变动和积分变化按发件箱顺序发布到事件总线，推送给订阅者
+	events := service.NewEventBus(cfg.GRPC.EventBuffer)
+	outboxPublisher := service.NewOutboxPublisher(dbRepo, events)
+	if err := outboxPublisher.Start(ctx); err != nil {
+		log.Fatalf("启动事件发布失败: %v", err)
+	}
+
 	// 初始化积分计算器
 	pointCalculator := service.NewPointsCalculator(&cfg.Points, dbRepo)
+	aggregator := service.NewPointsAggregator(cfg.Chains, dbRepo)
+	// 每次积分计算后重新评估会员等级
+	tierService, err := service.NewTierService(cfg.Points.Tiers, dbRepo)
+	if err != nil {
+		log.Fatalf("初始化会员等级失败: %v", err)
+	}
+	pointCalculator.OnRun(tierService.Evaluate)
+	// 按积分分录增量更新排行榜
+	leaderboard, err := service.NewLeaderboardService(cfg.Points.Leaderboard, cfg.Chains, dbRepo, aggregator)
+	if err != nil {
+		log.Fatalf("初始化排行榜失败: %v", err)
+	}
+	leaderboard.Start(ctx)
 	// 启动定时积分计算任务
 	err = pointCalculator.Start()
 	if err != nil {
 		return
 	}
 
+	// 启动 HTTP API
+	authService := service.NewAuthService(cfg.API.Auth, dbRepo)
+	identityService := service.NewIdentityService(dbRepo, cfg.API.SignatureTTL)
+	referralService := service.NewReferralService(dbRepo, cfg.API.SignatureTTL)
+	apiServer := api.NewServer(&cfg.API, dbRepo, api.Services{
+		Aggregator:  aggregator,
+		Identity:    identityService,
+		Referral:    referralService,
+		Calculator:  pointCalculator,
+		Adjustment:  service.NewAdjustmentService(dbRepo),
+		Leaderboard: leaderboard,
+		Tier:        tierService,
+		Events:      events,
+		Auth:        authService,
+		Export:      service.NewExportService(dbRepo),
+		Snapshot:    service.NewSnapshotService(dbRepo),
+	})
+	apiServer.Start()
+
+	// 启动内部 gRPC 服务
+	var grpcServer *rpc.Server
+	if cfg.GRPC.Listen != "" {
+		grpcServer = rpc.NewServer(&cfg.GRPC, dbRepo, authService, leaderboard, events)
+		if err := grpcServer.Start(); err != nil {
+			log.Fatalf("启动 gRPC 服务失败: %v", err)
+		}
+	}
+
+	// 启动 webhook 投递
+	webhookService := service.NewWebhookService(cfg.Webhook, dbRepo)
+	webhookService.Start(ctx)
+
+	// 启动事件输出
+	sinkForwarder, err := service.NewSinkForwarder(cfg.Sinks, dbRepo)
+	if err != nil {
+		log.Fatalf("初始化事件输出失败: %v", err)
+	}
+	if sinkForwarder != nil {
+		sinkForwarder.Start(ctx)
+	}
+
+	// 退回过期未兑换凭证的积分
+	voucherRefunder, err := service.NewVoucherRefunder(&cfg.Voucher, cfg.Chains, dbRepo)
+	if err != nil {
+		log.Fatalf("初始化凭证退回失败: %v", err)
+	}
+	if voucherRefunder != nil {
+		voucherRefunder.Start(ctx)
+	}
+
 	// 等待中断信号
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -57,6 +138,21 @@ func main() {
 	log.Println("Shutting down service...")
 	cancel()
 	pointCalculator.Stop()
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	apiServer.Stop(shutdownCtx)
+	if grpcServer != nil {
+		grpcServer.Stop(shutdownCtx)
+	}
+	shutdownCancel()
+	webhookService.Wait()
+	outboxPublisher.Wait()
+	leaderboard.Wait()
+	if sinkForwarder != nil {
+		sinkForwarder.Wait()
+	}
+	if voucherRefunder != nil {
+		voucherRefunder.Wait()
+	}
 	time.Sleep(5 * time.Second)
 	log.Println("Service stopped")
 }
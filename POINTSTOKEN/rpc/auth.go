@@ -0,0 +1,57 @@
+package rpc
+
+import (
+	"POINTSTOKEN/db"
+	"POINTSTOKEN/service"
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log"
+	"strings"
+)
+
+// authorize 校验请求元数据 authorization: Bearer <API 密钥>，密钥需具有 read 或 admin 权限
+func (s *Server) authorize(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	for _, value := range md.Get("authorization") {
+		if t, ok := strings.CutPrefix(value, "Bearer "); ok {
+			token = t
+			break
+		}
+	}
+	if !strings.HasPrefix(token, service.APIKeyPrefix) {
+		return status.Error(codes.Unauthenticated, "缺少 API 密钥")
+	}
+	key, err := s.auth.AuthenticateAPIKey(token)
+	if errors.Is(err, service.ErrUnauthorized) {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		log.Printf("认证 gRPC 请求失败: %v", err)
+		return status.Error(codes.Internal, "认证失败")
+	}
+	if !key.HasScope(db.ScopeRead) && !key.HasScope(db.ScopeAdmin) {
+		return status.Error(codes.PermissionDenied, "无权访问")
+	}
+	return nil
+}
+
+// unaryAuth 查询接口的认证拦截器
+func (s *Server) unaryAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamAuth 订阅接口的认证拦截器
+func (s *Server) streamAuth(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.authorize(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
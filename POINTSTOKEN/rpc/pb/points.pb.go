@@ -0,0 +1,1652 @@
+// 积分服务 gRPC 接口，供内部服务查询余额、积分并订阅实时变动
+// 修改后在 rpc/pb 目录执行:
+//   protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative points.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: points.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Chain struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	ChainId            uint64                 `protobuf:"varint,1,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`
+	Name               string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	ContractAddr       string                 `protobuf:"bytes,3,opt,name=contract_addr,json=contractAddr,proto3" json:"contract_addr,omitempty"`
+	LastProcessedBlock uint64                 `protobuf:"varint,4,opt,name=last_processed_block,json=lastProcessedBlock,proto3" json:"last_processed_block,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *Chain) Reset() {
+	*x = Chain{}
+	mi := &file_points_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Chain) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Chain) ProtoMessage() {}
+
+func (x *Chain) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Chain.ProtoReflect.Descriptor instead.
+func (*Chain) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Chain) GetChainId() uint64 {
+	if x != nil {
+		return x.ChainId
+	}
+	return 0
+}
+
+func (x *Chain) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Chain) GetContractAddr() string {
+	if x != nil {
+		return x.ContractAddr
+	}
+	return ""
+}
+
+func (x *Chain) GetLastProcessedBlock() uint64 {
+	if x != nil {
+		return x.LastProcessedBlock
+	}
+	return 0
+}
+
+type GetChainsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetChainsRequest) Reset() {
+	*x = GetChainsRequest{}
+	mi := &file_points_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetChainsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetChainsRequest) ProtoMessage() {}
+
+func (x *GetChainsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetChainsRequest.ProtoReflect.Descriptor instead.
+func (*GetChainsRequest) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{1}
+}
+
+type GetChainsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Chains        []*Chain               `protobuf:"bytes,1,rep,name=chains,proto3" json:"chains,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetChainsResponse) Reset() {
+	*x = GetChainsResponse{}
+	mi := &file_points_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetChainsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetChainsResponse) ProtoMessage() {}
+
+func (x *GetChainsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetChainsResponse.ProtoReflect.Descriptor instead.
+func (*GetChainsResponse) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetChainsResponse) GetChains() []*Chain {
+	if x != nil {
+		return x.Chains
+	}
+	return nil
+}
+
+type UserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChainId       uint64                 `protobuf:"varint,1,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserRequest) Reset() {
+	*x = UserRequest{}
+	mi := &file_points_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserRequest) ProtoMessage() {}
+
+func (x *UserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserRequest.ProtoReflect.Descriptor instead.
+func (*UserRequest) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *UserRequest) GetChainId() uint64 {
+	if x != nil {
+		return x.ChainId
+	}
+	return 0
+}
+
+func (x *UserRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type Balance struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChainId       uint64                 `protobuf:"varint,1,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Balance       string                 `protobuf:"bytes,3,opt,name=balance,proto3" json:"balance,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Balance) Reset() {
+	*x = Balance{}
+	mi := &file_points_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Balance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Balance) ProtoMessage() {}
+
+func (x *Balance) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Balance.ProtoReflect.Descriptor instead.
+func (*Balance) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Balance) GetChainId() uint64 {
+	if x != nil {
+		return x.ChainId
+	}
+	return 0
+}
+
+func (x *Balance) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Balance) GetBalance() string {
+	if x != nil {
+		return x.Balance
+	}
+	return ""
+}
+
+// 分页参数与 HTTP 接口一致，cursor 为上一页最后一条记录的 ID
+type HistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChainId       uint64                 `protobuf:"varint,1,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Cursor        uint64                 `protobuf:"varint,3,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Limit         int32                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	FromTime      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=from_time,json=fromTime,proto3" json:"from_time,omitempty"`
+	ToTime        *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=to_time,json=toTime,proto3" json:"to_time,omitempty"`
+	FromBlock     uint64                 `protobuf:"varint,7,opt,name=from_block,json=fromBlock,proto3" json:"from_block,omitempty"`
+	ToBlock       uint64                 `protobuf:"varint,8,opt,name=to_block,json=toBlock,proto3" json:"to_block,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryRequest) Reset() {
+	*x = HistoryRequest{}
+	mi := &file_points_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryRequest) ProtoMessage() {}
+
+func (x *HistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryRequest.ProtoReflect.Descriptor instead.
+func (*HistoryRequest) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *HistoryRequest) GetChainId() uint64 {
+	if x != nil {
+		return x.ChainId
+	}
+	return 0
+}
+
+func (x *HistoryRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *HistoryRequest) GetCursor() uint64 {
+	if x != nil {
+		return x.Cursor
+	}
+	return 0
+}
+
+func (x *HistoryRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *HistoryRequest) GetFromTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FromTime
+	}
+	return nil
+}
+
+func (x *HistoryRequest) GetToTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ToTime
+	}
+	return nil
+}
+
+func (x *HistoryRequest) GetFromBlock() uint64 {
+	if x != nil {
+		return x.FromBlock
+	}
+	return 0
+}
+
+func (x *HistoryRequest) GetToBlock() uint64 {
+	if x != nil {
+		return x.ToBlock
+	}
+	return 0
+}
+
+type BalanceChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	ChainId       uint64                 `protobuf:"varint,2,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`
+	Address       string                 `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	TxHash        string                 `protobuf:"bytes,4,opt,name=tx_hash,json=txHash,proto3" json:"tx_hash,omitempty"`
+	BlockNumber   uint64                 `protobuf:"varint,5,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	ChangeAmount  string                 `protobuf:"bytes,6,opt,name=change_amount,json=changeAmount,proto3" json:"change_amount,omitempty"`
+	BalanceAfter  string                 `protobuf:"bytes,7,opt,name=balance_after,json=balanceAfter,proto3" json:"balance_after,omitempty"`
+	EventType     string                 `protobuf:"bytes,8,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BalanceChange) Reset() {
+	*x = BalanceChange{}
+	mi := &file_points_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BalanceChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BalanceChange) ProtoMessage() {}
+
+func (x *BalanceChange) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BalanceChange.ProtoReflect.Descriptor instead.
+func (*BalanceChange) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *BalanceChange) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *BalanceChange) GetChainId() uint64 {
+	if x != nil {
+		return x.ChainId
+	}
+	return 0
+}
+
+func (x *BalanceChange) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *BalanceChange) GetTxHash() string {
+	if x != nil {
+		return x.TxHash
+	}
+	return ""
+}
+
+func (x *BalanceChange) GetBlockNumber() uint64 {
+	if x != nil {
+		return x.BlockNumber
+	}
+	return 0
+}
+
+func (x *BalanceChange) GetChangeAmount() string {
+	if x != nil {
+		return x.ChangeAmount
+	}
+	return ""
+}
+
+func (x *BalanceChange) GetBalanceAfter() string {
+	if x != nil {
+		return x.BalanceAfter
+	}
+	return ""
+}
+
+func (x *BalanceChange) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *BalanceChange) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type BalanceHistory struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Changes       []*BalanceChange       `protobuf:"bytes,1,rep,name=changes,proto3" json:"changes,omitempty"`
+	NextCursor    uint64                 `protobuf:"varint,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"` // 0 表示没有更多记录
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BalanceHistory) Reset() {
+	*x = BalanceHistory{}
+	mi := &file_points_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BalanceHistory) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BalanceHistory) ProtoMessage() {}
+
+func (x *BalanceHistory) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BalanceHistory.ProtoReflect.Descriptor instead.
+func (*BalanceHistory) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *BalanceHistory) GetChanges() []*BalanceChange {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+func (x *BalanceHistory) GetNextCursor() uint64 {
+	if x != nil {
+		return x.NextCursor
+	}
+	return 0
+}
+
+type Points struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChainId       uint64                 `protobuf:"varint,1,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	TotalPoints   string                 `protobuf:"bytes,3,opt,name=total_points,json=totalPoints,proto3" json:"total_points,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Points) Reset() {
+	*x = Points{}
+	mi := &file_points_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Points) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Points) ProtoMessage() {}
+
+func (x *Points) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Points.ProtoReflect.Descriptor instead.
+func (*Points) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Points) GetChainId() uint64 {
+	if x != nil {
+		return x.ChainId
+	}
+	return 0
+}
+
+func (x *Points) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Points) GetTotalPoints() string {
+	if x != nil {
+		return x.TotalPoints
+	}
+	return ""
+}
+
+type GetPointsAllChainsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Addresses     []string               `protobuf:"bytes,1,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPointsAllChainsRequest) Reset() {
+	*x = GetPointsAllChainsRequest{}
+	mi := &file_points_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPointsAllChainsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPointsAllChainsRequest) ProtoMessage() {}
+
+func (x *GetPointsAllChainsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPointsAllChainsRequest.ProtoReflect.Descriptor instead.
+func (*GetPointsAllChainsRequest) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetPointsAllChainsRequest) GetAddresses() []string {
+	if x != nil {
+		return x.Addresses
+	}
+	return nil
+}
+
+type GetPointsAllChainsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Points        []*Points              `protobuf:"bytes,1,rep,name=points,proto3" json:"points,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPointsAllChainsResponse) Reset() {
+	*x = GetPointsAllChainsResponse{}
+	mi := &file_points_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPointsAllChainsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPointsAllChainsResponse) ProtoMessage() {}
+
+func (x *GetPointsAllChainsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPointsAllChainsResponse.ProtoReflect.Descriptor instead.
+func (*GetPointsAllChainsResponse) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetPointsAllChainsResponse) GetPoints() []*Points {
+	if x != nil {
+		return x.Points
+	}
+	return nil
+}
+
+type PointsCalculation struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Id               uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	ChainId          uint64                 `protobuf:"varint,2,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`
+	Address          string                 `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	CalculatedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=calculated_at,json=calculatedAt,proto3" json:"calculated_at,omitempty"`
+	Balance          string                 `protobuf:"bytes,5,opt,name=balance,proto3" json:"balance,omitempty"`
+	PointsAdded      string                 `protobuf:"bytes,6,opt,name=points_added,json=pointsAdded,proto3" json:"points_added,omitempty"`
+	TotalPointsAfter string                 `protobuf:"bytes,7,opt,name=total_points_after,json=totalPointsAfter,proto3" json:"total_points_after,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *PointsCalculation) Reset() {
+	*x = PointsCalculation{}
+	mi := &file_points_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PointsCalculation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PointsCalculation) ProtoMessage() {}
+
+func (x *PointsCalculation) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PointsCalculation.ProtoReflect.Descriptor instead.
+func (*PointsCalculation) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *PointsCalculation) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *PointsCalculation) GetChainId() uint64 {
+	if x != nil {
+		return x.ChainId
+	}
+	return 0
+}
+
+func (x *PointsCalculation) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *PointsCalculation) GetCalculatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CalculatedAt
+	}
+	return nil
+}
+
+func (x *PointsCalculation) GetBalance() string {
+	if x != nil {
+		return x.Balance
+	}
+	return ""
+}
+
+func (x *PointsCalculation) GetPointsAdded() string {
+	if x != nil {
+		return x.PointsAdded
+	}
+	return ""
+}
+
+func (x *PointsCalculation) GetTotalPointsAfter() string {
+	if x != nil {
+		return x.TotalPointsAfter
+	}
+	return ""
+}
+
+type PointsHistory struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Calculations  []*PointsCalculation   `protobuf:"bytes,1,rep,name=calculations,proto3" json:"calculations,omitempty"`
+	NextCursor    uint64                 `protobuf:"varint,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PointsHistory) Reset() {
+	*x = PointsHistory{}
+	mi := &file_points_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PointsHistory) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PointsHistory) ProtoMessage() {}
+
+func (x *PointsHistory) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PointsHistory.ProtoReflect.Descriptor instead.
+func (*PointsHistory) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *PointsHistory) GetCalculations() []*PointsCalculation {
+	if x != nil {
+		return x.Calculations
+	}
+	return nil
+}
+
+func (x *PointsHistory) GetNextCursor() uint64 {
+	if x != nil {
+		return x.NextCursor
+	}
+	return 0
+}
+
+type LedgerEntry struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	ChainId        uint64                 `protobuf:"varint,2,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`
+	EntryType      string                 `protobuf:"bytes,3,opt,name=entry_type,json=entryType,proto3" json:"entry_type,omitempty"`
+	DebitAccount   string                 `protobuf:"bytes,4,opt,name=debit_account,json=debitAccount,proto3" json:"debit_account,omitempty"`
+	CreditAccount  string                 `protobuf:"bytes,5,opt,name=credit_account,json=creditAccount,proto3" json:"credit_account,omitempty"`
+	Amount         string                 `protobuf:"bytes,6,opt,name=amount,proto3" json:"amount,omitempty"`
+	IdempotencyKey string                 `protobuf:"bytes,7,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	Memo           string                 `protobuf:"bytes,8,opt,name=memo,proto3" json:"memo,omitempty"`
+	Operator       string                 `protobuf:"bytes,9,opt,name=operator,proto3" json:"operator,omitempty"`
+	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *LedgerEntry) Reset() {
+	*x = LedgerEntry{}
+	mi := &file_points_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LedgerEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LedgerEntry) ProtoMessage() {}
+
+func (x *LedgerEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LedgerEntry.ProtoReflect.Descriptor instead.
+func (*LedgerEntry) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *LedgerEntry) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *LedgerEntry) GetChainId() uint64 {
+	if x != nil {
+		return x.ChainId
+	}
+	return 0
+}
+
+func (x *LedgerEntry) GetEntryType() string {
+	if x != nil {
+		return x.EntryType
+	}
+	return ""
+}
+
+func (x *LedgerEntry) GetDebitAccount() string {
+	if x != nil {
+		return x.DebitAccount
+	}
+	return ""
+}
+
+func (x *LedgerEntry) GetCreditAccount() string {
+	if x != nil {
+		return x.CreditAccount
+	}
+	return ""
+}
+
+func (x *LedgerEntry) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *LedgerEntry) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *LedgerEntry) GetMemo() string {
+	if x != nil {
+		return x.Memo
+	}
+	return ""
+}
+
+func (x *LedgerEntry) GetOperator() string {
+	if x != nil {
+		return x.Operator
+	}
+	return ""
+}
+
+func (x *LedgerEntry) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type LedgerEntries struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*LedgerEntry         `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	NextCursor    uint64                 `protobuf:"varint,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"` // 0 表示没有更多记录
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LedgerEntries) Reset() {
+	*x = LedgerEntries{}
+	mi := &file_points_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LedgerEntries) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LedgerEntries) ProtoMessage() {}
+
+func (x *LedgerEntries) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LedgerEntries.ProtoReflect.Descriptor instead.
+func (*LedgerEntries) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *LedgerEntries) GetEntries() []*LedgerEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *LedgerEntries) GetNextCursor() uint64 {
+	if x != nil {
+		return x.NextCursor
+	}
+	return 0
+}
+
+type UserTier struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChainId       uint64                 `protobuf:"varint,1,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Tier          string                 `protobuf:"bytes,3,opt,name=tier,proto3" json:"tier,omitempty"` // 未达到任何等级时为空
+	Since         *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=since,proto3" json:"since,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserTier) Reset() {
+	*x = UserTier{}
+	mi := &file_points_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserTier) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserTier) ProtoMessage() {}
+
+func (x *UserTier) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserTier.ProtoReflect.Descriptor instead.
+func (*UserTier) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *UserTier) GetChainId() uint64 {
+	if x != nil {
+		return x.ChainId
+	}
+	return 0
+}
+
+func (x *UserTier) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *UserTier) GetTier() string {
+	if x != nil {
+		return x.Tier
+	}
+	return ""
+}
+
+func (x *UserTier) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+type GetLeaderboardRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Window        string                 `protobuf:"bytes,1,opt,name=window,proto3" json:"window,omitempty"`                   // total、daily、weekly、campaign，默认 total
+	ChainId       uint64                 `protobuf:"varint,2,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"` // 0 为跨链榜
+	At            *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=at,proto3" json:"at,omitempty"`                           // 日榜、周榜的日期，默认当前时间
+	Campaign      string                 `protobuf:"bytes,4,opt,name=campaign,proto3" json:"campaign,omitempty"`
+	Offset        int32                  `protobuf:"varint,5,opt,name=offset,proto3" json:"offset,omitempty"`
+	Limit         int32                  `protobuf:"varint,6,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLeaderboardRequest) Reset() {
+	*x = GetLeaderboardRequest{}
+	mi := &file_points_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLeaderboardRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLeaderboardRequest) ProtoMessage() {}
+
+func (x *GetLeaderboardRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLeaderboardRequest.ProtoReflect.Descriptor instead.
+func (*GetLeaderboardRequest) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GetLeaderboardRequest) GetWindow() string {
+	if x != nil {
+		return x.Window
+	}
+	return ""
+}
+
+func (x *GetLeaderboardRequest) GetChainId() uint64 {
+	if x != nil {
+		return x.ChainId
+	}
+	return 0
+}
+
+func (x *GetLeaderboardRequest) GetAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.At
+	}
+	return nil
+}
+
+func (x *GetLeaderboardRequest) GetCampaign() string {
+	if x != nil {
+		return x.Campaign
+	}
+	return ""
+}
+
+func (x *GetLeaderboardRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *GetLeaderboardRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type LeaderboardEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rank          uint64                 `protobuf:"varint,1,opt,name=rank,proto3" json:"rank,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Score         string                 `protobuf:"bytes,3,opt,name=score,proto3" json:"score,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaderboardEntry) Reset() {
+	*x = LeaderboardEntry{}
+	mi := &file_points_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaderboardEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaderboardEntry) ProtoMessage() {}
+
+func (x *LeaderboardEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaderboardEntry.ProtoReflect.Descriptor instead.
+func (*LeaderboardEntry) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *LeaderboardEntry) GetRank() uint64 {
+	if x != nil {
+		return x.Rank
+	}
+	return 0
+}
+
+func (x *LeaderboardEntry) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *LeaderboardEntry) GetScore() string {
+	if x != nil {
+		return x.Score
+	}
+	return ""
+}
+
+type Leaderboard struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Board         string                 `protobuf:"bytes,1,opt,name=board,proto3" json:"board,omitempty"`
+	Entries       []*LeaderboardEntry    `protobuf:"bytes,2,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Leaderboard) Reset() {
+	*x = Leaderboard{}
+	mi := &file_points_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Leaderboard) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Leaderboard) ProtoMessage() {}
+
+func (x *Leaderboard) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Leaderboard.ProtoReflect.Descriptor instead.
+func (*Leaderboard) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *Leaderboard) GetBoard() string {
+	if x != nil {
+		return x.Board
+	}
+	return ""
+}
+
+func (x *Leaderboard) GetEntries() []*LeaderboardEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+// 订阅过滤条件，为空表示不过滤
+type SubscribeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChainIds      []uint64               `protobuf:"varint,1,rep,packed,name=chain_ids,json=chainIds,proto3" json:"chain_ids,omitempty"`
+	Addresses     []string               `protobuf:"bytes,2,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeRequest) Reset() {
+	*x = SubscribeRequest{}
+	mi := &file_points_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeRequest) ProtoMessage() {}
+
+func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *SubscribeRequest) GetChainIds() []uint64 {
+	if x != nil {
+		return x.ChainIds
+	}
+	return nil
+}
+
+func (x *SubscribeRequest) GetAddresses() []string {
+	if x != nil {
+		return x.Addresses
+	}
+	return nil
+}
+
+type PointsDelta struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChainId       uint64                 `protobuf:"varint,1,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	EntryType     string                 `protobuf:"bytes,3,opt,name=entry_type,json=entryType,proto3" json:"entry_type,omitempty"` // 分录类型，出账时 delta 为负数
+	Delta         string                 `protobuf:"bytes,4,opt,name=delta,proto3" json:"delta,omitempty"`
+	TotalAfter    string                 `protobuf:"bytes,5,opt,name=total_after,json=totalAfter,proto3" json:"total_after,omitempty"`
+	CalculatedAt  *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=calculated_at,json=calculatedAt,proto3" json:"calculated_at,omitempty"`
+	LedgerId      uint64                 `protobuf:"varint,7,opt,name=ledger_id,json=ledgerId,proto3" json:"ledger_id,omitempty"` // points_ledger 分录ID
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PointsDelta) Reset() {
+	*x = PointsDelta{}
+	mi := &file_points_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PointsDelta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PointsDelta) ProtoMessage() {}
+
+func (x *PointsDelta) ProtoReflect() protoreflect.Message {
+	mi := &file_points_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PointsDelta.ProtoReflect.Descriptor instead.
+func (*PointsDelta) Descriptor() ([]byte, []int) {
+	return file_points_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *PointsDelta) GetChainId() uint64 {
+	if x != nil {
+		return x.ChainId
+	}
+	return 0
+}
+
+func (x *PointsDelta) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *PointsDelta) GetEntryType() string {
+	if x != nil {
+		return x.EntryType
+	}
+	return ""
+}
+
+func (x *PointsDelta) GetDelta() string {
+	if x != nil {
+		return x.Delta
+	}
+	return ""
+}
+
+func (x *PointsDelta) GetTotalAfter() string {
+	if x != nil {
+		return x.TotalAfter
+	}
+	return ""
+}
+
+func (x *PointsDelta) GetCalculatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CalculatedAt
+	}
+	return nil
+}
+
+func (x *PointsDelta) GetLedgerId() uint64 {
+	if x != nil {
+		return x.LedgerId
+	}
+	return 0
+}
+
+var File_points_proto protoreflect.FileDescriptor
+
+const file_points_proto_rawDesc = "" +
+	"\n" +
+	"\fpoints.proto\x12\x0epointstoken.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x8d\x01\n" +
+	"\x05Chain\x12\x19\n" +
+	"\bchain_id\x18\x01 \x01(\x04R\achainId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12#\n" +
+	"\rcontract_addr\x18\x03 \x01(\tR\fcontractAddr\x120\n" +
+	"\x14last_processed_block\x18\x04 \x01(\x04R\x12lastProcessedBlock\"\x12\n" +
+	"\x10GetChainsRequest\"B\n" +
+	"\x11GetChainsResponse\x12-\n" +
+	"\x06chains\x18\x01 \x03(\v2\x15.pointstoken.v1.ChainR\x06chains\"B\n" +
+	"\vUserRequest\x12\x19\n" +
+	"\bchain_id\x18\x01 \x01(\x04R\achainId\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\"X\n" +
+	"\aBalance\x12\x19\n" +
+	"\bchain_id\x18\x01 \x01(\x04R\achainId\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x18\n" +
+	"\abalance\x18\x03 \x01(\tR\abalance\"\x9b\x02\n" +
+	"\x0eHistoryRequest\x12\x19\n" +
+	"\bchain_id\x18\x01 \x01(\x04R\achainId\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x16\n" +
+	"\x06cursor\x18\x03 \x01(\x04R\x06cursor\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\x127\n" +
+	"\tfrom_time\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\bfromTime\x123\n" +
+	"\ato_time\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x06toTime\x12\x1d\n" +
+	"\n" +
+	"from_block\x18\a \x01(\x04R\tfromBlock\x12\x19\n" +
+	"\bto_block\x18\b \x01(\x04R\atoBlock\"\xb4\x02\n" +
+	"\rBalanceChange\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x19\n" +
+	"\bchain_id\x18\x02 \x01(\x04R\achainId\x12\x18\n" +
+	"\aaddress\x18\x03 \x01(\tR\aaddress\x12\x17\n" +
+	"\atx_hash\x18\x04 \x01(\tR\x06txHash\x12!\n" +
+	"\fblock_number\x18\x05 \x01(\x04R\vblockNumber\x12#\n" +
+	"\rchange_amount\x18\x06 \x01(\tR\fchangeAmount\x12#\n" +
+	"\rbalance_after\x18\a \x01(\tR\fbalanceAfter\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\b \x01(\tR\teventType\x129\n" +
+	"\n" +
+	"created_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"j\n" +
+	"\x0eBalanceHistory\x127\n" +
+	"\achanges\x18\x01 \x03(\v2\x1d.pointstoken.v1.BalanceChangeR\achanges\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\x04R\n" +
+	"nextCursor\"`\n" +
+	"\x06Points\x12\x19\n" +
+	"\bchain_id\x18\x01 \x01(\x04R\achainId\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12!\n" +
+	"\ftotal_points\x18\x03 \x01(\tR\vtotalPoints\"9\n" +
+	"\x19GetPointsAllChainsRequest\x12\x1c\n" +
+	"\taddresses\x18\x01 \x03(\tR\taddresses\"L\n" +
+	"\x1aGetPointsAllChainsResponse\x12.\n" +
+	"\x06points\x18\x01 \x03(\v2\x16.pointstoken.v1.PointsR\x06points\"\x84\x02\n" +
+	"\x11PointsCalculation\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x19\n" +
+	"\bchain_id\x18\x02 \x01(\x04R\achainId\x12\x18\n" +
+	"\aaddress\x18\x03 \x01(\tR\aaddress\x12?\n" +
+	"\rcalculated_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\fcalculatedAt\x12\x18\n" +
+	"\abalance\x18\x05 \x01(\tR\abalance\x12!\n" +
+	"\fpoints_added\x18\x06 \x01(\tR\vpointsAdded\x12,\n" +
+	"\x12total_points_after\x18\a \x01(\tR\x10totalPointsAfter\"w\n" +
+	"\rPointsHistory\x12E\n" +
+	"\fcalculations\x18\x01 \x03(\v2!.pointstoken.v1.PointsCalculationR\fcalculations\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\x04R\n" +
+	"nextCursor\"\xcf\x02\n" +
+	"\vLedgerEntry\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x19\n" +
+	"\bchain_id\x18\x02 \x01(\x04R\achainId\x12\x1d\n" +
+	"\n" +
+	"entry_type\x18\x03 \x01(\tR\tentryType\x12#\n" +
+	"\rdebit_account\x18\x04 \x01(\tR\fdebitAccount\x12%\n" +
+	"\x0ecredit_account\x18\x05 \x01(\tR\rcreditAccount\x12\x16\n" +
+	"\x06amount\x18\x06 \x01(\tR\x06amount\x12'\n" +
+	"\x0fidempotency_key\x18\a \x01(\tR\x0eidempotencyKey\x12\x12\n" +
+	"\x04memo\x18\b \x01(\tR\x04memo\x12\x1a\n" +
+	"\boperator\x18\t \x01(\tR\boperator\x129\n" +
+	"\n" +
+	"created_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"g\n" +
+	"\rLedgerEntries\x125\n" +
+	"\aentries\x18\x01 \x03(\v2\x1b.pointstoken.v1.LedgerEntryR\aentries\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\x04R\n" +
+	"nextCursor\"\x85\x01\n" +
+	"\bUserTier\x12\x19\n" +
+	"\bchain_id\x18\x01 \x01(\x04R\achainId\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x12\n" +
+	"\x04tier\x18\x03 \x01(\tR\x04tier\x120\n" +
+	"\x05since\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\"\xc0\x01\n" +
+	"\x15GetLeaderboardRequest\x12\x16\n" +
+	"\x06window\x18\x01 \x01(\tR\x06window\x12\x19\n" +
+	"\bchain_id\x18\x02 \x01(\x04R\achainId\x12*\n" +
+	"\x02at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x02at\x12\x1a\n" +
+	"\bcampaign\x18\x04 \x01(\tR\bcampaign\x12\x16\n" +
+	"\x06offset\x18\x05 \x01(\x05R\x06offset\x12\x14\n" +
+	"\x05limit\x18\x06 \x01(\x05R\x05limit\"V\n" +
+	"\x10LeaderboardEntry\x12\x12\n" +
+	"\x04rank\x18\x01 \x01(\x04R\x04rank\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x14\n" +
+	"\x05score\x18\x03 \x01(\tR\x05score\"_\n" +
+	"\vLeaderboard\x12\x14\n" +
+	"\x05board\x18\x01 \x01(\tR\x05board\x12:\n" +
+	"\aentries\x18\x02 \x03(\v2 .pointstoken.v1.LeaderboardEntryR\aentries\"M\n" +
+	"\x10SubscribeRequest\x12\x1b\n" +
+	"\tchain_ids\x18\x01 \x03(\x04R\bchainIds\x12\x1c\n" +
+	"\taddresses\x18\x02 \x03(\tR\taddresses\"\xf6\x01\n" +
+	"\vPointsDelta\x12\x19\n" +
+	"\bchain_id\x18\x01 \x01(\x04R\achainId\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x1d\n" +
+	"\n" +
+	"entry_type\x18\x03 \x01(\tR\tentryType\x12\x14\n" +
+	"\x05delta\x18\x04 \x01(\tR\x05delta\x12\x1f\n" +
+	"\vtotal_after\x18\x05 \x01(\tR\n" +
+	"totalAfter\x12?\n" +
+	"\rcalculated_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\fcalculatedAt\x12\x1b\n" +
+	"\tledger_id\x18\a \x01(\x04R\bledgerId2\x9d\a\n" +
+	"\rPointsService\x12P\n" +
+	"\tGetChains\x12 .pointstoken.v1.GetChainsRequest\x1a!.pointstoken.v1.GetChainsResponse\x12B\n" +
+	"\n" +
+	"GetBalance\x12\x1b.pointstoken.v1.UserRequest\x1a\x17.pointstoken.v1.Balance\x12S\n" +
+	"\x11GetBalanceHistory\x12\x1e.pointstoken.v1.HistoryRequest\x1a\x1e.pointstoken.v1.BalanceHistory\x12@\n" +
+	"\tGetPoints\x12\x1b.pointstoken.v1.UserRequest\x1a\x16.pointstoken.v1.Points\x12k\n" +
+	"\x12GetPointsAllChains\x12).pointstoken.v1.GetPointsAllChainsRequest\x1a*.pointstoken.v1.GetPointsAllChainsResponse\x12Q\n" +
+	"\x10GetPointsHistory\x12\x1e.pointstoken.v1.HistoryRequest\x1a\x1d.pointstoken.v1.PointsHistory\x12Q\n" +
+	"\x10GetLedgerEntries\x12\x1e.pointstoken.v1.HistoryRequest\x1a\x1d.pointstoken.v1.LedgerEntries\x12D\n" +
+	"\vGetUserTier\x12\x1b.pointstoken.v1.UserRequest\x1a\x18.pointstoken.v1.UserTier\x12T\n" +
+	"\x0eGetLeaderboard\x12%.pointstoken.v1.GetLeaderboardRequest\x1a\x1b.pointstoken.v1.Leaderboard\x12Y\n" +
+	"\x14StreamBalanceChanges\x12 .pointstoken.v1.SubscribeRequest\x1a\x1d.pointstoken.v1.BalanceChange0\x01\x12U\n" +
+	"\x12StreamPointsDeltas\x12 .pointstoken.v1.SubscribeRequest\x1a\x1b.pointstoken.v1.PointsDelta0\x01B\x17Z\x15POINTSTOKEN/rpc/pb;pbb\x06proto3"
+
+var (
+	file_points_proto_rawDescOnce sync.Once
+	file_points_proto_rawDescData []byte
+)
+
+func file_points_proto_rawDescGZIP() []byte {
+	file_points_proto_rawDescOnce.Do(func() {
+		file_points_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_points_proto_rawDesc), len(file_points_proto_rawDesc)))
+	})
+	return file_points_proto_rawDescData
+}
+
+var file_points_proto_msgTypes = make([]protoimpl.MessageInfo, 21)
+var file_points_proto_goTypes = []any{
+	(*Chain)(nil),                      // 0: pointstoken.v1.Chain
+	(*GetChainsRequest)(nil),           // 1: pointstoken.v1.GetChainsRequest
+	(*GetChainsResponse)(nil),          // 2: pointstoken.v1.GetChainsResponse
+	(*UserRequest)(nil),                // 3: pointstoken.v1.UserRequest
+	(*Balance)(nil),                    // 4: pointstoken.v1.Balance
+	(*HistoryRequest)(nil),             // 5: pointstoken.v1.HistoryRequest
+	(*BalanceChange)(nil),              // 6: pointstoken.v1.BalanceChange
+	(*BalanceHistory)(nil),             // 7: pointstoken.v1.BalanceHistory
+	(*Points)(nil),                     // 8: pointstoken.v1.Points
+	(*GetPointsAllChainsRequest)(nil),  // 9: pointstoken.v1.GetPointsAllChainsRequest
+	(*GetPointsAllChainsResponse)(nil), // 10: pointstoken.v1.GetPointsAllChainsResponse
+	(*PointsCalculation)(nil),          // 11: pointstoken.v1.PointsCalculation
+	(*PointsHistory)(nil),              // 12: pointstoken.v1.PointsHistory
+	(*LedgerEntry)(nil),                // 13: pointstoken.v1.LedgerEntry
+	(*LedgerEntries)(nil),              // 14: pointstoken.v1.LedgerEntries
+	(*UserTier)(nil),                   // 15: pointstoken.v1.UserTier
+	(*GetLeaderboardRequest)(nil),      // 16: pointstoken.v1.GetLeaderboardRequest
+	(*LeaderboardEntry)(nil),           // 17: pointstoken.v1.LeaderboardEntry
+	(*Leaderboard)(nil),                // 18: pointstoken.v1.Leaderboard
+	(*SubscribeRequest)(nil),           // 19: pointstoken.v1.SubscribeRequest
+	(*PointsDelta)(nil),                // 20: pointstoken.v1.PointsDelta
+	(*timestamppb.Timestamp)(nil),      // 21: google.protobuf.Timestamp
+}
+var file_points_proto_depIdxs = []int32{
+	0,  // 0: pointstoken.v1.GetChainsResponse.chains:type_name -> pointstoken.v1.Chain
+	21, // 1: pointstoken.v1.HistoryRequest.from_time:type_name -> google.protobuf.Timestamp
+	21, // 2: pointstoken.v1.HistoryRequest.to_time:type_name -> google.protobuf.Timestamp
+	21, // 3: pointstoken.v1.BalanceChange.created_at:type_name -> google.protobuf.Timestamp
+	6,  // 4: pointstoken.v1.BalanceHistory.changes:type_name -> pointstoken.v1.BalanceChange
+	8,  // 5: pointstoken.v1.GetPointsAllChainsResponse.points:type_name -> pointstoken.v1.Points
+	21, // 6: pointstoken.v1.PointsCalculation.calculated_at:type_name -> google.protobuf.Timestamp
+	11, // 7: pointstoken.v1.PointsHistory.calculations:type_name -> pointstoken.v1.PointsCalculation
+	21, // 8: pointstoken.v1.LedgerEntry.created_at:type_name -> google.protobuf.Timestamp
+	13, // 9: pointstoken.v1.LedgerEntries.entries:type_name -> pointstoken.v1.LedgerEntry
+	21, // 10: pointstoken.v1.UserTier.since:type_name -> google.protobuf.Timestamp
+	21, // 11: pointstoken.v1.GetLeaderboardRequest.at:type_name -> google.protobuf.Timestamp
+	17, // 12: pointstoken.v1.Leaderboard.entries:type_name -> pointstoken.v1.LeaderboardEntry
+	21, // 13: pointstoken.v1.PointsDelta.calculated_at:type_name -> google.protobuf.Timestamp
+	1,  // 14: pointstoken.v1.PointsService.GetChains:input_type -> pointstoken.v1.GetChainsRequest
+	3,  // 15: pointstoken.v1.PointsService.GetBalance:input_type -> pointstoken.v1.UserRequest
+	5,  // 16: pointstoken.v1.PointsService.GetBalanceHistory:input_type -> pointstoken.v1.HistoryRequest
+	3,  // 17: pointstoken.v1.PointsService.GetPoints:input_type -> pointstoken.v1.UserRequest
+	9,  // 18: pointstoken.v1.PointsService.GetPointsAllChains:input_type -> pointstoken.v1.GetPointsAllChainsRequest
+	5,  // 19: pointstoken.v1.PointsService.GetPointsHistory:input_type -> pointstoken.v1.HistoryRequest
+	5,  // 20: pointstoken.v1.PointsService.GetLedgerEntries:input_type -> pointstoken.v1.HistoryRequest
+	3,  // 21: pointstoken.v1.PointsService.GetUserTier:input_type -> pointstoken.v1.UserRequest
+	16, // 22: pointstoken.v1.PointsService.GetLeaderboard:input_type -> pointstoken.v1.GetLeaderboardRequest
+	19, // 23: pointstoken.v1.PointsService.StreamBalanceChanges:input_type -> pointstoken.v1.SubscribeRequest
+	19, // 24: pointstoken.v1.PointsService.StreamPointsDeltas:input_type -> pointstoken.v1.SubscribeRequest
+	2,  // 25: pointstoken.v1.PointsService.GetChains:output_type -> pointstoken.v1.GetChainsResponse
+	4,  // 26: pointstoken.v1.PointsService.GetBalance:output_type -> pointstoken.v1.Balance
+	7,  // 27: pointstoken.v1.PointsService.GetBalanceHistory:output_type -> pointstoken.v1.BalanceHistory
+	8,  // 28: pointstoken.v1.PointsService.GetPoints:output_type -> pointstoken.v1.Points
+	10, // 29: pointstoken.v1.PointsService.GetPointsAllChains:output_type -> pointstoken.v1.GetPointsAllChainsResponse
+	12, // 30: pointstoken.v1.PointsService.GetPointsHistory:output_type -> pointstoken.v1.PointsHistory
+	14, // 31: pointstoken.v1.PointsService.GetLedgerEntries:output_type -> pointstoken.v1.LedgerEntries
+	15, // 32: pointstoken.v1.PointsService.GetUserTier:output_type -> pointstoken.v1.UserTier
+	18, // 33: pointstoken.v1.PointsService.GetLeaderboard:output_type -> pointstoken.v1.Leaderboard
+	6,  // 34: pointstoken.v1.PointsService.StreamBalanceChanges:output_type -> pointstoken.v1.BalanceChange
+	20, // 35: pointstoken.v1.PointsService.StreamPointsDeltas:output_type -> pointstoken.v1.PointsDelta
+	25, // [25:36] is the sub-list for method output_type
+	14, // [14:25] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
+}
+
+func init() { file_points_proto_init() }
+func file_points_proto_init() {
+	if File_points_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_points_proto_rawDesc), len(file_points_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   21,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_points_proto_goTypes,
+		DependencyIndexes: file_points_proto_depIdxs,
+		MessageInfos:      file_points_proto_msgTypes,
+	}.Build()
+	File_points_proto = out.File
+	file_points_proto_goTypes = nil
+	file_points_proto_depIdxs = nil
+}
@@ -0,0 +1,516 @@
+// 积分服务 gRPC 接口，供内部服务查询余额、积分并订阅实时变动
+// 修改后在 rpc/pb 目录执行:
+//   protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative points.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: points.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PointsService_GetChains_FullMethodName            = "/pointstoken.v1.PointsService/GetChains"
+	PointsService_GetBalance_FullMethodName           = "/pointstoken.v1.PointsService/GetBalance"
+	PointsService_GetBalanceHistory_FullMethodName    = "/pointstoken.v1.PointsService/GetBalanceHistory"
+	PointsService_GetPoints_FullMethodName            = "/pointstoken.v1.PointsService/GetPoints"
+	PointsService_GetPointsAllChains_FullMethodName   = "/pointstoken.v1.PointsService/GetPointsAllChains"
+	PointsService_GetPointsHistory_FullMethodName     = "/pointstoken.v1.PointsService/GetPointsHistory"
+	PointsService_GetLedgerEntries_FullMethodName     = "/pointstoken.v1.PointsService/GetLedgerEntries"
+	PointsService_GetUserTier_FullMethodName          = "/pointstoken.v1.PointsService/GetUserTier"
+	PointsService_GetLeaderboard_FullMethodName       = "/pointstoken.v1.PointsService/GetLeaderboard"
+	PointsService_StreamBalanceChanges_FullMethodName = "/pointstoken.v1.PointsService/StreamBalanceChanges"
+	PointsService_StreamPointsDeltas_FullMethodName   = "/pointstoken.v1.PointsService/StreamPointsDeltas"
+)
+
+// PointsServiceClient is the client API for PointsService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PointsServiceClient interface {
+	// 查询接口，与 Repository 的读操作对应
+	GetChains(ctx context.Context, in *GetChainsRequest, opts ...grpc.CallOption) (*GetChainsResponse, error)
+	GetBalance(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*Balance, error)
+	GetBalanceHistory(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*BalanceHistory, error)
+	GetPoints(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*Points, error)
+	GetPointsAllChains(ctx context.Context, in *GetPointsAllChainsRequest, opts ...grpc.CallOption) (*GetPointsAllChainsResponse, error)
+	GetPointsHistory(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*PointsHistory, error)
+	GetLedgerEntries(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*LedgerEntries, error)
+	GetUserTier(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*UserTier, error)
+	GetLeaderboard(ctx context.Context, in *GetLeaderboardRequest, opts ...grpc.CallOption) (*Leaderboard, error)
+	// 订阅接口，链上事件入库、积分计算提交后实时推送
+	StreamBalanceChanges(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BalanceChange], error)
+	StreamPointsDeltas(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PointsDelta], error)
+}
+
+type pointsServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPointsServiceClient(cc grpc.ClientConnInterface) PointsServiceClient {
+	return &pointsServiceClient{cc}
+}
+
+func (c *pointsServiceClient) GetChains(ctx context.Context, in *GetChainsRequest, opts ...grpc.CallOption) (*GetChainsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetChainsResponse)
+	err := c.cc.Invoke(ctx, PointsService_GetChains_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pointsServiceClient) GetBalance(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*Balance, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Balance)
+	err := c.cc.Invoke(ctx, PointsService_GetBalance_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pointsServiceClient) GetBalanceHistory(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*BalanceHistory, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BalanceHistory)
+	err := c.cc.Invoke(ctx, PointsService_GetBalanceHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pointsServiceClient) GetPoints(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*Points, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Points)
+	err := c.cc.Invoke(ctx, PointsService_GetPoints_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pointsServiceClient) GetPointsAllChains(ctx context.Context, in *GetPointsAllChainsRequest, opts ...grpc.CallOption) (*GetPointsAllChainsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetPointsAllChainsResponse)
+	err := c.cc.Invoke(ctx, PointsService_GetPointsAllChains_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pointsServiceClient) GetPointsHistory(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*PointsHistory, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PointsHistory)
+	err := c.cc.Invoke(ctx, PointsService_GetPointsHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pointsServiceClient) GetLedgerEntries(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*LedgerEntries, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LedgerEntries)
+	err := c.cc.Invoke(ctx, PointsService_GetLedgerEntries_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pointsServiceClient) GetUserTier(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*UserTier, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UserTier)
+	err := c.cc.Invoke(ctx, PointsService_GetUserTier_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pointsServiceClient) GetLeaderboard(ctx context.Context, in *GetLeaderboardRequest, opts ...grpc.CallOption) (*Leaderboard, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Leaderboard)
+	err := c.cc.Invoke(ctx, PointsService_GetLeaderboard_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pointsServiceClient) StreamBalanceChanges(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BalanceChange], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PointsService_ServiceDesc.Streams[0], PointsService_StreamBalanceChanges_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, BalanceChange]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PointsService_StreamBalanceChangesClient = grpc.ServerStreamingClient[BalanceChange]
+
+func (c *pointsServiceClient) StreamPointsDeltas(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PointsDelta], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PointsService_ServiceDesc.Streams[1], PointsService_StreamPointsDeltas_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, PointsDelta]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PointsService_StreamPointsDeltasClient = grpc.ServerStreamingClient[PointsDelta]
+
+// PointsServiceServer is the server API for PointsService service.
+// All implementations must embed UnimplementedPointsServiceServer
+// for forward compatibility.
+type PointsServiceServer interface {
+	// 查询接口，与 Repository 的读操作对应
+	GetChains(context.Context, *GetChainsRequest) (*GetChainsResponse, error)
+	GetBalance(context.Context, *UserRequest) (*Balance, error)
+	GetBalanceHistory(context.Context, *HistoryRequest) (*BalanceHistory, error)
+	GetPoints(context.Context, *UserRequest) (*Points, error)
+	GetPointsAllChains(context.Context, *GetPointsAllChainsRequest) (*GetPointsAllChainsResponse, error)
+	GetPointsHistory(context.Context, *HistoryRequest) (*PointsHistory, error)
+	GetLedgerEntries(context.Context, *HistoryRequest) (*LedgerEntries, error)
+	GetUserTier(context.Context, *UserRequest) (*UserTier, error)
+	GetLeaderboard(context.Context, *GetLeaderboardRequest) (*Leaderboard, error)
+	// 订阅接口，链上事件入库、积分计算提交后实时推送
+	StreamBalanceChanges(*SubscribeRequest, grpc.ServerStreamingServer[BalanceChange]) error
+	StreamPointsDeltas(*SubscribeRequest, grpc.ServerStreamingServer[PointsDelta]) error
+	mustEmbedUnimplementedPointsServiceServer()
+}
+
+// UnimplementedPointsServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPointsServiceServer struct{}
+
+func (UnimplementedPointsServiceServer) GetChains(context.Context, *GetChainsRequest) (*GetChainsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetChains not implemented")
+}
+func (UnimplementedPointsServiceServer) GetBalance(context.Context, *UserRequest) (*Balance, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBalance not implemented")
+}
+func (UnimplementedPointsServiceServer) GetBalanceHistory(context.Context, *HistoryRequest) (*BalanceHistory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBalanceHistory not implemented")
+}
+func (UnimplementedPointsServiceServer) GetPoints(context.Context, *UserRequest) (*Points, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPoints not implemented")
+}
+func (UnimplementedPointsServiceServer) GetPointsAllChains(context.Context, *GetPointsAllChainsRequest) (*GetPointsAllChainsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPointsAllChains not implemented")
+}
+func (UnimplementedPointsServiceServer) GetPointsHistory(context.Context, *HistoryRequest) (*PointsHistory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPointsHistory not implemented")
+}
+func (UnimplementedPointsServiceServer) GetLedgerEntries(context.Context, *HistoryRequest) (*LedgerEntries, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLedgerEntries not implemented")
+}
+func (UnimplementedPointsServiceServer) GetUserTier(context.Context, *UserRequest) (*UserTier, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserTier not implemented")
+}
+func (UnimplementedPointsServiceServer) GetLeaderboard(context.Context, *GetLeaderboardRequest) (*Leaderboard, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLeaderboard not implemented")
+}
+func (UnimplementedPointsServiceServer) StreamBalanceChanges(*SubscribeRequest, grpc.ServerStreamingServer[BalanceChange]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamBalanceChanges not implemented")
+}
+func (UnimplementedPointsServiceServer) StreamPointsDeltas(*SubscribeRequest, grpc.ServerStreamingServer[PointsDelta]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamPointsDeltas not implemented")
+}
+func (UnimplementedPointsServiceServer) mustEmbedUnimplementedPointsServiceServer() {}
+func (UnimplementedPointsServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafePointsServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PointsServiceServer will
+// result in compilation errors.
+type UnsafePointsServiceServer interface {
+	mustEmbedUnimplementedPointsServiceServer()
+}
+
+func RegisterPointsServiceServer(s grpc.ServiceRegistrar, srv PointsServiceServer) {
+	// If the following call pancis, it indicates UnimplementedPointsServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PointsService_ServiceDesc, srv)
+}
+
+func _PointsService_GetChains_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetChainsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PointsServiceServer).GetChains(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PointsService_GetChains_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PointsServiceServer).GetChains(ctx, req.(*GetChainsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PointsService_GetBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PointsServiceServer).GetBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PointsService_GetBalance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PointsServiceServer).GetBalance(ctx, req.(*UserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PointsService_GetBalanceHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PointsServiceServer).GetBalanceHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PointsService_GetBalanceHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PointsServiceServer).GetBalanceHistory(ctx, req.(*HistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PointsService_GetPoints_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PointsServiceServer).GetPoints(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PointsService_GetPoints_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PointsServiceServer).GetPoints(ctx, req.(*UserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PointsService_GetPointsAllChains_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPointsAllChainsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PointsServiceServer).GetPointsAllChains(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PointsService_GetPointsAllChains_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PointsServiceServer).GetPointsAllChains(ctx, req.(*GetPointsAllChainsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PointsService_GetPointsHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PointsServiceServer).GetPointsHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PointsService_GetPointsHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PointsServiceServer).GetPointsHistory(ctx, req.(*HistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PointsService_GetLedgerEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PointsServiceServer).GetLedgerEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PointsService_GetLedgerEntries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PointsServiceServer).GetLedgerEntries(ctx, req.(*HistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PointsService_GetUserTier_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PointsServiceServer).GetUserTier(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PointsService_GetUserTier_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PointsServiceServer).GetUserTier(ctx, req.(*UserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PointsService_GetLeaderboard_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLeaderboardRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PointsServiceServer).GetLeaderboard(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PointsService_GetLeaderboard_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PointsServiceServer).GetLeaderboard(ctx, req.(*GetLeaderboardRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PointsService_StreamBalanceChanges_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PointsServiceServer).StreamBalanceChanges(m, &grpc.GenericServerStream[SubscribeRequest, BalanceChange]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PointsService_StreamBalanceChangesServer = grpc.ServerStreamingServer[BalanceChange]
+
+func _PointsService_StreamPointsDeltas_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PointsServiceServer).StreamPointsDeltas(m, &grpc.GenericServerStream[SubscribeRequest, PointsDelta]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PointsService_StreamPointsDeltasServer = grpc.ServerStreamingServer[PointsDelta]
+
+// PointsService_ServiceDesc is the grpc.ServiceDesc for PointsService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PointsService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pointstoken.v1.PointsService",
+	HandlerType: (*PointsServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetChains",
+			Handler:    _PointsService_GetChains_Handler,
+		},
+		{
+			MethodName: "GetBalance",
+			Handler:    _PointsService_GetBalance_Handler,
+		},
+		{
+			MethodName: "GetBalanceHistory",
+			Handler:    _PointsService_GetBalanceHistory_Handler,
+		},
+		{
+			MethodName: "GetPoints",
+			Handler:    _PointsService_GetPoints_Handler,
+		},
+		{
+			MethodName: "GetPointsAllChains",
+			Handler:    _PointsService_GetPointsAllChains_Handler,
+		},
+		{
+			MethodName: "GetPointsHistory",
+			Handler:    _PointsService_GetPointsHistory_Handler,
+		},
+		{
+			MethodName: "GetLedgerEntries",
+			Handler:    _PointsService_GetLedgerEntries_Handler,
+		},
+		{
+			MethodName: "GetUserTier",
+			Handler:    _PointsService_GetUserTier_Handler,
+		},
+		{
+			MethodName: "GetLeaderboard",
+			Handler:    _PointsService_GetLeaderboard_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamBalanceChanges",
+			Handler:       _PointsService_StreamBalanceChanges_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamPointsDeltas",
+			Handler:       _PointsService_StreamPointsDeltas_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "points.proto",
+}
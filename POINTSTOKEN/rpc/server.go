@@ -0,0 +1,402 @@
+package rpc
+
+import (
+	"POINTSTOKEN/config"
+	"POINTSTOKEN/db"
+	"POINTSTOKEN/rpc/pb"
+	"POINTSTOKEN/service"
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"log"
+	"net"
+	"time"
+)
+
+// Server 内部 gRPC 服务，查询接口直接读取 Repository，订阅接口从事件总线推送。
+// 全部接口需在元数据中携带具有 read 权限的 API 密钥
+type Server struct {
+	pb.UnimplementedPointsServiceServer
+	cfg         *config.GRPCConfig
+	repository  db.Repository
+	auth        *service.AuthService
+	leaderboard *service.LeaderboardService
+	events      *service.EventBus
+	grpcServer  *grpc.Server
+}
+
+func NewServer(cfg *config.GRPCConfig, repo db.Repository, auth *service.AuthService,
+	leaderboard *service.LeaderboardService, events *service.EventBus) *Server {
+	s := &Server{
+		cfg:         cfg,
+		repository:  repo,
+		auth:        auth,
+		leaderboard: leaderboard,
+		events:      events,
+	}
+	s.grpcServer = grpc.NewServer(grpc.UnaryInterceptor(s.unaryAuth), grpc.StreamInterceptor(s.streamAuth))
+	pb.RegisterPointsServiceServer(s.grpcServer, s)
+	return s
+}
+
+// Start 启动 gRPC 服务
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.cfg.Listen)
+	if err != nil {
+		return err
+	}
+	go func() {
+		log.Printf("gRPC 服务已启动，监听 %s", s.cfg.Listen)
+		if err := s.grpcServer.Serve(listener); err != nil {
+			log.Printf("gRPC 服务异常退出: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Stop 优雅关闭 gRPC 服务，ctx 超时后强制断开订阅流
+func (s *Server) Stop(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+	}
+}
+
+// parseAddress 解析请求中的地址
+func parseAddress(value string) (common.Address, error) {
+	if !common.IsHexAddress(value) {
+		return common.Address{}, status.Errorf(codes.InvalidArgument, "无效的地址: %s", value)
+	}
+	return common.HexToAddress(value), nil
+}
+
+func internalError(err error) error {
+	return status.Error(codes.Internal, err.Error())
+}
+
+// timestamp 转换时间，零值时间返回 nil
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func (s *Server) GetChains(ctx context.Context, req *pb.GetChainsRequest) (*pb.GetChainsResponse, error) {
+	chains, err := s.repository.GetChainInfos()
+	if err != nil {
+		return nil, internalError(err)
+	}
+	resp := &pb.GetChainsResponse{}
+	for _, c := range chains {
+		resp.Chains = append(resp.Chains, &pb.Chain{
+			ChainId:            c.ChainID,
+			Name:               c.Name,
+			ContractAddr:       c.ContractAddr,
+			LastProcessedBlock: c.LastProcessedBlock,
+		})
+	}
+	return resp, nil
+}
+
+func (s *Server) GetBalance(ctx context.Context, req *pb.UserRequest) (*pb.Balance, error) {
+	userAddr, err := parseAddress(req.Address)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := s.repository.GetUserBalance(req.ChainId, userAddr)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	return &pb.Balance{ChainId: req.ChainId, Address: userAddr.Hex(), Balance: balance}, nil
+}
+
+// historyFilter 转换分页和过滤参数，条数限制与 HTTP 接口一致
+func historyFilter(req *pb.HistoryRequest) (db.HistoryFilter, error) {
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = 50
+	}
+	if limit < 1 || limit > 500 {
+		return db.HistoryFilter{}, status.Error(codes.InvalidArgument, "limit 须在 1 到 500 之间")
+	}
+	filter := db.HistoryFilter{
+		Cursor:    req.Cursor,
+		Limit:     limit,
+		FromBlock: req.FromBlock,
+		ToBlock:   req.ToBlock,
+	}
+	if req.FromTime != nil {
+		filter.FromTime = req.FromTime.AsTime()
+	}
+	if req.ToTime != nil {
+		filter.ToTime = req.ToTime.AsTime()
+	}
+	return filter, nil
+}
+
+func balanceChange(c db.UserBalanceChange) *pb.BalanceChange {
+	return &pb.BalanceChange{
+		Id:           c.ID,
+		ChainId:      c.ChainID,
+		Address:      c.UserAddr.Hex(),
+		TxHash:       c.TxHash.Hex(),
+		BlockNumber:  c.BlockNumber,
+		ChangeAmount: c.BalanceChange.ToBigInt().String(),
+		BalanceAfter: c.BalanceAfter.ToBigInt().String(),
+		EventType:    c.EventType,
+		CreatedAt:    timestamp(c.CreatedAt),
+	}
+}
+
+func (s *Server) GetBalanceHistory(ctx context.Context, req *pb.HistoryRequest) (*pb.BalanceHistory, error) {
+	userAddr, err := parseAddress(req.Address)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := historyFilter(req)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := s.repository.GetBalanceHistory(req.ChainId, userAddr, filter)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	resp := &pb.BalanceHistory{}
+	for _, c := range changes {
+		resp.Changes = append(resp.Changes, balanceChange(c))
+	}
+	if len(changes) == filter.Limit {
+		resp.NextCursor = changes[len(changes)-1].ID
+	}
+	return resp, nil
+}
+
+func (s *Server) GetPoints(ctx context.Context, req *pb.UserRequest) (*pb.Points, error) {
+	userAddr, err := parseAddress(req.Address)
+	if err != nil {
+		return nil, err
+	}
+	points, err := s.repository.GetUserPoints(req.ChainId, userAddr)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	return &pb.Points{ChainId: req.ChainId, Address: userAddr.Hex(), TotalPoints: points}, nil
+}
+
+func (s *Server) GetPointsAllChains(ctx context.Context, req *pb.GetPointsAllChainsRequest) (*pb.GetPointsAllChainsResponse, error) {
+	if len(req.Addresses) > 500 {
+		return nil, status.Error(codes.InvalidArgument, "地址数量不能超过 500")
+	}
+	userAddrs := make([]common.Address, 0, len(req.Addresses))
+	for _, value := range req.Addresses {
+		userAddr, err := parseAddress(value)
+		if err != nil {
+			return nil, err
+		}
+		userAddrs = append(userAddrs, userAddr)
+	}
+	userPoints, err := s.repository.GetUserPointsAllChains(userAddrs)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	resp := &pb.GetPointsAllChainsResponse{}
+	for _, up := range userPoints {
+		resp.Points = append(resp.Points, &pb.Points{
+			ChainId:     up.ChainID,
+			Address:     up.UserAddr.Hex(),
+			TotalPoints: up.TotalPoints.ToBigInt().String(),
+		})
+	}
+	return resp, nil
+}
+
+func (s *Server) GetPointsHistory(ctx context.Context, req *pb.HistoryRequest) (*pb.PointsHistory, error) {
+	userAddr, err := parseAddress(req.Address)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := historyFilter(req)
+	if err != nil {
+		return nil, err
+	}
+	calculations, err := s.repository.GetPointsCalculations(req.ChainId, userAddr, filter)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	resp := &pb.PointsHistory{}
+	for _, c := range calculations {
+		resp.Calculations = append(resp.Calculations, &pb.PointsCalculation{
+			Id:               c.ID,
+			ChainId:          c.ChainID,
+			Address:          c.UserAddr.Hex(),
+			CalculatedAt:     timestamp(c.CalculatedAt),
+			Balance:          c.Balance.ToBigInt().String(),
+			PointsAdded:      c.PointsAdded.ToBigInt().String(),
+			TotalPointsAfter: c.TotalPointsAfter.ToBigInt().String(),
+		})
+	}
+	if len(calculations) == filter.Limit {
+		resp.NextCursor = calculations[len(calculations)-1].ID
+	}
+	return resp, nil
+}
+
+func (s *Server) GetLedgerEntries(ctx context.Context, req *pb.HistoryRequest) (*pb.LedgerEntries, error) {
+	userAddr, err := parseAddress(req.Address)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := historyFilter(req)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.repository.GetLedgerEntries(req.ChainId, userAddr, filter)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	resp := &pb.LedgerEntries{}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, &pb.LedgerEntry{
+			Id:             e.ID,
+			ChainId:        e.ChainID,
+			EntryType:      e.EntryType,
+			DebitAccount:   e.DebitAccount,
+			CreditAccount:  e.CreditAccount,
+			Amount:         e.Amount.ToBigInt().String(),
+			IdempotencyKey: e.IdempotencyKey,
+			Memo:           e.Memo,
+			Operator:       e.Operator,
+			CreatedAt:      timestamp(e.CreatedAt),
+		})
+	}
+	if len(entries) == filter.Limit {
+		resp.NextCursor = entries[len(entries)-1].ID
+	}
+	return resp, nil
+}
+
+func (s *Server) GetUserTier(ctx context.Context, req *pb.UserRequest) (*pb.UserTier, error) {
+	userAddr, err := parseAddress(req.Address)
+	if err != nil {
+		return nil, err
+	}
+	tier, err := s.repository.GetUserTier(req.ChainId, userAddr)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	resp := &pb.UserTier{ChainId: req.ChainId, Address: userAddr.Hex()}
+	if tier != nil {
+		resp.Tier = tier.Tier
+		resp.Since = timestamp(tier.Since)
+	}
+	return resp, nil
+}
+
+func (s *Server) GetLeaderboard(ctx context.Context, req *pb.GetLeaderboardRequest) (*pb.Leaderboard, error) {
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = 20
+	}
+	if req.Offset < 0 || limit < 1 || limit > 100 {
+		return nil, status.Error(codes.InvalidArgument, "无效的 offset 或 limit")
+	}
+	at := time.Now()
+	if req.At != nil {
+		at = req.At.AsTime()
+	}
+	board, entries, err := s.leaderboard.Top(req.Window, req.ChainId, at, req.Campaign, int(req.Offset), limit)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp := &pb.Leaderboard{Board: board}
+	for _, entry := range entries {
+		resp.Entries = append(resp.Entries, &pb.LeaderboardEntry{
+			Rank:    entry.Rank,
+			Address: entry.UserAddr.Hex(),
+			Score:   entry.Score.ToBigInt().String(),
+		})
+	}
+	return resp, nil
+}
+
+// subscribeFilter 转换订阅过滤条件
+func subscribeFilter(eventType string, req *pb.SubscribeRequest) (service.EventFilter, error) {
+	filter := service.EventFilter{Types: []string{eventType}, ChainIDs: req.ChainIds}
+	for _, value := range req.Addresses {
+		userAddr, err := parseAddress(value)
+		if err != nil {
+			return filter, err
+		}
+		filter.Addresses = append(filter.Addresses, userAddr)
+	}
+	return filter, nil
+}
+
+// stream 将订阅到的事件逐条发送，客户端断开或订阅因消费过慢被关闭时结束
+func (s *Server) stream(ctx context.Context, filter service.EventFilter, send func(service.Event) error) error {
+	sub := s.events.Subscribe(filter)
+	defer sub.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-sub.C:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "订阅消费过慢，已断开")
+			}
+			if err := send(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Server) StreamBalanceChanges(req *pb.SubscribeRequest, stream grpc.ServerStreamingServer[pb.BalanceChange]) error {
+	filter, err := subscribeFilter(service.EventBalanceChange, req)
+	if err != nil {
+		return err
+	}
+	return s.stream(stream.Context(), filter, func(e service.Event) error {
+		b := e.Balance
+		return stream.Send(&pb.BalanceChange{
+			Id:           b.ID,
+			ChainId:      b.ChainID,
+			Address:      b.UserAddr.Hex(),
+			TxHash:       b.TxHash.Hex(),
+			BlockNumber:  b.BlockNumber,
+			ChangeAmount: b.ChangeAmount.String(),
+			BalanceAfter: b.BalanceAfter.String(),
+			EventType:    b.EventType,
+			CreatedAt:    timestamp(b.Timestamp),
+		})
+	})
+}
+
+func (s *Server) StreamPointsDeltas(req *pb.SubscribeRequest, stream grpc.ServerStreamingServer[pb.PointsDelta]) error {
+	filter, err := subscribeFilter(service.EventPointsDelta, req)
+	if err != nil {
+		return err
+	}
+	return s.stream(stream.Context(), filter, func(e service.Event) error {
+		p := e.Points
+		return stream.Send(&pb.PointsDelta{
+			ChainId:      p.ChainID,
+			Address:      p.UserAddr.Hex(),
+			EntryType:    p.EntryType,
+			Delta:        p.Delta.String(),
+			TotalAfter:   p.TotalAfter.String(),
+			CalculatedAt: timestamp(p.CalculatedAt),
+			LedgerId:     p.LedgerID,
+		})
+	})
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"POINTSTOKEN/db"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// PointsAdjustment 一条人工发放或扣回请求
+type PointsAdjustment struct {
+	ChainID  uint64
+	UserAddr common.Address
+	Action   string //grant 或 clawback
+	Amount   *big.Int
+	Reason   string
+	Key      string //幂等键，为空时随机生成并在分录中返回，重试时传入同一键不会重复记账
+}
+
+// AdjustmentResult 批量导入中单条调整的结果
+type AdjustmentResult struct {
+	Line       int
+	Adjustment PointsAdjustment
+	Entry      *db.LedgerEntry
+	Err        error
+}
+
+// AdjustmentService 社区运营人工发放、扣回积分
+type AdjustmentService struct {
+	repo db.Repository
+}
+
+func NewAdjustmentService(repo db.Repository) *AdjustmentService {
+	return &AdjustmentService{repo: repo}
+}
+
+// idempotencyKey 返回调整的幂等键，未指定时随机生成
+// 不按内容生成：同一用户相同数量和原因的发放可能是合法的多次发放
+func (a *PointsAdjustment) idempotencyKey() (string, error) {
+	if a.Key != "" {
+		return a.Key, nil
+	}
+	var nonce [8]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%s:%s", a.Action, a.UserAddr.Hex(), hex.EncodeToString(nonce[:])), nil
+}
+
+// validate 校验调整参数
+func (a *PointsAdjustment) validate() error {
+	if a.Action != db.LedgerGrant && a.Action != db.LedgerClawback {
+		return fmt.Errorf("无效的操作: %s", a.Action)
+	}
+	if a.ChainID == 0 {
+		return errors.New("链ID不能为空")
+	}
+	if a.Amount == nil || a.Amount.Sign() <= 0 {
+		return errors.New("积分数量必须大于0")
+	}
+	if strings.TrimSpace(a.Reason) == "" {
+		return errors.New("原因不能为空")
+	}
+	return nil
+}
+
+// Adjust 为地址发放或扣回积分，operator 为操作人
+func (s *AdjustmentService) Adjust(adj PointsAdjustment, operator string) (*db.LedgerEntry, error) {
+	if err := adj.validate(); err != nil {
+		return nil, err
+	}
+	key, err := adj.idempotencyKey()
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.AdjustPoints(adj.ChainID, adj.UserAddr, adj.Action, adj.Amount.String(), key, adj.Reason, operator)
+}
+
+// Import 逐条执行批量调整，单条失败不影响其他调整
+func (s *AdjustmentService) Import(adjs []PointsAdjustment, operator string) []AdjustmentResult {
+	results := make([]AdjustmentResult, 0, len(adjs))
+	for i, adj := range adjs {
+		entry, err := s.Adjust(adj, operator)
+		results = append(results, AdjustmentResult{Line: i + 2, Adjustment: adj, Entry: entry, Err: err})
+	}
+	return results
+}
+
+// GetAdjustments 获取最近的人工调整记录
+func (s *AdjustmentService) GetAdjustments(chainID uint64, limit int) ([]db.LedgerEntry, error) {
+	return s.repo.GetAdjustments(chainID, limit)
+}
+
+// adjustmentColumns 批量导入 CSV 的列，key 列可省略
+var adjustmentColumns = []string{"chain_id", "address", "action", "amount", "reason", "key"}
+
+// ParseAdjustmentsCSV 解析批量调整 CSV，首行为表头：chain_id,address,action,amount,reason[,key]
+func ParseAdjustmentsCSV(r io.Reader) ([]PointsAdjustment, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("读取表头失败: %v", err)
+	}
+	index := make(map[string]int)
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range adjustmentColumns[:5] {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("缺少列: %s", name)
+		}
+	}
+
+	var adjs []PointsAdjustment
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %v", line, err)
+		}
+		field := func(name string) string {
+			i, ok := index[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		chainID, err := strconv.ParseUint(field("chain_id"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: 无效的链ID: %s", line, field("chain_id"))
+		}
+		if !common.IsHexAddress(field("address")) {
+			return nil, fmt.Errorf("第 %d 行: 无效的地址: %s", line, field("address"))
+		}
+		amount, ok := new(big.Int).SetString(field("amount"), 10)
+		if !ok {
+			return nil, fmt.Errorf("第 %d 行: 无效的积分数量: %s", line, field("amount"))
+		}
+		adj := PointsAdjustment{
+			ChainID:  chainID,
+			UserAddr: common.HexToAddress(field("address")),
+			Action:   strings.ToLower(field("action")),
+			Amount:   amount,
+			Reason:   field("reason"),
+			Key:      field("key"),
+		}
+		if err := adj.validate(); err != nil {
+			return nil, fmt.Errorf("第 %d 行: %v", line, err)
+		}
+		adjs = append(adjs, adj)
+	}
+	return adjs, nil
+}
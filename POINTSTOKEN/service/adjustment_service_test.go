@@ -0,0 +1,108 @@
+package service
+
+import (
+	"POINTSTOKEN/db"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestParseAdjustmentsCSV(t *testing.T) {
+	addr := "0x00000000000000000000000000000000000000a1"
+	tests := []struct {
+		name    string
+		input   string
+		want    []PointsAdjustment
+		wantErr string
+	}{
+		{
+			name: "省略 key 列",
+			input: "chain_id,address,action,amount,reason\n" +
+				"11155111," + addr + ",grant,100,AMA\n" +
+				"11155111, " + addr + " ,CLAWBACK,5,刷量\n",
+			want: []PointsAdjustment{
+				{ChainID: 11155111, UserAddr: common.HexToAddress(addr), Action: db.LedgerGrant, Amount: big.NewInt(100), Reason: "AMA"},
+				{ChainID: 11155111, UserAddr: common.HexToAddress(addr), Action: db.LedgerClawback, Amount: big.NewInt(5), Reason: "刷量"},
+			},
+		},
+		{
+			name: "列顺序任意且带 key",
+			input: "Reason,Key,Amount,Action,Address,Chain_ID\n" +
+				"AMA,ama-1,100,grant," + addr + ",1\n",
+			want: []PointsAdjustment{
+				{ChainID: 1, UserAddr: common.HexToAddress(addr), Action: db.LedgerGrant, Amount: big.NewInt(100), Reason: "AMA", Key: "ama-1"},
+			},
+		},
+		{
+			name:    "缺少列",
+			input:   "chain_id,address,action,amount\n1," + addr + ",grant,100\n",
+			wantErr: "缺少列: reason",
+		},
+		{
+			name:    "无效地址",
+			input:   "chain_id,address,action,amount,reason\n1,0x123,grant,100,AMA\n",
+			wantErr: "第 2 行: 无效的地址",
+		},
+		{
+			name:    "无效数量",
+			input:   "chain_id,address,action,amount,reason\n1," + addr + ",grant,1.5,AMA\n",
+			wantErr: "第 2 行: 无效的积分数量",
+		},
+		{
+			name:    "无效操作",
+			input:   "chain_id,address,action,amount,reason\n1," + addr + ",burn,100,AMA\n",
+			wantErr: "第 2 行: 无效的操作",
+		},
+		{
+			name:    "原因为空",
+			input:   "chain_id,address,action,amount,reason\n1," + addr + ",grant,100, \n",
+			wantErr: "第 2 行: 原因不能为空",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAdjustmentsCSV(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d adjustments, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				g, w := got[i], tt.want[i]
+				if g.ChainID != w.ChainID || g.UserAddr != w.UserAddr || g.Action != w.Action ||
+					g.Amount.Cmp(w.Amount) != 0 || g.Reason != w.Reason || g.Key != w.Key {
+					t.Errorf("adjustment %d = %+v, want %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestAdjustmentIdempotencyKey(t *testing.T) {
+	adj := PointsAdjustment{ChainID: 1, UserAddr: common.HexToAddress("0xa1"), Action: db.LedgerGrant,
+		Amount: big.NewInt(100), Reason: "AMA"}
+	first, err := adj.idempotencyKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := adj.idempotencyKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Errorf("相同内容的两次调整生成了相同的幂等键 %s", first)
+	}
+
+	adj.Key = "ama-1"
+	if key, _ := adj.idempotencyKey(); key != "ama-1" {
+		t.Errorf("key = %s, want ama-1", key)
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"POINTSTOKEN/config"
+	"POINTSTOKEN/db"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// 令牌前缀，用于区分 API 密钥和登录会话
+const (
+	APIKeyPrefix  = "pt_"
+	SessionPrefix = "ps_"
+)
+
+// apiKeyTouchInterval 最后使用时间的更新间隔
+const apiKeyTouchInterval = time.Minute
+
+// ErrUnauthorized 令牌无效、已过期或已吊销
+var ErrUnauthorized = errors.New("无效的认证令牌")
+
+// AuthService API 密钥和钱包登录（EIP-4361）认证
+type AuthService struct {
+	cfg  config.AuthConfig
+	repo db.Repository
+}
+
+func NewAuthService(cfg config.AuthConfig, repo db.Repository) *AuthService {
+	return &AuthService{cfg: cfg, repo: repo}
+}
+
+// randomToken 生成带前缀的随机令牌
+func randomToken(prefix string, n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(buf), nil
+}
+
+// hashToken 令牌的 SHA-256 摘要，数据库只保存摘要
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey 创建 API 密钥，返回的明文只在此时可见
+func (a *AuthService) CreateAPIKey(name string, scopes []string, createdBy string) (string, *db.APIKey, error) {
+	if name == "" {
+		return "", nil, errors.New("请填写密钥名称")
+	}
+	if len(scopes) == 0 {
+		return "", nil, errors.New("请指定密钥权限")
+	}
+	for _, scope := range scopes {
+		if scope != db.ScopeRead && scope != db.ScopeAdmin {
+			return "", nil, fmt.Errorf("无效的权限: %s", scope)
+		}
+	}
+	token, err := randomToken(APIKeyPrefix, 32)
+	if err != nil {
+		return "", nil, err
+	}
+	key := &db.APIKey{
+		Name:      name,
+		KeyPrefix: token[:len(APIKeyPrefix)+8],
+		KeyHash:   hashToken(token),
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+	if err := a.repo.CreateAPIKey(key); err != nil {
+		return "", nil, err
+	}
+	log.Printf("%s 创建了 API 密钥 %s（%s），权限 %s", createdBy, key.Name, key.KeyPrefix, strings.Join(scopes, ","))
+	return token, key, nil
+}
+
+// GetAPIKeys 获取全部 API 密钥
+func (a *AuthService) GetAPIKeys() ([]db.APIKey, error) {
+	return a.repo.GetAPIKeys()
+}
+
+// RevokeAPIKey 吊销 API 密钥，返回密钥是否存在且未吊销
+func (a *AuthService) RevokeAPIKey(id uint64) (bool, error) {
+	return a.repo.RevokeAPIKey(id, time.Now())
+}
+
+// AuthenticateAPIKey 校验 API 密钥
+func (a *AuthService) AuthenticateAPIKey(token string) (*db.APIKey, error) {
+	key, err := a.repo.GetAPIKeyByHash(hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrUnauthorized
+	}
+	if err := a.repo.TouchAPIKey(key.ID, time.Now(), apiKeyTouchInterval); err != nil {
+		log.Printf("更新 API 密钥 %d 使用时间失败: %v", key.ID, err)
+	}
+	return key, nil
+}
+
+// NewNonce 生成钱包登录随机数
+func (a *AuthService) NewNonce() (string, error) {
+	if a.cfg.Domain == "" {
+		return "", errors.New("未启用钱包登录")
+	}
+	nonce, err := randomToken("", 16)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	if err := a.repo.CreateSIWENonce(nonce, now, now.Add(a.cfg.NonceTTL)); err != nil {
+		return "", err
+	}
+	return nonce, nil
+}
+
+// SignIn 校验 EIP-4361 登录消息和签名，签发会话令牌
+func (a *AuthService) SignIn(message string, signature string) (string, *db.AuthSession, error) {
+	if a.cfg.Domain == "" {
+		return "", nil, errors.New("未启用钱包登录")
+	}
+	msg, err := ParseSIWEMessage(message)
+	if err != nil {
+		return "", nil, err
+	}
+	if msg.Domain != a.cfg.Domain {
+		return "", nil, errors.New("登录消息域名不匹配")
+	}
+	now := time.Now()
+	if msg.ExpirationTime != nil && !now.Before(*msg.ExpirationTime) {
+		return "", nil, errors.New("登录消息已过期")
+	}
+	if msg.NotBefore != nil && now.Before(*msg.NotBefore) {
+		return "", nil, errors.New("登录消息尚未生效")
+	}
+	if now.Sub(msg.IssuedAt) > a.cfg.NonceTTL || msg.IssuedAt.Sub(now) > time.Minute {
+		return "", nil, errors.New("登录消息签发时间无效")
+	}
+	if err := VerifyPersonalSign(msg.Address, message, signature); err != nil {
+		return "", nil, fmt.Errorf("签名验证失败: %w", err)
+	}
+	//签名通过后再使用随机数，避免无效请求消耗随机数
+	ok, err := a.repo.ConsumeSIWENonce(msg.Nonce, now)
+	if err != nil {
+		return "", nil, err
+	}
+	if !ok {
+		return "", nil, errors.New("登录随机数无效或已使用")
+	}
+
+	token, err := randomToken(SessionPrefix, 32)
+	if err != nil {
+		return "", nil, err
+	}
+	session := &db.AuthSession{
+		UserAddr:  msg.Address,
+		ChainID:   msg.ChainID,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(a.cfg.SessionTTL),
+	}
+	if msg.ExpirationTime != nil && msg.ExpirationTime.Before(session.ExpiresAt) {
+		session.ExpiresAt = *msg.ExpirationTime
+	}
+	if err := a.repo.CreateSession(hashToken(token), session); err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+// AuthenticateSession 校验会话令牌
+func (a *AuthService) AuthenticateSession(token string) (*db.AuthSession, error) {
+	session, err := a.repo.GetSessionByHash(hashToken(token), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrUnauthorized
+	}
+	return session, nil
+}
+
+// SignOut 注销会话
+func (a *AuthService) SignOut(token string) error {
+	return a.repo.RevokeSession(hashToken(token), time.Now())
+}
@@ -5,11 +5,16 @@ import (
 	"POINTSTOKEN/db"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 	"log"
 	"math/big"
 	"strings"
@@ -463,6 +468,7 @@ type ChainHandler struct {
 	contract      common.Address
 	repository    db.Repository
 	confirmations uint64
+	checkedAddrs  map[common.Address]bool //已做过合约检测的地址
 }
 
 // NewChainManager 创建新的链管理器
@@ -506,6 +512,7 @@ func NewChainHandler(cfg config.ChainConfig, repository db.Repository) (*ChainHa
 		contract:      common.HexToAddress(cfg.ContractAddr),
 		repository:    repository,
 		confirmations: cfg.Confirmations,
+		checkedAddrs:  make(map[common.Address]bool),
 	}, nil
 }
 
@@ -533,12 +540,17 @@ func (h *ChainHandler) StartEVentListener(ctx context.Context) {
 	if lastBlock == 0 {
 		header, err := h.client.HeaderByNumber(ctx, nil)
 		if err != nil {
-			log.Printf("failed to fetch header for chain %s, %s", h.config.Name, h.config.ChainID)
+			log.Printf("failed to fetch header for chain %s, %d", h.config.Name, h.config.ChainID)
 			return
 		}
 		lastBlock = header.Number.Uint64() - 100
 	}
 
+	//检测已记录的地址中的合约地址
+	if h.config.ExcludeContracts {
+		h.detectKnownContracts(ctx)
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -565,7 +577,17 @@ func (h *ChainHandler) StartEVentListener(ctx context.Context) {
 				time.Sleep(h.config.PollInterval)
 				continue
 			}
-			// 处理从lastBlock+1到safeBlock的区块
+			// 先处理链上推荐注册事件：登记可重复执行，失败时整段区块重试，
+			// 且 processBlocks 会推进已保存的区块高度，放在其后失败会漏掉该段的推荐事件
+			if h.config.ReferralContract != "" {
+				if err := h.processReferralEvents(ctx, lastBlock+1, safeBlock); err != nil {
+					log.Printf("Error processing referral events on chain %s: %v", h.config.Name, err)
+					time.Sleep(h.config.PollInterval)
+					continue
+				}
+			}
+
+			// 处理从lastBlock+1到safeBlock的区块，重试时已记录的余额变动按日志去重
 			log.Printf("Processing blocks %d to %d on chain %s", lastBlock+1, safeBlock, h.config.Name)
 			if err := h.processBlocks(ctx, lastBlock+1, safeBlock); err != nil {
 				log.Printf("Error processing blocks on chain %s: %v", h.config.Name, err)
@@ -573,9 +595,16 @@ func (h *ChainHandler) StartEVentListener(ctx context.Context) {
 				continue
 			}
 
-			// 更新最后处理的区块
+			// 更新最后处理的区块及其时间，按时间查询历史余额时不能超过该时间
+			safeHeader, err := h.client.HeaderByNumber(ctx, new(big.Int).SetUint64(safeBlock))
+			if err != nil {
+				log.Printf("failed to get header of block %d on chain %s: %v", safeBlock, h.config.Name, err)
+				time.Sleep(h.config.PollInterval)
+				continue
+			}
 			lastBlock = safeBlock
-			if err := h.repository.UpdateChainLastBlock(h.config.ChainID, lastBlock); err != nil {
+			blockTime := time.Unix(int64(safeHeader.Time), 0)
+			if err := h.repository.UpdateChainLastBlock(h.config.ChainID, lastBlock, blockTime); err != nil {
 				log.Printf("Failed to update last processed block: %v", err)
 			}
 		}
@@ -609,39 +638,27 @@ func (h *ChainHandler) processBlocks(ctx context.Context, start uint64, end uint
 		//return err
 	}
 
-	fmt.Printf("找到 %d 笔相关交易:\n", len(logs))
-	for _, vLog := range logs {
-		// 解析事件数据
-		var transferEvent struct {
-			From  common.Address
-			To    common.Address
-			Value *big.Int
-		}
-		if err := parsedABI.UnpackIntoInterface(&transferEvent, "Transfer", vLog.Data); err != nil {
-			log.Printf("解析事件失败: %v", err)
-			continue
-		}
-		// 从日志中获取索引字段
-		transferEvent.From = common.HexToAddress(vLog.Topics[1].Hex())
-		transferEvent.To = common.HexToAddress(vLog.Topics[2].Hex())
-		evenType := "transfer"
-		zeroAddr := common.Address{}.Hex()
-		if transferEvent.From.Hex() == zeroAddr {
-			evenType = "mint"
-		} else if transferEvent.To.Hex() == zeroAddr {
-			evenType = "burn"
-		}
-
-		// 获取用户token 余额
-		balanceFrom, _, _, err := getUserTokenBalance(context.Background(), h.client, h.contract, transferEvent.From)
-		if err != nil {
-			return err
-		}
-		balanceTo, _, _, err := getUserTokenBalance(context.Background(), h.client, h.contract, transferEvent.To)
-		if err != nil {
-			return err
+	log.Printf("链 %d 区块 %d-%d 找到 %d 笔相关交易", h.config.ChainID, startBlock, endBlock, len(logs))
+	if h.config.ExcludeContracts {
+		//批量检测本批次涉及的地址，避免逐笔同步调用 eth_getCode
+		addrs := make([]common.Address, 0, 2*len(logs))
+		for _, vLog := range logs {
+			if len(vLog.Topics) >= 3 {
+				addrs = append(addrs, common.HexToAddress(vLog.Topics[1].Hex()), common.HexToAddress(vLog.Topics[2].Hex()))
+			}
 		}
-		err = h.repository.UpdateUserBalance(h.config.ChainID, transferEvent.From, balanceFrom.Uint64())
+		h.detectContracts(ctx, addrs)
+	}
+	balanceAt := func(userAddr common.Address, blockNumber uint64) (*big.Int, error) {
+		return balanceOfAt(ctx, h.client, h.contract, userAddr, blockNumber)
+	}
+	transfers, err := parseTransfers(parsedABI, logs, balanceAt)
+	if err != nil {
+		return err
+	}
+	for _, t := range transfers {
+		vLog := t.Log
+		err = h.repository.UpdateUserBalance(h.config.ChainID, t.From, t.BalanceFrom.String())
 		if err != nil {
 			return err
 		}
@@ -655,18 +672,18 @@ func (h *ChainHandler) processBlocks(ctx context.Context, start uint64, end uint
 		timeStamp := time.Unix(int64(block.Time()), 0)
 		//timeStamp := time.Unix(int64(vLog.BlockTimestamp), 0).In(time.FixedZone("CST", 8*3600))
 		log.Printf("区块时间timeStamp: %s ", timeStamp)
-		err = h.repository.RecordBalanceChange(h.config.ChainID, transferEvent.From, vLog.TxHash,
-			vLog.BlockNumber, transferEvent.Value.Uint64(), balanceFrom.String(), evenType, timeStamp)
+		_, err = h.repository.RecordBalanceChange(h.config.ChainID, t.From, vLog.TxHash, vLog.Index,
+			vLog.BlockNumber, t.Value.String(), t.BalanceFrom.String(), t.EventType, timeStamp)
 		if err != nil {
 			return err
 		}
 
-		err = h.repository.UpdateUserBalance(h.config.ChainID, transferEvent.To, balanceTo.Uint64())
+		err = h.repository.UpdateUserBalance(h.config.ChainID, t.To, t.BalanceTo.String())
 		if err != nil {
 			return err
 		}
-		err = h.repository.RecordBalanceChange(h.config.ChainID, transferEvent.To, vLog.TxHash,
-			vLog.BlockNumber, transferEvent.Value.Uint64(), balanceTo.String(), evenType, timeStamp)
+		_, err = h.repository.RecordBalanceChange(h.config.ChainID, t.To, vLog.TxHash, vLog.Index,
+			vLog.BlockNumber, t.Value.String(), t.BalanceTo.String(), t.EventType, timeStamp)
 		if err != nil {
 			return err
 		}
@@ -680,63 +697,222 @@ func (h *ChainHandler) processBlocks(ctx context.Context, start uint64, end uint
 	return nil
 }
 
-// 获取用户在指定ERC-20代币合约中的余额
-func getUserTokenBalance(ctx context.Context, client *ethclient.Client, tokenContract common.Address, userAddress common.Address) (*big.Int, uint8, string, error) {
-	// 解析ABI
-	parsedABI, err := abi.JSON(strings.NewReader(contractAbi))
-	if err != nil {
-		return nil, 0, "", fmt.Errorf("解析ABI失败: %w", err)
-	}
+// transferLog 解析后的 Transfer 日志，BalanceFrom、BalanceTo 为双方在日志所在区块结束时的余额
+type transferLog struct {
+	Log         types.Log
+	From        common.Address
+	To          common.Address
+	Value       *big.Int
+	EventType   string
+	BalanceFrom *big.Int
+	BalanceTo   *big.Int
+}
 
-	// 调用balanceOf方法
-	data, err := parsedABI.Pack("balanceOf", userAddress)
-	if err != nil {
-		return nil, 0, "", fmt.Errorf("打包调用数据失败: %w", err)
+// parseTransfers 解析 Transfer 日志并通过 balanceAt 读取双方在日志所在区块的余额。
+// 余额按区块读取而不是读取最新余额，补同步历史区块时记录的 balance_after 才与区块高度对应；
+// 同一区块内的多笔转账得到的都是区块结束时的余额，按区块查询历史余额时结果正确
+func parseTransfers(parsedABI abi.ABI, logs []types.Log,
+	balanceAt func(userAddr common.Address, blockNumber uint64) (*big.Int, error)) ([]transferLog, error) {
+	transfers := make([]transferLog, 0, len(logs))
+	for _, vLog := range logs {
+		// 解析事件数据
+		var transferEvent struct {
+			From  common.Address
+			To    common.Address
+			Value *big.Int
+		}
+		if err := parsedABI.UnpackIntoInterface(&transferEvent, "Transfer", vLog.Data); err != nil {
+			log.Printf("解析事件失败: %v", err)
+			continue
+		}
+		if len(vLog.Topics) < 3 {
+			log.Printf("Transfer 事件缺少索引字段: %s", vLog.TxHash.Hex())
+			continue
+		}
+		// 从日志中获取索引字段
+		t := transferLog{
+			Log:       vLog,
+			From:      common.HexToAddress(vLog.Topics[1].Hex()),
+			To:        common.HexToAddress(vLog.Topics[2].Hex()),
+			Value:     transferEvent.Value,
+			EventType: "transfer",
+		}
+		if t.From == (common.Address{}) {
+			t.EventType = "mint"
+		} else if t.To == (common.Address{}) {
+			t.EventType = "burn"
+		}
+
+		// 获取双方在该区块的 token 余额
+		var err error
+		if t.BalanceFrom, err = balanceAt(t.From, vLog.BlockNumber); err != nil {
+			return nil, err
+		}
+		if t.BalanceTo, err = balanceAt(t.To, vLog.BlockNumber); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
 	}
+	return transfers, nil
+}
 
-	// 执行合约调用
-	msg := ethereum.CallMsg{
-		To:   &tokenContract,
-		Data: data,
+// processReferralEvents 处理指定区块范围内的推荐注册事件
+// 事件的 topics[1] 为被推荐人，topics[2] 为推荐人
+func (h *ChainHandler) processReferralEvents(ctx context.Context, start uint64, end uint64) error {
+	query := ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(start),
+		ToBlock:   new(big.Int).SetUint64(end),
+		Addresses: []common.Address{common.HexToAddress(h.config.ReferralContract)},
+		Topics: [][]common.Hash{
+			{crypto.Keccak256Hash([]byte(h.config.ReferralEvent))},
+		},
 	}
-	result, err := client.CallContract(ctx, msg, nil)
+	logs, err := h.client.FilterLogs(ctx, query)
 	if err != nil {
-		return nil, 0, "", fmt.Errorf("调用合约失败: %w", err)
+		return fmt.Errorf("查询推荐事件失败: %w", err)
 	}
-
-	// 解析余额
-	var balance *big.Int
-	if err := parsedABI.UnpackIntoInterface(&balance, "balanceOf", result); err != nil {
-		return nil, 0, "", fmt.Errorf("解析余额失败: %w", err)
+	for _, vLog := range logs {
+		if len(vLog.Topics) < 3 {
+			continue
+		}
+		referee := common.HexToAddress(vLog.Topics[1].Hex())
+		referrer := common.HexToAddress(vLog.Topics[2].Hex())
+		err := h.repository.RegisterReferral(referee, referrer, db.ReferralSourceEvent, vLog.TxHash.Hex())
+		if errors.Is(err, db.ErrReferralExists) || errors.Is(err, db.ErrReferralCycle) {
+			log.Printf("忽略推荐事件 %s: %v", vLog.TxHash.Hex(), err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		log.Printf("链 %s 上地址 %s 登记推荐人 %s", h.config.Name, referee.Hex(), referrer.Hex())
 	}
+	return nil
+}
 
-	// 获取代币小数位
-	decimalsData, err := parsedABI.Pack("decimals")
+// detectKnownContracts 对链上已记录余额的地址做合约检测
+func (h *ChainHandler) detectKnownContracts(ctx context.Context) {
+	balances, err := h.repository.GetAllUserBalance()
 	if err != nil {
-		return balance, 18, "", fmt.Errorf("打包decimals调用数据失败: %w", err)
+		log.Printf("获取用户余额失败: %v", err)
+		return
 	}
-	decimalsResult, err := client.CallContract(ctx, ethereum.CallMsg{To: &tokenContract, Data: decimalsData}, nil)
-	var decimals uint8
-	if err != nil {
-		return balance, 18, "", fmt.Errorf("获取小数位失败，使用默认18位: %w", err)
+	addrs := make([]common.Address, 0, len(balances))
+	for _, balance := range balances {
+		if balance.ChainID == h.config.ChainID {
+			addrs = append(addrs, balance.UserAddr)
+		}
+	}
+	h.detectContracts(ctx, addrs)
+}
+
+// contractCheckBatchSize 每次批量 eth_getCode 请求的地址数
+const contractCheckBatchSize = 100
+
+// detectContracts 通过批量 eth_getCode 检测合约地址并加入积分排除名单，已检测过的地址跳过
+func (h *ChainHandler) detectContracts(ctx context.Context, addrs []common.Address) {
+	pending := make([]common.Address, 0, len(addrs))
+	seen := make(map[common.Address]bool, len(addrs))
+	for _, addr := range addrs {
+		if addr == (common.Address{}) || h.checkedAddrs[addr] || seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		pending = append(pending, addr)
+	}
+	for start := 0; start < len(pending); start += contractCheckBatchSize {
+		batch := pending[start:min(start+contractCheckBatchSize, len(pending))]
+		codes := make([]hexutil.Bytes, len(batch))
+		elems := make([]rpc.BatchElem, len(batch))
+		for i, addr := range batch {
+			elems[i] = rpc.BatchElem{Method: "eth_getCode", Args: []interface{}{addr, "latest"}, Result: &codes[i]}
+		}
+		if err := h.client.Client().BatchCallContext(ctx, elems); err != nil {
+			log.Printf("批量获取地址代码失败: %v", err)
+			continue
+		}
+		for i, addr := range batch {
+			if elems[i].Error != nil {
+				log.Printf("获取地址 %s 代码失败: %v", addr.Hex(), elems[i].Error)
+				continue
+			}
+			h.checkedAddrs[addr] = true
+			if len(codes[i]) == 0 {
+				continue
+			}
+			log.Printf("链 %s 上检测到合约地址 %s，不参与积分计算", h.config.Name, addr.Hex())
+			err := h.repository.AddExclusion(h.config.ChainID, addr, "合约地址", db.ExclusionSourceContract)
+			if err != nil {
+				log.Printf("添加排除地址失败: %v", err)
+			}
+		}
 	}
-	if err := parsedABI.UnpackIntoInterface(&decimals, "decimals", decimalsResult); err != nil {
-		return balance, 18, "", fmt.Errorf("解析小数位失败，使用默认18位: %w", err)
+}
+
+// reindexBatch 重建余额时每批读取的余额变动记录数
+const reindexBatch = 1000
+
+// ReindexBalances 重新读取区块 fromBlock 及之后每条余额变动所在区块结束时的余额，修正 balance_after。
+// 修正前同步的记录按同步时的最新余额写入，补同步历史区块时与区块高度不对应；读取历史区块需要归档节点。
+// 返回读取余额的次数和更新的记录数
+func (h *ChainHandler) ReindexBalances(ctx context.Context, fromBlock uint64) (int, int64, error) {
+	type key struct {
+		userAddr    common.Address
+		blockNumber uint64
+	}
+	var afterID uint64
+	var reads int
+	var updated int64
+	for {
+		refs, err := h.repository.GetBalanceChangeRefs(h.config.ChainID, fromBlock, afterID, reindexBatch)
+		if err != nil {
+			return reads, updated, err
+		}
+		//同一地址同一区块的记录一次更新
+		seen := make(map[key]bool, len(refs))
+		for _, ref := range refs {
+			afterID = ref.ID
+			k := key{ref.UserAddr, ref.BlockNumber}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			balance, err := balanceOfAt(ctx, h.client, h.contract, ref.UserAddr, ref.BlockNumber)
+			if err != nil {
+				return reads, updated, err
+			}
+			reads++
+			n, err := h.repository.UpdateBalanceAfter(h.config.ChainID, ref.UserAddr, ref.BlockNumber, balance.String())
+			if err != nil {
+				return reads, updated, err
+			}
+			updated += n
+		}
+		if len(refs) < reindexBatch {
+			return reads, updated, nil
+		}
 	}
+}
 
-	// 获取代币符号
-	symbolData, err := parsedABI.Pack("symbol")
+// balanceOfAt 获取用户在区块高度 blockNumber 结束时的代币余额，查询较早的区块需要归档节点
+func balanceOfAt(ctx context.Context, client *ethclient.Client, tokenContract common.Address, userAddress common.Address,
+	blockNumber uint64) (*big.Int, error) {
+	parsedABI, err := abi.JSON(strings.NewReader(contractAbi))
 	if err != nil {
-		return balance, decimals, "", fmt.Errorf("打包symbol调用数据失败: %w", err)
+		return nil, fmt.Errorf("解析ABI失败: %w", err)
 	}
-	symbolResult, err := client.CallContract(ctx, ethereum.CallMsg{To: &tokenContract, Data: symbolData}, nil)
-	var symbol string
+	data, err := parsedABI.Pack("balanceOf", userAddress)
 	if err != nil {
-		return balance, decimals, "未知代币", fmt.Errorf("获取代币符号失败: %w", err)
+		return nil, fmt.Errorf("打包调用数据失败: %w", err)
 	}
-	if err := parsedABI.UnpackIntoInterface(&symbol, "symbol", symbolResult); err != nil {
-		return balance, decimals, "未知代币", fmt.Errorf("解析代币符号失败: %w", err)
+	msg := ethereum.CallMsg{To: &tokenContract, Data: data}
+	result, err := client.CallContract(ctx, msg, new(big.Int).SetUint64(blockNumber))
+	if err != nil {
+		return nil, fmt.Errorf("调用合约失败（区块 %d）: %w", blockNumber, err)
 	}
-
-	return balance, decimals, symbol, nil
+	var balance *big.Int
+	if err := parsedABI.UnpackIntoInterface(&balance, "balanceOf", result); err != nil {
+		return nil, fmt.Errorf("解析余额失败: %w", err)
+	}
+	return balance, nil
 }
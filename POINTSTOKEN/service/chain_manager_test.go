@@ -0,0 +1,130 @@
+package service
+
+import (
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// chainTransfer 测试链上的一笔转账
+type chainTransfer struct {
+	block uint64
+	from  int
+	to    int
+	value int64
+}
+
+// chainBalances 按转账计算地址在区块结束时的余额，模拟按区块调用 balanceOf
+func chainBalances(transfers []chainTransfer) func(common.Address, uint64) (*big.Int, error) {
+	return func(userAddr common.Address, blockNumber uint64) (*big.Int, error) {
+		balance := new(big.Int)
+		if userAddr == (common.Address{}) {
+			return balance, nil
+		}
+		for _, t := range transfers {
+			if t.block > blockNumber {
+				continue
+			}
+			if testAddr(t.to) == userAddr {
+				balance.Add(balance, big.NewInt(t.value))
+			}
+			if testAddr(t.from) == userAddr {
+				balance.Sub(balance, big.NewInt(t.value))
+			}
+		}
+		return balance, nil
+	}
+}
+
+func transferLogs(parsedABI abi.ABI, transfers []chainTransfer) []types.Log {
+	logs := make([]types.Log, 0, len(transfers))
+	for i, tr := range transfers {
+		logs = append(logs, types.Log{
+			Topics: []common.Hash{
+				parsedABI.Events["Transfer"].ID,
+				common.BytesToHash(testAddr(tr.from).Bytes()),
+				common.BytesToHash(testAddr(tr.to).Bytes()),
+			},
+			Data:        common.BigToHash(big.NewInt(tr.value)).Bytes(),
+			BlockNumber: tr.block,
+			TxHash:      common.BigToHash(big.NewInt(int64(i + 1))),
+			Index:       uint(i),
+		})
+	}
+	return logs
+}
+
+// 一次处理的区块范围内有多笔转账，其中两笔在同一区块：每条记录的余额为所在区块结束时的余额，
+// 按区块取每个地址最后一条记录得到的余额与链上该区块的余额一致
+func TestParseTransfersBalanceAtBlock(t *testing.T) {
+	parsedABI, err := abi.JSON(strings.NewReader(contractAbi))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfers := []chainTransfer{
+		{block: 10, from: 0, to: 1, value: 100},
+		{block: 10, from: 1, to: 2, value: 30},
+		{block: 12, from: 1, to: 2, value: 20},
+		{block: 15, from: 2, to: 3, value: 50},
+		{block: 15, from: 3, to: 0, value: 10},
+	}
+	balanceAt := chainBalances(transfers)
+	var calls []uint64
+	parsed, err := parseTransfers(parsedABI, transferLogs(parsedABI, transfers),
+		func(userAddr common.Address, blockNumber uint64) (*big.Int, error) {
+			calls = append(calls, blockNumber)
+			return balanceAt(userAddr, blockNumber)
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != len(transfers) {
+		t.Fatalf("parsed %d transfers, want %d", len(parsed), len(transfers))
+	}
+
+	wantTypes := []string{"mint", "transfer", "transfer", "transfer", "burn"}
+	//双方在所在区块结束时的余额
+	wantBalances := [][2]int64{{0, 70}, {70, 30}, {50, 50}, {0, 40}, {40, 0}}
+	for i, p := range parsed {
+		if p.From != testAddr(transfers[i].from) || p.To != testAddr(transfers[i].to) ||
+			p.Value.Int64() != transfers[i].value || p.EventType != wantTypes[i] {
+			t.Errorf("transfer %d = %s -> %s %s %s", i, p.From.Hex(), p.To.Hex(), p.Value, p.EventType)
+		}
+		if p.BalanceFrom.Int64() != wantBalances[i][0] || p.BalanceTo.Int64() != wantBalances[i][1] {
+			t.Errorf("transfer %d balances = %s/%s, want %d/%d", i, p.BalanceFrom, p.BalanceTo, wantBalances[i][0], wantBalances[i][1])
+		}
+	}
+	for i, block := range calls {
+		if block != transfers[i/2].block {
+			t.Errorf("balanceOf call %d at block %d, want %d", i, block, transfers[i/2].block)
+		}
+	}
+
+	//模拟 GetLastBalanceChange：取区块高度及之前最后一条记录的余额
+	type row struct {
+		addr    common.Address
+		block   uint64
+		balance int64
+	}
+	var rows []row
+	for _, p := range parsed {
+		rows = append(rows, row{p.From, p.Log.BlockNumber, p.BalanceFrom.Int64()}, row{p.To, p.Log.BlockNumber, p.BalanceTo.Int64()})
+	}
+	for block := uint64(9); block <= 16; block++ {
+		for user := 1; user <= 3; user++ {
+			var got int64
+			for _, r := range rows {
+				if r.addr == testAddr(user) && r.block <= block {
+					got = r.balance
+				}
+			}
+			want, _ := balanceAt(testAddr(user), block)
+			if got != want.Int64() {
+				t.Errorf("地址 %d 在区块 %d 的余额 = %d, want %s", user, block, got, want)
+			}
+		}
+	}
+}
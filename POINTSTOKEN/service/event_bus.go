@@ -0,0 +1,164 @@
+package service
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	EventBalanceChange = "balance_change" //链上转账入库后的余额变动
+	EventPointsDelta   = "points_delta"   //积分分录提交后的积分变化
+)
+
+// BalanceEvent 余额变动事件，与 balance_changes 中的一条记录对应
+type BalanceEvent struct {
+	ID           uint64 //balance_changes 记录ID
+	ChainID      uint64
+	UserAddr     common.Address
+	TxHash       common.Hash
+	BlockNumber  uint64
+	EventType    string //mint、burn、transfer
+	ChangeAmount *big.Int
+	BalanceAfter *big.Int
+	Timestamp    time.Time
+}
+
+// PointsEvent 积分变化事件，与账本中的一条分录对应
+type PointsEvent struct {
+	LedgerID     uint64 //points_ledger 分录ID
+	ChainID      uint64
+	UserAddr     common.Address
+	EntryType    string
+	Delta        *big.Int //出账时为负数
+	TotalAfter   *big.Int
+	CalculatedAt time.Time //分录时间
+}
+
+// Event 事件总线上的事件，按 Type 取 Balance 或 Points，Seq 为发件箱序号
+type Event struct {
+	Type    string
+	Seq     uint64
+	Balance *BalanceEvent
+	Points  *PointsEvent
+}
+
+// ChainID 事件所属的链
+func (e Event) ChainID() uint64 {
+	if e.Balance != nil {
+		return e.Balance.ChainID
+	}
+	return e.Points.ChainID
+}
+
+// UserAddr 事件所属的地址
+func (e Event) UserAddr() common.Address {
+	if e.Balance != nil {
+		return e.Balance.UserAddr
+	}
+	return e.Points.UserAddr
+}
+
+// EventFilter 订阅过滤条件，各项为空表示不过滤
+type EventFilter struct {
+	Types     []string
+	ChainIDs  []uint64
+	Addresses []common.Address
+}
+
+// Match 判断事件是否满足过滤条件
+func (f EventFilter) Match(e Event) bool {
+	if len(f.Types) > 0 && !contains(f.Types, e.Type) {
+		return false
+	}
+	if len(f.ChainIDs) > 0 && !contains(f.ChainIDs, e.ChainID()) {
+		return false
+	}
+	if len(f.Addresses) > 0 && !contains(f.Addresses, e.UserAddr()) {
+		return false
+	}
+	return true
+}
+
+func contains[T comparable](values []T, v T) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription 事件订阅，事件从 C 读取；订阅者消费过慢时订阅被关闭，C 随之关闭
+type Subscription struct {
+	C      <-chan Event
+	ch     chan Event
+	filter EventFilter
+	bus    *EventBus
+	closed bool
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	s.bus.unsubscribe(s)
+}
+
+// EventBus 进程内事件总线，由 OutboxPublisher 按发件箱顺序发布，发布不阻塞
+type EventBus struct {
+	mu     sync.Mutex
+	buffer int
+	subs   map[*Subscription]bool
+}
+
+// NewEventBus 创建事件总线，buffer 为每个订阅的缓冲事件数
+func NewEventBus(buffer int) *EventBus {
+	if buffer <= 0 {
+		buffer = 256
+	}
+	return &EventBus{buffer: buffer, subs: make(map[*Subscription]bool)}
+}
+
+// Subscribe 订阅满足过滤条件的事件
+func (b *EventBus) Subscribe(filter EventFilter) *Subscription {
+	ch := make(chan Event, b.buffer)
+	sub := &Subscription{C: ch, ch: ch, filter: filter, bus: b}
+	b.mu.Lock()
+	b.subs[sub] = true
+	b.mu.Unlock()
+	return sub
+}
+
+func (b *EventBus) unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !sub.closed {
+		sub.closed = true
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Publish 向订阅者发布事件，总线为 nil 时忽略
+func (b *EventBus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs {
+		if !sub.filter.Match(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			log.Printf("事件订阅缓冲已满，关闭订阅")
+			sub.closed = true
+			delete(b.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"POINTSTOKEN/config"
+	"POINTSTOKEN/db"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// 输出类型
+const (
+	SinkFile   = "file"   //按大小和时间轮转的 NDJSON 文件
+	SinkStdout = "stdout" //逐行输出 NDJSON 到标准输出
+	SinkQueue  = "queue"  //本地目录消息队列
+)
+
+// SinkRecord 输出的一条事件，按 Type 取 Balance 或 Points，金额、积分为十进制字符串
+type SinkRecord struct {
+	Type        string       `json:"type"`
+	ChainID     uint64       `json:"chain_id"`
+	Address     string       `json:"address"`
+	Balance     *SinkBalance `json:"balance,omitempty"`
+	Points      *SinkPoints  `json:"points,omitempty"`
+	PublishedAt time.Time    `json:"published_at"`
+}
+
+type SinkBalance struct {
+	ID           uint64    `json:"id"` //balance_changes 记录ID
+	TxHash       string    `json:"tx_hash"`
+	BlockNumber  uint64    `json:"block_number"`
+	EventType    string    `json:"event_type"`
+	ChangeAmount string    `json:"change_amount"`
+	BalanceAfter string    `json:"balance_after"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+type SinkPoints struct {
+	LedgerID     uint64    `json:"ledger_id"` //points_ledger 分录ID
+	EntryType    string    `json:"entry_type"`
+	Delta        string    `json:"delta"`
+	TotalAfter   string    `json:"total_after"`
+	CalculatedAt time.Time `json:"calculated_at"`
+}
+
+// NewSinkRecord 将发件箱事件转换为输出记录
+func NewSinkRecord(e Event, publishedAt time.Time) *SinkRecord {
+	record := &SinkRecord{
+		Type:        e.Type,
+		ChainID:     e.ChainID(),
+		Address:     e.UserAddr().Hex(),
+		PublishedAt: publishedAt,
+	}
+	if b := e.Balance; b != nil {
+		record.Balance = &SinkBalance{
+			ID:           b.ID,
+			TxHash:       b.TxHash.Hex(),
+			BlockNumber:  b.BlockNumber,
+			EventType:    b.EventType,
+			ChangeAmount: b.ChangeAmount.String(),
+			BalanceAfter: b.BalanceAfter.String(),
+			Timestamp:    b.Timestamp,
+		}
+	}
+	if p := e.Points; p != nil {
+		record.Points = &SinkPoints{
+			LedgerID:     p.LedgerID,
+			EntryType:    p.EntryType,
+			Delta:        p.Delta.String(),
+			TotalAfter:   p.TotalAfter.String(),
+			CalculatedAt: p.CalculatedAt,
+		}
+	}
+	return record
+}
+
+// Sink 事件输出，Write 由同一个协程调用
+type Sink interface {
+	Write(record *SinkRecord) error
+	Close() error
+}
+
+// NewSink 按配置创建输出
+func NewSink(cfg config.SinkConfig) (Sink, error) {
+	switch cfg.Type {
+	case SinkFile:
+		return NewFileSink(cfg.Path, cfg.MaxSize, cfg.RotateInterval, cfg.MaxFiles)
+	case SinkStdout:
+		return NewStdoutSink(), nil
+	case SinkQueue:
+		return NewQueueSink(cfg.Path)
+	default:
+		return nil, fmt.Errorf("未知的输出类型: %s", cfg.Type)
+	}
+}
+
+const (
+	sinksConsumer = "sinks" //发件箱消费者名称
+	sinksBatch    = 500
+)
+
+// SinkForwarder 按持久化的游标读取发件箱，将余额变动和积分变化写入全部输出。
+// 输出失败或进程重启后从游标处重新输出，事件不会丢失，但可能重复输出，消费方按 id、ledger_id 去重
+type SinkForwarder struct {
+	cfg    config.SinksConfig
+	sinks  []Sink
+	outbox *outboxReader
+	wg     sync.WaitGroup
+}
+
+// NewSinkForwarder 按配置创建全部输出，未配置输出时返回 nil
+func NewSinkForwarder(cfg config.SinksConfig, repo db.Repository) (*SinkForwarder, error) {
+	if len(cfg.Outputs) == 0 {
+		return nil, nil
+	}
+	f := &SinkForwarder{cfg: cfg, outbox: newOutboxReader(sinksConsumer, repo)}
+	for _, output := range cfg.Outputs {
+		sink, err := NewSink(output)
+		if err != nil {
+			f.closeSinks()
+			return nil, err
+		}
+		f.sinks = append(f.sinks, sink)
+	}
+	return f, nil
+}
+
+// Start 开始转发，ctx 取消后关闭全部输出
+func (f *SinkForwarder) Start(ctx context.Context) {
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		defer f.closeSinks()
+		ticker := time.NewTicker(f.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			if err := f.forward(ctx); err != nil {
+				log.Printf("转发事件输出失败: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// forward 将游标之后的事件写入全部输出，全部写入成功后推进游标
+func (f *SinkForwarder) forward(ctx context.Context) error {
+	for ctx.Err() == nil {
+		events, err := f.outbox.next(sinksBatch)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		now := time.Now()
+		for i := range events {
+			e, err := EventFromOutbox(&events[i])
+			if err != nil {
+				log.Printf("%v", err)
+				continue
+			}
+			record := NewSinkRecord(e, now)
+			for j, sink := range f.sinks {
+				if err := sink.Write(record); err != nil {
+					return fmt.Errorf("写入事件输出 %s 失败: %v", f.cfg.Outputs[j].Type, err)
+				}
+			}
+		}
+		if err := f.outbox.commit(events); err != nil || len(events) < sinksBatch {
+			return err
+		}
+	}
+	return nil
+}
+
+// Wait 等待转发结束并关闭全部输出
+func (f *SinkForwarder) Wait() {
+	f.wg.Wait()
+}
+
+func (f *SinkForwarder) closeSinks() {
+	for i, sink := range f.sinks {
+		if err := sink.Close(); err != nil {
+			log.Printf("关闭事件输出 %s 失败: %v", f.cfg.Outputs[i].Type, err)
+		}
+	}
+}
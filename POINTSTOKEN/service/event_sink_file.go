@@ -0,0 +1,141 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	sinkFilePrefix  = "events-"
+	sinkFileSuffix  = ".ndjson"
+	sinkPartSuffix  = ".part" //写入中的文件，轮转后去掉后缀，消费方只读取 .ndjson 文件
+	sinkFileTimeFmt = "20060102T150405Z"
+)
+
+// FileSink 将事件按行写入 NDJSON 文件，超过 maxSize 字节或打开超过 interval 后轮转，
+// 只保留最近 maxFiles 个已轮转文件；各项为 0 表示不限制
+type FileSink struct {
+	dir      string
+	maxSize  int64
+	interval time.Duration
+	maxFiles int
+
+	file     *os.File
+	name     string
+	size     int64
+	openedAt time.Time
+	seq      int
+}
+
+func NewFileSink(dir string, maxSize int64, interval time.Duration, maxFiles int) (*FileSink, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("文件输出未配置目录")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	//上次退出时未完成轮转的文件直接转为已完成
+	parts, err := filepath.Glob(filepath.Join(dir, sinkFilePrefix+"*"+sinkFileSuffix+sinkPartSuffix))
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts {
+		if err := os.Rename(part, part[:len(part)-len(sinkPartSuffix)]); err != nil {
+			return nil, err
+		}
+	}
+	return &FileSink{dir: dir, maxSize: maxSize, interval: interval, maxFiles: maxFiles}, nil
+}
+
+func (s *FileSink) Write(record *SinkRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	now := time.Now()
+	if s.file != nil && s.size > 0 && ((s.maxSize > 0 && s.size+int64(len(line)) > s.maxSize) ||
+		(s.interval > 0 && now.Sub(s.openedAt) >= s.interval)) {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	if s.file == nil {
+		if err := s.open(now); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+func (s *FileSink) open(now time.Time) error {
+	s.seq++
+	s.name = filepath.Join(s.dir, fmt.Sprintf("%s%s-%04d%s", sinkFilePrefix, now.UTC().Format(sinkFileTimeFmt), s.seq, sinkFileSuffix))
+	file, err := os.OpenFile(s.name+sinkPartSuffix, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	s.file = file
+	s.size = 0
+	s.openedAt = now
+	return nil
+}
+
+// rotate 关闭当前文件并去掉 .part 后缀，清理超出数量的旧文件
+func (s *FileSink) rotate() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(s.name+sinkPartSuffix, s.name); err != nil {
+		return err
+	}
+	if s.maxFiles <= 0 {
+		return nil
+	}
+	files, err := filepath.Glob(filepath.Join(s.dir, sinkFilePrefix+"*"+sinkFileSuffix))
+	if err != nil {
+		return err
+	}
+	//文件名以时间开头，按名称排序即按时间排序
+	sort.Strings(files)
+	for len(files) > s.maxFiles {
+		if err := os.Remove(files[0]); err != nil {
+			return err
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+func (s *FileSink) Close() error {
+	return s.rotate()
+}
+
+// StdoutSink 将事件按行输出到标准输出
+type StdoutSink struct {
+	enc *json.Encoder
+}
+
+func NewStdoutSink() *StdoutSink {
+	return &StdoutSink{enc: json.NewEncoder(os.Stdout)}
+}
+
+func (s *StdoutSink) Write(record *SinkRecord) error {
+	return s.enc.Encode(record)
+}
+
+func (s *StdoutSink) Close() error {
+	return nil
+}
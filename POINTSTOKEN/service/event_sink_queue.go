@@ -0,0 +1,50 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// QueueSink 本地目录消息队列，代替正式消息队列供本地和测试环境消费：
+// 每条事件先写入 tmp/ 再原子地移动到 new/，文件名按写入顺序递增；
+// 消费方按文件名顺序读取 new/ 中的文件，处理完成后删除或移走
+type QueueSink struct {
+	tmpDir string
+	newDir string
+	seq    uint64
+}
+
+func NewQueueSink(dir string) (*QueueSink, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("队列输出未配置目录")
+	}
+	s := &QueueSink{tmpDir: filepath.Join(dir, "tmp"), newDir: filepath.Join(dir, "new")}
+	for _, d := range []string{s.tmpDir, s.newDir} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *QueueSink) Write(record *SinkRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	//纳秒时间戳加序号，重启后仍保持递增
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d.json", time.Now().UnixNano(), s.seq%1000000)
+	tmp := filepath.Join(s.tmpDir, name)
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.newDir, name))
+}
+
+func (s *QueueSink) Close() error {
+	return nil
+}
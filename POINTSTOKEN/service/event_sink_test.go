@@ -0,0 +1,93 @@
+package service
+
+import (
+	"POINTSTOKEN/config"
+	"POINTSTOKEN/db"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// outboxRepo 只实现发件箱读取和游标的测试仓库
+type outboxRepo struct {
+	db.Repository
+	events []db.OutboxEvent
+	cursor uint64
+}
+
+func (r *outboxRepo) GetOutboxCursor(consumer string) (uint64, error) {
+	return r.cursor, nil
+}
+
+func (r *outboxRepo) SaveOutboxCursor(consumer string, lastSeq uint64) error {
+	r.cursor = lastSeq
+	return nil
+}
+
+func (r *outboxRepo) GetOutboxEventsAfter(afterSeq uint64, eventTypes []string, filter db.EventFilter, limit int) ([]db.OutboxEvent, error) {
+	var result []db.OutboxEvent
+	for _, e := range r.events {
+		if e.Seq > afterSeq && len(result) < limit {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+// recordSink 记录写入的分录ID，failures 次写入失败后恢复
+type recordSink struct {
+	failures int
+	ledgers  []uint64
+}
+
+func (s *recordSink) Write(record *SinkRecord) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("写入失败")
+	}
+	s.ledgers = append(s.ledgers, record.Points.LedgerID)
+	return nil
+}
+
+func (s *recordSink) Close() error {
+	return nil
+}
+
+func TestSinkForwarderResumesFromCursor(t *testing.T) {
+	repo := &outboxRepo{}
+	for i := uint64(1); i <= 3; i++ {
+		payload, _ := json.Marshal(db.PointsPayload{LedgerID: i * 10, ChainID: 1, EntryType: db.LedgerAccrual, Delta: "1", TotalAfter: "1"})
+		repo.events = append(repo.events, db.OutboxEvent{ID: i, Seq: i, EventType: db.OutboxPoints, ChainID: 1, Payload: payload})
+	}
+	sink := &recordSink{failures: 1}
+	f := &SinkForwarder{
+		cfg:    config.SinksConfig{Outputs: []config.SinkConfig{{Type: SinkStdout}}},
+		sinks:  []Sink{sink},
+		outbox: newOutboxReader(sinksConsumer, repo),
+	}
+
+	if err := f.forward(context.Background()); err == nil {
+		t.Fatal("输出失败时应返回错误")
+	}
+	if repo.cursor != 0 {
+		t.Fatalf("输出失败后游标 = %d, want 0", repo.cursor)
+	}
+
+	if err := f.forward(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if repo.cursor != 3 {
+		t.Errorf("cursor = %d, want 3", repo.cursor)
+	}
+	want := []uint64{10, 20, 30}
+	if len(sink.ledgers) != len(want) {
+		t.Fatalf("ledgers = %v, want %v", sink.ledgers, want)
+	}
+	for i := range want {
+		if sink.ledgers[i] != want[i] {
+			t.Errorf("ledgers = %v, want %v", sink.ledgers, want)
+			break
+		}
+	}
+}
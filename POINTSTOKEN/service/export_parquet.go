@@ -0,0 +1,334 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// 最小实现的 Parquet 写入：全部列为 REQUIRED，PLAIN 编码、不压缩，每 parquetRowGroupSize 行一个行组，
+// 文件元数据按 Thrift Compact 协议编码；内存中只保留当前行组的数据
+
+const (
+	parquetMagic        = "PAR1"
+	parquetRowGroupSize = 65536
+	parquetCreatedBy    = "POINTSTOKEN export"
+)
+
+// Parquet 物理类型、转换类型和编码，取值见 parquet.thrift
+const (
+	parquetTypeInt64     = 2
+	parquetTypeByteArray = 6
+
+	parquetConvertedUTF8            = 0
+	parquetConvertedTimestampMillis = 9
+
+	parquetRepetitionRequired = 0
+	parquetEncodingPlain      = 0
+	parquetEncodingRLE        = 3
+	parquetCodecUncompressed  = 0
+	parquetPageData           = 0
+)
+
+// ExportColumn 导出列
+type ExportColumn struct {
+	Name string
+	Type string //int64、string、timestamp
+}
+
+// 导出列类型
+const (
+	ColumnInt64     = "int64"
+	ColumnString    = "string"
+	ColumnTimestamp = "timestamp"
+)
+
+type parquetChunk struct {
+	physicalType int32
+	data         bytes.Buffer
+}
+
+type parquetChunkMeta struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+type parquetRowGroup struct {
+	chunks []parquetChunkMeta
+	rows   int64
+	bytes  int64
+}
+
+// ParquetWriter 按行写入 Parquet 文件，Close 时写入文件元数据
+type ParquetWriter struct {
+	w         io.Writer
+	offset    int64
+	columns   []ExportColumn
+	chunks    []parquetChunk
+	rows      int64
+	rowGroups []parquetRowGroup
+}
+
+func NewParquetWriter(w io.Writer, columns []ExportColumn) (*ParquetWriter, error) {
+	p := &ParquetWriter{w: w, columns: columns, chunks: make([]parquetChunk, len(columns))}
+	for i, column := range columns {
+		switch column.Type {
+		case ColumnInt64, ColumnTimestamp:
+			p.chunks[i].physicalType = parquetTypeInt64
+		case ColumnString:
+			p.chunks[i].physicalType = parquetTypeByteArray
+		default:
+			return nil, fmt.Errorf("不支持的列类型: %s", column.Type)
+		}
+	}
+	if err := p.write([]byte(parquetMagic)); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *ParquetWriter) write(b []byte) error {
+	n, err := p.w.Write(b)
+	p.offset += int64(n)
+	return err
+}
+
+// WriteRow 写入一行，values 与列一一对应：int64 列为 int64，string 列为 string，timestamp 列为 time.Time
+func (p *ParquetWriter) WriteRow(values []interface{}) error {
+	if len(values) != len(p.columns) {
+		return fmt.Errorf("列数不匹配: %d != %d", len(values), len(p.columns))
+	}
+	var buf [8]byte
+	for i, value := range values {
+		chunk := &p.chunks[i]
+		switch v := value.(type) {
+		case int64:
+			binary.LittleEndian.PutUint64(buf[:], uint64(v))
+			chunk.data.Write(buf[:])
+		case time.Time:
+			binary.LittleEndian.PutUint64(buf[:], uint64(v.UnixMilli()))
+			chunk.data.Write(buf[:])
+		case string:
+			binary.LittleEndian.PutUint32(buf[:4], uint32(len(v)))
+			chunk.data.Write(buf[:4])
+			chunk.data.WriteString(v)
+		default:
+			return fmt.Errorf("列 %s 的值类型不支持: %T", p.columns[i].Name, value)
+		}
+	}
+	p.rows++
+	if p.rows >= parquetRowGroupSize {
+		return p.flushRowGroup()
+	}
+	return nil
+}
+
+// flushRowGroup 将当前行组的每列写为一个数据页
+func (p *ParquetWriter) flushRowGroup() error {
+	if p.rows == 0 {
+		return nil
+	}
+	group := parquetRowGroup{rows: p.rows}
+	for i := range p.chunks {
+		chunk := &p.chunks[i]
+		var header thriftWriter
+		header.begin()
+		header.i32(1, parquetPageData)
+		header.i32(2, int32(chunk.data.Len()))
+		header.i32(3, int32(chunk.data.Len()))
+		header.structBegin(5)
+		header.i32(1, int32(p.rows))
+		header.i32(2, parquetEncodingPlain)
+		header.i32(3, parquetEncodingRLE)
+		header.i32(4, parquetEncodingRLE)
+		header.structEnd()
+		header.end()
+
+		meta := parquetChunkMeta{offset: p.offset, values: p.rows}
+		if err := p.write(header.buf.Bytes()); err != nil {
+			return err
+		}
+		if err := p.write(chunk.data.Bytes()); err != nil {
+			return err
+		}
+		meta.size = p.offset - meta.offset
+		group.bytes += meta.size
+		group.chunks = append(group.chunks, meta)
+		chunk.data.Reset()
+	}
+	p.rowGroups = append(p.rowGroups, group)
+	p.rows = 0
+	return nil
+}
+
+// Close 写入剩余的行和文件元数据
+func (p *ParquetWriter) Close() error {
+	if err := p.flushRowGroup(); err != nil {
+		return err
+	}
+	var totalRows int64
+	for _, group := range p.rowGroups {
+		totalRows += group.rows
+	}
+
+	var meta thriftWriter
+	meta.begin()
+	meta.i32(1, 1)
+	meta.listBegin(2, thriftStruct, len(p.columns)+1)
+	meta.elemBegin()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(p.columns)))
+	meta.elemEnd()
+	for i, column := range p.columns {
+		meta.elemBegin()
+		meta.i32(1, p.chunks[i].physicalType)
+		meta.i32(3, parquetRepetitionRequired)
+		meta.binary(4, column.Name)
+		switch column.Type {
+		case ColumnString:
+			meta.i32(6, parquetConvertedUTF8)
+		case ColumnTimestamp:
+			meta.i32(6, parquetConvertedTimestampMillis)
+		}
+		meta.elemEnd()
+	}
+	meta.i64(3, totalRows)
+	meta.listBegin(4, thriftStruct, len(p.rowGroups))
+	for _, group := range p.rowGroups {
+		meta.elemBegin()
+		meta.listBegin(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			meta.elemBegin()
+			meta.i64(2, chunk.offset)
+			meta.structBegin(3)
+			meta.i32(1, p.chunks[i].physicalType)
+			meta.listBegin(2, thriftI32, 1)
+			meta.listI32(parquetEncodingPlain)
+			meta.listBegin(3, thriftBinary, 1)
+			meta.listBinary(p.columns[i].Name)
+			meta.i32(4, parquetCodecUncompressed)
+			meta.i64(5, chunk.values)
+			meta.i64(6, chunk.size)
+			meta.i64(7, chunk.size)
+			meta.i64(9, chunk.offset)
+			meta.structEnd()
+			meta.elemEnd()
+		}
+		meta.i64(2, group.bytes)
+		meta.i64(3, group.rows)
+		meta.elemEnd()
+	}
+	meta.binary(6, parquetCreatedBy)
+	meta.end()
+
+	if err := p.write(meta.buf.Bytes()); err != nil {
+		return err
+	}
+	var footer [4]byte
+	binary.LittleEndian.PutUint32(footer[:], uint32(meta.buf.Len()))
+	if err := p.write(footer[:]); err != nil {
+		return err
+	}
+	return p.write([]byte(parquetMagic))
+}
+
+// Thrift Compact 协议的类型编号
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter Thrift Compact 协议编码，只实现 Parquet 元数据用到的类型
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16 //各层结构体上一个字段的编号
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	t.buf.Write(b[:n])
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func (t *thriftWriter) field(typ byte, id int16) {
+	top := len(t.last) - 1
+	if delta := id - t.last[top]; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(zigzag(int64(id)))
+	}
+	t.last[top] = id
+}
+
+func (t *thriftWriter) begin() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) end() {
+	t.buf.WriteByte(0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(thriftI32, id)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(thriftI64, id)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) binary(id int16, v string) {
+	t.field(thriftBinary, id)
+	t.varint(uint64(len(v)))
+	t.buf.WriteString(v)
+}
+
+func (t *thriftWriter) structBegin(id int16) {
+	t.field(thriftStruct, id)
+	t.begin()
+}
+
+func (t *thriftWriter) structEnd() {
+	t.end()
+}
+
+func (t *thriftWriter) listBegin(id int16, elemType byte, size int) {
+	t.field(thriftList, id)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		t.buf.WriteByte(0xf0 | elemType)
+		t.varint(uint64(size))
+	}
+}
+
+// elemBegin、elemEnd 列表中的结构体元素
+func (t *thriftWriter) elemBegin() {
+	t.begin()
+}
+
+func (t *thriftWriter) elemEnd() {
+	t.end()
+}
+
+func (t *thriftWriter) listI32(v int32) {
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) listBinary(v string) {
+	t.varint(uint64(len(v)))
+	t.buf.WriteString(v)
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// thriftReader Thrift Compact 协议解码，结构体解码为 字段编号 -> 值，用于读回写入的 Parquet 文件
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (t *thriftReader) byte() byte {
+	b := t.data[t.pos]
+	t.pos++
+	return b
+}
+
+func (t *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(t.data[t.pos:])
+	if n <= 0 {
+		panic("无效的 varint")
+	}
+	t.pos += n
+	return v
+}
+
+func (t *thriftReader) zigzag() int64 {
+	v := t.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (t *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case 1, 2: //bool 的值编码在类型中
+		return typ == 1
+	case thriftI32, thriftI64:
+		return t.zigzag()
+	case thriftBinary:
+		n := int(t.varint())
+		v := string(t.data[t.pos : t.pos+n])
+		t.pos += n
+		return v
+	case thriftList:
+		header := t.byte()
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(t.varint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = t.value(header & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		return t.structure()
+	default:
+		panic(fmt.Sprintf("不支持的 Thrift 类型: %d", typ))
+	}
+}
+
+func (t *thriftReader) structure() map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var last int16
+	for {
+		header := t.byte()
+		if header == 0 {
+			return fields
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(t.zigzag())
+		}
+		fields[id] = t.value(header & 0x0f)
+		last = id
+	}
+}
+
+// readParquet 按文件元数据读回全部列，int64、timestamp 列为 int64，string 列为 string
+func readParquet(t *testing.T, data []byte) (names []string, types []int64, columns [][]interface{}, rowGroups int) {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte(parquetMagic)) || !bytes.HasSuffix(data, []byte(parquetMagic)) {
+		t.Fatal("文件首尾缺少 PAR1")
+	}
+	metaLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	metaStart := len(data) - 8 - metaLen
+	meta := (&thriftReader{data: data[:len(data)-8], pos: metaStart}).structure()
+
+	schema := meta[2].([]interface{})
+	root := schema[0].(map[int16]interface{})
+	if root[5].(int64) != int64(len(schema)-1) {
+		t.Fatalf("根节点子节点数 = %d, 列数 = %d", root[5], len(schema)-1)
+	}
+	for _, element := range schema[1:] {
+		e := element.(map[int16]interface{})
+		names = append(names, e[4].(string))
+		converted := int64(-1)
+		if c, ok := e[6]; ok {
+			converted = c.(int64)
+		}
+		types = append(types, converted)
+		if e[3].(int64) != parquetRepetitionRequired {
+			t.Errorf("列 %s 不是 REQUIRED", e[4])
+		}
+	}
+	columns = make([][]interface{}, len(names))
+
+	var total int64
+	for _, g := range meta[4].([]interface{}) {
+		group := g.(map[int16]interface{})
+		rows := group[3].(int64)
+		total += rows
+		for i, c := range group[1].([]interface{}) {
+			chunk := c.(map[int16]interface{})[3].(map[int16]interface{})
+			if chunk[3].([]interface{})[0].(string) != names[i] {
+				t.Fatalf("列块路径 = %v, want %s", chunk[3], names[i])
+			}
+			if chunk[5].(int64) != rows {
+				t.Fatalf("列块值数 = %d, 行组行数 = %d", chunk[5], rows)
+			}
+			r := &thriftReader{data: data, pos: int(chunk[9].(int64))}
+			header := r.structure()
+			page := header[5].(map[int16]interface{})
+			if header[1].(int64) != parquetPageData || page[2].(int64) != parquetEncodingPlain || page[1].(int64) != rows {
+				t.Fatalf("数据页头无效: %v", header)
+			}
+			if int64(r.pos)-chunk[9].(int64)+header[3].(int64) != chunk[7].(int64) {
+				t.Fatalf("列块大小 = %d, 页头 + 数据 = %d", chunk[7], int64(r.pos)-chunk[9].(int64)+header[3].(int64))
+			}
+			values := data[r.pos : r.pos+int(header[3].(int64))]
+			for n := int64(0); n < rows; n++ {
+				switch chunk[1].(int64) {
+				case parquetTypeInt64:
+					columns[i] = append(columns[i], int64(binary.LittleEndian.Uint64(values)))
+					values = values[8:]
+				case parquetTypeByteArray:
+					size := binary.LittleEndian.Uint32(values)
+					columns[i] = append(columns[i], string(values[4:4+size]))
+					values = values[4+size:]
+				default:
+					t.Fatalf("未知的物理类型: %d", chunk[1])
+				}
+			}
+			if len(values) != 0 {
+				t.Fatalf("列 %s 数据页剩余 %d 字节", names[i], len(values))
+			}
+		}
+		rowGroups++
+	}
+	if meta[3].(int64) != total {
+		t.Fatalf("num_rows = %d, 行组合计 %d", meta[3], total)
+	}
+	return names, types, columns, rowGroups
+}
+
+func TestParquetWriterRoundTrip(t *testing.T) {
+	columns := []ExportColumn{{"id", ColumnInt64}, {"address", ColumnString}, {"created_at", ColumnTimestamp}}
+	var buf bytes.Buffer
+	w, err := NewParquetWriter(&buf, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//超过一个行组，最后一个行组不满
+	rows := parquetRowGroupSize + 3
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < rows; i++ {
+		if err := w.WriteRow([]interface{}{int64(i) - 1, fmt.Sprintf("地址-%d", i), base.Add(time.Duration(i) * time.Millisecond)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	names, types, values, rowGroups := readParquet(t, buf.Bytes())
+	if rowGroups != 2 {
+		t.Errorf("rowGroups = %d, want 2", rowGroups)
+	}
+	wantTypes := []int64{-1, parquetConvertedUTF8, parquetConvertedTimestampMillis}
+	for i, column := range columns {
+		if names[i] != column.Name || types[i] != wantTypes[i] {
+			t.Errorf("列 %d = %s/%d, want %s/%d", i, names[i], types[i], column.Name, wantTypes[i])
+		}
+		if len(values[i]) != rows {
+			t.Fatalf("列 %s 读回 %d 行, want %d", column.Name, len(values[i]), rows)
+		}
+	}
+	for i := 0; i < rows; i++ {
+		if values[0][i] != int64(i)-1 || values[1][i] != fmt.Sprintf("地址-%d", i) ||
+			values[2][i] != base.Add(time.Duration(i)*time.Millisecond).UnixMilli() {
+			t.Fatalf("第 %d 行 = %v/%v/%v", i, values[0][i], values[1][i], values[2][i])
+		}
+	}
+}
+
+func TestParquetWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewParquetWriter(&buf, []ExportColumn{{"id", ColumnInt64}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	names, _, values, rowGroups := readParquet(t, buf.Bytes())
+	if len(names) != 1 || rowGroups != 0 || len(values[0]) != 0 {
+		t.Errorf("names = %v, rowGroups = %d, values = %v", names, rowGroups, values)
+	}
+}
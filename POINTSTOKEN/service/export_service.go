@@ -0,0 +1,198 @@
+package service
+
+import (
+	"POINTSTOKEN/db"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 导出的数据集
+const (
+	ExportBalances       = "balances"
+	ExportBalanceChanges = "balance-changes"
+	ExportLedger         = "ledger"
+)
+
+// 导出格式
+const (
+	ExportCSV     = "csv"
+	ExportParquet = "parquet"
+)
+
+// exportColumns 各数据集的列，金额以十进制字符串导出避免精度丢失
+var exportColumns = map[string][]ExportColumn{
+	ExportBalances: {
+		{"chain_id", ColumnInt64},
+		{"address", ColumnString},
+		{"balance", ColumnString},
+		{"updated_at", ColumnTimestamp},
+	},
+	ExportBalanceChanges: {
+		{"id", ColumnInt64},
+		{"chain_id", ColumnInt64},
+		{"address", ColumnString},
+		{"tx_hash", ColumnString},
+		{"block_number", ColumnInt64},
+		{"event_type", ColumnString},
+		{"change_amount", ColumnString},
+		{"balance_after", ColumnString},
+		{"created_at", ColumnTimestamp},
+	},
+	ExportLedger: {
+		{"id", ColumnInt64},
+		{"chain_id", ColumnInt64},
+		{"entry_type", ColumnString},
+		{"debit_account", ColumnString},
+		{"credit_account", ColumnString},
+		{"amount", ColumnString},
+		{"idempotency_key", ColumnString},
+		{"memo", ColumnString},
+		{"operator", ColumnString},
+		{"created_at", ColumnTimestamp},
+	},
+}
+
+// ExportRequest 导出参数，From、To 为时间段 [From, To)，余额快照忽略时间段
+type ExportRequest struct {
+	Dataset string
+	Format  string
+	ChainID uint64
+	From    time.Time
+	To      time.Time
+}
+
+// Validate 检查导出参数
+func (req *ExportRequest) Validate() error {
+	if _, ok := exportColumns[req.Dataset]; !ok {
+		return fmt.Errorf("无效的数据集: %s", req.Dataset)
+	}
+	if req.Format != ExportCSV && req.Format != ExportParquet {
+		return fmt.Errorf("无效的导出格式: %s", req.Format)
+	}
+	if req.ChainID == 0 {
+		return fmt.Errorf("请指定链 ID")
+	}
+	if req.Dataset != ExportBalances && !req.To.After(req.From) {
+		return fmt.Errorf("结束时间必须晚于开始时间")
+	}
+	return nil
+}
+
+// FileName 导出文件的默认名称
+func (req *ExportRequest) FileName() string {
+	return fmt.Sprintf("%s-%d.%s", req.Dataset, req.ChainID, req.Format)
+}
+
+// rowWriter 导出文件的写入方式
+type rowWriter interface {
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+// csvRowWriter 以 CSV 写入，首行为列名，时间为 RFC3339 格式
+type csvRowWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVRowWriter(w io.Writer, columns []ExportColumn) (*csvRowWriter, error) {
+	c := &csvRowWriter{w: csv.NewWriter(w), record: make([]string, len(columns))}
+	for i, column := range columns {
+		c.record[i] = column.Name
+	}
+	if err := c.w.Write(c.record); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *csvRowWriter) WriteRow(values []interface{}) error {
+	for i, value := range values {
+		switch v := value.(type) {
+		case int64:
+			c.record[i] = strconv.FormatInt(v, 10)
+		case time.Time:
+			c.record[i] = v.UTC().Format(time.RFC3339)
+		case string:
+			c.record[i] = csvEscape(v)
+		default:
+			return fmt.Errorf("值类型不支持: %T", value)
+		}
+	}
+	return c.w.Write(c.record)
+}
+
+// csvEscape 以 = + - @ 制表符或回车开头的文本在表格软件中会被当作公式执行，前面加单引号；
+// 负数金额等纯整数保持原样
+func csvEscape(v string) string {
+	if v == "" || !strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return v
+	}
+	if _, ok := new(big.Int).SetString(v, 10); ok {
+		return v
+	}
+	return "'" + v
+}
+
+func (c *csvRowWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// ExportService 余额、余额变动和积分分录的导出，逐行读取数据库并写出，不在内存中保存完整结果
+type ExportService struct {
+	repo db.Repository
+}
+
+func NewExportService(repo db.Repository) *ExportService {
+	return &ExportService{repo: repo}
+}
+
+// Export 将数据集写入 w，返回导出的行数
+func (e *ExportService) Export(w io.Writer, req ExportRequest) (int64, error) {
+	if err := req.Validate(); err != nil {
+		return 0, err
+	}
+	columns := exportColumns[req.Dataset]
+	var out rowWriter
+	var err error
+	if req.Format == ExportParquet {
+		out, err = NewParquetWriter(w, columns)
+	} else {
+		out, err = newCSVRowWriter(w, columns)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var rows int64
+	write := func(values ...interface{}) error {
+		rows++
+		return out.WriteRow(values)
+	}
+	switch req.Dataset {
+	case ExportBalances:
+		err = e.repo.StreamUserBalances(req.ChainID, func(b *db.ExportBalance) error {
+			return write(int64(b.ChainID), b.UserAddr.Hex(), b.Balance.ToBigInt().String(), b.UpdatedAt)
+		})
+	case ExportBalanceChanges:
+		err = e.repo.StreamBalanceChanges(req.ChainID, req.From, req.To, func(c *db.UserBalanceChange) error {
+			return write(int64(c.ID), int64(c.ChainID), c.UserAddr.Hex(), c.TxHash.Hex(), int64(c.BlockNumber),
+				c.EventType, c.BalanceChange.ToBigInt().String(), c.BalanceAfter.ToBigInt().String(), c.CreatedAt)
+		})
+	case ExportLedger:
+		err = e.repo.StreamLedgerEntries(req.ChainID, req.From, req.To, func(entry *db.LedgerEntry) error {
+			return write(int64(entry.ID), int64(entry.ChainID), entry.EntryType, entry.DebitAccount, entry.CreditAccount,
+				entry.Amount.ToBigInt().String(), entry.IdempotencyKey, entry.Memo, entry.Operator, entry.CreatedAt)
+		})
+	}
+	if err != nil {
+		return rows, err
+	}
+	return rows, out.Close()
+}
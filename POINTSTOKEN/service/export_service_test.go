@@ -0,0 +1,64 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+)
+
+func TestCSVEscape(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"0x00000000000000000000000000000000000000a1", "0x00000000000000000000000000000000000000a1"},
+		{"-1000000000000000000000", "-1000000000000000000000"},
+		{"+5", "+5"},
+		{"=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"},
+		{"+cmd|' /C calc'!A0", "'+cmd|' /C calc'!A0"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"\r=1", "'\r=1"},
+		{"补发 =1", "补发 =1"},
+	}
+	for _, tt := range tests {
+		if got := csvEscape(tt.value); got != tt.want {
+			t.Errorf("csvEscape(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCSVRowWriter(t *testing.T) {
+	var buf bytes.Buffer
+	columns := []ExportColumn{{"id", ColumnInt64}, {"memo", ColumnString}, {"amount", ColumnString}, {"created_at", ColumnTimestamp}}
+	w, err := newCSVRowWriter(&buf, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 1, 1, 8, 0, 0, 0, time.FixedZone("UTC+8", 8*3600))
+	if err := w.WriteRow([]interface{}{int64(1), "=1+1", "-5", at}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"id", "memo", "amount", "created_at"}, {"1", "'=1+1", "-5", "2026-01-01T00:00:00Z"}}
+	if len(records) != len(want) {
+		t.Fatalf("records = %v, want %v", records, want)
+	}
+	for i := range want {
+		for j := range want[i] {
+			if records[i][j] != want[i][j] {
+				t.Errorf("records[%d][%d] = %q, want %q", i, j, records[i][j], want[i][j])
+			}
+		}
+	}
+}
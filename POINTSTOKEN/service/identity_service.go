@@ -0,0 +1,97 @@
+package service
+
+import (
+	"POINTSTOKEN/db"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"log"
+	"time"
+)
+
+// WalletProof 钱包对关联消息的 personal_sign 签名
+type WalletProof struct {
+	Address   common.Address
+	Signature string
+}
+
+// IdentityService 地址关联服务，通过 EIP-191 签名验证钱包所有权
+type IdentityService struct {
+	db           db.Repository
+	signatureTTL time.Duration
+}
+
+func NewIdentityService(repo db.Repository, signatureTTL time.Duration) *IdentityService {
+	return &IdentityService{
+		db:           repo,
+		signatureTTL: signatureTTL,
+	}
+}
+
+// LinkMessage 返回钱包需要签名的关联消息
+func LinkMessage(identityAddr common.Address, wallet common.Address, issuedAt time.Time) string {
+	return fmt.Sprintf("POINTSTOKEN 地址关联\n"+
+		"Account: %s\n"+
+		"Wallet: %s\n"+
+		"Issued At: %s", identityAddr.Hex(), wallet.Hex(), issuedAt.UTC().Format(time.RFC3339))
+}
+
+// LinkWallets 验证每个钱包的签名后将其关联到 identityAddr，identityAddr 自身也必须签名
+func (s *IdentityService) LinkWallets(identityAddr common.Address, issuedAt time.Time, proofs []WalletProof) error {
+	if time.Since(issuedAt) > s.signatureTTL || time.Until(issuedAt) > time.Minute {
+		return errors.New("签名消息已过期")
+	}
+
+	signedByIdentity := false
+	wallets := make([]db.LinkedWallet, 0, len(proofs))
+	for _, proof := range proofs {
+		message := LinkMessage(identityAddr, proof.Address, issuedAt)
+		if err := VerifyPersonalSign(proof.Address, message, proof.Signature); err != nil {
+			return fmt.Errorf("钱包 %s 签名验证失败: %w", proof.Address.Hex(), err)
+		}
+		if proof.Address == identityAddr {
+			signedByIdentity = true
+		}
+		wallets = append(wallets, db.LinkedWallet{UserAddr: proof.Address, Signature: proof.Signature})
+	}
+	if !signedByIdentity {
+		return fmt.Errorf("缺少账户地址 %s 的签名", identityAddr.Hex())
+	}
+
+	if err := s.db.LinkAddresses(identityAddr, wallets); err != nil {
+		return fmt.Errorf("保存地址关联失败: %w", err)
+	}
+	log.Printf("账户 %s 关联了 %d 个钱包", identityAddr.Hex(), len(wallets))
+	return nil
+}
+
+// GetLinkedAddresses 获取与地址属于同一用户的全部地址
+func (s *IdentityService) GetLinkedAddresses(userAddr common.Address) ([]common.Address, error) {
+	return s.db.GetLinkedAddresses(userAddr)
+}
+
+// VerifyPersonalSign 验证 EIP-191 personal_sign 签名是否由 addr 签出
+func VerifyPersonalSign(addr common.Address, message string, signature string) error {
+	sig, err := hexutil.Decode(signature)
+	if err != nil {
+		return fmt.Errorf("无效的签名格式: %w", err)
+	}
+	if len(sig) != crypto.SignatureLength {
+		return fmt.Errorf("签名长度应为 %d 字节", crypto.SignatureLength)
+	}
+	//钱包返回的 v 为 27/28
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pubKey, err := crypto.SigToPub(accounts.TextHash([]byte(message)), sig)
+	if err != nil {
+		return fmt.Errorf("恢复公钥失败: %w", err)
+	}
+	if crypto.PubkeyToAddress(*pubKey) != addr {
+		return errors.New("签名地址不匹配")
+	}
+	return nil
+}
@@ -0,0 +1,375 @@
+package service
+
+import (
+	"POINTSTOKEN/config"
+	"POINTSTOKEN/db"
+	. "POINTSTOKEN/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// 排行榜统计窗口
+const (
+	WindowTotal    = "total"    //当前总积分
+	WindowDaily    = "daily"    //当天获得的积分（UTC）
+	WindowWeekly   = "weekly"   //本周获得的积分（ISO 周，UTC）
+	WindowCampaign = "campaign" //活动期间获得的积分
+)
+
+const (
+	leaderboardConsumer = "leaderboard" //发件箱消费者名称
+	leaderboardBatch    = 500
+)
+
+// campaign 解析后的活动时间段
+type campaign struct {
+	name  string
+	start time.Time
+	end   time.Time
+}
+
+// LeaderboardRank 用户在排行榜中的名次及前后的用户
+type LeaderboardRank struct {
+	Entry *db.LeaderboardEntry
+	Above []db.LeaderboardEntry
+	Below []db.LeaderboardEntry
+}
+
+// LeaderboardService 排行榜，按发件箱中的积分分录增量更新积分有变化的用户。
+// 榜单以用户标识地址计分，关联的钱包合并为一条记录
+type LeaderboardService struct {
+	repo         db.Repository
+	aggregator   *PointsAggregator
+	chains       []uint64
+	campaigns    map[string]campaign
+	pollInterval time.Duration
+	outbox       *outboxReader
+	wg           sync.WaitGroup
+}
+
+func NewLeaderboardService(cfg config.LeaderboardConfig, chainsConfig []config.ChainConfig,
+	repo db.Repository, aggregator *PointsAggregator) (*LeaderboardService, error) {
+	l := &LeaderboardService{
+		repo:         repo,
+		aggregator:   aggregator,
+		campaigns:    make(map[string]campaign),
+		pollInterval: cfg.PollInterval,
+		outbox:       newOutboxReader(leaderboardConsumer, repo),
+	}
+	for _, chainConfig := range chainsConfig {
+		l.chains = append(l.chains, chainConfig.ChainID)
+	}
+	for _, c := range cfg.Campaigns {
+		start, err := time.Parse(time.RFC3339, c.Start)
+		if err != nil {
+			return nil, fmt.Errorf("活动 %s 开始时间无效: %v", c.Name, err)
+		}
+		end, err := time.Parse(time.RFC3339, c.End)
+		if err != nil {
+			return nil, fmt.Errorf("活动 %s 结束时间无效: %v", c.Name, err)
+		}
+		if c.Name == "" || !end.After(start) {
+			return nil, fmt.Errorf("无效的活动配置: %s", c.Name)
+		}
+		l.campaigns[c.Name] = campaign{name: c.Name, start: start, end: end}
+	}
+	return l, nil
+}
+
+// windowRange 返回统计窗口的榜单名称和时间段，总榜没有时间段
+func (l *LeaderboardService) windowRange(window string, at time.Time, campaignName string) (string, time.Time, time.Time, error) {
+	at = at.UTC()
+	switch window {
+	case WindowTotal, "":
+		return WindowTotal, time.Time{}, time.Time{}, nil
+	case WindowDaily:
+		start := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+		return WindowDaily + ":" + start.Format(time.DateOnly), start, start.AddDate(0, 0, 1), nil
+	case WindowWeekly:
+		day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%s:%d-W%02d", WindowWeekly, year, week), start, start.AddDate(0, 0, 7), nil
+	case WindowCampaign:
+		c, ok := l.campaigns[campaignName]
+		if !ok {
+			return "", time.Time{}, time.Time{}, fmt.Errorf("未知的活动: %s", campaignName)
+		}
+		return WindowCampaign + ":" + c.name, c.start, c.end, nil
+	default:
+		return "", time.Time{}, time.Time{}, fmt.Errorf("无效的排行榜窗口: %s", window)
+	}
+}
+
+// Start 启动排行榜更新，积分计算、手动调整、兑换、过期、衰减和重算写入的分录都经发件箱触发更新，
+// 其他进程（如命令行）写入的分录同样会被处理，ctx 取消后停止
+func (l *LeaderboardService) Start(ctx context.Context) {
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		ticker := time.NewTicker(l.pollInterval)
+		defer ticker.Stop()
+		for {
+			if err := l.consume(); err != nil {
+				log.Printf("更新排行榜失败: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait 等待进行中的更新结束
+func (l *LeaderboardService) Wait() {
+	l.wg.Wait()
+}
+
+// ledgerWrites 一条链上同一天（UTC）写入的分录涉及的用户及分录时间范围
+type ledgerWrites struct {
+	chain uint64
+	from  time.Time
+	to    time.Time
+	users []common.Address
+	seen  map[common.Address]bool
+}
+
+// consume 处理发件箱中新的积分分录，按链和分录日期分组更新涉及的用户
+func (l *LeaderboardService) consume() error {
+	for {
+		events, err := l.outbox.next(leaderboardBatch)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		groups, err := groupLedgerWrites(events)
+		if err != nil {
+			return err
+		}
+		for _, g := range groups {
+			if err := l.refresh(g.chain, g.users, g.from, g.to); err != nil {
+				return fmt.Errorf("更新链 %d 排行榜失败: %v", g.chain, err)
+			}
+		}
+		if err := l.outbox.commit(events); err != nil || len(events) < leaderboardBatch {
+			return err
+		}
+	}
+}
+
+// groupLedgerWrites 将积分分录事件按链和分录日期分组，其他事件忽略
+func groupLedgerWrites(events []db.OutboxEvent) ([]*ledgerWrites, error) {
+	type key struct {
+		chain uint64
+		day   time.Time
+	}
+	index := make(map[key]*ledgerWrites)
+	var groups []*ledgerWrites
+	for _, e := range events {
+		if e.EventType != db.OutboxPoints {
+			continue
+		}
+		var payload db.PointsPayload
+		if err := json.Unmarshal(e.Payload, &payload); err != nil {
+			return nil, fmt.Errorf("解析发件箱事件 %d 失败: %v", e.ID, err)
+		}
+		at := payload.CreatedAt.UTC()
+		if payload.CreatedAt.IsZero() {
+			at = e.CreatedAt.UTC()
+		}
+		k := key{chain: e.ChainID, day: time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)}
+		g, ok := index[k]
+		if !ok {
+			g = &ledgerWrites{chain: e.ChainID, from: at, to: at, seen: make(map[common.Address]bool)}
+			index[k] = g
+			groups = append(groups, g)
+		}
+		if at.Before(g.from) {
+			g.from = at
+		}
+		if at.After(g.to) {
+			g.to = at
+		}
+		if !g.seen[e.UserAddr] {
+			g.seen[e.UserAddr] = true
+			g.users = append(g.users, e.UserAddr)
+		}
+	}
+	return groups, nil
+}
+
+// identities 将地址归并到所属用户，返回用户标识地址及其全部关联地址，顺序与首次出现的顺序一致
+func (l *LeaderboardService) identities(users []common.Address) ([]common.Address, map[common.Address][]common.Address, error) {
+	var order []common.Address
+	members := make(map[common.Address][]common.Address)
+	for _, userAddr := range users {
+		identity, err := l.repo.GetIdentityAddr(userAddr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("获取用户标识地址失败: %v", err)
+		}
+		if _, ok := members[identity]; ok {
+			continue
+		}
+		linked, err := l.repo.GetLinkedAddresses(identity)
+		if err != nil {
+			return nil, nil, fmt.Errorf("获取关联地址失败: %v", err)
+		}
+		order = append(order, identity)
+		members[identity] = linked
+	}
+	return order, members, nil
+}
+
+// refresh 更新用户所在的总榜，以及分录时间 [from, to] 所在的日榜、周榜和期间进行中的活动榜，
+// from 和 to 在同一天
+func (l *LeaderboardService) refresh(chain uint64, users []common.Address, from time.Time, to time.Time) error {
+	identities, members, err := l.identities(users)
+	if err != nil {
+		return err
+	}
+	if err := l.refreshTotal(chain, identities, members); err != nil {
+		return err
+	}
+
+	type window struct {
+		name     string
+		campaign string
+	}
+	windows := []window{{name: WindowDaily}, {name: WindowWeekly}}
+	for name, c := range l.campaigns {
+		//活动结束后入账的积分不计入活动榜，结束前的积分在入账时已更新
+		if !to.Before(c.start) && from.Before(c.end) {
+			windows = append(windows, window{name: WindowCampaign, campaign: name})
+		}
+	}
+	for _, w := range windows {
+		board, start, end, err := l.windowRange(w.name, from, w.campaign)
+		if err != nil {
+			return err
+		}
+		if err := l.refreshWindow(board, chain, identities, members, start, end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveBoard 保存单链榜和跨链榜，并移除已关联到其他用户标识下的钱包的单独记录
+func (l *LeaderboardService) saveBoard(board string, chain uint64, chainEntries []db.LeaderboardEntry,
+	allEntries []db.LeaderboardEntry, merged []common.Address) error {
+	if err := l.repo.SaveLeaderboardScores(board, chain, chainEntries); err != nil {
+		return fmt.Errorf("保存排行榜 %s 失败: %v", board, err)
+	}
+	if err := l.repo.SaveLeaderboardScores(board, 0, allEntries); err != nil {
+		return fmt.Errorf("保存跨链排行榜 %s 失败: %v", board, err)
+	}
+	if err := l.repo.DeleteLeaderboardScores(board, chain, merged); err != nil {
+		return fmt.Errorf("清理排行榜 %s 失败: %v", board, err)
+	}
+	if err := l.repo.DeleteLeaderboardScores(board, 0, merged); err != nil {
+		return fmt.Errorf("清理跨链排行榜 %s 失败: %v", board, err)
+	}
+	return nil
+}
+
+// mergedWallets 返回关联到用户标识下的其他钱包
+func mergedWallets(identities []common.Address, members map[common.Address][]common.Address) []common.Address {
+	var merged []common.Address
+	for _, identity := range identities {
+		for _, addr := range members[identity] {
+			if addr != identity {
+				merged = append(merged, addr)
+			}
+		}
+	}
+	return merged
+}
+
+// refreshTotal 按用户全部关联地址的积分更新单链总榜和跨链总榜
+func (l *LeaderboardService) refreshTotal(chain uint64, identities []common.Address, members map[common.Address][]common.Address) error {
+	var chainEntries, allEntries []db.LeaderboardEntry
+	for _, identity := range identities {
+		aggregated, err := l.aggregator.GetIdentityPoints(identity)
+		if err != nil {
+			return err
+		}
+		chainScore := big.NewInt(0)
+		for _, cp := range aggregated.Chains {
+			if cp.ChainID == chain {
+				chainScore.Add(chainScore, cp.Points)
+			}
+		}
+		chainEntries = append(chainEntries, db.LeaderboardEntry{UserAddr: identity, Score: *FromBigInt(chainScore)})
+		allEntries = append(allEntries, db.LeaderboardEntry{UserAddr: identity, Score: *FromBigInt(aggregated.Total)})
+	}
+	return l.saveBoard(WindowTotal, chain, chainEntries, allEntries, mergedWallets(identities, members))
+}
+
+// refreshWindow 按账本重新统计用户全部关联地址在时间段内获得的积分，更新单链榜和跨链榜
+func (l *LeaderboardService) refreshWindow(board string, chain uint64, identities []common.Address,
+	members map[common.Address][]common.Address, start time.Time, end time.Time) error {
+	var chainEntries, allEntries []db.LeaderboardEntry
+	for _, identity := range identities {
+		chainScore := big.NewInt(0)
+		allScore := big.NewInt(0)
+		for _, chainID := range l.chains {
+			earned := big.NewInt(0)
+			for _, userAddr := range members[identity] {
+				earnedStr, err := l.repo.SumUserEarned(chainID, userAddr, start, end)
+				if err != nil {
+					return fmt.Errorf("统计用户积分失败: %v", err)
+				}
+				if amount, ok := new(big.Int).SetString(earnedStr, 10); ok {
+					earned.Add(earned, amount)
+				}
+			}
+			if chainID == chain {
+				chainScore.Set(earned)
+			}
+			allScore.Add(allScore, l.aggregator.weighted(chainID, earned))
+		}
+		chainEntries = append(chainEntries, db.LeaderboardEntry{UserAddr: identity, Score: *FromBigInt(chainScore)})
+		allEntries = append(allEntries, db.LeaderboardEntry{UserAddr: identity, Score: *FromBigInt(allScore)})
+	}
+	return l.saveBoard(board, chain, chainEntries, allEntries, mergedWallets(identities, members))
+}
+
+// Top 分页获取排行榜，chainID 为 0 时为跨链榜，at 用于确定日榜、周榜
+func (l *LeaderboardService) Top(window string, chainID uint64, at time.Time, campaignName string,
+	offset int, limit int) (string, []db.LeaderboardEntry, error) {
+	board, _, _, err := l.windowRange(window, at, campaignName)
+	if err != nil {
+		return "", nil, err
+	}
+	entries, err := l.repo.GetLeaderboard(board, chainID, offset, limit)
+	return board, entries, err
+}
+
+// Rank 获取地址所属用户在排行榜中的名次及前后各 neighbours 名用户，不在榜上时 Entry 为 nil
+func (l *LeaderboardService) Rank(window string, chainID uint64, at time.Time, campaignName string,
+	userAddr common.Address, neighbours int) (*LeaderboardRank, error) {
+	board, _, _, err := l.windowRange(window, at, campaignName)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := l.repo.GetIdentityAddr(userAddr)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := l.repo.GetLeaderboardEntry(board, chainID, identity)
+	if err != nil || entry == nil {
+		return &LeaderboardRank{Entry: entry}, err
+	}
+	above, below, err := l.repo.GetLeaderboardNeighbours(entry, neighbours)
+	if err != nil {
+		return nil, err
+	}
+	return &LeaderboardRank{Entry: entry, Above: above, Below: below}, nil
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"POINTSTOKEN/db"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestGroupLedgerWrites(t *testing.T) {
+	day1 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	day2 := time.Date(2026, 1, 2, 1, 0, 0, 0, time.UTC)
+	points := func(id uint64, chain uint64, user int, at time.Time) db.OutboxEvent {
+		payload, _ := json.Marshal(db.PointsPayload{ChainID: chain, Address: testAddr(user).Hex(), CreatedAt: at})
+		return db.OutboxEvent{ID: id, EventType: db.OutboxPoints, ChainID: chain, UserAddr: testAddr(user), Payload: payload}
+	}
+
+	events := []db.OutboxEvent{
+		points(1, 1, 1, day1),
+		{ID: 2, EventType: db.OutboxBalanceChange, ChainID: 1, UserAddr: testAddr(9), Payload: []byte(`{}`)},
+		points(3, 1, 2, day1.Add(time.Hour)),
+		points(4, 1, 1, day1.Add(2*time.Hour)),
+		points(5, 1, 1, day2),
+		points(6, 2, 1, day1),
+	}
+	groups, err := groupLedgerWrites(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 3 {
+		t.Fatalf("got %d groups, want 3", len(groups))
+	}
+	g := groups[0]
+	if g.chain != 1 || len(g.users) != 2 || g.users[0] != testAddr(1) || g.users[1] != testAddr(2) {
+		t.Errorf("group 0 = chain %d users %v", g.chain, g.users)
+	}
+	if !g.from.Equal(day1) || !g.to.Equal(day1.Add(2*time.Hour)) {
+		t.Errorf("group 0 range = [%s, %s]", g.from, g.to)
+	}
+	if groups[1].chain != 1 || !groups[1].from.Equal(day2) || len(groups[1].users) != 1 {
+		t.Errorf("group 1 = %+v", groups[1])
+	}
+	if groups[2].chain != 2 || len(groups[2].users) != 1 {
+		t.Errorf("group 2 = %+v", groups[2])
+	}
+}
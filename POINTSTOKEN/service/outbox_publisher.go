@@ -0,0 +1,154 @@
+package service
+
+import (
+	"POINTSTOKEN/db"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	outboxPublishInterval = time.Second //读取发件箱新事件的间隔
+	outboxPublishBatch    = 500
+)
+
+// outboxEventTypes 总线事件类型对应的发件箱事件类型
+var outboxEventTypes = map[string]string{
+	EventBalanceChange: db.OutboxBalanceChange,
+	EventPointsDelta:   db.OutboxPoints,
+}
+
+// OutboxEventTypes 将总线事件类型转换为发件箱事件类型，用于按序号补发
+func OutboxEventTypes(types []string) []string {
+	var result []string
+	for _, t := range types {
+		if outboxType, ok := outboxEventTypes[t]; ok {
+			result = append(result, outboxType)
+		}
+	}
+	return result
+}
+
+// EventFromOutbox 将发件箱事件转换为总线事件，Seq 为发件箱序号
+func EventFromOutbox(e *db.OutboxEvent) (Event, error) {
+	parse := func(value string) *big.Int {
+		n, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			return big.NewInt(0)
+		}
+		return n
+	}
+	switch e.EventType {
+	case db.OutboxBalanceChange:
+		var payload db.BalanceChangePayload
+		if err := json.Unmarshal(e.Payload, &payload); err != nil {
+			return Event{}, fmt.Errorf("解析发件箱事件 %d 失败: %v", e.ID, err)
+		}
+		return Event{Type: EventBalanceChange, Seq: e.Seq, Balance: &BalanceEvent{
+			ID:           payload.ID,
+			ChainID:      payload.ChainID,
+			UserAddr:     common.HexToAddress(payload.Address),
+			TxHash:       common.HexToHash(payload.TxHash),
+			BlockNumber:  payload.BlockNumber,
+			EventType:    payload.EventType,
+			ChangeAmount: parse(payload.ChangeAmount),
+			BalanceAfter: parse(payload.BalanceAfter),
+			Timestamp:    payload.Timestamp,
+		}}, nil
+	case db.OutboxPoints:
+		var payload db.PointsPayload
+		if err := json.Unmarshal(e.Payload, &payload); err != nil {
+			return Event{}, fmt.Errorf("解析发件箱事件 %d 失败: %v", e.ID, err)
+		}
+		return Event{Type: EventPointsDelta, Seq: e.Seq, Points: &PointsEvent{
+			LedgerID:     payload.LedgerID,
+			ChainID:      payload.ChainID,
+			UserAddr:     common.HexToAddress(payload.Address),
+			EntryType:    payload.EntryType,
+			Delta:        parse(payload.Delta),
+			TotalAfter:   parse(payload.TotalAfter),
+			CalculatedAt: payload.CreatedAt,
+		}}, nil
+	default:
+		return Event{}, fmt.Errorf("未知的发件箱事件类型: %s", e.EventType)
+	}
+}
+
+// OutboxPublisher 为新提交的发件箱事件分配序号，再按序号读取并发布到事件总线，
+// 其他进程（如命令行）提交的余额变动和积分分录同样会被分配序号并推送
+type OutboxPublisher struct {
+	repo db.Repository
+	bus  *EventBus
+	wg   sync.WaitGroup
+}
+
+func NewOutboxPublisher(repo db.Repository, bus *EventBus) *OutboxPublisher {
+	return &OutboxPublisher{repo: repo, bus: bus}
+}
+
+// Start 从当前最新的事件之后开始发布，ctx 取消后停止
+func (o *OutboxPublisher) Start(ctx context.Context) error {
+	cursor, err := o.repo.GetOutboxLastSeq()
+	if err != nil {
+		return fmt.Errorf("获取发件箱序号失败: %v", err)
+	}
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+		ticker := time.NewTicker(outboxPublishInterval)
+		defer ticker.Stop()
+		for {
+			var err error
+			if cursor, err = o.publish(cursor); err != nil {
+				log.Printf("发布发件箱事件失败: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// Wait 等待发布结束
+func (o *OutboxPublisher) Wait() {
+	o.wg.Wait()
+}
+
+// publish 为已提交的事件分配序号后发布 cursor 之后的事件，返回发布到的序号
+func (o *OutboxPublisher) publish(cursor uint64) (uint64, error) {
+	for {
+		n, err := o.repo.SequenceOutboxEvents(outboxPublishBatch)
+		if err != nil {
+			return cursor, fmt.Errorf("分配发件箱序号失败: %v", err)
+		}
+		if n < outboxPublishBatch {
+			break
+		}
+	}
+	for {
+		events, err := o.repo.GetOutboxEventsAfter(cursor, nil, db.EventFilter{}, outboxPublishBatch)
+		if err != nil {
+			return cursor, err
+		}
+		for i := range events {
+			e, err := EventFromOutbox(&events[i])
+			if err != nil {
+				log.Printf("%v", err)
+			} else {
+				o.bus.Publish(e)
+			}
+			cursor = events[i].Seq
+		}
+		if len(events) < outboxPublishBatch {
+			return cursor, nil
+		}
+	}
+}
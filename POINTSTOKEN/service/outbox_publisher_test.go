@@ -0,0 +1,88 @@
+package service
+
+import (
+	"POINTSTOKEN/db"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEventFromOutbox(t *testing.T) {
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	payload, _ := json.Marshal(db.PointsPayload{LedgerID: 7, ChainID: 1, Address: testAddr(1).Hex(),
+		EntryType: db.LedgerRedemption, Delta: "-50", TotalAfter: "150", CreatedAt: at})
+	e, err := EventFromOutbox(&db.OutboxEvent{ID: 3, Seq: 9, EventType: db.OutboxPoints, Payload: payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != EventPointsDelta || e.Seq != 9 || e.Points.LedgerID != 7 || e.Points.UserAddr != testAddr(1) ||
+		e.Points.Delta.Int64() != -50 || e.Points.TotalAfter.Int64() != 150 || !e.Points.CalculatedAt.Equal(at) {
+		t.Errorf("points event = %+v %+v", e, e.Points)
+	}
+
+	payload, _ = json.Marshal(db.BalanceChangePayload{ID: 5, ChainID: 1, Address: testAddr(2).Hex(),
+		EventType: "transfer", ChangeAmount: "10", BalanceAfter: "90", Timestamp: at})
+	e, err = EventFromOutbox(&db.OutboxEvent{ID: 4, Seq: 10, EventType: db.OutboxBalanceChange, Payload: payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != EventBalanceChange || e.Seq != 10 || e.Balance.ID != 5 || e.Balance.UserAddr != testAddr(2) ||
+		e.Balance.ChangeAmount.Int64() != 10 || e.Balance.BalanceAfter.Int64() != 90 {
+		t.Errorf("balance event = %+v %+v", e, e.Balance)
+	}
+
+	if _, err := EventFromOutbox(&db.OutboxEvent{EventType: "unknown"}); err == nil {
+		t.Error("expected error for unknown event type")
+	}
+}
+
+func TestOutboxEventTypes(t *testing.T) {
+	got := OutboxEventTypes([]string{EventPointsDelta, EventBalanceChange, "other"})
+	if len(got) != 2 || got[0] != db.OutboxPoints || got[1] != db.OutboxBalanceChange {
+		t.Errorf("OutboxEventTypes = %v", got)
+	}
+}
+
+// sequencingRepo 未分配序号的事件对读取不可见，分配序号后追加到发件箱
+type sequencingRepo struct {
+	outboxRepo
+	pending []db.OutboxEvent
+}
+
+func (r *sequencingRepo) SequenceOutboxEvents(limit int) (int, error) {
+	n := 0
+	for len(r.pending) > 0 && n < limit {
+		e := r.pending[0]
+		r.pending = r.pending[1:]
+		e.Seq = uint64(len(r.events) + 1)
+		r.events = append(r.events, e)
+		n++
+	}
+	return n, nil
+}
+
+func TestOutboxPublisherSequencesBeforePublish(t *testing.T) {
+	repo := &sequencingRepo{}
+	//ID 较大的事件先提交，先获得序号
+	for _, id := range []uint64{5, 3} {
+		payload, _ := json.Marshal(db.PointsPayload{LedgerID: id, ChainID: 1, Address: testAddr(1).Hex(), Delta: "1", TotalAfter: "1"})
+		repo.pending = append(repo.pending, db.OutboxEvent{ID: id, EventType: db.OutboxPoints, ChainID: 1, Payload: payload})
+	}
+	bus := NewEventBus(10)
+	sub := bus.Subscribe(EventFilter{})
+	defer sub.Close()
+
+	cursor, err := NewOutboxPublisher(repo, bus).publish(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor != 2 || len(repo.pending) != 0 {
+		t.Fatalf("cursor = %d, pending = %d, want 2, 0", cursor, len(repo.pending))
+	}
+	for i, want := range []uint64{5, 3} {
+		e := <-sub.C
+		if e.Seq != uint64(i+1) || e.Points.LedgerID != want {
+			t.Errorf("event %d = seq %d ledger %d, want seq %d ledger %d", i, e.Seq, e.Points.LedgerID, i+1, want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"POINTSTOKEN/db"
+	"fmt"
+)
+
+// outboxReader 按持久化的游标顺序读取发件箱事件，重启后从上次的位置继续，事件至少处理一次。
+// 发件箱序号在事件提交后才分配且连续递增，按序号读取不会错过晚提交的事件
+type outboxReader struct {
+	consumer string
+	repo     db.Repository
+	cursor   uint64
+	loaded   bool
+}
+
+func newOutboxReader(consumer string, repo db.Repository) *outboxReader {
+	return &outboxReader{consumer: consumer, repo: repo}
+}
+
+// next 读取游标之后的一批事件
+func (o *outboxReader) next(limit int) ([]db.OutboxEvent, error) {
+	if !o.loaded {
+		cursor, err := o.repo.GetOutboxCursor(o.consumer)
+		if err != nil {
+			return nil, fmt.Errorf("获取发件箱游标失败: %v", err)
+		}
+		o.cursor = cursor
+		o.loaded = true
+	}
+	return o.repo.GetOutboxEventsAfter(o.cursor, nil, db.EventFilter{}, limit)
+}
+
+// commit 处理完一批事件后将游标推进到最后一个事件并保存
+func (o *outboxReader) commit(events []db.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	cursor := events[len(events)-1].Seq
+	if err := o.repo.SaveOutboxCursor(o.consumer, cursor); err != nil {
+		return fmt.Errorf("保存发件箱游标失败: %v", err)
+	}
+	o.cursor = cursor
+	return nil
+}
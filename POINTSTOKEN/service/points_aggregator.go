@@ -0,0 +1,117 @@
+package service
+
+import (
+	"POINTSTOKEN/config"
+	"POINTSTOKEN/db"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+)
+
+// weightScale 权重放大倍数，按整数运算避免浮点误差
+const weightScale = 10000
+
+// ChainPoints 单链积分明细
+type ChainPoints struct {
+	ChainID  uint64
+	Name     string
+	UserAddr common.Address
+	Points   *big.Int
+	Weight   float64
+	Weighted *big.Int
+}
+
+// AggregatedPoints 用户跨链汇总积分
+type AggregatedPoints struct {
+	UserAddr  common.Address
+	Addresses []common.Address //参与汇总的地址，包括关联地址
+	Total     *big.Int         //按链权重加权后的总积分
+	Chains    []ChainPoints
+}
+
+// PointsAggregator 跨链积分汇总，只统计已配置的链
+type PointsAggregator struct {
+	db     db.Repository
+	chains map[uint64]config.ChainConfig
+}
+
+func NewPointsAggregator(chainsConfig []config.ChainConfig, repo db.Repository) *PointsAggregator {
+	chains := make(map[uint64]config.ChainConfig)
+	for _, chainConfig := range chainsConfig {
+		chains[chainConfig.ChainID] = chainConfig
+	}
+	return &PointsAggregator{
+		db:     repo,
+		chains: chains,
+	}
+}
+
+// weighted 返回按链权重加权后的积分，未配置的链权重为0
+func (a *PointsAggregator) weighted(chainID uint64, points *big.Int) *big.Int {
+	chainConfig, ok := a.chains[chainID]
+	if !ok {
+		return big.NewInt(0)
+	}
+	weighted := new(big.Int).Mul(points, big.NewInt(int64(*chainConfig.PointsWeight*weightScale)))
+	return weighted.Div(weighted, big.NewInt(weightScale))
+}
+
+// GetAggregatedPoints 汇总地址（及可选的关联地址）在所有链上的加权积分
+func (a *PointsAggregator) GetAggregatedPoints(userAddr common.Address, linked ...common.Address) (*AggregatedPoints, error) {
+	addresses := []common.Address{userAddr}
+	seen := map[common.Address]bool{userAddr: true}
+	for _, addr := range linked {
+		if !seen[addr] {
+			seen[addr] = true
+			addresses = append(addresses, addr)
+		}
+	}
+
+	userPoints, err := a.db.GetUserPointsAllChains(addresses)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户积分失败: %w", err)
+	}
+
+	result := &AggregatedPoints{
+		UserAddr:  userAddr,
+		Addresses: addresses,
+		Total:     big.NewInt(0),
+	}
+	for _, up := range userPoints {
+		chainConfig, ok := a.chains[up.ChainID]
+		if !ok {
+			continue
+		}
+		points := new(big.Int).Set(up.TotalPoints.ToBigInt())
+		weighted := a.weighted(up.ChainID, points)
+
+		result.Chains = append(result.Chains, ChainPoints{
+			ChainID:  up.ChainID,
+			Name:     chainConfig.Name,
+			UserAddr: up.UserAddr,
+			Points:   points,
+			Weight:   *chainConfig.PointsWeight,
+			Weighted: weighted,
+		})
+		result.Total.Add(result.Total, weighted)
+	}
+	return result, nil
+}
+
+// GetIdentityPoints 汇总地址及其所有关联地址的跨链积分
+func (a *PointsAggregator) GetIdentityPoints(userAddr common.Address) (*AggregatedPoints, error) {
+	linked, err := a.db.GetLinkedAddresses(userAddr)
+	if err != nil {
+		return nil, fmt.Errorf("获取关联地址失败: %w", err)
+	}
+	return a.GetAggregatedPoints(userAddr, linked...)
+}
+
+// WeightedTotal 按链权重汇总一组积分，未配置的链不计入
+func (a *PointsAggregator) WeightedTotal(userPoints []db.UserPoints) *big.Int {
+	total := big.NewInt(0)
+	for _, up := range userPoints {
+		total.Add(total, a.weighted(up.ChainID, up.TotalPoints.ToBigInt()))
+	}
+	return total
+}
@@ -15,14 +15,19 @@ import (
 var ZeroAddress common.Address = common.HexToAddress("0x0000000000000000000000000000000000000000")
 
 type PointsCalculator struct {
-	db       *db.DBRepository
-	cron     *cron.Cron
-	pointCfg *config.PointsConfig
-	entryID  cron.EntryID
-	running  bool
+	db        db.Repository
+	cron      *cron.Cron
+	pointCfg  *config.PointsConfig
+	entryID   cron.EntryID
+	policyID  cron.EntryID
+	running   bool
+	listeners []RunListener
 }
 
-func NewPointsCalculator(pointCfg *config.PointsConfig, db *db.DBRepository) *PointsCalculator {
+// RunListener 单链积分计算完成后的回调，users 为本次参与计算的地址及获得推荐奖励的推荐人
+type RunListener func(summary *RunSummary, users []common.Address)
+
+func NewPointsCalculator(pointCfg *config.PointsConfig, db db.Repository) *PointsCalculator {
 	// 配置 cron 解析器，支持可选的秒字段（6字段或5字段格式）
 	cronParser := cron.NewParser(
 		cron.SecondOptional |
@@ -41,12 +46,22 @@ func NewPointsCalculator(pointCfg *config.PointsConfig, db *db.DBRepository) *Po
 	}
 }
 
+// OnRun 注册积分计算完成后的回调，需在 Start 之前调用
+func (p *PointsCalculator) OnRun(listener RunListener) {
+	p.listeners = append(p.listeners, listener)
+}
+
 func (p *PointsCalculator) Start() error {
 	if p.running {
 		return fmt.Errorf("积分计算服务已在运行")
 	}
 	p.running = true
 
+	//同步配置文件中的排除名单
+	if err := p.syncConfigExclusions(); err != nil {
+		return fmt.Errorf("同步积分排除名单失败: %v", err)
+	}
+
 	//添加定时任务
 	entryID, err := p.cron.AddFunc(p.pointCfg.CronSpec, p.calculatePoints)
 	if err != nil {
@@ -54,6 +69,15 @@ func (p *PointsCalculator) Start() error {
 	}
 	p.entryID = entryID
 
+	//添加积分过期、衰减任务
+	if p.policiesEnabled() {
+		policyID, err := p.cron.AddFunc(p.pointCfg.PolicyCronSpec, p.applyPointsPolicies)
+		if err != nil {
+			return fmt.Errorf("添加过期衰减任务失败: %v", err)
+		}
+		p.policyID = policyID
+	}
+
 	// 立即执行一次，然后按计划运行
 	go p.calculatePoints()
 
@@ -67,6 +91,7 @@ func (p *PointsCalculator) Stop() {
 		return
 	}
 	p.cron.Remove(p.entryID)
+	p.cron.Remove(p.policyID)
 	p.cron.Stop()
 	p.running = false
 	log.Println("积分计算服务已停止")
@@ -80,113 +105,321 @@ func (p *PointsCalculator) calculatePoints() {
 	}
 
 	for _, chain := range chains {
-		if err := p.calculatePointsForChain(chain); err != nil {
-			log.Printf("为链 %s 计算积分失败: %v", chain, err)
+		if err := p.runChain(chain); err != nil {
+			log.Printf("为链 %d 计算积分失败: %v", chain, err)
 		}
 	}
 }
 
-func (p *PointsCalculator) calculatePointsForChain(chain uint64) error {
+// pointsLockTimeout 定时计算等待链积分锁的最长时间
+const pointsLockTimeout = 5 * time.Minute
+
+// runChain 计算单链积分，并在 points_runs 中记录本次运行的时间、规则版本、结果或错误
+func (p *PointsCalculator) runChain(chain uint64) error {
+	//与重算替换互斥，避免替换在本次计算读取上次计算时间之后提交导致重复计分
+	unlock, err := p.db.LockChainPoints(chain, pointsLockTimeout)
+	if err != nil {
+		return fmt.Errorf("获取链积分锁失败: %v", err)
+	}
+	defer unlock()
+
+	now := time.Now()
+	run := &db.PointsRun{
+		ChainID:      chain,
+		RuleVersion:  p.RuleVersion(),
+		StartedAt:    now,
+		CalculatedAt: now,
+	}
+	if err := p.db.CreatePointsRun(run); err != nil {
+		return fmt.Errorf("记录积分计算运行失败: %v", err)
+	}
+
+	summary, err := p.calculatePointsForChain(chain, run.ID, run.CalculatedAt)
+	if summary != nil {
+		run.UsersProcessed = summary.UsersProcessed
+		run.UsersExcluded = summary.UsersExcluded
+		run.UsersCapped = summary.UsersCapped
+		run.UsersPenalized = summary.UsersPenalized
+		run.PointsUncapped = *FromBigInt(summary.PointsUncapped)
+		run.PointsEmitted = *FromBigInt(summary.PointsEmitted)
+		run.ReferralPoints = *FromBigInt(summary.ReferralPoints)
+		run.BudgetScaled = summary.BudgetScaled
+		run.EntriesCount = summary.EntriesCount
+		run.EntriesDigest = summary.EntriesDigest
+	}
+	run.Status = db.RunSucceeded
+	if err != nil {
+		run.Status = db.RunFailed
+		run.Error = err.Error()
+	}
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	if ferr := p.db.FinishPointsRun(run); ferr != nil {
+		log.Printf("更新积分计算运行记录 %d 失败: %v", run.ID, ferr)
+	}
+	return err
+}
+
+// pointsAccrual 单个用户本次积分计算结果
+type pointsAccrual struct {
+	UserAddr  common.Address
+	Balance   BigInt           //最新余额
+	Points    *big.Int         //本次新增积分
+	Uncapped  *big.Int         //应用上限前的新增积分
+	Accrued   *big.Int         //按持仓累计、应用等级倍数和刷量处罚前的积分
+	Referrals []referralReward //按本次新增积分计算、已应用上限的推荐奖励
+}
+
+// calculatePointsForChain 计算单链截至 calculatedAt 的积分并以运行ID写入账本，出错时也返回已完成部分的汇总
+func (p *PointsCalculator) calculatePointsForChain(chain uint64, runID uint64, calculatedAt time.Time) (*RunSummary, error) {
 	log.Printf("")
 	log.Printf("=======================")
 	log.Printf("==========%v===========", chain)
 	log.Printf("=======================")
-	users, err := p.db.GetBalanceChange(chain)
+	result, err := p.accruePoints(chain, calculatedAt, false)
 	if err != nil {
-		return fmt.Errorf("获取用户余额失败: %v", err)
+		return nil, err
 	}
-	if len(users) == 0 {
-		log.Printf("链 %s 上没有需要计算积分的用户", chain)
-		return nil
+	if err := p.db.RecordExclusionAudit(chain, calculatedAt, result.Excluded); err != nil {
+		return nil, fmt.Errorf("记录排除地址失败: %v", err)
 	}
-
-	tx, err := p.db.Db.Begin()
-	if err != nil {
-		return fmt.Errorf("创建事务失败: %v", err)
+	for _, e := range result.Excluded {
+		log.Printf("链:%v, 地址:%s 不参与积分计算: %s (%s)", chain, e.UserAddr.Hex(), e.Reason, e.Source)
+	}
+	summary := result.summary(chain, calculatedAt)
+	//发放积分和推荐奖励按已写入账本的分录累计，中途失败时运行记录与账本一致
+	summary.PointsEmitted = big.NewInt(0)
+	summary.ReferralPoints = big.NewInt(0)
+	for _, skipped := range result.Skipped {
+		_, _, err := p.db.RecordAccrual(chain, runID, skipped.UserAddr, calculatedAt,
+			skipped.Balance.ToBigInt().String(), "0", nil)
+		if err != nil {
+			return summary, fmt.Errorf("记录排除用户计算时间失败: %v", err)
+		}
+	}
+	if len(result.Accruals) == 0 {
+		log.Printf("链 %d 上没有需要计算积分的用户", chain)
+		return summary, nil
 	}
-	defer tx.Rollback()
-	log.Printf("链 %v 上用户: %s ", chain, users)
 
-	userAddr := users[0].UserAddr
-	addPoints := big.NewInt(0)
-	var startTime time.Time
-	var endTime time.Time
-	var balanceAfter BigInt
-	for i, user := range users {
-		if userAddr == ZeroAddress {
-			userAddr = user.UserAddr
+	var touched []common.Address
+	var entries []string
+	for _, accrual := range result.Accruals {
+		touched = append(touched, accrual.UserAddr)
+		//被上限削减或刷量处罚为0的用户也要记录计算时间，这部分积分不会顺延到之后的计算
+		if accrual.Accrued.Sign() == 0 {
 			continue
 		}
-		log.Printf("用户地址：%s", users[i].UserAddr.String())
-		isLast := i == len(users)-1
-		//获取上一次计算积分的时间
-		lastTime, err := p.db.GetPointLastRecordTime(chain, users[i].UserAddr)
+		referrals := referralCredits(accrual, calculatedAt)
+		//积分以 accrual 分录记入账本，推荐奖励和本次计算记录在同一事务中写入
+		totalAfter, _, err := p.db.RecordAccrual(chain, runID, accrual.UserAddr, calculatedAt,
+			accrual.Balance.ToBigInt().String(), accrual.Points.String(), referrals)
+		if err != nil {
+			summary.setEntries(entries)
+			return summary, fmt.Errorf("记录用户积分失败: %v", err)
+		}
+		log.Printf("链:%v， 地址：%s, 新增积分:%s, 用户积分:%s", chain, accrual.UserAddr.String(),
+			accrual.Points.String(), totalAfter)
+		summary.PointsEmitted.Add(summary.PointsEmitted, accrual.Points)
+		if accrual.Points.Sign() > 0 {
+			entries = append(entries, entryLine(db.LedgerAccrual, accrual.UserAddr.Hex(), accrual.Points))
+		}
+
+		for _, referral := range referrals {
+			log.Printf("链:%v, 推荐人:%s, 推荐奖励:%s", chain, referral.Referrer.Hex(), referral.Amount.String())
+			touched = append(touched, referral.Referrer)
+			summary.ReferralPoints.Add(summary.ReferralPoints, referral.Amount)
+			entries = append(entries, entryLine(db.LedgerReferral, referral.Referrer.Hex(), referral.Amount))
+		}
+	}
+	summary.setEntries(entries)
+	log.Printf("链 %d 积分计算完成: 用户 %d, 排除 %d, 刷量处罚 %d, 受上限限制 %d, 上限前积分 %s, 发放积分 %s, 推荐奖励 %s, 预算缩减 %v",
+		chain, summary.UsersProcessed, summary.UsersExcluded, summary.UsersPenalized, summary.UsersCapped, summary.PointsUncapped.String(),
+		summary.PointsEmitted.String(), summary.ReferralPoints.String(), summary.BudgetScaled)
 
-		//有交易记录，还未开始计算积分
-		if lastTime.IsZero() && !isLast {
-			startTime = user.CreatedAt
-			endTime = users[i+1].CreatedAt
-			balanceAfter = user.BalanceAfter
+	for _, listener := range p.listeners {
+		listener(summary, uniqueAddresses(touched))
+	}
+	return summary, nil
+}
+
+// uniqueAddresses 去除重复地址，保持原有顺序
+func uniqueAddresses(addrs []common.Address) []common.Address {
+	seen := make(map[common.Address]bool)
+	var unique []common.Address
+	for _, addr := range addrs {
+		if !seen[addr] {
+			seen[addr] = true
+			unique = append(unique, addr)
 		}
-		//计算最后一条转账后到当前时间的积分
-		if isLast {
-			startTime = user.CreatedAt
-			endTime = time.Now()
-			balanceAfter = user.BalanceAfter
+	}
+	return unique
+}
+
+// accrualResult 单链积分计算结果
+type accrualResult struct {
+	Accruals      []*pointsAccrual
+	Excluded      []db.Exclusion   //本次被排除的地址
+	Skipped       []*pointsAccrual //被排除但本次计分区间有持仓的用户，只记录计算时间
+	BudgetScaled  bool             //是否因纪元预算按比例缩减
+	WashPenalized int              //因刷量被打折或不计分的用户数
+	exclusions    map[common.Address]db.Exclusion
+}
+
+// RunSummary 单链积分计算汇总
+type RunSummary struct {
+	ChainID        uint64
+	CalculatedAt   time.Time
+	UsersProcessed int
+	UsersExcluded  int
+	UsersCapped    int      //因上限被削减的用户数
+	UsersPenalized int      //因刷量被打折或不计分的用户数
+	PointsUncapped *big.Int //应用上限前的持币积分
+	PointsEmitted  *big.Int //实际发放的持币积分
+	ReferralPoints *big.Int //推荐奖励积分
+	BudgetScaled   bool
+	EntriesCount   int    //写入的持币积分、推荐奖励分录数
+	EntriesDigest  string //写入分录的摘要，用于与账本核对
+}
+
+// summary 汇总本次计算结果
+func (r *accrualResult) summary(chain uint64, calculatedAt time.Time) *RunSummary {
+	summary := &RunSummary{
+		ChainID:        chain,
+		CalculatedAt:   calculatedAt,
+		UsersProcessed: len(r.Accruals),
+		UsersExcluded:  len(r.Excluded),
+		PointsUncapped: big.NewInt(0),
+		PointsEmitted:  big.NewInt(0),
+		ReferralPoints: big.NewInt(0),
+		UsersPenalized: r.WashPenalized,
+		BudgetScaled:   r.BudgetScaled,
+	}
+	for _, accrual := range r.Accruals {
+		summary.PointsUncapped.Add(summary.PointsUncapped, accrual.Uncapped)
+		summary.PointsEmitted.Add(summary.PointsEmitted, accrual.Points)
+		for _, reward := range accrual.Referrals {
+			summary.ReferralPoints.Add(summary.ReferralPoints, reward.Amount)
 		}
-		//若该条交易记录的创建时间大于上一次计算积分的时间，则从上一次计算积分的时间开始计算积分
-		log.Printf("CreatedAt:%s  ,lastTime:%s", user.CreatedAt, lastTime)
-		if user.CreatedAt.After(lastTime) && !lastTime.IsZero() {
-			startTime = lastTime
-			endTime = user.CreatedAt
-			balanceAfter = user.BalanceAfter
+		if accrual.Points.Cmp(accrual.Uncapped) < 0 {
+			summary.UsersCapped++
 		}
+	}
+	return summary
+}
 
-		addPoints, err = p.calculate(addPoints, startTime, endTime, balanceAfter)
+// accruePoints 按余额变动记录计算每个用户截至 until 的新增积分，结果尚未写入账本
+// 每段余额持有区间 [变动时间, 下一次变动时间) 按该段余额计分，已计算过的区间从上次计算时间开始
+// dryRun 时不写入任何数据（如刷量标记），用于预览
+func (p *PointsCalculator) accruePoints(chain uint64, until time.Time, dryRun bool) (*accrualResult, error) {
+	changes, err := p.db.GetBalanceChange(chain)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户余额失败: %v", err)
+	}
+	excluded, err := p.loadExclusions(chain)
+	if err != nil {
+		return nil, err
+	}
+	var washActivity map[common.Address][]time.Time
+	if p.pointCfg.WashTrading.Enabled {
+		washActivity, err = p.washActivity(chain, until.Add(-p.pointCfg.WashTrading.Lookback), dryRun)
 		if err != nil {
-			return fmt.Errorf("计算用户积分失败: %v", err)
+			return nil, err
 		}
+	}
 
-		log.Printf("用户地址积分：%s", addPoints.String())
-		flag := !isLast && user.UserAddr != users[i+1].UserAddr
-		if isLast || flag {
+	result := &accrualResult{exclusions: excluded}
+	for _, userChanges := range groupBalanceChanges(changes) {
+		userAddr := userChanges[0].UserAddr
+		balance := userChanges[len(userChanges)-1].BalanceAfter
+		//获取上一次计算积分的时间
+		lastTime, err := p.db.GetPointLastRecordTime(chain, userAddr)
+		if err != nil {
+			return nil, fmt.Errorf("获取用户积分计算时间失败: %v", err)
+		}
 
-			points, err := p.db.GetUserPoints(chain, userAddr)
-			if err != nil {
-				return fmt.Errorf("获取用户积分失败: %v", err)
+		addPoints, err := p.accrueUserPoints(userChanges, lastTime, until)
+		if err != nil {
+			return nil, err
+		}
+		accrued := new(big.Int).Set(addPoints)
+		//被排除的用户不计分，但仍记录计算时间，恢复计分后不会补发排除期间的积分
+		if exclusion, ok := excluded[userAddr]; ok {
+			result.Excluded = append(result.Excluded, exclusion)
+			if addPoints.Sign() > 0 {
+				result.Skipped = append(result.Skipped, &pointsAccrual{
+					UserAddr: userAddr,
+					Balance:  balance,
+					Points:   big.NewInt(0),
+					Uncapped: big.NewInt(0),
+				})
 			}
-			pointsF := new(big.Int)
-			pointsF.SetString(points, 10)
-
-			totalPointsAfter := big.NewInt(0)
-			totalPointsAfter.Add(addPoints, pointsF)
-
-			log.Printf("链:%v， 地址：%s, 用户积分:%s", chain, userAddr.String(), totalPointsAfter.String())
-			value, err := p.db.UpdateUserPoints(chain, userAddr, totalPointsAfter.String())
+			continue
+		}
+		//按用户当前会员等级的倍数放大
+		if len(p.pointCfg.Tiers) > 0 {
+			tier, err := p.db.GetUserTier(chain, userAddr)
 			if err != nil {
-				return err
-			}
-			log.Println("用户总积分:s%", value)
-			if addPoints == big.NewInt(0) {
-				continue
+				return nil, fmt.Errorf("获取用户等级失败: %v", err)
 			}
-			err = p.db.RecordPointsCalculation(chain, userAddr, user.CreatedAt,
-				user.BalanceAfter.ToBigInt().String(),
-				addPoints.String(),
-				totalPointsAfter.String())
-			if err != nil {
-				return err
+			if tier != nil {
+				applyTierMultiplier(addPoints, p.pointCfg.Tiers, tier.Tier)
 			}
+		}
+		//本次计分区间内有刷量活动的用户按配置打折或不计分
+		if hasWashActivity(washActivity[userAddr], lastTime, until) {
+			p.applyWashPenalty(addPoints)
+			result.WashPenalized++
+		}
+		result.Accruals = append(result.Accruals, &pointsAccrual{
+			UserAddr: userAddr,
+			Balance:  balance,
+			Points:   addPoints,
+			Uncapped: new(big.Int).Set(addPoints),
+			Accrued:  accrued,
+		})
+	}
 
-			lastTime = user.CreatedAt
-			userAddr = user.UserAddr
+	if err := p.applyCaps(chain, result, until, p.db); err != nil {
+		return nil, fmt.Errorf("应用积分上限失败: %v", err)
+	}
+	return result, nil
+}
+
+// accrueUserPoints 计算单个用户在 [from, until) 区间内按持有余额累计的积分
+func (p *PointsCalculator) accrueUserPoints(userChanges []db.UserBalanceChange, from time.Time, until time.Time) (*big.Int, error) {
+	addPoints := big.NewInt(0)
+	for i, change := range userChanges {
+		startTime := change.CreatedAt
+		endTime := until
+		if i < len(userChanges)-1 && userChanges[i+1].CreatedAt.Before(until) {
+			endTime = userChanges[i+1].CreatedAt
+		}
+		if startTime.Before(from) {
+			startTime = from
+		}
+		if !endTime.After(startTime) {
+			continue
+		}
+		var err error
+		addPoints, err = p.calculate(addPoints, startTime, endTime, change.BalanceAfter)
+		if err != nil {
+			return nil, fmt.Errorf("计算用户积分失败: %v", err)
 		}
 	}
-	err = tx.Commit()
-	if err != nil {
-		return err
+	return addPoints, nil
+}
+
+// groupBalanceChanges 将按用户、区块排序的余额变动按用户分组
+func groupBalanceChanges(changes []db.UserBalanceChange) [][]db.UserBalanceChange {
+	var groups [][]db.UserBalanceChange
+	for i, change := range changes {
+		if i == 0 || change.UserAddr != changes[i-1].UserAddr {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], change)
 	}
-	return nil
+	return groups
 }
 
 func (p *PointsCalculator) calculate(addPoints *big.Int, startTime time.Time, endTime time.Time, balanceAfter BigInt) (*big.Int, error) {
@@ -198,28 +431,22 @@ func (p *PointsCalculator) calculate(addPoints *big.Int, startTime time.Time, en
 	// 示例：若 Rate=0.01（即 1/100），先转为分子=1、分母=100
 	rateNumerator := big.NewInt(int64(p.pointCfg.Rate * 1000)) // 假设 Rate 保留2位小数，放大1000倍为整数
 	rateDenominator := big.NewInt(1000)                        // 对应分母
-	log.Printf("小时：%f，rate ：%s, balanceAfter: %s", hours, rateNumerator.String(), balanceAfter.ToBigInt().String())
 	// 2. 处理 hours（假设是 int64）
 	sec := float64(60 * 60 * 1e9)
 	hoursBig0 := hours * sec
-	log.Printf("rateNumerator:%s  ,hoursBig0:%f", rateNumerator.String(), hoursBig0)
 	hoursBig := big.NewInt(int64(hoursBig0))
 	bSec := big.NewInt(60 * 60 * 1e9)
-	log.Printf("rateNumerator:%s  ,hoursBig:%s", rateNumerator.String(), hoursBig.String())
 	tempMul1 := new(big.Int).Mul(rateNumerator, hoursBig)
 	tempMul2 := new(big.Int).Mul(bSec, rateDenominator)
 
 	balanceAfterPtr := balanceAfter // 关键：值类型转指针类型
-	log.Printf("balanceAfter:%s  ,tempMul1:%s", balanceAfter.ToBigInt().String(), tempMul1.String())
 	mulResult := new(big.Int).Mul(balanceAfterPtr.ToBigInt(), tempMul1)
 	bigInt := new(big.Int).Div(mulResult, tempMul2)
-	log.Printf("mulResult:%s  ,tempMul2:%s", mulResult.String(), tempMul2.String())
 	// 6. 累加至 addPoints（假设 addPoints 是 *big.Int 类型，需初始化）
 	if addPoints == nil {
 		addPoints = new(big.Int) // 初始化空的 big.Int 指针
 	}
 
 	addPoints.Add(addPoints, bigInt) // 累加：addPoints = addPoints + mulResult
-	log.Printf("addPoints:%s  ,bigInt:%s", addPoints.String(), bigInt.String())
 	return addPoints, nil
 }
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"POINTSTOKEN/config"
+	"POINTSTOKEN/db"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// calcRepo 只实现单链积分计算所需方法的测试仓库，积分计算记录和入账保存在内存中
+type calcRepo struct {
+	db.Repository
+	changes    []db.UserBalanceChange
+	exclusions []db.Exclusion
+	washFlags  []db.WashFlag
+	lastTime   map[common.Address]time.Time
+	credited   map[common.Address]*big.Int
+}
+
+func newCalcRepo(changes ...db.UserBalanceChange) *calcRepo {
+	return &calcRepo{
+		changes:  changes,
+		lastTime: make(map[common.Address]time.Time),
+		credited: make(map[common.Address]*big.Int),
+	}
+}
+
+func (r *calcRepo) GetBalanceChange(chainID uint64) ([]db.UserBalanceChange, error) {
+	return r.changes, nil
+}
+
+func (r *calcRepo) GetExclusions(chainID uint64) ([]db.Exclusion, error) {
+	return r.exclusions, nil
+}
+
+func (r *calcRepo) RecordExclusionAudit(chainID uint64, calculatedAt time.Time, exclusions []db.Exclusion) error {
+	return nil
+}
+
+func (r *calcRepo) GetTransfers(chainID uint64, since time.Time) ([]db.Transfer, error) {
+	return nil, nil
+}
+
+func (r *calcRepo) SaveWashFlags(flags []db.WashFlag) error {
+	return nil
+}
+
+func (r *calcRepo) GetWashFlags(chainID uint64, since time.Time, status string) ([]db.WashFlag, error) {
+	var result []db.WashFlag
+	for _, f := range r.washFlags {
+		if !f.ActivityAt.Before(since) {
+			result = append(result, f)
+		}
+	}
+	return result, nil
+}
+
+func (r *calcRepo) GetPointLastRecordTime(chainId uint64, userAddr common.Address) (time.Time, error) {
+	return r.lastTime[userAddr], nil
+}
+
+func (r *calcRepo) RecordAccrual(chainID uint64, runID uint64, userAddr common.Address, calculatedAt time.Time,
+	balance string, pointsAdded string, referrals []db.ReferralCredit) (string, uint64, error) {
+	if _, ok := r.credited[userAddr]; !ok {
+		r.credited[userAddr] = big.NewInt(0)
+	}
+	r.credited[userAddr].Add(r.credited[userAddr], parsePoints(pointsAdded))
+	r.lastTime[userAddr] = calculatedAt
+	return r.credited[userAddr].String(), 0, nil
+}
+
+func TestCalculatePointsExcludedWindowNotPaid(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	user := testAddr(1)
+	repo := newCalcRepo(testBalanceChange(100, base))
+	repo.exclusions = []db.Exclusion{{UserAddr: user, Reason: "交易所", Source: db.ExclusionSourceManual}}
+	p := &PointsCalculator{pointCfg: &config.PointsConfig{Rate: 1}, db: repo}
+
+	//第一次计算时被排除：不入账，但记录计算时间
+	if _, err := p.calculatePointsForChain(1, 1, base.Add(24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if got := repo.credited[user]; got == nil || got.Sign() != 0 {
+		t.Fatalf("排除期间入账 = %v, want 0", got)
+	}
+	if !repo.lastTime[user].Equal(base.Add(24 * time.Hour)) {
+		t.Fatalf("lastTime = %s, want 排除期间的计算时间", repo.lastTime[user])
+	}
+
+	//恢复计分后只计算恢复后的区间
+	repo.exclusions = nil
+	if _, err := p.calculatePointsForChain(1, 2, base.Add(48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	want, err := p.accrueUserPoints(repo.changes, base.Add(24*time.Hour), base.Add(48*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want.Sign() <= 0 || repo.credited[user].Cmp(want) != 0 {
+		t.Errorf("恢复后入账 = %s, want %s", repo.credited[user], want)
+	}
+}
+
+func TestCalculatePointsWashExcludedWindowNotPaid(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	user := testAddr(1)
+	repo := newCalcRepo(testBalanceChange(100, base))
+	repo.washFlags = []db.WashFlag{{UserAddr: user, FlagType: db.WashRoundTrip, ActivityAt: base.Add(12 * time.Hour)}}
+	wash := testWashConfig()
+	wash.Enabled = true
+	wash.Action = "exclude"
+	wash.Lookback = 24 * time.Hour
+	p := &PointsCalculator{pointCfg: &config.PointsConfig{Rate: 1, WashTrading: wash}, db: repo}
+
+	//第一次计算区间内有刷量活动：不计分，但记录计算时间
+	summary, err := p.calculatePointsForChain(1, 1, base.Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.UsersPenalized != 1 || repo.credited[user] == nil || repo.credited[user].Sign() != 0 {
+		t.Fatalf("penalized = %d, 入账 = %v, want 1, 0", summary.UsersPenalized, repo.credited[user])
+	}
+
+	//标记超出回看窗口后，只计算之后的区间，被处罚的区间不会补发
+	if _, err := p.calculatePointsForChain(1, 2, base.Add(48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	want, err := p.accrueUserPoints(repo.changes, base.Add(24*time.Hour), base.Add(48*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want.Sign() <= 0 || repo.credited[user].Cmp(want) != 0 {
+		t.Errorf("第二次计算后入账 = %s, want %s", repo.credited[user], want)
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"POINTSTOKEN/db"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"time"
+)
+
+// parseCap 解析上限配置，留空表示不限制
+func parseCap(value string) (*big.Int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	capValue, ok := new(big.Int).SetString(value, 10)
+	if !ok || capValue.Sign() < 0 {
+		return nil, fmt.Errorf("无效的积分上限: %s", value)
+	}
+	return capValue, nil
+}
+
+// cappedTypes 计入用户上限和纪元预算的入账类型
+var cappedTypes = []string{db.LedgerAccrual, db.LedgerReferral}
+
+// limitPoints 将新增积分限制在 cap - accrued 以内
+func limitPoints(points *big.Int, capValue *big.Int, accrued *big.Int) {
+	remaining := new(big.Int).Sub(capValue, accrued)
+	if remaining.Sign() < 0 {
+		remaining.SetInt64(0)
+	}
+	if points.Cmp(remaining) > 0 {
+		points.Set(remaining)
+	}
+}
+
+// parsePoints 解析十进制积分，无效时按 0 处理
+func parsePoints(value string) *big.Int {
+	points, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return big.NewInt(0)
+	}
+	return points
+}
+
+// capsLedger 计算上限时查询已发放积分的来源，正式计算使用账本，重算使用内存记录
+type capsLedger interface {
+	SumUserCredits(chainID uint64, userAddr common.Address, entryTypes []string, since time.Time) (string, error)
+	SumChainCredits(chainID uint64, entryTypes []string, since time.Time) (string, error)
+}
+
+// applyCaps 计算推荐奖励并依次应用用户周期上限、用户累计上限和全局纪元预算
+// 持币积分先受上限限制，推荐奖励按限制后的持币积分计算，再计入推荐人自己的上限；
+// 纪元预算不足时持币积分和推荐奖励按 剩余预算/本次合计 等比例缩减
+func (p *PointsCalculator) applyCaps(chain uint64, result *accrualResult, until time.Time, ledger capsLedger) error {
+	caps := p.pointCfg.Caps
+	userPerPeriod, err := parseCap(caps.UserPerPeriod)
+	if err != nil {
+		return err
+	}
+	userLifetime, err := parseCap(caps.UserLifetime)
+	if err != nil {
+		return err
+	}
+	epochBudget, err := parseCap(caps.EpochBudget)
+	if err != nil {
+		return err
+	}
+
+	//本次已分配给用户但尚未入账的积分，同一用户的持币积分和推荐奖励共用上限
+	periodStart := until.Truncate(caps.Period)
+	pending := make(map[common.Address]*big.Int)
+	limit := func(userAddr common.Address, points *big.Int) error {
+		if points.Sign() == 0 {
+			return nil
+		}
+		assigned, ok := pending[userAddr]
+		if !ok {
+			assigned = big.NewInt(0)
+			pending[userAddr] = assigned
+		}
+		if userPerPeriod != nil {
+			accrued, err := ledger.SumUserCredits(chain, userAddr, cappedTypes, periodStart)
+			if err != nil {
+				return fmt.Errorf("获取用户周期积分失败: %v", err)
+			}
+			limitPoints(points, userPerPeriod, new(big.Int).Add(parsePoints(accrued), assigned))
+		}
+		if userLifetime != nil {
+			accrued, err := ledger.SumUserCredits(chain, userAddr, cappedTypes, time.Unix(0, 0))
+			if err != nil {
+				return fmt.Errorf("获取用户累计积分失败: %v", err)
+			}
+			limitPoints(points, userLifetime, new(big.Int).Add(parsePoints(accrued), assigned))
+		}
+		assigned.Add(assigned, points)
+		return nil
+	}
+
+	for _, accrual := range result.Accruals {
+		if err := limit(accrual.UserAddr, accrual.Points); err != nil {
+			return err
+		}
+	}
+	for _, accrual := range result.Accruals {
+		rewards, err := p.computeReferralRewards(accrual, result.exclusions)
+		if err != nil {
+			return err
+		}
+		accrual.Referrals = nil
+		for _, reward := range rewards {
+			if err := limit(reward.Referrer, reward.Amount); err != nil {
+				return err
+			}
+			if reward.Amount.Sign() > 0 {
+				accrual.Referrals = append(accrual.Referrals, reward)
+			}
+		}
+	}
+
+	if epochBudget == nil {
+		return nil
+	}
+	emittedStr, err := ledger.SumChainCredits(chain, cappedTypes, until.Truncate(caps.Epoch))
+	if err != nil {
+		return fmt.Errorf("获取纪元已发放积分失败: %v", err)
+	}
+	remaining := new(big.Int).Set(epochBudget)
+	limitPoints(remaining, epochBudget, parsePoints(emittedStr))
+
+	total := big.NewInt(0)
+	for _, accrual := range result.Accruals {
+		total.Add(total, accrual.Points)
+		for _, reward := range accrual.Referrals {
+			total.Add(total, reward.Amount)
+		}
+	}
+	if total.Cmp(remaining) <= 0 {
+		return nil
+	}
+	result.BudgetScaled = true
+	scale := func(points *big.Int) {
+		points.Mul(points, remaining)
+		points.Div(points, total)
+	}
+	for _, accrual := range result.Accruals {
+		scale(accrual.Points)
+		rewards := accrual.Referrals[:0]
+		for _, reward := range accrual.Referrals {
+			scale(reward.Amount)
+			if reward.Amount.Sign() > 0 {
+				rewards = append(rewards, reward)
+			}
+		}
+		accrual.Referrals = rewards
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"POINTSTOKEN/config"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestLimitPoints(t *testing.T) {
+	tests := []struct {
+		points, capValue, accrued, want int64
+	}{
+		{50, 100, 0, 50},
+		{50, 100, 60, 40},
+		{50, 100, 100, 0},
+		{50, 100, 150, 0},
+		{0, 100, 0, 0},
+	}
+	for _, tt := range tests {
+		points := big.NewInt(tt.points)
+		limitPoints(points, big.NewInt(tt.capValue), big.NewInt(tt.accrued))
+		if points.Int64() != tt.want {
+			t.Errorf("limitPoints(%d, %d, %d) = %d, want %d", tt.points, tt.capValue, tt.accrued, points.Int64(), tt.want)
+		}
+	}
+}
+
+func TestApplyCaps(t *testing.T) {
+	alice := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	bob := common.HexToAddress("0x00000000000000000000000000000000000000b0")
+	until := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	earlier := time.Date(2026, 1, 2, 1, 0, 0, 0, time.UTC)
+	yesterday := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		caps       config.CapsConfig
+		credited   map[common.Address][]int64 //[0] 昨天入账，[1] 今天入账
+		points     map[common.Address]int64
+		want       map[common.Address]int64
+		wantScaled bool
+	}{
+		{
+			name:   "不限制",
+			caps:   config.CapsConfig{Period: 24 * time.Hour, Epoch: 24 * time.Hour},
+			points: map[common.Address]int64{alice: 100, bob: 50},
+			want:   map[common.Address]int64{alice: 100, bob: 50},
+		},
+		{
+			name:     "周期上限扣除本周期已入账",
+			caps:     config.CapsConfig{UserPerPeriod: "120", Period: 24 * time.Hour, Epoch: 24 * time.Hour},
+			credited: map[common.Address][]int64{alice: {500, 30}},
+			points:   map[common.Address]int64{alice: 100, bob: 150},
+			want:     map[common.Address]int64{alice: 90, bob: 120},
+		},
+		{
+			name:     "累计上限",
+			caps:     config.CapsConfig{UserLifetime: "550", Period: 24 * time.Hour, Epoch: 24 * time.Hour},
+			credited: map[common.Address][]int64{alice: {500, 30}},
+			points:   map[common.Address]int64{alice: 100, bob: 50},
+			want:     map[common.Address]int64{alice: 20, bob: 50},
+		},
+		{
+			name:       "纪元预算按比例缩减",
+			caps:       config.CapsConfig{EpochBudget: "100", Period: 24 * time.Hour, Epoch: 24 * time.Hour},
+			credited:   map[common.Address][]int64{alice: {500, 40}},
+			points:     map[common.Address]int64{alice: 80, bob: 40},
+			want:       map[common.Address]int64{alice: 40, bob: 20},
+			wantScaled: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PointsCalculator{pointCfg: &config.PointsConfig{Caps: tt.caps}}
+			ledger := newReplayCredits()
+			for addr, amounts := range tt.credited {
+				ledger.add(addr, yesterday, big.NewInt(amounts[0]))
+				ledger.add(addr, earlier, big.NewInt(amounts[1]))
+			}
+			result := &accrualResult{}
+			for _, addr := range []common.Address{alice, bob} {
+				result.Accruals = append(result.Accruals, &pointsAccrual{
+					UserAddr: addr,
+					Points:   big.NewInt(tt.points[addr]),
+					Uncapped: big.NewInt(tt.points[addr]),
+				})
+			}
+			if err := p.applyCaps(1, result, until, ledger); err != nil {
+				t.Fatal(err)
+			}
+			for _, accrual := range result.Accruals {
+				if got := accrual.Points.Int64(); got != tt.want[accrual.UserAddr] {
+					t.Errorf("%s: points = %d, want %d", accrual.UserAddr.Hex(), got, tt.want[accrual.UserAddr])
+				}
+			}
+			if result.BudgetScaled != tt.wantScaled {
+				t.Errorf("BudgetScaled = %v, want %v", result.BudgetScaled, tt.wantScaled)
+			}
+		})
+	}
+}
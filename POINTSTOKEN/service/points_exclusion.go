@@ -0,0 +1,43 @@
+package service
+
+import (
+	"POINTSTOKEN/db"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// syncConfigExclusions 将配置文件中的排除地址同步到数据库
+func (p *PointsCalculator) syncConfigExclusions() error {
+	exclusions := make([]db.Exclusion, 0, len(p.pointCfg.Exclusions))
+	for _, e := range p.pointCfg.Exclusions {
+		if !common.IsHexAddress(e.Address) {
+			return fmt.Errorf("无效的排除地址: %s", e.Address)
+		}
+		exclusions = append(exclusions, db.Exclusion{
+			ChainID:  e.ChainID,
+			UserAddr: common.HexToAddress(e.Address),
+			Reason:   e.Reason,
+			Source:   db.ExclusionSourceConfig,
+		})
+	}
+	return p.db.ReplaceConfigExclusions(exclusions)
+}
+
+// loadExclusions 加载对链生效的排除名单，零地址始终排除
+func (p *PointsCalculator) loadExclusions(chain uint64) (map[common.Address]db.Exclusion, error) {
+	exclusions, err := p.db.GetExclusions(chain)
+	if err != nil {
+		return nil, fmt.Errorf("获取排除名单失败: %v", err)
+	}
+	excluded := make(map[common.Address]db.Exclusion)
+	//链级别的记录排在全局记录之前，优先生效
+	for _, e := range exclusions {
+		if _, ok := excluded[e.UserAddr]; !ok {
+			excluded[e.UserAddr] = e
+		}
+	}
+	if _, ok := excluded[ZeroAddress]; !ok {
+		excluded[ZeroAddress] = db.Exclusion{UserAddr: ZeroAddress, Reason: "零地址", Source: db.ExclusionSourceConfig}
+	}
+	return excluded, nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"POINTSTOKEN/db"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"log"
+	"math/big"
+	"time"
+)
+
+// policiesEnabled 是否配置了过期或衰减策略
+func (p *PointsCalculator) policiesEnabled() bool {
+	return p.pointCfg.Expiry.Days > 0 || p.pointCfg.Decay.Percent > 0
+}
+
+// applyPointsPolicies 定时执行积分过期与衰减，以 expiry/decay 出账分录记入账本
+func (p *PointsCalculator) applyPointsPolicies() {
+	log.Println("开始执行积分过期、衰减任务...")
+	chains, err := p.db.GetChains()
+	if err != nil {
+		log.Printf("获取链信息失败: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, chain := range chains {
+		users, err := p.db.GetPointsUsers(chain)
+		if err != nil {
+			log.Printf("获取链 %d 积分用户失败: %v", chain, err)
+			continue
+		}
+		for _, userAddr := range users {
+			if p.pointCfg.Expiry.Days > 0 {
+				if err := p.expirePoints(chain, userAddr, now); err != nil {
+					log.Printf("链:%v, 地址:%s 积分过期失败: %v", chain, userAddr.Hex(), err)
+				}
+			}
+			if p.pointCfg.Decay.Percent > 0 {
+				if err := p.decayPoints(chain, userAddr, now); err != nil {
+					log.Printf("链:%v, 地址:%s 积分衰减失败: %v", chain, userAddr.Hex(), err)
+				}
+			}
+		}
+	}
+}
+
+// expirePoints 将入账满 Expiry.Days 天且未被消耗的积分过期
+func (p *PointsCalculator) expirePoints(chain uint64, userAddr common.Address, now time.Time) error {
+	cutoff := now.AddDate(0, 0, -p.pointCfg.Expiry.Days)
+	amount, err := p.db.GetExpirablePoints(chain, userAddr, cutoff)
+	if err != nil {
+		return err
+	}
+	if amount == "0" {
+		return nil
+	}
+	key := fmt.Sprintf("%s:%s:%d", db.LedgerExpiry, userAddr.Hex(), cutoff.Unix())
+	memo := fmt.Sprintf("%s 之前入账的积分已过期", cutoff.Format(time.DateOnly))
+	_, err = p.db.SpendPoints(chain, userAddr, db.LedgerExpiry, amount, key, memo)
+	if err != nil {
+		return err
+	}
+	log.Printf("链:%v, 地址:%s, 过期积分:%s", chain, userAddr.Hex(), amount)
+	return nil
+}
+
+// decayPoints 按余额的 Decay.Percent% 衰减，每个周期只执行一次
+func (p *PointsCalculator) decayPoints(chain uint64, userAddr common.Address, now time.Time) error {
+	//以周期序号作为幂等键，本周期已衰减过则跳过，否则按新余额计算的数量会与已有分录冲突
+	period := now.Unix() / int64(p.pointCfg.Decay.Period.Seconds())
+	key := fmt.Sprintf("%s:%s:%d", db.LedgerDecay, userAddr.Hex(), period)
+	existing, err := p.db.GetLedgerEntryByKey(chain, key)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return nil
+	}
+
+	balanceStr, err := p.db.GetLedgerBalance(chain, userAddr)
+	if err != nil {
+		return err
+	}
+	balance, ok := new(big.Int).SetString(balanceStr, 10)
+	if !ok || balance.Sign() <= 0 {
+		return nil
+	}
+	//百分比放大10000倍为整数，避免浮点误差
+	percent := big.NewInt(int64(p.pointCfg.Decay.Percent * 10000))
+	amount := new(big.Int).Mul(balance, percent)
+	amount.Div(amount, big.NewInt(100*10000))
+	if amount.Sign() == 0 {
+		return nil
+	}
+
+	memo := fmt.Sprintf("按余额 %s 衰减 %v%%", balance.String(), p.pointCfg.Decay.Percent)
+	_, err = p.db.SpendPoints(chain, userAddr, db.LedgerDecay, amount.String(), key, memo)
+	if errors.Is(err, db.ErrIdempotencyConflict) {
+		//并发执行时另一方已完成本周期的衰减
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("链:%v, 地址:%s, 衰减积分:%s", chain, userAddr.Hex(), amount.String())
+	return nil
+}
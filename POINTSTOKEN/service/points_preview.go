@@ -0,0 +1,174 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"io"
+	"math/big"
+	"text/tabwriter"
+	"time"
+)
+
+// PreviewRow 预览中单个用户的积分变化
+type PreviewRow struct {
+	UserAddr      common.Address
+	Balance       *big.Int
+	CurrentPoints *big.Int
+	Uncapped      *big.Int //应用上限前的持币积分
+	Accrual       *big.Int //持币积分
+	Referral      *big.Int //作为推荐人获得的奖励
+	Delta         *big.Int
+	PointsAfter   *big.Int
+}
+
+// PointsPreview 积分计算预览，不写入任何数据
+type PointsPreview struct {
+	ChainID uint64
+	Until   time.Time
+	Rows    []*PreviewRow
+	Summary *RunSummary
+}
+
+// Preview 以当前数据和配置试算链上截至 until 的积分，userAddr 不为空时只输出该地址
+// 上限和预算按全链计算，因此单个地址的结果与正式计算一致
+func (p *PointsCalculator) Preview(chain uint64, until time.Time, userAddr *common.Address) (*PointsPreview, error) {
+	result, err := p.accruePoints(chain, until, true)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make(map[common.Address]*PreviewRow)
+	var order []common.Address
+	rowOf := func(addr common.Address) *PreviewRow {
+		if row, ok := rows[addr]; ok {
+			return row
+		}
+		row := &PreviewRow{
+			UserAddr: addr,
+			Balance:  big.NewInt(0),
+			Uncapped: big.NewInt(0),
+			Accrual:  big.NewInt(0),
+			Referral: big.NewInt(0),
+		}
+		rows[addr] = row
+		order = append(order, addr)
+		return row
+	}
+
+	summary := result.summary(chain, until)
+	for _, accrual := range result.Accruals {
+		row := rowOf(accrual.UserAddr)
+		row.Balance = accrual.Balance.ToBigInt()
+		row.Uncapped = accrual.Uncapped
+		row.Accrual = accrual.Points
+
+		for _, reward := range accrual.Referrals {
+			referrerRow := rowOf(reward.Referrer)
+			referrerRow.Referral.Add(referrerRow.Referral, reward.Amount)
+		}
+	}
+
+	preview := &PointsPreview{
+		ChainID: chain,
+		Until:   until,
+		Summary: summary,
+	}
+	for _, addr := range order {
+		if userAddr != nil && addr != *userAddr {
+			continue
+		}
+		row := rows[addr]
+		current, err := p.db.GetUserPoints(chain, addr)
+		if err != nil {
+			return nil, fmt.Errorf("获取用户积分失败: %v", err)
+		}
+		row.CurrentPoints, _ = new(big.Int).SetString(current, 10)
+		if row.CurrentPoints == nil {
+			row.CurrentPoints = big.NewInt(0)
+		}
+		row.Delta = new(big.Int).Add(row.Accrual, row.Referral)
+		row.PointsAfter = new(big.Int).Add(row.CurrentPoints, row.Delta)
+		preview.Rows = append(preview.Rows, row)
+	}
+	return preview, nil
+}
+
+// previewHeader 预览输出的列
+var previewHeader = []string{"address", "balance", "current_points", "uncapped", "accrual", "referral", "delta", "points_after"}
+
+func (r *PreviewRow) columns() []string {
+	return []string{r.UserAddr.Hex(), r.Balance.String(), r.CurrentPoints.String(), r.Uncapped.String(),
+		r.Accrual.String(), r.Referral.String(), r.Delta.String(), r.PointsAfter.String()}
+}
+
+// WritePreview 按 table、json 或 csv 格式输出预览
+func WritePreview(w io.Writer, preview *PointsPreview, format string) error {
+	switch format {
+	case "json":
+		rows := make([]map[string]string, 0, len(preview.Rows))
+		for _, row := range preview.Rows {
+			item := make(map[string]string)
+			for i, value := range row.columns() {
+				item[previewHeader[i]] = value
+			}
+			rows = append(rows, item)
+		}
+		s := preview.Summary
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string]interface{}{
+			"chain_id": preview.ChainID,
+			"until":    preview.Until,
+			"rows":     rows,
+			"summary": map[string]interface{}{
+				"users_processed": s.UsersProcessed,
+				"users_excluded":  s.UsersExcluded,
+				"users_penalized": s.UsersPenalized,
+				"users_capped":    s.UsersCapped,
+				"points_uncapped": s.PointsUncapped.String(),
+				"points_emitted":  s.PointsEmitted.String(),
+				"referral_points": s.ReferralPoints.String(),
+				"budget_scaled":   s.BudgetScaled,
+			},
+		})
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(previewHeader); err != nil {
+			return err
+		}
+		for _, row := range preview.Rows {
+			if err := cw.Write(row.columns()); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "table", "":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for i, h := range previewHeader {
+			if i > 0 {
+				fmt.Fprint(tw, "\t")
+			}
+			fmt.Fprint(tw, h)
+		}
+		fmt.Fprintln(tw)
+		for _, row := range preview.Rows {
+			for i, value := range row.columns() {
+				if i > 0 {
+					fmt.Fprint(tw, "\t")
+				}
+				fmt.Fprint(tw, value)
+			}
+			fmt.Fprintln(tw)
+		}
+		s := preview.Summary
+		fmt.Fprintf(tw, "\n链 %d 截至 %s: 用户 %d, 排除 %d, 刷量处罚 %d, 受上限限制 %d, 上限前积分 %s, 发放积分 %s, 推荐奖励 %s, 预算缩减 %v\n",
+			preview.ChainID, preview.Until.Format(time.DateTime), s.UsersProcessed, s.UsersExcluded, s.UsersPenalized,
+			s.UsersCapped, s.PointsUncapped.String(), s.PointsEmitted.String(), s.ReferralPoints.String(), s.BudgetScaled)
+		return tw.Flush()
+	default:
+		return fmt.Errorf("不支持的输出格式: %s", format)
+	}
+}
@@ -0,0 +1,412 @@
+package service
+
+import (
+	"POINTSTOKEN/db"
+	. "POINTSTOKEN/types"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"io"
+	"math/big"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// creditSeries 按时间顺序追加的入账及其前缀和
+type creditSeries struct {
+	times  []time.Time
+	prefix []*big.Int
+}
+
+func (c *creditSeries) add(t time.Time, amount *big.Int) {
+	total := new(big.Int).Set(amount)
+	if len(c.prefix) > 0 {
+		total.Add(total, c.prefix[len(c.prefix)-1])
+	}
+	c.times = append(c.times, t)
+	c.prefix = append(c.prefix, total)
+}
+
+// since 返回 since 及之后的入账合计
+func (c *creditSeries) since(t time.Time) *big.Int {
+	if len(c.prefix) == 0 {
+		return big.NewInt(0)
+	}
+	idx := sort.Search(len(c.times), func(i int) bool { return !c.times[i].Before(t) })
+	total := new(big.Int).Set(c.prefix[len(c.prefix)-1])
+	if idx > 0 {
+		total.Sub(total, c.prefix[idx-1])
+	}
+	return total
+}
+
+// replayCredits 重算时在内存中记录的持币积分和推荐奖励入账，代替账本提供上限计算所需的合计
+type replayCredits struct {
+	users map[common.Address]*creditSeries
+	chain creditSeries
+}
+
+func newReplayCredits() *replayCredits {
+	return &replayCredits{users: make(map[common.Address]*creditSeries)}
+}
+
+func (r *replayCredits) add(userAddr common.Address, t time.Time, amount *big.Int) {
+	series, ok := r.users[userAddr]
+	if !ok {
+		series = &creditSeries{}
+		r.users[userAddr] = series
+	}
+	series.add(t, amount)
+	r.chain.add(t, amount)
+}
+
+func (r *replayCredits) SumUserCredits(chainID uint64, userAddr common.Address, entryTypes []string, since time.Time) (string, error) {
+	series, ok := r.users[userAddr]
+	if !ok {
+		return "0", nil
+	}
+	return series.since(since).String(), nil
+}
+
+func (r *replayCredits) SumChainCredits(chainID uint64, entryTypes []string, since time.Time) (string, error) {
+	return r.chain.since(since).String(), nil
+}
+
+// total 返回全部入账合计
+func (c *creditSeries) total() *big.Int {
+	if len(c.prefix) == 0 {
+		return big.NewInt(0)
+	}
+	return new(big.Int).Set(c.prefix[len(c.prefix)-1])
+}
+
+// before 返回 t 之前的入账合计
+func (c *creditSeries) before(t time.Time) *big.Int {
+	return new(big.Int).Sub(c.total(), c.since(t))
+}
+
+// holdingAt 由按时间排序的余额变动得到 at 时的余额及本次连续持有的开始时间，与 GetHoldingInfo 的口径一致
+func holdingAt(changes []db.UserBalanceChange, at time.Time) (*big.Int, time.Time) {
+	balance := big.NewInt(0)
+	var since time.Time
+	for _, change := range changes {
+		if change.CreatedAt.After(at) {
+			break
+		}
+		after := change.BalanceAfter.ToBigInt()
+		if after.Sign() <= 0 {
+			since = time.Time{}
+		} else if since.IsZero() {
+			since = change.CreatedAt
+		}
+		balance = new(big.Int).Set(after)
+	}
+	if balance.Sign() <= 0 {
+		return big.NewInt(0), time.Time{}
+	}
+	return balance, since
+}
+
+// replayTier 按当前等级配置评估用户在 at 时的等级，积分为重放到 at 时的入账加上账本中其他分录
+func replayTier(rules []tierRule, changes []db.UserBalanceChange, credits *creditSeries, other *creditSeries, at time.Time) string {
+	points := big.NewInt(0)
+	if credits != nil {
+		points.Add(points, credits.before(at))
+	}
+	if other != nil {
+		points.Add(points, other.before(at))
+	}
+	if points.Sign() < 0 {
+		points.SetInt64(0)
+	}
+	balance, since := holdingAt(changes, at)
+	var holding time.Duration
+	if !since.IsZero() {
+		holding = at.Sub(since)
+	}
+	return selectTier(rules, points, balance, holding)
+}
+
+// RecomputeRow 单个用户重算积分与线上积分的对比
+type RecomputeRow struct {
+	UserAddr   common.Address
+	Live       *big.Int
+	Accrual    *big.Int
+	Referral   *big.Int
+	Other      *big.Int //发放、兑换、过期等分录净额
+	Recomputed *big.Int
+	Diff       *big.Int //重算 - 线上
+	Shortfall  *big.Int //扣减类分录超过重算积分、无法冲回的部分
+}
+
+// Recomputation 单链从头重算的结果
+type Recomputation struct {
+	ChainID         uint64
+	Until           time.Time
+	Rows            []*RecomputeRow //仅包含有差异的用户
+	Users           int
+	LiveTotal       *big.Int
+	RecomputedTotal *big.Int
+	ShortfallUsers  int //积分已被兑换等分录用掉、无法全额冲回的用户数
+}
+
+// Recompute 按当前配置从第一条余额变动开始重放，重新计算链上截至 until 的积分并写入影子表 user_points_recompute
+// 重放以上限周期为步长逐段计算，依次应用排除名单、等级倍数、刷量处罚、推荐奖励、上限和纪元预算，
+// 其他分录（发放、兑换、过期、衰减等）按账本原样保留。等级按当前配置和重放出的积分、余额、持有时长在每段开始时重新评估，
+// 不使用线上的等级历史。返回与线上积分的差异，确认后由 ApplyRecomputation 替换
+func (p *PointsCalculator) Recompute(chain uint64, until time.Time) (*Recomputation, error) {
+	changes, err := p.db.GetBalanceChange(chain)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户余额失败: %v", err)
+	}
+	excluded, err := p.loadExclusions(chain)
+	if err != nil {
+		return nil, err
+	}
+	var washActivity map[common.Address][]time.Time
+	if p.pointCfg.WashTrading.Enabled {
+		if washActivity, err = p.washActivity(chain, time.Unix(0, 0), true); err != nil {
+			return nil, err
+		}
+	}
+
+	tierRules, err := parseTiers(p.pointCfg.Tiers)
+	if err != nil {
+		return nil, fmt.Errorf("等级配置无效: %v", err)
+	}
+	//其他分录按时间累计，用于评估重放过程中的等级
+	netChanges, err := p.db.GetLedgerNetChanges(chain)
+	if err != nil {
+		return nil, fmt.Errorf("获取账本分录失败: %v", err)
+	}
+	otherSeries := make(map[common.Address]*creditSeries)
+	for _, change := range netChanges {
+		series, ok := otherSeries[change.UserAddr]
+		if !ok {
+			series = &creditSeries{}
+			otherSeries[change.UserAddr] = series
+		}
+		series.add(change.CreatedAt, change.Amount)
+	}
+
+	groups := groupBalanceChanges(changes)
+	accrued := make(map[common.Address]*big.Int)
+	referrals := make(map[common.Address]*big.Int)
+	addTo := func(m map[common.Address]*big.Int, addr common.Address, amount *big.Int) {
+		if _, ok := m[addr]; !ok {
+			m[addr] = big.NewInt(0)
+		}
+		m[addr].Add(m[addr], amount)
+	}
+	balances := make(map[common.Address]BigInt)
+	var start time.Time
+	for _, userChanges := range groups {
+		first, last := userChanges[0], userChanges[len(userChanges)-1]
+		if start.IsZero() || first.CreatedAt.Before(start) {
+			start = first.CreatedAt
+		}
+		balances[first.UserAddr] = last.BalanceAfter
+	}
+
+	step := p.pointCfg.Caps.Period
+	credits := newReplayCredits()
+	for windowStart := start.Truncate(step); !start.IsZero() && windowStart.Before(until); windowStart = windowStart.Add(step) {
+		windowEnd := windowStart.Add(step)
+		if windowEnd.After(until) {
+			windowEnd = until
+		}
+		result := &accrualResult{exclusions: excluded}
+		for _, userChanges := range groups {
+			userAddr := userChanges[0].UserAddr
+			if _, ok := excluded[userAddr]; ok {
+				continue
+			}
+			addPoints, err := p.accrueUserPoints(userChanges, windowStart, windowEnd)
+			if err != nil {
+				return nil, err
+			}
+			if addPoints.Sign() == 0 {
+				continue
+			}
+			if len(tierRules) > 0 {
+				tier := replayTier(tierRules, userChanges, credits.users[userAddr], otherSeries[userAddr], windowStart)
+				applyTierMultiplier(addPoints, p.pointCfg.Tiers, tier)
+			}
+			if hasWashActivity(washActivity[userAddr], windowStart, windowEnd) {
+				p.applyWashPenalty(addPoints)
+			}
+			result.Accruals = append(result.Accruals, &pointsAccrual{
+				UserAddr: userAddr,
+				Points:   addPoints,
+				Uncapped: new(big.Int).Set(addPoints),
+			})
+		}
+		if err := p.applyCaps(chain, result, windowEnd, credits); err != nil {
+			return nil, fmt.Errorf("应用积分上限失败: %v", err)
+		}
+		//推荐奖励与线上计算一样按每个时间段的持币积分计算并受上限限制
+		for _, accrual := range result.Accruals {
+			credits.add(accrual.UserAddr, windowEnd, accrual.Points)
+			addTo(accrued, accrual.UserAddr, accrual.Points)
+			for _, reward := range accrual.Referrals {
+				credits.add(reward.Referrer, windowEnd, reward.Amount)
+				addTo(referrals, reward.Referrer, reward.Amount)
+			}
+		}
+	}
+
+	other := make(map[common.Address]*big.Int)
+	for addr, series := range otherSeries {
+		other[addr] = series.total()
+	}
+	livePoints, err := p.db.GetChainUserPoints(chain)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户积分失败: %v", err)
+	}
+	live := make(map[common.Address]*big.Int)
+	for _, up := range livePoints {
+		live[up.UserAddr] = new(big.Int).Set(up.TotalPoints.ToBigInt())
+	}
+
+	users := make(map[common.Address]bool)
+	for _, m := range []map[common.Address]*big.Int{accrued, referrals, other, live} {
+		for addr := range m {
+			users[addr] = true
+		}
+	}
+	valueOf := func(m map[common.Address]*big.Int, addr common.Address) *big.Int {
+		if v, ok := m[addr]; ok {
+			return v
+		}
+		return big.NewInt(0)
+	}
+
+	recomputation := &Recomputation{
+		ChainID:         chain,
+		Until:           until,
+		Users:           len(users),
+		LiveTotal:       big.NewInt(0),
+		RecomputedTotal: big.NewInt(0),
+	}
+	var shadow []db.RecomputedPoints
+	for addr := range users {
+		row := &RecomputeRow{
+			UserAddr: addr,
+			Live:     valueOf(live, addr),
+			Accrual:  valueOf(accrued, addr),
+			Referral: valueOf(referrals, addr),
+			Other:    valueOf(other, addr),
+		}
+		row.Recomputed = new(big.Int).Add(row.Accrual, row.Referral)
+		row.Recomputed.Add(row.Recomputed, row.Other)
+		//扣减类分录超过重算积分时按0处理，差额作为无法冲回的部分列出
+		row.Shortfall = big.NewInt(0)
+		if row.Recomputed.Sign() < 0 {
+			row.Shortfall.Neg(row.Recomputed)
+			row.Recomputed.SetInt64(0)
+			recomputation.ShortfallUsers++
+		}
+		row.Diff = new(big.Int).Sub(row.Recomputed, row.Live)
+		recomputation.LiveTotal.Add(recomputation.LiveTotal, row.Live)
+		recomputation.RecomputedTotal.Add(recomputation.RecomputedTotal, row.Recomputed)
+		if row.Diff.Sign() != 0 || row.Shortfall.Sign() != 0 {
+			recomputation.Rows = append(recomputation.Rows, row)
+		}
+
+		shadow = append(shadow, db.RecomputedPoints{
+			ChainID:        chain,
+			UserAddr:       addr,
+			Balance:        balances[addr],
+			AccrualPoints:  *FromBigInt(row.Accrual),
+			ReferralPoints: *FromBigInt(row.Referral),
+			OtherPoints:    *FromBigInt(row.Other),
+			TotalPoints:    *FromBigInt(row.Recomputed),
+			ComputedUntil:  until,
+		})
+	}
+	sort.Slice(recomputation.Rows, func(i, j int) bool {
+		return recomputation.Rows[i].UserAddr.Hex() < recomputation.Rows[j].UserAddr.Hex()
+	})
+
+	if err := p.db.SaveRecomputation(chain, shadow); err != nil {
+		return nil, fmt.Errorf("保存重算结果失败: %v", err)
+	}
+	return recomputation, nil
+}
+
+// recomputeHeader 重算差异输出的列
+var recomputeHeader = []string{"address", "live", "accrual", "referral", "other", "recomputed", "diff", "shortfall"}
+
+func (r *RecomputeRow) columns() []string {
+	return []string{r.UserAddr.Hex(), r.Live.String(), r.Accrual.String(), r.Referral.String(),
+		r.Other.String(), r.Recomputed.String(), r.Diff.String(), r.Shortfall.String()}
+}
+
+// WriteRecomputation 按 table、json 或 csv 格式输出重算差异
+func WriteRecomputation(w io.Writer, rc *Recomputation, format string) error {
+	switch format {
+	case "json":
+		rows := make([]map[string]string, 0, len(rc.Rows))
+		for _, row := range rc.Rows {
+			item := make(map[string]string)
+			for i, value := range row.columns() {
+				item[recomputeHeader[i]] = value
+			}
+			rows = append(rows, item)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string]interface{}{
+			"chain_id":         rc.ChainID,
+			"until":            rc.Until,
+			"users":            rc.Users,
+			"live_total":       rc.LiveTotal.String(),
+			"recomputed_total": rc.RecomputedTotal.String(),
+			"shortfall_users":  rc.ShortfallUsers,
+			"rows":             rows,
+		})
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(recomputeHeader); err != nil {
+			return err
+		}
+		for _, row := range rc.Rows {
+			if err := cw.Write(row.columns()); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "table", "":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for i, h := range recomputeHeader {
+			if i > 0 {
+				fmt.Fprint(tw, "\t")
+			}
+			fmt.Fprint(tw, h)
+		}
+		fmt.Fprintln(tw)
+		for _, row := range rc.Rows {
+			for i, value := range row.columns() {
+				if i > 0 {
+					fmt.Fprint(tw, "\t")
+				}
+				fmt.Fprint(tw, value)
+			}
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "\n链 %d 截至 %s: 用户 %d, 有差异 %d, 无法全额冲回 %d, 线上合计 %s, 重算合计 %s\n",
+			rc.ChainID, rc.Until.Format(time.DateTime), rc.Users, len(rc.Rows), rc.ShortfallUsers,
+			rc.LiveTotal.String(), rc.RecomputedTotal.String())
+		return tw.Flush()
+	default:
+		return fmt.Errorf("不支持的输出格式: %s", format)
+	}
+}
+
+// ApplyRecomputation 用影子表中的重算结果替换线上积分，返回被调整的用户数和因积分不足冲回被截断的用户数
+func (p *PointsCalculator) ApplyRecomputation(chain uint64) (int, int, error) {
+	return p.db.ApplyRecomputation(chain)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"POINTSTOKEN/config"
+	"POINTSTOKEN/db"
+	. "POINTSTOKEN/types"
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func testBalanceChange(balance int64, at time.Time) db.UserBalanceChange {
+	return db.UserBalanceChange{UserAddr: testAddr(1), BalanceAfter: *FromBigInt(big.NewInt(balance)), CreatedAt: at}
+}
+
+func TestHoldingAt(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	changes := []db.UserBalanceChange{
+		testBalanceChange(100, base),
+		testBalanceChange(0, base.Add(24*time.Hour)),
+		testBalanceChange(50, base.Add(48*time.Hour)),
+		testBalanceChange(80, base.Add(72*time.Hour)),
+	}
+	tests := []struct {
+		name        string
+		at          time.Time
+		wantBalance int64
+		wantSince   time.Time
+	}{
+		{"首次转入前", base.Add(-time.Hour), 0, time.Time{}},
+		{"首次持有期间", base.Add(time.Hour), 100, base},
+		{"清仓后", base.Add(30 * time.Hour), 0, time.Time{}},
+		{"再次持有从重新转入时开始", base.Add(80 * time.Hour), 80, base.Add(48 * time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balance, since := holdingAt(changes, tt.at)
+			if balance.Int64() != tt.wantBalance || !since.Equal(tt.wantSince) {
+				t.Errorf("holdingAt = (%s, %s), want (%d, %s)", balance, since, tt.wantBalance, tt.wantSince)
+			}
+		})
+	}
+}
+
+func TestReplayTier(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rules, err := parseTiers([]config.TierConfig{
+		{Name: "bronze"},
+		{Name: "silver", MinPoints: "100", MinBalance: "50", MinHolding: 48 * time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := []db.UserBalanceChange{testBalanceChange(100, base)}
+	credits := &creditSeries{}
+	credits.add(base.Add(24*time.Hour), big.NewInt(60))
+	credits.add(base.Add(48*time.Hour), big.NewInt(60))
+	credits.add(base.Add(96*time.Hour), big.NewInt(20))
+	other := &creditSeries{}
+	other.add(base.Add(36*time.Hour), big.NewInt(-30))
+
+	tests := []struct {
+		name    string
+		credits *creditSeries
+		other   *creditSeries
+		at      time.Time
+		want    string
+	}{
+		{"持有时长不足", credits, other, base.Add(24 * time.Hour), "bronze"},
+		{"积分扣除其他分录后不足", credits, other, base.Add(72 * time.Hour), "bronze"},
+		{"不扣除其他分录时满足全部门槛", credits, nil, base.Add(72 * time.Hour), "silver"},
+		{"之后的入账补足积分", credits, other, base.Add(120 * time.Hour), "silver"},
+		{"没有积分", nil, nil, base.Add(120 * time.Hour), "bronze"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replayTier(rules, changes, tt.credits, tt.other, tt.at); got != tt.want {
+				t.Errorf("tier = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteRecomputationShortfall(t *testing.T) {
+	rc := &Recomputation{
+		ChainID: 1,
+		Rows: []*RecomputeRow{{
+			UserAddr: testAddr(1), Live: big.NewInt(0), Accrual: big.NewInt(100), Referral: big.NewInt(0),
+			Other: big.NewInt(-150), Recomputed: big.NewInt(0), Diff: big.NewInt(0), Shortfall: big.NewInt(50),
+		}},
+		Users:           1,
+		LiveTotal:       big.NewInt(0),
+		RecomputedTotal: big.NewInt(0),
+		ShortfallUsers:  1,
+	}
+	var buf bytes.Buffer
+	if err := WriteRecomputation(&buf, rc, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	want := "address,live,accrual,referral,other,recomputed,diff,shortfall\n" +
+		testAddr(1).Hex() + ",0,100,0,-150,0,0,50\n"
+	if buf.String() != want {
+		t.Errorf("csv = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"POINTSTOKEN/db"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"time"
+)
+
+// referralReward 推荐人应得的推荐奖励
+type referralReward struct {
+	Referrer common.Address
+	Level    int
+	Amount   *big.Int
+}
+
+// computeReferralRewards 按层级比例计算被推荐人的各级推荐人应得的推荐奖励
+// 推荐奖励只按持币积分计算，不会再向上级传递；被排除的推荐人不获得奖励
+func (p *PointsCalculator) computeReferralRewards(accrual *pointsAccrual,
+	excluded map[common.Address]db.Exclusion) ([]referralReward, error) {
+	percents := p.pointCfg.Referral.Percents
+	if len(percents) == 0 || accrual.Points.Sign() <= 0 {
+		return nil, nil
+	}
+	referrers, err := p.db.GetReferrers(accrual.UserAddr, len(percents))
+	if err != nil {
+		return nil, fmt.Errorf("获取推荐人失败: %v", err)
+	}
+
+	var rewards []referralReward
+	for level, referrer := range referrers {
+		if _, ok := excluded[referrer]; ok {
+			continue
+		}
+		reward := new(big.Int).Mul(accrual.Points, big.NewInt(int64(percents[level]*10000)))
+		reward.Div(reward, big.NewInt(100*10000))
+		if reward.Sign() <= 0 {
+			continue
+		}
+		rewards = append(rewards, referralReward{Referrer: referrer, Level: level + 1, Amount: reward})
+	}
+	return rewards, nil
+}
+
+// referralCredits 将已应用上限的推荐奖励转换为分录，由 RecordAccrual 与持币积分在同一事务中记入
+func referralCredits(accrual *pointsAccrual, calculatedAt time.Time) []db.ReferralCredit {
+	credits := make([]db.ReferralCredit, 0, len(accrual.Referrals))
+	for _, reward := range accrual.Referrals {
+		credits = append(credits, db.ReferralCredit{
+			Referrer: reward.Referrer,
+			Amount:   reward.Amount,
+			IdempotencyKey: fmt.Sprintf("%s:%s:%s:%d:%d", db.LedgerReferral, reward.Referrer.Hex(), accrual.UserAddr.Hex(),
+				reward.Level, calculatedAt.Unix()),
+			Memo: fmt.Sprintf("%d级推荐 %s", reward.Level, accrual.UserAddr.Hex()),
+		})
+	}
+	return credits
+}
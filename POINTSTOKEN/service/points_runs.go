@@ -0,0 +1,113 @@
+package service
+
+import (
+	"POINTSTOKEN/db"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// RuleVersion 返回积分规则配置的摘要，规则变化后版本随之变化
+func (p *PointsCalculator) RuleVersion() string {
+	data, err := json.Marshal(p.pointCfg)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// entryLine 分录摘要中的一行：类型:账户:数量
+func entryLine(entryType string, account string, amount *big.Int) string {
+	return fmt.Sprintf("%s:%s:%s", entryType, account, amount.String())
+}
+
+// entriesDigest 对分录行排序后计算摘要，与写入顺序无关
+func entriesDigest(lines []string) string {
+	sorted := append([]string(nil), lines...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *RunSummary) setEntries(lines []string) {
+	s.EntriesCount = len(lines)
+	s.EntriesDigest = entriesDigest(lines)
+}
+
+// RunVerification 运行记录与账本的核对结果
+type RunVerification struct {
+	Run            *db.PointsRun
+	LedgerEntries  int
+	LedgerEmitted  *big.Int
+	LedgerReferral *big.Int
+	LedgerDigest   string
+	Mismatches     []string //不一致项，为空表示核对通过
+}
+
+// GetRuns 获取最近的积分计算运行记录
+func (p *PointsCalculator) GetRuns(chain uint64, limit int) ([]db.PointsRun, error) {
+	return p.db.GetPointsRuns(chain, limit)
+}
+
+// GetRun 获取运行记录，不存在时返回 nil
+func (p *PointsCalculator) GetRun(id uint64) (*db.PointsRun, error) {
+	return p.db.GetPointsRun(id)
+}
+
+// VerifyRun 按运行ID从账本中取回本次写入的分录，核对分录数、发放积分、推荐奖励和摘要
+func (p *PointsCalculator) VerifyRun(id uint64) (*RunVerification, error) {
+	run, err := p.db.GetPointsRun(id)
+	if err != nil {
+		return nil, err
+	}
+	if run == nil {
+		return nil, fmt.Errorf("运行记录 %d 不存在", id)
+	}
+	entries, err := p.db.GetRunLedgerEntries(run.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取运行分录失败: %v", err)
+	}
+
+	v := &RunVerification{
+		Run:            run,
+		LedgerEntries:  len(entries),
+		LedgerEmitted:  big.NewInt(0),
+		LedgerReferral: big.NewInt(0),
+	}
+	var lines []string
+	for _, entry := range entries {
+		amount := entry.Amount.ToBigInt()
+		switch entry.EntryType {
+		case db.LedgerAccrual:
+			v.LedgerEmitted.Add(v.LedgerEmitted, amount)
+		case db.LedgerReferral:
+			v.LedgerReferral.Add(v.LedgerReferral, amount)
+		}
+		lines = append(lines, entryLine(entry.EntryType, entry.CreditAccount, amount))
+	}
+	v.LedgerDigest = entriesDigest(lines)
+
+	if run.Status == db.RunRunning {
+		v.Mismatches = append(v.Mismatches, "运行尚未结束")
+	}
+	if v.LedgerEntries != run.EntriesCount {
+		v.Mismatches = append(v.Mismatches, fmt.Sprintf("分录数: 记录 %d, 账本 %d", run.EntriesCount, v.LedgerEntries))
+	}
+	if v.LedgerEmitted.Cmp(run.PointsEmitted.ToBigInt()) != 0 {
+		v.Mismatches = append(v.Mismatches, fmt.Sprintf("发放积分: 记录 %s, 账本 %s",
+			run.PointsEmitted.ToBigInt().String(), v.LedgerEmitted.String()))
+	}
+	if v.LedgerReferral.Cmp(run.ReferralPoints.ToBigInt()) != 0 {
+		v.Mismatches = append(v.Mismatches, fmt.Sprintf("推荐奖励: 记录 %s, 账本 %s",
+			run.ReferralPoints.ToBigInt().String(), v.LedgerReferral.String()))
+	}
+	if v.LedgerDigest != run.EntriesDigest {
+		v.Mismatches = append(v.Mismatches, fmt.Sprintf("分录摘要: 记录 %s, 账本 %s", run.EntriesDigest, v.LedgerDigest))
+	}
+	return v, nil
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"POINTSTOKEN/db"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"log"
+	"time"
+)
+
+// ReferralService 推荐关系登记服务
+type ReferralService struct {
+	db           db.Repository
+	signatureTTL time.Duration
+}
+
+func NewReferralService(repo db.Repository, signatureTTL time.Duration) *ReferralService {
+	return &ReferralService{
+		db:           repo,
+		signatureTTL: signatureTTL,
+	}
+}
+
+// ReferralMessage 返回被推荐人需要签名的推荐登记消息
+func ReferralMessage(referee common.Address, referrer common.Address, issuedAt time.Time) string {
+	return fmt.Sprintf("POINTSTOKEN 推荐登记\n"+
+		"Referee: %s\n"+
+		"Referrer: %s\n"+
+		"Issued At: %s", referee.Hex(), referrer.Hex(), issuedAt.UTC().Format(time.RFC3339))
+}
+
+// RegisterSigned 验证被推荐人的 personal_sign 签名后登记推荐关系
+func (s *ReferralService) RegisterSigned(referee common.Address, referrer common.Address, issuedAt time.Time, signature string) error {
+	if time.Since(issuedAt) > s.signatureTTL || time.Until(issuedAt) > time.Minute {
+		return errors.New("签名消息已过期")
+	}
+	message := ReferralMessage(referee, referrer, issuedAt)
+	if err := VerifyPersonalSign(referee, message, signature); err != nil {
+		return fmt.Errorf("被推荐人签名验证失败: %w", err)
+	}
+	if err := s.db.RegisterReferral(referee, referrer, db.ReferralSourceSignature, signature); err != nil {
+		return err
+	}
+	log.Printf("地址 %s 登记推荐人 %s", referee.Hex(), referrer.Hex())
+	return nil
+}
+
+// GetReferees 获取推荐人直接推荐的地址
+func (s *ReferralService) GetReferees(referrer common.Address) ([]db.Referral, error) {
+	return s.db.GetReferees(referrer)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const siweHeaderSuffix = " wants you to sign in with your Ethereum account:"
+
+// SIWEMessage EIP-4361 登录消息
+type SIWEMessage struct {
+	Domain         string
+	Address        common.Address
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        uint64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// ParseSIWEMessage 按 EIP-4361 格式解析登录消息
+func ParseSIWEMessage(message string) (*SIWEMessage, error) {
+	lines := strings.Split(message, "\n")
+	if len(lines) < 4 {
+		return nil, errors.New("登录消息格式错误")
+	}
+	domain, ok := strings.CutSuffix(lines[0], siweHeaderSuffix)
+	if !ok || domain == "" {
+		return nil, errors.New("登录消息缺少域名")
+	}
+	//地址必须为 EIP-55 校验和格式
+	if !common.IsHexAddress(lines[1]) || common.HexToAddress(lines[1]).Hex() != lines[1] {
+		return nil, errors.New("登录消息地址无效")
+	}
+	msg := &SIWEMessage{Domain: domain, Address: common.HexToAddress(lines[1])}
+	if lines[2] != "" {
+		return nil, errors.New("登录消息格式错误")
+	}
+
+	//地址后为可选的说明，说明前后各有一个空行
+	i := 3
+	if !strings.HasPrefix(lines[i], "URI: ") {
+		if lines[i] != "" {
+			if i+1 >= len(lines) || lines[i+1] != "" {
+				return nil, errors.New("登录消息格式错误")
+			}
+			msg.Statement = lines[i]
+			i++
+		}
+		i++
+	}
+
+	fields := make(map[string]string)
+	for _, key := range []string{"URI", "Version", "Chain ID", "Nonce", "Issued At", "Expiration Time", "Not Before", "Request ID"} {
+		if i < len(lines) {
+			if value, ok := strings.CutPrefix(lines[i], key+": "); ok {
+				fields[key] = value
+				i++
+			}
+		}
+	}
+	if i < len(lines) && lines[i] == "Resources:" {
+		for i++; i < len(lines); i++ {
+			resource, ok := strings.CutPrefix(lines[i], "- ")
+			if !ok {
+				break
+			}
+			msg.Resources = append(msg.Resources, resource)
+		}
+	}
+	if i != len(lines) {
+		return nil, fmt.Errorf("登录消息第 %d 行无法识别", i+1)
+	}
+
+	for _, key := range []string{"URI", "Version", "Chain ID", "Nonce", "Issued At"} {
+		if fields[key] == "" {
+			return nil, fmt.Errorf("登录消息缺少 %s", key)
+		}
+	}
+	msg.URI = fields["URI"]
+	msg.Version = fields["Version"]
+	if msg.Version != "1" {
+		return nil, errors.New("不支持的登录消息版本")
+	}
+	chainID, err := strconv.ParseUint(fields["Chain ID"], 10, 64)
+	if err != nil {
+		return nil, errors.New("登录消息链ID无效")
+	}
+	msg.ChainID = chainID
+	msg.Nonce = fields["Nonce"]
+	if msg.IssuedAt, err = time.Parse(time.RFC3339, fields["Issued At"]); err != nil {
+		return nil, errors.New("登录消息签发时间无效")
+	}
+	for key, target := range map[string]**time.Time{"Expiration Time": &msg.ExpirationTime, "Not Before": &msg.NotBefore} {
+		if value := fields[key]; value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("登录消息 %s 无效", key)
+			}
+			*target = &t
+		}
+	}
+	msg.RequestID = fields["Request ID"]
+	return msg, nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSIWEMessage(t *testing.T) {
+	addr := common.HexToAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+	header := "example.com wants you to sign in with your Ethereum account:\n" + addr.Hex() + "\n\n"
+	fields := "URI: https://example.com/login\nVersion: 1\nChain ID: 11155111\nNonce: abc123\nIssued At: 2026-01-01T00:00:00Z"
+
+	t.Run("完整消息", func(t *testing.T) {
+		msg, err := ParseSIWEMessage(header + "Sign in to POINTS\n\n" + fields +
+			"\nExpiration Time: 2026-01-02T00:00:00Z\nNot Before: 2026-01-01T00:00:00Z\nRequest ID: req-1" +
+			"\nResources:\n- https://example.com/a\n- https://example.com/b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		issuedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		if msg.Domain != "example.com" || msg.Address != addr || msg.Statement != "Sign in to POINTS" ||
+			msg.URI != "https://example.com/login" || msg.Version != "1" || msg.ChainID != 11155111 ||
+			msg.Nonce != "abc123" || !msg.IssuedAt.Equal(issuedAt) || msg.RequestID != "req-1" {
+			t.Errorf("msg = %+v", msg)
+		}
+		if msg.ExpirationTime == nil || !msg.ExpirationTime.Equal(issuedAt.Add(24*time.Hour)) {
+			t.Errorf("ExpirationTime = %v", msg.ExpirationTime)
+		}
+		if msg.NotBefore == nil || !msg.NotBefore.Equal(issuedAt) {
+			t.Errorf("NotBefore = %v", msg.NotBefore)
+		}
+		if len(msg.Resources) != 2 || msg.Resources[1] != "https://example.com/b" {
+			t.Errorf("Resources = %v", msg.Resources)
+		}
+	})
+
+	t.Run("省略说明和可选字段", func(t *testing.T) {
+		msg, err := ParseSIWEMessage(header + fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Statement != "" || msg.ExpirationTime != nil || msg.NotBefore != nil || len(msg.Resources) != 0 {
+			t.Errorf("msg = %+v", msg)
+		}
+	})
+
+	lower := strings.Replace(header, addr.Hex(), strings.ToLower(addr.Hex()), 1)
+	errTests := []struct {
+		name    string
+		message string
+		wantErr string
+	}{
+		{"行数不足", "example.com wants you to sign in with your Ethereum account:", "登录消息格式错误"},
+		{"缺少域名", strings.Replace(header, "example.com", "", 1) + fields, "登录消息缺少域名"},
+		{"地址不是校验和格式", lower + fields, "登录消息地址无效"},
+		{"说明后缺少空行", header + "Sign in\n" + fields, "登录消息格式错误"},
+		{"缺少必填字段", header + strings.Replace(fields, "\nNonce: abc123", "", 1), "登录消息缺少 Nonce"},
+		{"字段顺序错误", header + "URI: https://example.com/login\nChain ID: 1\nVersion: 1\nNonce: a\nIssued At: 2026-01-01T00:00:00Z", "无法识别"},
+		{"不支持的版本", header + strings.Replace(fields, "Version: 1", "Version: 2", 1), "不支持的登录消息版本"},
+		{"无效的链ID", header + strings.Replace(fields, "Chain ID: 11155111", "Chain ID: sepolia", 1), "登录消息链ID无效"},
+		{"无效的签发时间", header + strings.Replace(fields, "2026-01-01T00:00:00Z", "2026-01-01", 1), "登录消息签发时间无效"},
+		{"无效的过期时间", header + fields + "\nExpiration Time: tomorrow", "登录消息 Expiration Time 无效"},
+		{"多余的行", header + fields + "\nextra", "无法识别"},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSIWEMessage(tt.message)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
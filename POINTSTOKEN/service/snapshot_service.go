@@ -0,0 +1,132 @@
+package service
+
+import (
+	"POINTSTOKEN/db"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"time"
+)
+
+var (
+	ErrUnknownChain   = errors.New("链不存在")
+	ErrBlockNotSynced = errors.New("区块尚未同步") //查询的区块高度尚未同步，结果会不完整
+)
+
+// HistoricalBalance 用户在某一区块高度的余额
+type HistoricalBalance struct {
+	ChainID         uint64
+	UserAddr        common.Address
+	BlockNumber     uint64
+	Balance         string
+	LastChangeBlock uint64     //此前最后一次变动的区块，从未变动时为 0
+	LastChangeAt    *time.Time //此前最后一次变动的时间，从未变动时为 nil
+}
+
+// SnapshotService 由余额变动计算历史余额和持有人快照，已物化的快照直接读取
+type SnapshotService struct {
+	repo db.Repository
+}
+
+func NewSnapshotService(repo db.Repository) *SnapshotService {
+	return &SnapshotService{repo: repo}
+}
+
+// ResolveBlock 确定查询的区块高度：指定时间时取该时间及之前最后一次余额变动的区块，否则使用 blockNumber
+// 区块高度和时间都不能超过链已同步的区块，否则之后尚未同步的余额变动不会计入结果
+func (s *SnapshotService) ResolveBlock(chainID uint64, blockNumber uint64, at time.Time) (uint64, error) {
+	lastBlock, err := s.repo.GetChainLastBlock(chainID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%w: %d", ErrUnknownChain, chainID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !at.IsZero() {
+		lastTime, err := s.repo.GetChainLastBlockTime(chainID)
+		if err != nil {
+			return 0, err
+		}
+		if at.After(lastTime) {
+			return 0, fmt.Errorf("%w: 链 %d 已同步到区块 %d（%s）", ErrBlockNotSynced, chainID, lastBlock, lastTime.UTC().Format(time.RFC3339))
+		}
+		return s.repo.GetBlockAtTime(chainID, at)
+	}
+	if blockNumber > lastBlock {
+		return 0, fmt.Errorf("%w: 链 %d 已同步到区块 %d", ErrBlockNotSynced, chainID, lastBlock)
+	}
+	return blockNumber, nil
+}
+
+// BalanceAt 获取用户在区块高度 blockNumber 的余额
+func (s *SnapshotService) BalanceAt(chainID uint64, userAddr common.Address, blockNumber uint64) (*HistoricalBalance, error) {
+	change, err := s.repo.GetLastBalanceChange(chainID, userAddr, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	balance := &HistoricalBalance{ChainID: chainID, UserAddr: userAddr, BlockNumber: blockNumber, Balance: "0"}
+	if change != nil {
+		balance.Balance = change.BalanceAfter.ToBigInt().String()
+		balance.LastChangeBlock = change.BlockNumber
+		balance.LastChangeAt = &change.CreatedAt
+	}
+	return balance, nil
+}
+
+// Holders 按地址顺序从 after 之后分页获取区块高度 blockNumber 的持有人，存在物化快照时返回快照 ID
+func (s *SnapshotService) Holders(chainID uint64, blockNumber uint64, after string, limit int) ([]db.HolderBalance, uint64, error) {
+	snapshot, err := s.repo.GetHolderSnapshot(chainID, blockNumber)
+	if err != nil {
+		return nil, 0, err
+	}
+	if snapshot != nil {
+		holders, err := s.repo.GetSnapshotHolders(snapshot.ID, after, limit)
+		return holders, snapshot.ID, err
+	}
+	holders, err := s.repo.GetHolders(chainID, blockNumber, after, limit)
+	return holders, 0, err
+}
+
+// StreamHolders 按地址顺序逐行回调区块高度 blockNumber 的持有人
+func (s *SnapshotService) StreamHolders(chainID uint64, blockNumber uint64, fn func(*db.HolderBalance) error) error {
+	snapshot, err := s.repo.GetHolderSnapshot(chainID, blockNumber)
+	if err != nil {
+		return err
+	}
+	if snapshot != nil {
+		return s.repo.StreamSnapshotHolders(snapshot.ID, fn)
+	}
+	return s.repo.StreamHolders(chainID, blockNumber, fn)
+}
+
+// CreateSnapshot 物化区块高度 blockNumber 的持有人快照
+func (s *SnapshotService) CreateSnapshot(chainID uint64, blockNumber uint64, createdBy string) (*db.HolderSnapshot, error) {
+	existing, err := s.repo.GetHolderSnapshot(chainID, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("链 %d 区块 %d 的快照已存在", chainID, blockNumber)
+	}
+	snapshot := &db.HolderSnapshot{
+		ChainID:     chainID,
+		BlockNumber: blockNumber,
+		CreatedBy:   createdBy,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.repo.CreateHolderSnapshot(snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// GetSnapshots 获取物化快照，chainID 为 0 时返回全部链
+func (s *SnapshotService) GetSnapshots(chainID uint64) ([]db.HolderSnapshot, error) {
+	return s.repo.GetHolderSnapshots(chainID)
+}
+
+// DeleteSnapshot 删除物化快照
+func (s *SnapshotService) DeleteSnapshot(id uint64) (bool, error) {
+	return s.repo.DeleteHolderSnapshot(id)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"POINTSTOKEN/db"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+// syncedChainRepo 只实现链同步进度和按时间查区块的测试仓库
+type syncedChainRepo struct {
+	db.Repository
+	lastBlock     uint64
+	lastBlockTime time.Time
+	blockAtTime   uint64
+}
+
+func (r *syncedChainRepo) GetChainLastBlock(chainID uint64) (uint64, error) {
+	if chainID != 1 {
+		return 0, sql.ErrNoRows
+	}
+	return r.lastBlock, nil
+}
+
+func (r *syncedChainRepo) GetChainLastBlockTime(chainID uint64) (time.Time, error) {
+	return r.lastBlockTime, nil
+}
+
+func (r *syncedChainRepo) GetBlockAtTime(chainID uint64, at time.Time) (uint64, error) {
+	return r.blockAtTime, nil
+}
+
+func TestResolveBlock(t *testing.T) {
+	synced := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo := &syncedChainRepo{lastBlock: 100, lastBlockTime: synced, blockAtTime: 90}
+	s := NewSnapshotService(repo)
+
+	tests := []struct {
+		name    string
+		chainID uint64
+		block   uint64
+		at      time.Time
+		want    uint64
+		wantErr error
+	}{
+		{name: "已同步的区块", chainID: 1, block: 100, want: 100},
+		{name: "未同步的区块", chainID: 1, block: 101, wantErr: ErrBlockNotSynced},
+		{name: "已同步的时间", chainID: 1, at: synced, want: 90},
+		{name: "晚于最后同步区块的时间", chainID: 1, at: synced.Add(time.Second), wantErr: ErrBlockNotSynced},
+		{name: "未知的链", chainID: 2, block: 1, wantErr: ErrUnknownChain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.ResolveBlock(tt.chainID, tt.block, tt.at)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("block = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	//升级后尚未记录同步时间时，按时间查询视为未同步
+	repo.lastBlockTime = time.Time{}
+	if _, err := s.ResolveBlock(1, 0, synced); !errors.Is(err, ErrBlockNotSynced) {
+		t.Errorf("err = %v, want %v", err, ErrBlockNotSynced)
+	}
+}
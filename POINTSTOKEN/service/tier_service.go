@@ -0,0 +1,184 @@
+package service
+
+import (
+	"POINTSTOKEN/config"
+	"POINTSTOKEN/db"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"log"
+	"math/big"
+	"time"
+)
+
+// tierRule 解析后的等级门槛，rank 越大等级越高
+type tierRule struct {
+	name       string
+	rank       int
+	minPoints  *big.Int
+	minBalance *big.Int
+	minHolding time.Duration
+}
+
+// parseTiers 解析等级配置，门槛数量留空表示不限制
+func parseTiers(tiers []config.TierConfig) ([]tierRule, error) {
+	var rules []tierRule
+	seen := make(map[string]bool)
+	for i, t := range tiers {
+		if t.Name == "" || seen[t.Name] {
+			return nil, fmt.Errorf("等级名称为空或重复: %q", t.Name)
+		}
+		seen[t.Name] = true
+		minPoints, err := parseCap(t.MinPoints)
+		if err != nil {
+			return nil, fmt.Errorf("等级 %s 积分门槛无效: %v", t.Name, err)
+		}
+		minBalance, err := parseCap(t.MinBalance)
+		if err != nil {
+			return nil, fmt.Errorf("等级 %s 余额门槛无效: %v", t.Name, err)
+		}
+		rules = append(rules, tierRule{
+			name:       t.Name,
+			rank:       i + 1,
+			minPoints:  minPoints,
+			minBalance: minBalance,
+			minHolding: t.MinHolding,
+		})
+	}
+	return rules, nil
+}
+
+// selectTier 返回满足全部门槛的最高等级，都不满足时为空
+func selectTier(rules []tierRule, points *big.Int, balance *big.Int, holding time.Duration) string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		rule := rules[i]
+		if rule.minPoints != nil && points.Cmp(rule.minPoints) < 0 {
+			continue
+		}
+		if rule.minBalance != nil && balance.Cmp(rule.minBalance) < 0 {
+			continue
+		}
+		if holding < rule.minHolding {
+			continue
+		}
+		return rule.name
+	}
+	return ""
+}
+
+// applyTierMultiplier 按用户等级的倍数放大持币积分，未配置的等级倍数为1
+func applyTierMultiplier(points *big.Int, tiers []config.TierConfig, tier string) {
+	for _, t := range tiers {
+		if t.Name == tier {
+			points.Mul(points, big.NewInt(int64(t.Multiplier*weightScale)))
+			points.Div(points, big.NewInt(weightScale))
+			return
+		}
+	}
+}
+
+// TierService 会员等级评估，在每次积分计算后按积分、余额和持有时长重新评估用户等级
+type TierService struct {
+	repo  db.Repository
+	rules []tierRule
+}
+
+func NewTierService(tiers []config.TierConfig, repo db.Repository) (*TierService, error) {
+	rules, err := parseTiers(tiers)
+	if err != nil {
+		return nil, err
+	}
+	return &TierService{repo: repo, rules: rules}, nil
+}
+
+// Tiers 返回按从低到高排列的等级名称
+func (t *TierService) Tiers() []string {
+	names := make([]string, 0, len(t.rules))
+	for _, rule := range t.rules {
+		names = append(names, rule.name)
+	}
+	return names
+}
+
+// rank 返回等级的排序，未达到任何等级为0
+func (t *TierService) rank(tier string) int {
+	for _, rule := range t.rules {
+		if rule.name == tier {
+			return rule.rank
+		}
+	}
+	return 0
+}
+
+// Evaluate 作为 RunListener 在积分计算后重新评估用户等级
+func (t *TierService) Evaluate(summary *RunSummary, users []common.Address) {
+	if len(t.rules) == 0 {
+		return
+	}
+	for _, userAddr := range users {
+		if _, err := t.EvaluateUser(summary.ChainID, userAddr, summary.CalculatedAt); err != nil {
+			log.Printf("评估链 %d 地址 %s 的会员等级失败: %v", summary.ChainID, userAddr.Hex(), err)
+		}
+	}
+}
+
+// EvaluateUser 评估用户在 at 时应处的等级并记录变化，返回评估后的等级
+func (t *TierService) EvaluateUser(chain uint64, userAddr common.Address, at time.Time) (string, error) {
+	pointsStr, err := t.repo.GetUserPoints(chain, userAddr)
+	if err != nil {
+		return "", fmt.Errorf("获取用户积分失败: %v", err)
+	}
+	points, ok := new(big.Int).SetString(pointsStr, 10)
+	if !ok {
+		points = big.NewInt(0)
+	}
+	balanceStr, holdingSince, err := t.repo.GetHoldingInfo(chain, userAddr)
+	if err != nil {
+		return "", fmt.Errorf("获取用户持有信息失败: %v", err)
+	}
+	balance, ok := new(big.Int).SetString(balanceStr, 10)
+	if !ok {
+		balance = big.NewInt(0)
+	}
+	var holding time.Duration
+	if !holdingSince.IsZero() {
+		holding = at.Sub(holdingSince)
+	}
+
+	tier := selectTier(t.rules, points, balance, holding)
+	current, err := t.repo.GetUserTier(chain, userAddr)
+	if err != nil {
+		return "", fmt.Errorf("获取用户等级失败: %v", err)
+	}
+	from := ""
+	if current != nil {
+		from = current.Tier
+	}
+	if from == tier {
+		return tier, nil
+	}
+	direction := db.TierUpgrade
+	if t.rank(tier) < t.rank(from) {
+		direction = db.TierDowngrade
+	}
+	changed, err := t.repo.SetUserTier(chain, userAddr, tier, direction, at)
+	if err != nil {
+		return "", fmt.Errorf("更新用户等级失败: %v", err)
+	}
+	if changed {
+		log.Printf("链:%v, 地址:%s 等级 %q -> %q", chain, userAddr.Hex(), from, tier)
+	}
+	return tier, nil
+}
+
+// GetUserTier 获取用户当前等级和等级变更历史
+func (t *TierService) GetUserTier(chain uint64, userAddr common.Address) (*db.UserTier, []db.TierChange, error) {
+	current, err := t.repo.GetUserTier(chain, userAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	history, err := t.repo.GetTierHistory(chain, &userAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return current, history, nil
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"POINTSTOKEN/config"
+	"POINTSTOKEN/db"
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// voucherRefundBatch 每批检查的过期凭证数
+const voucherRefundBatch = 100
+
+// voucherChain 兑换合约所在链的只读调用
+type voucherChain interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+// VoucherRefunder 退回过期未兑换凭证扣减的积分
+// 凭证过了截止时间后无法再兑换，按确认区块查询兑换合约的 nonce 状态：已使用的标记为已兑换，未使用的退回积分
+type VoucherRefunder struct {
+	cfg           *config.VoucherConfig
+	repo          db.Repository
+	chain         voucherChain
+	contract      common.Address
+	selector      []byte
+	confirmations uint64
+	wg            sync.WaitGroup
+}
+
+// NewVoucherRefunder 连接兑换合约所在的链，未配置 nonce_used_method 时返回 nil
+func NewVoucherRefunder(cfg *config.VoucherConfig, chains []config.ChainConfig, repo db.Repository) (*VoucherRefunder, error) {
+	if cfg.NonceUsedMethod == "" {
+		return nil, nil
+	}
+	if !common.IsHexAddress(cfg.VerifyingContract) {
+		return nil, fmt.Errorf("无效的verifying_contract: %s", cfg.VerifyingContract)
+	}
+	for _, chain := range chains {
+		if chain.ChainID != cfg.ChainID {
+			continue
+		}
+		client, err := ethclient.Dial(chain.RPCUrl)
+		if err != nil {
+			return nil, fmt.Errorf("连接链 %d 失败: %w", chain.ChainID, err)
+		}
+		return newVoucherRefunder(cfg, repo, client, chain.Confirmations), nil
+	}
+	return nil, fmt.Errorf("未配置兑换合约所在的链: %d", cfg.ChainID)
+}
+
+func newVoucherRefunder(cfg *config.VoucherConfig, repo db.Repository, chain voucherChain, confirmations uint64) *VoucherRefunder {
+	method := strings.ReplaceAll(cfg.NonceUsedMethod, " ", "")
+	return &VoucherRefunder{
+		cfg:           cfg,
+		repo:          repo,
+		chain:         chain,
+		contract:      common.HexToAddress(cfg.VerifyingContract),
+		selector:      crypto.Keccak256([]byte(method))[:4],
+		confirmations: confirmations,
+	}
+}
+
+// Start 按 refund_interval 定期退回过期凭证，ctx 取消后退出
+func (r *VoucherRefunder) Start(ctx context.Context) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.cfg.RefundInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := r.Refund(ctx); err != nil {
+				log.Printf("退回过期凭证失败: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait 等待定时任务退出
+func (r *VoucherRefunder) Wait() {
+	r.wg.Wait()
+}
+
+// Refund 结清确认区块时间之前已过期的凭证，返回退回的凭证数
+func (r *VoucherRefunder) Refund(ctx context.Context) (int, error) {
+	latest, err := r.chain.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("获取最新区块失败: %w", err)
+	}
+	block := latest
+	if r.confirmations > 0 && latest.Number.Uint64() > r.confirmations {
+		block, err = r.chain.HeaderByNumber(ctx, new(big.Int).Sub(latest.Number, new(big.Int).SetUint64(r.confirmations)))
+		if err != nil {
+			return 0, fmt.Errorf("获取确认区块失败: %w", err)
+		}
+	}
+	//截止时间早于确认区块时间的凭证此后不可能再被兑换
+	blockTime := time.Unix(int64(block.Time), 0)
+
+	refunded := 0
+	for ctx.Err() == nil {
+		vouchers, err := r.repo.GetUnsettledVouchers(blockTime, voucherRefundBatch)
+		if err != nil || len(vouchers) == 0 {
+			return refunded, err
+		}
+		for _, v := range vouchers {
+			used, err := r.nonceUsed(ctx, v.UserAddr, v.Nonce, block.Number)
+			if err != nil {
+				return refunded, err
+			}
+			if used {
+				if err := r.repo.MarkVoucherRedeemed(v.UserAddr, v.Nonce); err != nil {
+					return refunded, err
+				}
+				continue
+			}
+			entry, err := r.repo.RefundVoucher(v.UserAddr, v.Nonce)
+			if err != nil {
+				return refunded, fmt.Errorf("退回凭证 %s:%d 失败: %w", v.UserAddr.Hex(), v.Nonce, err)
+			}
+			if entry != nil {
+				refunded++
+				log.Printf("链:%v, 地址:%s, 凭证 nonce:%d 过期未兑换, 退回积分:%s", v.ChainID, v.UserAddr.Hex(), v.Nonce, v.Amount.ToBigInt().String())
+			}
+		}
+	}
+	return refunded, ctx.Err()
+}
+
+// nonceUsed 在指定区块查询兑换合约中凭证的 nonce 是否已使用
+func (r *VoucherRefunder) nonceUsed(ctx context.Context, userAddr common.Address, nonce uint64, blockNumber *big.Int) (bool, error) {
+	data := append([]byte{}, r.selector...)
+	data = append(data, common.LeftPadBytes(userAddr.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(new(big.Int).SetUint64(nonce).Bytes(), 32)...)
+	result, err := r.chain.CallContract(ctx, ethereum.CallMsg{To: &r.contract, Data: data}, blockNumber)
+	if err != nil {
+		return false, fmt.Errorf("查询凭证 nonce 失败（区块 %s）: %w", blockNumber, err)
+	}
+	if len(result) != 32 {
+		return false, fmt.Errorf("%s 返回值无效: %x", r.cfg.NonceUsedMethod, result)
+	}
+	return new(big.Int).SetBytes(result).Sign() != 0, nil
+}
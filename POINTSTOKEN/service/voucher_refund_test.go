@@ -0,0 +1,115 @@
+package service
+
+import (
+	"POINTSTOKEN/config"
+	"POINTSTOKEN/db"
+	. "POINTSTOKEN/types"
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// voucherRepo 只实现凭证结清的测试仓库
+type voucherRepo struct {
+	db.Repository
+	vouchers []db.PointsVoucher
+	refunded []uint64
+}
+
+func (r *voucherRepo) GetUnsettledVouchers(before time.Time, limit int) ([]db.PointsVoucher, error) {
+	var result []db.PointsVoucher
+	for _, v := range r.vouchers {
+		if v.Deadline.Before(before) && v.RedeemedAt == nil && v.RefundedAt == nil && len(result) < limit {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+func (r *voucherRepo) MarkVoucherRedeemed(userAddr common.Address, nonce uint64) error {
+	now := time.Now()
+	r.vouchers[nonce-1].RedeemedAt = &now
+	return nil
+}
+
+func (r *voucherRepo) RefundVoucher(userAddr common.Address, nonce uint64) (*db.LedgerEntry, error) {
+	now := time.Now()
+	r.vouchers[nonce-1].RefundedAt = &now
+	r.refunded = append(r.refunded, nonce)
+	return &db.LedgerEntry{EntryType: db.LedgerRefund}, nil
+}
+
+// voucherContract 按区块返回兑换合约中已使用的 nonce
+type voucherContract struct {
+	latest    uint64
+	blockTime func(number uint64) uint64
+	used      map[uint64]uint64 //nonce -> 兑换所在区块
+	calledAt  []uint64
+}
+
+func (c *voucherContract) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	n := c.latest
+	if number != nil {
+		n = number.Uint64()
+	}
+	return &types.Header{Number: new(big.Int).SetUint64(n), Time: c.blockTime(n)}, nil
+}
+
+func (c *voucherContract) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	c.calledAt = append(c.calledAt, blockNumber.Uint64())
+	nonce := new(big.Int).SetBytes(msg.Data[4+32:]).Uint64()
+	result := make([]byte, 32)
+	if block, ok := c.used[nonce]; ok && block <= blockNumber.Uint64() {
+		result[31] = 1
+	}
+	return result, nil
+}
+
+func TestVoucherRefunderRefundsUnredeemed(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	user := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	repo := &voucherRepo{}
+	//nonce 1、2 已过期，nonce 3 截止时间晚于确认区块
+	for i, deadline := range []time.Duration{time.Hour, 2 * time.Hour, 10 * time.Hour} {
+		repo.vouchers = append(repo.vouchers, db.PointsVoucher{
+			ChainID: 1, UserAddr: user, Amount: *FromBigInt(big.NewInt(100)), Nonce: uint64(i + 1), Deadline: base.Add(deadline),
+		})
+	}
+	//每个区块 1 小时，最新区块 10，确认 2 个区块后按区块 8 的时间和状态结清
+	chain := &voucherContract{
+		latest:    10,
+		blockTime: func(n uint64) uint64 { return uint64(base.Add(time.Duration(n) * time.Hour).Unix()) },
+		used:      map[uint64]uint64{1: 1},
+	}
+	cfg := &config.VoucherConfig{NonceUsedMethod: "usedNonces(address,uint256)", VerifyingContract: "0x00000000000000000000000000000000000000cc"}
+	refunder := newVoucherRefunder(cfg, repo, chain, 2)
+
+	refunded, err := refunder.Refund(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refunded != 1 || len(repo.refunded) != 1 || repo.refunded[0] != 2 {
+		t.Fatalf("refunded = %d %v, want [2]", refunded, repo.refunded)
+	}
+	if repo.vouchers[0].RedeemedAt == nil || repo.vouchers[0].RefundedAt != nil {
+		t.Errorf("已兑换的凭证应标记为已兑换: %+v", repo.vouchers[0])
+	}
+	if repo.vouchers[2].RedeemedAt != nil || repo.vouchers[2].RefundedAt != nil {
+		t.Errorf("未过期的凭证不应结清: %+v", repo.vouchers[2])
+	}
+	for _, block := range chain.calledAt {
+		if block != 8 {
+			t.Errorf("查询区块 = %d, want 8", block)
+		}
+	}
+
+	//再次执行不会重复退回
+	refunded, err = refunder.Refund(context.Background())
+	if err != nil || refunded != 0 {
+		t.Errorf("refunded = %d, err = %v, want 0", refunded, err)
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"POINTSTOKEN/config"
+	"POINTSTOKEN/db"
+	"crypto/ecdsa"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"log"
+	"math/big"
+	"os"
+	"time"
+)
+
+// voucherTypes EIP-712 凭证结构定义
+var voucherTypes = apitypes.Types{
+	"EIP712Domain": {
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "verifyingContract", Type: "address"},
+	},
+	"PointsVoucher": {
+		{Name: "user", Type: "address"},
+		{Name: "amount", Type: "uint256"},
+		{Name: "nonce", Type: "uint256"},
+		{Name: "deadline", Type: "uint256"},
+	},
+}
+
+// VoucherService 积分兑换凭证服务，使用本地keystore账户签发EIP-712凭证
+type VoucherService struct {
+	db         *db.DBRepository
+	cfg        *config.VoucherConfig
+	privateKey *ecdsa.PrivateKey
+	signer     common.Address
+}
+
+func NewVoucherService(cfg *config.VoucherConfig, db *db.DBRepository) (*VoucherService, error) {
+	if cfg.KeystorePath == "" {
+		return nil, fmt.Errorf("未配置凭证签名keystore")
+	}
+	keyJson, err := os.ReadFile(cfg.KeystorePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取keystore失败: %w", err)
+	}
+	key, err := keystore.DecryptKey(keyJson, cfg.KeystorePassword)
+	if err != nil {
+		return nil, fmt.Errorf("解密keystore失败: %w", err)
+	}
+	if !common.IsHexAddress(cfg.VerifyingContract) {
+		return nil, fmt.Errorf("无效的verifying_contract: %s", cfg.VerifyingContract)
+	}
+
+	log.Printf("凭证签名账户: %s", key.Address.Hex())
+	return &VoucherService{
+		db:         db,
+		cfg:        cfg,
+		privateKey: key.PrivateKey,
+		signer:     key.Address,
+	}, nil
+}
+
+// Signer 返回签名账户地址
+func (v *VoucherService) Signer() common.Address {
+	return v.signer
+}
+
+// IssueVoucher 为用户签发不超过其未兑换积分的凭证，并扣减相应积分
+func (v *VoucherService) IssueVoucher(chainID uint64, userAddr common.Address, amount *big.Int) (*db.PointsVoucher, error) {
+	if amount == nil || amount.Sign() <= 0 {
+		return nil, fmt.Errorf("兑换数量必须大于0")
+	}
+	deadline := time.Now().Add(v.cfg.ValidFor).Truncate(time.Second)
+
+	voucher, err := v.db.CreateVoucher(chainID, userAddr, amount.String(), deadline, func(nonce uint64) (string, error) {
+		return v.sign(userAddr, amount, nonce, deadline)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("签发凭证失败: %w", err)
+	}
+	log.Printf("链:%v, 地址:%s, 签发凭证 nonce:%d, 数量:%s", chainID, userAddr.Hex(), voucher.Nonce, amount.String())
+	return voucher, nil
+}
+
+// TypedData 构造凭证的EIP-712结构化数据，可用于前端或合约端验证
+func (v *VoucherService) TypedData(userAddr common.Address, amount *big.Int, nonce uint64, deadline time.Time) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types:       voucherTypes,
+		PrimaryType: "PointsVoucher",
+		Domain: apitypes.TypedDataDomain{
+			Name:              v.cfg.DomainName,
+			Version:           v.cfg.DomainVersion,
+			ChainId:           math.NewHexOrDecimal256(int64(v.cfg.ChainID)),
+			VerifyingContract: common.HexToAddress(v.cfg.VerifyingContract).Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"user":     userAddr.Hex(),
+			"amount":   (*math.HexOrDecimal256)(amount),
+			"nonce":    math.NewHexOrDecimal256(int64(nonce)),
+			"deadline": math.NewHexOrDecimal256(deadline.Unix()),
+		},
+	}
+}
+
+// sign 计算EIP-712哈希并签名，返回 r||s||v 格式（v 为 27/28）
+func (v *VoucherService) sign(userAddr common.Address, amount *big.Int, nonce uint64, deadline time.Time) (string, error) {
+	hash, _, err := apitypes.TypedDataAndHash(v.TypedData(userAddr, amount, nonce, deadline))
+	if err != nil {
+		return "", fmt.Errorf("计算EIP-712哈希失败: %w", err)
+	}
+	sig, err := crypto.Sign(hash, v.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("签名失败: %w", err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return hexutil.Encode(sig), nil
+}
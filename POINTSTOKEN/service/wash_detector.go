@@ -0,0 +1,228 @@
+package service
+
+import (
+	"POINTSTOKEN/config"
+	"POINTSTOKEN/db"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"log"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// DetectWashTrading 对转账记录做刷量检测，返回来回转账、环形转账和短时持有标记
+// 铸造、销毁不参与检测
+func DetectWashTrading(cfg config.WashConfig, transfers []db.Transfer) []db.WashFlag {
+	var filtered []db.Transfer
+	for _, t := range transfers {
+		if t.From != ZeroAddress && t.To != ZeroAddress && t.From != t.To {
+			filtered = append(filtered, t)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].CreatedAt.Before(filtered[j].CreatedAt)
+	})
+
+	var flags []db.WashFlag
+	flags = append(flags, detectRoundTrips(cfg, filtered)...)
+	flags = append(flags, detectCircularFlows(cfg, filtered)...)
+	flags = append(flags, detectShortHoldings(cfg, filtered)...)
+	return flags
+}
+
+func newWashFlag(t db.Transfer, userAddr common.Address, flagType string, counterparty string, detail string) db.WashFlag {
+	return db.WashFlag{
+		ChainID:      t.ChainID,
+		UserAddr:     userAddr,
+		FlagType:     flagType,
+		TxHash:       t.TxHash,
+		Counterparty: counterparty,
+		Detail:       detail,
+		ActivityAt:   t.CreatedAt,
+		Status:       db.WashStatusOpen,
+	}
+}
+
+// amountsClose 判断两笔金额的偏差是否在 tolerance% 以内
+func amountsClose(a *big.Int, b *big.Int, tolerance float64) bool {
+	diff := new(big.Int).Sub(a, b)
+	diff.Abs(diff)
+	//diff * 100 * 10000 <= a * tolerance * 10000
+	left := new(big.Int).Mul(diff, big.NewInt(100*10000))
+	right := new(big.Int).Mul(a, big.NewInt(int64(tolerance*10000)))
+	return left.Cmp(right) <= 0
+}
+
+// detectRoundTrips A→B 之后窗口内 B→A 金额相近，双方都被标记
+func detectRoundTrips(cfg config.WashConfig, transfers []db.Transfer) []db.WashFlag {
+	var flags []db.WashFlag
+	for i, out := range transfers {
+		for _, back := range transfers[i+1:] {
+			if back.CreatedAt.Sub(out.CreatedAt) > cfg.RoundTripWindow {
+				break
+			}
+			if back.From != out.To || back.To != out.From {
+				continue
+			}
+			if !amountsClose(out.Amount.ToBigInt(), back.Amount.ToBigInt(), cfg.AmountTolerance) {
+				continue
+			}
+			detail := fmt.Sprintf("%s 内往返转账，去程 %s", back.CreatedAt.Sub(out.CreatedAt), out.TxHash.Hex())
+			flags = append(flags,
+				newWashFlag(back, out.From, db.WashRoundTrip, out.To.Hex(), detail),
+				newWashFlag(back, out.To, db.WashRoundTrip, out.From.Hex(), detail))
+			break
+		}
+	}
+	return flags
+}
+
+// 环形转账检测的搜索上限，避免交易所等高频地址使深度优先搜索的代价失控
+const (
+	maxCycleLength = 8    //最多涉及的地址数，配置超过时按此截断
+	maxCycleFanOut = 32   //每个地址在窗口内最多展开的转出笔数
+	maxCycleSteps  = 4096 //每个起点最多展开的次数，超出时放弃该起点
+)
+
+// detectCircularFlows 窗口内按时间顺序形成 A→B→…→A 的环（至少3个地址），环上地址都被标记
+func detectCircularFlows(cfg config.WashConfig, transfers []db.Transfer) []db.WashFlag {
+	//transfers 已按时间排序，每个地址的转出下标同样有序
+	outgoing := make(map[common.Address][]int)
+	for i, t := range transfers {
+		outgoing[t.From] = append(outgoing[t.From], i)
+	}
+	cycleLength := min(cfg.MaxCycleLength, maxCycleLength)
+
+	var flags []db.WashFlag
+	flagged := make(map[string]bool)
+	for start := range transfers {
+		first := transfers[start]
+		path := []int{start}
+		visited := map[common.Address]bool{first.From: true, first.To: true}
+		steps := 0
+
+		var walk func(last int) bool
+		walk = func(last int) bool {
+			outs := outgoing[transfers[last].To]
+			expanded := 0
+			for _, next := range outs[sort.SearchInts(outs, last+1):] {
+				t := transfers[next]
+				if t.CreatedAt.Sub(first.CreatedAt) > cfg.CircularWindow {
+					break
+				}
+				if t.To == first.From && len(path) >= 2 {
+					path = append(path, next)
+					return true
+				}
+				if visited[t.To] || len(path)+1 >= cycleLength {
+					continue
+				}
+				if expanded >= maxCycleFanOut || steps >= maxCycleSteps {
+					break
+				}
+				expanded++
+				steps++
+				visited[t.To] = true
+				path = append(path, next)
+				if walk(next) {
+					return true
+				}
+				path = path[:len(path)-1]
+				delete(visited, t.To)
+			}
+			return false
+		}
+		if !walk(start) {
+			continue
+		}
+
+		detail := fmt.Sprintf("%d 个地址间的环形转账，起点 %s", len(path), first.TxHash.Hex())
+		for _, idx := range path {
+			t := transfers[idx]
+			key := t.From.Hex() + t.TxHash.Hex()
+			if flagged[key] {
+				continue
+			}
+			flagged[key] = true
+			flags = append(flags, newWashFlag(t, t.From, db.WashCircular, t.To.Hex(), detail))
+		}
+	}
+	return flags
+}
+
+// detectShortHoldings 转入后不足 MinHolding 即转出，转出方被标记
+func detectShortHoldings(cfg config.WashConfig, transfers []db.Transfer) []db.WashFlag {
+	var flags []db.WashFlag
+	lastIn := make(map[common.Address]db.Transfer)
+	for _, t := range transfers {
+		if in, ok := lastIn[t.From]; ok {
+			held := t.CreatedAt.Sub(in.CreatedAt)
+			if held < cfg.MinHolding {
+				detail := fmt.Sprintf("转入 %s 后 %s 即转出", in.TxHash.Hex(), held)
+				flags = append(flags, newWashFlag(t, t.From, db.WashShortHolding, t.To.Hex(), detail))
+			}
+			delete(lastIn, t.From)
+		}
+		lastIn[t.To] = t
+	}
+	return flags
+}
+
+// washActivity 检测 since 之后的转账并保存刷量标记，返回每个地址被标记活动的时间
+// 被审核为误报的标记不计入；dryRun 时新检测到的标记只在内存中使用，不写入数据库
+func (p *PointsCalculator) washActivity(chain uint64, since time.Time, dryRun bool) (map[common.Address][]time.Time, error) {
+	transfers, err := p.db.GetTransfers(chain, since)
+	if err != nil {
+		return nil, fmt.Errorf("获取转账记录失败: %v", err)
+	}
+	detected := DetectWashTrading(p.pointCfg.WashTrading, transfers)
+	if !dryRun {
+		if err := p.db.SaveWashFlags(detected); err != nil {
+			return nil, fmt.Errorf("保存刷量标记失败: %v", err)
+		}
+		if len(detected) > 0 {
+			log.Printf("链 %d 检测到 %d 条刷量标记", chain, len(detected))
+		}
+	}
+
+	stored, err := p.db.GetWashFlags(chain, since, "")
+	if err != nil {
+		return nil, fmt.Errorf("获取刷量标记失败: %v", err)
+	}
+	dismissed := make(map[string]bool)
+	for _, f := range stored {
+		if f.Status == db.WashStatusDismissed {
+			dismissed[f.UserAddr.Hex()+f.FlagType+f.TxHash.Hex()] = true
+		}
+	}
+	activity := make(map[common.Address][]time.Time)
+	for _, f := range append(stored, detected...) {
+		if dismissed[f.UserAddr.Hex()+f.FlagType+f.TxHash.Hex()] {
+			continue
+		}
+		activity[f.UserAddr] = append(activity[f.UserAddr], f.ActivityAt)
+	}
+	return activity, nil
+}
+
+// hasWashActivity 判断 (from, until] 内是否有刷量活动
+func hasWashActivity(times []time.Time, from time.Time, until time.Time) bool {
+	for _, t := range times {
+		if t.After(from) && !t.After(until) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyWashPenalty 对本次计分区间内有刷量活动的用户打折或不计分
+func (p *PointsCalculator) applyWashPenalty(points *big.Int) {
+	if p.pointCfg.WashTrading.Action == "exclude" {
+		points.SetInt64(0)
+		return
+	}
+	keep := big.NewInt(int64((100 - p.pointCfg.WashTrading.DiscountPercent) * 10000))
+	points.Mul(points, keep)
+	points.Div(points, big.NewInt(100*10000))
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"POINTSTOKEN/config"
+	"POINTSTOKEN/db"
+	. "POINTSTOKEN/types"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func testWashConfig() config.WashConfig {
+	return config.WashConfig{
+		RoundTripWindow: time.Hour,
+		CircularWindow:  24 * time.Hour,
+		MaxCycleLength:  4,
+		MinHolding:      10 * time.Minute,
+		AmountTolerance: 5,
+	}
+}
+
+func testAddr(n int) common.Address {
+	return common.BigToAddress(big.NewInt(int64(n)))
+}
+
+func testTransfer(seq int, from int, to int, amount int64, at time.Time) db.Transfer {
+	return db.Transfer{
+		ChainID:   1,
+		TxHash:    common.BigToHash(big.NewInt(int64(seq))),
+		From:      testAddr(from),
+		To:        testAddr(to),
+		Amount:    *FromBigInt(big.NewInt(amount)),
+		EventType: "transfer",
+		CreatedAt: at,
+	}
+}
+
+// flagSet 以 地址/类型 统计标记数
+func flagSet(flags []db.WashFlag) map[string]int {
+	set := make(map[string]int)
+	for _, f := range flags {
+		set[fmt.Sprintf("%s/%s", f.UserAddr.Hex(), f.FlagType)]++
+	}
+	return set
+}
+
+func TestDetectWashTrading(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	key := func(n int, flagType string) string {
+		return fmt.Sprintf("%s/%s", testAddr(n).Hex(), flagType)
+	}
+
+	tests := []struct {
+		name      string
+		transfers []db.Transfer
+		want      map[string]int
+	}{
+		{
+			name: "来回转账",
+			transfers: []db.Transfer{
+				testTransfer(1, 1, 2, 1000, base),
+				testTransfer(2, 2, 1, 980, base.Add(30*time.Minute)),
+			},
+			want: map[string]int{key(1, db.WashRoundTrip): 1, key(2, db.WashRoundTrip): 1},
+		},
+		{
+			name: "金额偏差过大或超出窗口不算来回转账",
+			transfers: []db.Transfer{
+				testTransfer(1, 1, 2, 1000, base),
+				testTransfer(2, 2, 1, 500, base.Add(30*time.Minute)),
+				testTransfer(3, 3, 4, 1000, base),
+				testTransfer(4, 4, 3, 1000, base.Add(2*time.Hour)),
+			},
+			want: map[string]int{},
+		},
+		{
+			name: "三个地址的环形转账",
+			transfers: []db.Transfer{
+				testTransfer(1, 1, 2, 1000, base),
+				testTransfer(2, 2, 3, 1000, base.Add(time.Hour)),
+				testTransfer(3, 3, 1, 1000, base.Add(2*time.Hour)),
+			},
+			want: map[string]int{key(1, db.WashCircular): 1, key(2, db.WashCircular): 1, key(3, db.WashCircular): 1},
+		},
+		{
+			name: "环长超过上限不标记",
+			transfers: []db.Transfer{
+				testTransfer(1, 1, 2, 1000, base),
+				testTransfer(2, 2, 3, 1000, base.Add(time.Hour)),
+				testTransfer(3, 3, 4, 1000, base.Add(2*time.Hour)),
+				testTransfer(4, 4, 5, 1000, base.Add(3*time.Hour)),
+				testTransfer(5, 5, 1, 1000, base.Add(4*time.Hour)),
+			},
+			want: map[string]int{},
+		},
+		{
+			name: "短时持有",
+			transfers: []db.Transfer{
+				testTransfer(1, 1, 2, 1000, base),
+				testTransfer(2, 2, 3, 1000, base.Add(5*time.Minute)),
+			},
+			want: map[string]int{key(2, db.WashShortHolding): 1},
+		},
+		{
+			name: "铸造、销毁和自转账不参与检测",
+			transfers: []db.Transfer{
+				testTransfer(1, 0, 1, 1000, base),
+				testTransfer(2, 1, 1, 1000, base.Add(time.Minute)),
+				testTransfer(3, 1, 0, 1000, base.Add(time.Hour)),
+			},
+			want: map[string]int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := flagSet(DetectWashTrading(testWashConfig(), tt.transfers))
+			if len(got) != len(tt.want) {
+				t.Fatalf("flags = %v, want %v", got, tt.want)
+			}
+			for k, n := range tt.want {
+				if got[k] != n {
+					t.Errorf("flags[%s] = %d, want %d (all: %v)", k, got[k], n, got)
+				}
+			}
+		})
+	}
+}
+
+// 高频地址与大量地址互相转账时，环形检测的搜索受扇出和步数限制，应在有限时间内完成
+func TestDetectCircularFlowsBounded(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfg := testWashConfig()
+	cfg.MaxCycleLength = 100
+
+	var transfers []db.Transfer
+	seq := 0
+	for round := 0; round < 20; round++ {
+		for user := 1; user <= 100; user++ {
+			seq++
+			transfers = append(transfers, testTransfer(seq, 1000, user, 10, base.Add(time.Duration(seq)*time.Second)))
+			seq++
+			transfers = append(transfers, testTransfer(seq, user, 1000+user, 10, base.Add(time.Duration(seq)*time.Second)))
+		}
+	}
+
+	done := make(chan []db.WashFlag, 1)
+	go func() { done <- detectCircularFlows(cfg, transfers) }()
+	select {
+	case flags := <-done:
+		if len(flags) != 0 {
+			t.Errorf("unexpected circular flags: %d", len(flags))
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("环形转账检测未在限定时间内完成")
+	}
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"POINTSTOKEN/config"
+	"POINTSTOKEN/db"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	webhookBatch     = 100
+	webhookPurgeGap  = time.Hour        //清理过期发件箱事件的间隔
+	webhookLeaseGap  = 10 * time.Second //每条投递的租约在请求超时之外预留的时间，用于记录结果
+	webhookMaxError  = 500              //投递日志中保存的错误信息最大长度
+	webhookMaxBody   = 1024             //读取响应体的最大长度
+	webhookUserAgent = "POINTSTOKEN-Webhook/1"
+)
+
+// WebhookMessage webhook 请求体，id 为投递记录ID，重试时不变，接收方可据此去重
+type WebhookMessage struct {
+	ID        uint64          `json:"id"`
+	EventType string          `json:"event_type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// SignWebhook 计算签名：hex(HMAC-SHA256(secret, timestamp + "." + body))，
+// 通过请求头 X-Webhook-Signature: sha256=<签名> 发送，接收方应同时校验 X-Webhook-Timestamp 防止重放
+func SignWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookService 将发件箱中的事件分发给匹配的订阅并投递，失败时退避重试
+type WebhookService struct {
+	cfg    config.WebhookConfig
+	repo   db.Repository
+	client *http.Client
+	wg     sync.WaitGroup
+	purged time.Time //上次清理发件箱的时间
+}
+
+func NewWebhookService(cfg config.WebhookConfig, repo db.Repository) *WebhookService {
+	return &WebhookService{
+		cfg:    cfg,
+		repo:   repo,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Start 启动分发和投递，ctx 取消后停止
+func (w *WebhookService) Start(ctx context.Context) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			if err := w.dispatch(); err != nil {
+				log.Printf("分发 webhook 事件失败: %v", err)
+			}
+			if err := w.deliver(ctx); err != nil {
+				log.Printf("投递 webhook 失败: %v", err)
+			}
+			if err := w.purge(); err != nil {
+				log.Printf("清理发件箱失败: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait 等待进行中的投递结束
+func (w *WebhookService) Wait() {
+	w.wg.Wait()
+}
+
+// dispatch 为未分发的事件生成投递记录
+func (w *WebhookService) dispatch() error {
+	for {
+		events, err := w.repo.GetUndispatchedEvents(webhookBatch)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		subs, err := w.repo.GetWebhookSubscriptions(true)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for i := range events {
+			event := &events[i]
+			var matched []uint64
+			for _, sub := range subs {
+				if webhookMatch(&sub, event) {
+					matched = append(matched, sub.ID)
+				}
+			}
+			if err := w.repo.DispatchEvent(event, matched, now); err != nil {
+				return fmt.Errorf("分发事件 %d 失败: %v", event.ID, err)
+			}
+		}
+		if len(events) < webhookBatch {
+			return nil
+		}
+	}
+}
+
+func webhookMatch(sub *db.WebhookSubscription, event *db.OutboxEvent) bool {
+	if len(sub.EventTypes) > 0 && !contains(sub.EventTypes, event.EventType) {
+		return false
+	}
+	if len(sub.ChainIDs) > 0 && !contains(sub.ChainIDs, event.ChainID) {
+		return false
+	}
+	if len(sub.Addresses) > 0 && !contains(sub.Addresses, event.UserAddr) {
+		return false
+	}
+	return true
+}
+
+// deliver 投递到期的记录，不同订阅并发投递，同一订阅按顺序投递，一条失败后该订阅本轮剩余的记录不再发送，
+// 等失败的记录重试成功或不再重试后才继续，接收方收到的事件顺序与发件箱一致。
+// 领取时每条记录按在订阅中的顺序租约到其请求超时之后，进程中断时由下一轮重新投递
+func (w *WebhookService) deliver(ctx context.Context) error {
+	for ctx.Err() == nil {
+		deliveries, err := w.repo.ClaimDueDeliveries(time.Now(), w.cfg.Timeout+webhookLeaseGap, webhookBatch)
+		if err != nil {
+			return err
+		}
+		var wg sync.WaitGroup
+		for _, queue := range groupDeliveries(deliveries) {
+			wg.Add(1)
+			go func(queue []*db.WebhookDelivery) {
+				defer wg.Done()
+				for _, d := range queue {
+					if ctx.Err() != nil {
+						return
+					}
+					if !w.send(d) {
+						return
+					}
+				}
+			}(queue)
+		}
+		wg.Wait()
+		if len(deliveries) < webhookBatch {
+			return nil
+		}
+	}
+	return nil
+}
+
+// groupDeliveries 按订阅分组，保持组内和组间的领取顺序
+func groupDeliveries(deliveries []db.WebhookDelivery) [][]*db.WebhookDelivery {
+	index := make(map[uint64]int)
+	var groups [][]*db.WebhookDelivery
+	for i := range deliveries {
+		d := &deliveries[i]
+		n, ok := index[d.SubscriptionID]
+		if !ok {
+			n = len(groups)
+			index[d.SubscriptionID] = n
+			groups = append(groups, nil)
+		}
+		groups[n] = append(groups[n], d)
+	}
+	return groups
+}
+
+// send 发送一次请求并记录结果，2xx 视为成功；返回是否可以继续投递该订阅后面的记录
+func (w *WebhookService) send(d *db.WebhookDelivery) bool {
+	attempt := &db.DeliveryAttempt{DeliveryID: d.ID, Attempt: d.Attempts + 1}
+	start := time.Now()
+	statusCode, err := w.post(d)
+	attempt.CreatedAt = time.Now()
+	attempt.Duration = attempt.CreatedAt.Sub(start)
+	attempt.StatusCode = statusCode
+
+	status := db.DeliveryDelivered
+	next := attempt.CreatedAt
+	if err != nil {
+		attempt.Error = err.Error()
+		if len(attempt.Error) > webhookMaxError {
+			attempt.Error = strings.ToValidUTF8(attempt.Error[:webhookMaxError], "")
+		}
+		status = db.DeliveryPending
+		next = attempt.CreatedAt.Add(w.backoff(attempt.Attempt))
+		if attempt.Attempt >= w.cfg.MaxAttempts {
+			status = db.DeliveryFailed
+			log.Printf("webhook 投递 %d 已失败 %d 次，不再重试: %v", d.ID, attempt.Attempt, err)
+		}
+	}
+	if err := w.repo.RecordDeliveryAttempt(attempt, status, next); err != nil {
+		log.Printf("记录 webhook 投递 %d 结果失败: %v", d.ID, err)
+		return false
+	}
+	return status != db.DeliveryPending
+}
+
+// purge 每隔 webhookPurgeGap 删除超过保留时间、所有消费者都已处理过的发件箱事件及投递记录
+func (w *WebhookService) purge() error {
+	now := time.Now()
+	if now.Sub(w.purged) < webhookPurgeGap {
+		return nil
+	}
+	w.purged = now
+	total := 0
+	for {
+		n, err := w.repo.PurgeOutbox(now.Add(-w.cfg.Retention), webhookBatch*10)
+		total += n
+		if err != nil {
+			return err
+		}
+		if n < webhookBatch*10 {
+			break
+		}
+	}
+	if total > 0 {
+		log.Printf("已清理 %d 条过期的发件箱事件", total)
+	}
+	return nil
+}
+
+func (w *WebhookService) post(d *db.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(WebhookMessage{ID: d.ID, EventType: d.EventType, CreatedAt: d.CreatedAt, Data: d.Payload})
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequest(http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+	req.Header.Set("X-Webhook-Id", strconv.FormatUint(d.SubscriptionID, 10))
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(d.ID, 10))
+	req.Header.Set("X-Webhook-Event", d.EventType)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+SignWebhook(d.Secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookMaxBody))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("HTTP %d: %s", resp.StatusCode, respBody)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff 第 n 次失败后的重试间隔
+func (w *WebhookService) backoff(n int) time.Duration {
+	delay := w.cfg.RetryBase
+	for i := 1; i < n && delay < w.cfg.RetryMax; i++ {
+		delay *= 2
+	}
+	if delay > w.cfg.RetryMax {
+		delay = w.cfg.RetryMax
+	}
+	return delay
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"POINTSTOKEN/config"
+	"POINTSTOKEN/db"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGroupDeliveries(t *testing.T) {
+	deliveries := []db.WebhookDelivery{
+		{ID: 1, SubscriptionID: 10},
+		{ID: 2, SubscriptionID: 20},
+		{ID: 3, SubscriptionID: 10},
+		{ID: 4, SubscriptionID: 30},
+		{ID: 5, SubscriptionID: 20},
+	}
+	groups := groupDeliveries(deliveries)
+	want := [][]uint64{{1, 3}, {2, 5}, {4}}
+	if len(groups) != len(want) {
+		t.Fatalf("got %d groups, want %d", len(groups), len(want))
+	}
+	for i, group := range groups {
+		if len(group) != len(want[i]) {
+			t.Fatalf("group %d has %d deliveries, want %d", i, len(group), len(want[i]))
+		}
+		for j, d := range group {
+			if d.ID != want[i][j] {
+				t.Errorf("group %d[%d] = %d, want %d", i, j, d.ID, want[i][j])
+			}
+		}
+	}
+}
+
+func TestSignWebhook(t *testing.T) {
+	//hex(HMAC-SHA256("secret", "1700000000.{}"))
+	want := "b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163"
+	if got := SignWebhook("secret", "1700000000", []byte("{}")); got != want {
+		t.Errorf("SignWebhook = %s, want %s", got, want)
+	}
+}
+
+// deliveryRepo 第一次领取返回全部投递记录，之后返回空，记录每次投递结果
+type deliveryRepo struct {
+	db.Repository
+	mu         sync.Mutex
+	deliveries []db.WebhookDelivery
+	results    map[uint64]string
+}
+
+func (r *deliveryRepo) ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) ([]db.WebhookDelivery, error) {
+	deliveries := r.deliveries
+	r.deliveries = nil
+	return deliveries, nil
+}
+
+func (r *deliveryRepo) RecordDeliveryAttempt(attempt *db.DeliveryAttempt, status string, nextAttemptAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results[attempt.DeliveryID] = status
+	return nil
+}
+
+func TestDeliverStopsSubscriptionAtFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//订阅 10 的第一条投递失败
+		if r.Header.Get("X-Webhook-Delivery") == "1" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	repo := &deliveryRepo{results: make(map[uint64]string)}
+	for _, d := range []struct{ id, sub uint64 }{{1, 10}, {2, 20}, {3, 10}, {4, 20}} {
+		repo.deliveries = append(repo.deliveries, db.WebhookDelivery{ID: d.id, SubscriptionID: d.sub, URL: server.URL, Payload: []byte("{}")})
+	}
+	w := NewWebhookService(config.WebhookConfig{Timeout: time.Second, MaxAttempts: 3, RetryBase: time.Second, RetryMax: time.Minute}, repo)
+	if err := w.deliver(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[uint64]string{1: db.DeliveryPending, 2: db.DeliveryDelivered, 4: db.DeliveryDelivered}
+	if len(repo.results) != len(want) {
+		t.Fatalf("results = %v, want %v", repo.results, want)
+	}
+	for id, status := range want {
+		if repo.results[id] != status {
+			t.Errorf("delivery %d = %q, want %q", id, repo.results[id], status)
+		}
+	}
+}